@@ -21,10 +21,23 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Secret_Create_FullMethodName     = "/gophkeeper.Secret/Create"
-	Secret_GetSecrets_FullMethodName = "/gophkeeper.Secret/GetSecrets"
-	Secret_Update_FullMethodName     = "/gophkeeper.Secret/Update"
-	Secret_Delete_FullMethodName     = "/gophkeeper.Secret/Delete"
+	Secret_Create_FullMethodName         = "/gophkeeper.Secret/Create"
+	Secret_ReserveID_FullMethodName      = "/gophkeeper.Secret/ReserveID"
+	Secret_GetSecrets_FullMethodName     = "/gophkeeper.Secret/GetSecrets"
+	Secret_Update_FullMethodName         = "/gophkeeper.Secret/Update"
+	Secret_Delete_FullMethodName         = "/gophkeeper.Secret/Delete"
+	Secret_ListTrash_FullMethodName      = "/gophkeeper.Secret/ListTrash"
+	Secret_Restore_FullMethodName        = "/gophkeeper.Secret/Restore"
+	Secret_Purge_FullMethodName          = "/gophkeeper.Secret/Purge"
+	Secret_Sync_FullMethodName           = "/gophkeeper.Secret/Sync"
+	Secret_Share_FullMethodName          = "/gophkeeper.Secret/Share"
+	Secret_Unshare_FullMethodName        = "/gophkeeper.Secret/Unshare"
+	Secret_ListVersions_FullMethodName   = "/gophkeeper.Secret/ListVersions"
+	Secret_RestoreVersion_FullMethodName = "/gophkeeper.Secret/RestoreVersion"
+	Secret_FindByDomain_FullMethodName   = "/gophkeeper.Secret/FindByDomain"
+	Secret_Watch_FullMethodName          = "/gophkeeper.Secret/Watch"
+	Secret_UploadBlob_FullMethodName     = "/gophkeeper.Secret/UploadBlob"
+	Secret_DownloadBlob_FullMethodName   = "/gophkeeper.Secret/DownloadBlob"
 )
 
 // SecretClient is the client API for Secret service.
@@ -32,9 +45,49 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SecretClient interface {
 	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ReserveID reserves the ID of a secret to create. Reservations which are not used
+	// by Create expire after a day.
+	ReserveID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ReserveIDResponse, error)
 	GetSecrets(ctx context.Context, in *GetSecretsRequest, opts ...grpc.CallOption) (*GetSecretsResponse, error)
 	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Delete moves a secret to the trash. Other devices learn about it as about a deletion.
+	// The secrets in the trash are purged once they are older than the retention period.
 	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error)
+	// Restore moves a secret out of the trash. Other devices learn about it on sync.
+	Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Purge removes a secret in the trash permanently along with its versions and blob.
+	Purge(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error)
+	// Share grants another user access to a secret or changes the access granted before.
+	// The server keeps a copy of the secret encrypted with the key of the user, so sharing
+	// is not available with client-side encryption of either user and fails with
+	// FAILED_PRECONDITION. Binary secrets cannot be shared. Only the owner can share a secret.
+	Share(ctx context.Context, in *ShareRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Unshare revokes the access to a secret granted to another user.
+	Unshare(ctx context.Context, in *UnshareRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListVersions returns the previous versions of a secret kept by the server. Versions
+	// are removed once they are older than the retention period or there are too many of them.
+	ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error)
+	// RestoreVersion makes a previous version of a secret current again. The restore is an update
+	// of the secret, so the replaced version is kept and other devices learn about it on sync.
+	RestoreVersion(ctx context.Context, in *RestoreVersionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// FindByDomain finds the credentials of a website for autofill. The domains and the logins
+	// of credentials are encrypted, they are looked up by keyed hashes stored along with them.
+	// Credentials stored before the hashes were introduced are found once they are updated or
+	// the upgrade-secrets command of the server is run.
+	// The lookup is not available with client-side encryption and fails with FAILED_PRECONDITION.
+	FindByDomain(ctx context.Context, in *FindByDomainRequest, opts ...grpc.CallOption) (*FindByDomainResponse, error)
+	// Watch streams the changes of the user's secrets made on any device. The stream ends
+	// with UNAVAILABLE when events may have been missed and with UNAUTHENTICATED when
+	// the token expires; the client should sync and watch again.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Secret_WatchClient, error)
+	// UploadBlob stores a file sent in chunks and returns the ID of the blob, which is then
+	// attached to a binary secret with Create or Update. A blob that is not attached to
+	// a secret within a day is removed. The chunks are returned by DownloadBlob as they
+	// were sent, so chunks encrypted by the client can be decrypted one by one.
+	UploadBlob(ctx context.Context, opts ...grpc.CallOption) (Secret_UploadBlobClient, error)
+	DownloadBlob(ctx context.Context, in *DownloadBlobRequest, opts ...grpc.CallOption) (Secret_DownloadBlobClient, error)
 }
 
 type secretClient struct {
@@ -54,6 +107,15 @@ func (c *secretClient) Create(ctx context.Context, in *CreateRequest, opts ...gr
 	return out, nil
 }
 
+func (c *secretClient) ReserveID(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ReserveIDResponse, error) {
+	out := new(ReserveIDResponse)
+	err := c.cc.Invoke(ctx, Secret_ReserveID_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *secretClient) GetSecrets(ctx context.Context, in *GetSecretsRequest, opts ...grpc.CallOption) (*GetSecretsResponse, error) {
 	out := new(GetSecretsResponse)
 	err := c.cc.Invoke(ctx, Secret_GetSecrets_FullMethodName, in, out, opts...)
@@ -81,14 +143,233 @@ func (c *secretClient) Delete(ctx context.Context, in *DeleteRequest, opts ...gr
 	return out, nil
 }
 
+func (c *secretClient) ListTrash(ctx context.Context, in *ListTrashRequest, opts ...grpc.CallOption) (*ListTrashResponse, error) {
+	out := new(ListTrashResponse)
+	err := c.cc.Invoke(ctx, Secret_ListTrash_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Restore(ctx context.Context, in *RestoreRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Secret_Restore_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Purge(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Secret_Purge_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*SyncResponse, error) {
+	out := new(SyncResponse)
+	err := c.cc.Invoke(ctx, Secret_Sync_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Share(ctx context.Context, in *ShareRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Secret_Share_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Unshare(ctx context.Context, in *UnshareRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Secret_Unshare_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) ListVersions(ctx context.Context, in *ListVersionsRequest, opts ...grpc.CallOption) (*ListVersionsResponse, error) {
+	out := new(ListVersionsResponse)
+	err := c.cc.Invoke(ctx, Secret_ListVersions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) RestoreVersion(ctx context.Context, in *RestoreVersionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Secret_RestoreVersion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) FindByDomain(ctx context.Context, in *FindByDomainRequest, opts ...grpc.CallOption) (*FindByDomainResponse, error) {
+	out := new(FindByDomainResponse)
+	err := c.cc.Invoke(ctx, Secret_FindByDomain_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Secret_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Secret_ServiceDesc.Streams[0], Secret_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secretWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Secret_WatchClient interface {
+	Recv() (*SecretEvent, error)
+	grpc.ClientStream
+}
+
+type secretWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *secretWatchClient) Recv() (*SecretEvent, error) {
+	m := new(SecretEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *secretClient) UploadBlob(ctx context.Context, opts ...grpc.CallOption) (Secret_UploadBlobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Secret_ServiceDesc.Streams[1], Secret_UploadBlob_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secretUploadBlobClient{stream}
+	return x, nil
+}
+
+type Secret_UploadBlobClient interface {
+	Send(*UploadBlobRequest) error
+	CloseAndRecv() (*UploadBlobResponse, error)
+	grpc.ClientStream
+}
+
+type secretUploadBlobClient struct {
+	grpc.ClientStream
+}
+
+func (x *secretUploadBlobClient) Send(m *UploadBlobRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *secretUploadBlobClient) CloseAndRecv() (*UploadBlobResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadBlobResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *secretClient) DownloadBlob(ctx context.Context, in *DownloadBlobRequest, opts ...grpc.CallOption) (Secret_DownloadBlobClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Secret_ServiceDesc.Streams[2], Secret_DownloadBlob_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &secretDownloadBlobClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Secret_DownloadBlobClient interface {
+	Recv() (*BlobChunk, error)
+	grpc.ClientStream
+}
+
+type secretDownloadBlobClient struct {
+	grpc.ClientStream
+}
+
+func (x *secretDownloadBlobClient) Recv() (*BlobChunk, error) {
+	m := new(BlobChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // SecretServer is the server API for Secret service.
 // All implementations must embed UnimplementedSecretServer
 // for forward compatibility
 type SecretServer interface {
 	Create(context.Context, *CreateRequest) (*emptypb.Empty, error)
+	// ReserveID reserves the ID of a secret to create. Reservations which are not used
+	// by Create expire after a day.
+	ReserveID(context.Context, *emptypb.Empty) (*ReserveIDResponse, error)
 	GetSecrets(context.Context, *GetSecretsRequest) (*GetSecretsResponse, error)
 	Update(context.Context, *UpdateRequest) (*emptypb.Empty, error)
+	// Delete moves a secret to the trash. Other devices learn about it as about a deletion.
+	// The secrets in the trash are purged once they are older than the retention period.
 	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error)
+	// Restore moves a secret out of the trash. Other devices learn about it on sync.
+	Restore(context.Context, *RestoreRequest) (*emptypb.Empty, error)
+	// Purge removes a secret in the trash permanently along with its versions and blob.
+	Purge(context.Context, *PurgeRequest) (*emptypb.Empty, error)
+	Sync(context.Context, *SyncRequest) (*SyncResponse, error)
+	// Share grants another user access to a secret or changes the access granted before.
+	// The server keeps a copy of the secret encrypted with the key of the user, so sharing
+	// is not available with client-side encryption of either user and fails with
+	// FAILED_PRECONDITION. Binary secrets cannot be shared. Only the owner can share a secret.
+	Share(context.Context, *ShareRequest) (*emptypb.Empty, error)
+	// Unshare revokes the access to a secret granted to another user.
+	Unshare(context.Context, *UnshareRequest) (*emptypb.Empty, error)
+	// ListVersions returns the previous versions of a secret kept by the server. Versions
+	// are removed once they are older than the retention period or there are too many of them.
+	ListVersions(context.Context, *ListVersionsRequest) (*ListVersionsResponse, error)
+	// RestoreVersion makes a previous version of a secret current again. The restore is an update
+	// of the secret, so the replaced version is kept and other devices learn about it on sync.
+	RestoreVersion(context.Context, *RestoreVersionRequest) (*emptypb.Empty, error)
+	// FindByDomain finds the credentials of a website for autofill. The domains and the logins
+	// of credentials are encrypted, they are looked up by keyed hashes stored along with them.
+	// Credentials stored before the hashes were introduced are found once they are updated or
+	// the upgrade-secrets command of the server is run.
+	// The lookup is not available with client-side encryption and fails with FAILED_PRECONDITION.
+	FindByDomain(context.Context, *FindByDomainRequest) (*FindByDomainResponse, error)
+	// Watch streams the changes of the user's secrets made on any device. The stream ends
+	// with UNAVAILABLE when events may have been missed and with UNAUTHENTICATED when
+	// the token expires; the client should sync and watch again.
+	Watch(*WatchRequest, Secret_WatchServer) error
+	// UploadBlob stores a file sent in chunks and returns the ID of the blob, which is then
+	// attached to a binary secret with Create or Update. A blob that is not attached to
+	// a secret within a day is removed. The chunks are returned by DownloadBlob as they
+	// were sent, so chunks encrypted by the client can be decrypted one by one.
+	UploadBlob(Secret_UploadBlobServer) error
+	DownloadBlob(*DownloadBlobRequest, Secret_DownloadBlobServer) error
 	mustEmbedUnimplementedSecretServer()
 }
 
@@ -99,6 +380,9 @@ type UnimplementedSecretServer struct {
 func (UnimplementedSecretServer) Create(context.Context, *CreateRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
 }
+func (UnimplementedSecretServer) ReserveID(context.Context, *emptypb.Empty) (*ReserveIDResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveID not implemented")
+}
 func (UnimplementedSecretServer) GetSecrets(context.Context, *GetSecretsRequest) (*GetSecretsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetSecrets not implemented")
 }
@@ -108,6 +392,42 @@ func (UnimplementedSecretServer) Update(context.Context, *UpdateRequest) (*empty
 func (UnimplementedSecretServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedSecretServer) ListTrash(context.Context, *ListTrashRequest) (*ListTrashResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTrash not implemented")
+}
+func (UnimplementedSecretServer) Restore(context.Context, *RestoreRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restore not implemented")
+}
+func (UnimplementedSecretServer) Purge(context.Context, *PurgeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Purge not implemented")
+}
+func (UnimplementedSecretServer) Sync(context.Context, *SyncRequest) (*SyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedSecretServer) Share(context.Context, *ShareRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Share not implemented")
+}
+func (UnimplementedSecretServer) Unshare(context.Context, *UnshareRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unshare not implemented")
+}
+func (UnimplementedSecretServer) ListVersions(context.Context, *ListVersionsRequest) (*ListVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListVersions not implemented")
+}
+func (UnimplementedSecretServer) RestoreVersion(context.Context, *RestoreVersionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreVersion not implemented")
+}
+func (UnimplementedSecretServer) FindByDomain(context.Context, *FindByDomainRequest) (*FindByDomainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindByDomain not implemented")
+}
+func (UnimplementedSecretServer) Watch(*WatchRequest, Secret_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedSecretServer) UploadBlob(Secret_UploadBlobServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadBlob not implemented")
+}
+func (UnimplementedSecretServer) DownloadBlob(*DownloadBlobRequest, Secret_DownloadBlobServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadBlob not implemented")
+}
 func (UnimplementedSecretServer) mustEmbedUnimplementedSecretServer() {}
 
 // UnsafeSecretServer may be embedded to opt out of forward compatibility for this service.
@@ -139,6 +459,24 @@ func _Secret_Create_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Secret_ReserveID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).ReserveID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_ReserveID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).ReserveID(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Secret_GetSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetSecretsRequest)
 	if err := dec(in); err != nil {
@@ -193,6 +531,236 @@ func _Secret_Delete_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Secret_ListTrash_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTrashRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).ListTrash(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_ListTrash_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).ListTrash(ctx, req.(*ListTrashRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Restore_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).Restore(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_Restore_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).Restore(ctx, req.(*RestoreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Purge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).Purge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_Purge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).Purge(ctx, req.(*PurgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Share_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).Share(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_Share_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).Share(ctx, req.(*ShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Unshare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnshareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).Unshare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_Unshare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).Unshare(ctx, req.(*UnshareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_ListVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).ListVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_ListVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).ListVersions(ctx, req.(*ListVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_RestoreVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).RestoreVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_RestoreVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).RestoreVersion(ctx, req.(*RestoreVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_FindByDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindByDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretServer).FindByDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Secret_FindByDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretServer).FindByDomain(ctx, req.(*FindByDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Secret_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SecretServer).Watch(m, &secretWatchServer{stream})
+}
+
+type Secret_WatchServer interface {
+	Send(*SecretEvent) error
+	grpc.ServerStream
+}
+
+type secretWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *secretWatchServer) Send(m *SecretEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Secret_UploadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SecretServer).UploadBlob(&secretUploadBlobServer{stream})
+}
+
+type Secret_UploadBlobServer interface {
+	SendAndClose(*UploadBlobResponse) error
+	Recv() (*UploadBlobRequest, error)
+	grpc.ServerStream
+}
+
+type secretUploadBlobServer struct {
+	grpc.ServerStream
+}
+
+func (x *secretUploadBlobServer) SendAndClose(m *UploadBlobResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *secretUploadBlobServer) Recv() (*UploadBlobRequest, error) {
+	m := new(UploadBlobRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Secret_DownloadBlob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadBlobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SecretServer).DownloadBlob(m, &secretDownloadBlobServer{stream})
+}
+
+type Secret_DownloadBlobServer interface {
+	Send(*BlobChunk) error
+	grpc.ServerStream
+}
+
+type secretDownloadBlobServer struct {
+	grpc.ServerStream
+}
+
+func (x *secretDownloadBlobServer) Send(m *BlobChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Secret_ServiceDesc is the grpc.ServiceDesc for Secret service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -204,6 +772,10 @@ var Secret_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Create",
 			Handler:    _Secret_Create_Handler,
 		},
+		{
+			MethodName: "ReserveID",
+			Handler:    _Secret_ReserveID_Handler,
+		},
 		{
 			MethodName: "GetSecrets",
 			Handler:    _Secret_GetSecrets_Handler,
@@ -216,7 +788,59 @@ var Secret_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _Secret_Delete_Handler,
 		},
+		{
+			MethodName: "ListTrash",
+			Handler:    _Secret_ListTrash_Handler,
+		},
+		{
+			MethodName: "Restore",
+			Handler:    _Secret_Restore_Handler,
+		},
+		{
+			MethodName: "Purge",
+			Handler:    _Secret_Purge_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Secret_Sync_Handler,
+		},
+		{
+			MethodName: "Share",
+			Handler:    _Secret_Share_Handler,
+		},
+		{
+			MethodName: "Unshare",
+			Handler:    _Secret_Unshare_Handler,
+		},
+		{
+			MethodName: "ListVersions",
+			Handler:    _Secret_ListVersions_Handler,
+		},
+		{
+			MethodName: "RestoreVersion",
+			Handler:    _Secret_RestoreVersion_Handler,
+		},
+		{
+			MethodName: "FindByDomain",
+			Handler:    _Secret_FindByDomain_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Secret_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadBlob",
+			Handler:       _Secret_UploadBlob_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadBlob",
+			Handler:       _Secret_DownloadBlob_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/secret.proto",
 }
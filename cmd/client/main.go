@@ -35,7 +35,7 @@ func main() {
 	authClient := pb.NewAuthClient(conn)
 	secretsClient := pb.NewSecretClient(conn)
 
-	ui := tui.NewApplication(authClient, secretsClient)
+	ui := tui.NewApplication(authClient, secretsClient, cfg)
 
 	if err := ui.App.SetRoot(ui.Pages, true).EnableMouse(true).Run(); err != nil {
 		log.Fatal().Err(err).Msg("client error")
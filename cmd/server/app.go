@@ -11,9 +11,11 @@ import (
 )
 
 // Application holds the gRPC server, logger and the address of the server.
+// Stop stops the background jobs of the server, which also ends the open streams.
 type Application struct {
 	server  *grpc.Server
 	log     *zerolog.Logger
+	stop    func()
 	address string
 }
 
@@ -22,10 +24,12 @@ func NewApplication(
 	server *grpc.Server,
 	log *zerolog.Logger,
 	address string,
+	stop func(),
 ) Application {
 	return Application{
 		server:  server,
 		log:     log,
+		stop:    stop,
 		address: address,
 	}
 }
@@ -44,6 +48,8 @@ func (app Application) RunServer() {
 		signal.Notify(sigint, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 		<-sigint
 
+		// Streams never end on their own, so they are ended before the graceful stop waits for them.
+		app.stop()
 		app.server.GracefulStop()
 
 		close(idleConnsClosed)
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -8,6 +9,7 @@ import (
 	"google.golang.org/grpc/credentials"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/blobstore"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/config"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/handlers"
@@ -33,19 +35,91 @@ func main() {
 	}
 	defer pgPool.Close()
 
-	jwtManager := jwt.NewJWTManager(cfg.Server.Secret)
-	cryptoSrvc := encryption.NewCryptoService(cfg.Server.Secret)
+	jwtManager := jwt.NewJWTManager(cfg.Server.JWTSecret, cfg.Server.AccessTokenTTL)
+	cryptoSrvc := encryption.NewCryptoService(
+		cfg.Server.Secret,
+		cfg.Server.SecretVersion,
+		cfg.Server.PreviousSecret,
+		cfg.Server.LegacyCipherTexts,
+	)
 
 	authRepo := repository.NewAuthRepository(pgPool)
 	secretRepo := repository.NewSecretRepository(pgPool)
+	keyRepo := repository.NewKeyRepository(pgPool)
+	tokenRepo := repository.NewTokenRepository(pgPool)
+	sessionRepo := repository.NewSessionRepository(pgPool)
+	blobRepo := repository.NewBlobRepository(pgPool)
+	orgRepo := repository.NewOrganizationRepository(pgPool)
 
-	authService := services.NewAuthService(authRepo, &log, jwtManager)
-	secretService := services.NewSecretService(secretRepo, &log, cryptoSrvc)
+	blobStore, err := blobstore.New(context.Background(), cfg.Server, pgPool)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to setup blob store")
+	}
+
+	keyService := services.NewKeyService(keyRepo, &log, cryptoSrvc)
+
+	if len(os.Args) > 1 && os.Args[1] == rotateKeyCommand {
+		rotateKey(keyService, &log)
+
+		return
+	}
+
+	authService := services.NewAuthService(
+		authRepo,
+		tokenRepo,
+		sessionRepo,
+		&log,
+		jwtManager,
+		keyService,
+		cryptoSrvc,
+		cfg.Server.RefreshTokenTTL,
+		services.LockoutPolicy{
+			MaxFailedLogins: cfg.Server.MaxFailedLogins,
+			Duration:        cfg.Server.LockoutDuration,
+		},
+	)
+	secretBroker := services.NewSecretBroker(repository.NewSecretListener(pgPool), &log)
+	secretService := services.NewSecretService(
+		secretRepo,
+		&log,
+		cryptoSrvc,
+		keyService,
+		secretBroker,
+		services.HistoryRetention{
+			MaxAge:      cfg.Server.HistoryMaxAge,
+			MaxVersions: cfg.Server.HistoryMaxVersions,
+		},
+		cfg.Server.TrashRetention,
+	)
+
+	blobService := services.NewBlobService(
+		blobRepo,
+		blobStore,
+		&log,
+		cryptoSrvc,
+		keyService,
+		cfg.Server.MaxBlobSize,
+		cfg.Server.LegacyCipherTexts,
+	)
+
+	if len(os.Args) > 1 && os.Args[1] == upgradeSecretsCommand {
+		upgradeSecrets(secretService, blobService, &log)
+
+		return
+	}
+
+	organizationService := services.NewOrganizationService(orgRepo, &log, cryptoSrvc)
 
 	authHandler := handlers.NewAuthHandler(authService, &log)
-	secretHandler := handlers.NewSecretHandler(secretService, &log)
+	secretHandler := handlers.NewSecretHandler(secretService, blobService, &log)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService, &log)
 
-	authInterceptor := interceptors.NewAuthInterceptor(jwtManager)
+	authInterceptor := interceptors.NewAuthInterceptor(jwtManager, authService)
+	rateLimitInterceptor := interceptors.NewRateLimitInterceptor(
+		jwtManager,
+		interceptors.RateLimit{PerMinute: cfg.Server.IPRateLimit, Burst: cfg.Server.IPRateBurst},
+		interceptors.RateLimit{PerMinute: cfg.Server.LoginRateLimit, Burst: cfg.Server.LoginRateBurst},
+	)
 
 	creds, err := credentials.NewServerTLSFromFile(cfg.Server.CertPath, cfg.Server.KeyPath)
 	if err != nil {
@@ -54,15 +128,29 @@ func main() {
 
 	opts := []grpc.ServerOption{
 		grpc.Creds(creds),
-		grpc.UnaryInterceptor(authInterceptor.UnaryServerInterceptor),
+		grpc.ChainUnaryInterceptor(
+			authInterceptor.UnaryServerInterceptor,
+			rateLimitInterceptor.UnaryServerInterceptor,
+		),
+		grpc.ChainStreamInterceptor(
+			authInterceptor.StreamServerInterceptor,
+		),
 	}
 
 	server := grpc.NewServer(opts...)
 
 	pb.RegisterAuthServer(server, authHandler)
 	pb.RegisterSecretServer(server, secretHandler)
+	pb.RegisterOrganizationServer(server, organizationHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go secretBroker.Run(ctx)
+	go blobService.Run(ctx)
+	go secretService.Run(ctx)
 
-	app := NewApplication(server, &log, fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port))
+	app := NewApplication(server, &log, fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port), cancel)
 
 	app.RunServer()
 }
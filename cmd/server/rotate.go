@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
+)
+
+const (
+	rotateKeyCommand   = "rotate-key"
+	rotateKeyBatchSize = 100
+)
+
+// rotateKey re-wraps the keys of all users and organizations with the current master key.
+// It is started with the rotate-key argument once the new master secret is configured.
+func rotateKey(keyService services.KeyService, log *zerolog.Logger) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	processed, err := keyService.RotateMasterKey(ctx, rotateKeyBatchSize)
+	if err != nil {
+		log.Error().Err(err).Int("processed", processed).Msg("master key rotation was interrupted, run it again to resume")
+		stop()
+		os.Exit(1)
+	}
+
+	log.Info().Int("processed", processed).Msg("master key rotation completed")
+}
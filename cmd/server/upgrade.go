@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
+)
+
+const (
+	upgradeSecretsCommand   = "upgrade-secrets"
+	upgradeSecretsBatchSize = 100
+)
+
+// upgradeSecrets re-encrypts the secrets and the files of binary secrets stored in the legacy
+// formats and stores the missing hashes of the files and blind indexes of the credentials.
+// It is started with the upgrade-secrets argument, after which the fallback to the legacy
+// formats can be turned off with GKEEPER_LEGACY_CIPHER_TEXTS.
+func upgradeSecrets(secretService services.SecretService, blobService services.BlobService, log *zerolog.Logger) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	upgraded, err := secretService.UpgradeSecrets(ctx, upgradeSecretsBatchSize)
+	if err != nil {
+		log.Error().Err(err).Int("upgraded", upgraded).Msg("secrets upgrade was interrupted, run it again to resume")
+		stop()
+		os.Exit(1)
+	}
+
+	log.Info().Int("upgraded", upgraded).Msg("secrets upgrade completed")
+
+	upgraded, err = blobService.UpgradeBlobs(ctx, upgradeSecretsBatchSize)
+	if err != nil {
+		log.Error().Err(err).Int("upgraded", upgraded).Msg("blobs upgrade was interrupted, run it again to resume")
+		stop()
+		os.Exit(1)
+	}
+
+	log.Info().Int("upgraded", upgraded).Msg("blobs upgrade completed")
+}
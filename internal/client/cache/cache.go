@@ -0,0 +1,295 @@
+// Package cache implements the encrypted on-disk copy of the user's secrets, which lets
+// the client show the secrets and record changes while the server is unreachable.
+package cache
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+const (
+	magic    = "GKC1"
+	saltLen  = 16
+	keyLen   = 32
+	fileExt  = ".cache"
+	filePerm = 0o600
+	dirPerm  = 0o700
+
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+var (
+	// ErrNotFound is returned by Load when there is no cache of the user.
+	ErrNotFound = errors.New("cache not found")
+	// ErrInvalidPassword is returned by Load when the cache cannot be decrypted with the password.
+	ErrInvalidPassword = errors.New("cache cannot be decrypted with the password")
+)
+
+// Cache is the encrypted file holding the State of one user of one server.
+// It is encrypted with a key derived from the master password of the user,
+// so it can be opened without the server.
+type Cache struct {
+	aead cipher.AEAD
+	path string
+	name string
+	salt []byte
+}
+
+// Open returns the cache of the user in the directory. The name identifies the user
+// and the server, the password is the master password of the user. If the cache exists,
+// its key is derived from the password, otherwise a new key is derived for it.
+func Open(dir, name, password string) (*Cache, error) {
+	sum := sha256.Sum256([]byte(name))
+
+	c := &Cache{
+		path: filepath.Join(dir, hex.EncodeToString(sum[:])+fileExt),
+		name: name,
+	}
+
+	salt, err := c.readSalt()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := c.setKey(password, salt); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Load reads and decrypts the state stored in the cache.
+// ErrNotFound is returned if nothing was saved yet.
+func (c *Cache) Load() (*State, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	nonceStart := len(magic) + saltLen
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceStart+nonceSize || string(data[:len(magic)]) != magic ||
+		!bytes.Equal(data[len(magic):nonceStart], c.salt) {
+		return nil, ErrInvalidPassword
+	}
+
+	plain, err := c.aead.Open(nil, data[nonceStart:nonceStart+nonceSize], data[nonceStart+nonceSize:], []byte(c.name))
+	if err != nil {
+		return nil, ErrInvalidPassword
+	}
+
+	return decodeState(plain)
+}
+
+// Save encrypts the state and replaces the cache with it.
+func (c *Cache) Save(state *State) error {
+	plain, err := encodeState(state)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	data := make([]byte, 0, len(magic)+saltLen+len(nonce)+len(plain)+c.aead.Overhead())
+	data = append(data, magic...)
+	data = append(data, c.salt...)
+	data = append(data, nonce...)
+	data = c.aead.Seal(data, nonce, plain, []byte(c.name))
+
+	if err := os.MkdirAll(filepath.Dir(c.path), dirPerm); err != nil {
+		return err
+	}
+
+	// The cache is replaced atomically, so a crash never leaves it half-written.
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), filePerm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path)
+}
+
+// Rekey derives a new key of the cache from the new password. The cache keeps
+// the old key on disk until the next Save.
+func (c *Cache) Rekey(password string) error {
+	return c.setKey(password, nil)
+}
+
+// Remove deletes the cache from disk.
+func (c *Cache) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Cache) readSalt() ([]byte, error) {
+	f, err := os.Open(c.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(magic)+saltLen)
+	if _, err := io.ReadFull(f, header); err != nil || string(header[:len(magic)]) != magic {
+		// A damaged cache is overwritten by the next Save.
+		return nil, ErrNotFound
+	}
+
+	return header[len(magic):], nil
+}
+
+// setKey derives the key of the cache from the password. A new random salt
+// is generated if the salt is nil.
+func (c *Cache) setKey(password string, salt []byte) error {
+	if salt == nil {
+		salt = make([]byte, saltLen)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, keyLen)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	c.aead = aead
+	c.salt = salt
+
+	return nil
+}
+
+// fileState is the serialized form of State.
+type fileState struct {
+	Secrets  [][]byte        `json:"secrets"`
+	Pending  []fileOperation `json:"pending"`
+	Revision int64           `json:"revision"`
+	NextID   int64           `json:"next_id"`
+}
+
+type fileOperation struct {
+	Kind   string `json:"kind"`
+	Secret []byte `json:"secret"`
+	Base   []byte `json:"base,omitempty"`
+}
+
+func encodeState(state *State) ([]byte, error) {
+	fs := fileState{
+		Secrets:  make([][]byte, len(state.Secrets)),
+		Pending:  make([]fileOperation, len(state.Pending)),
+		Revision: state.Revision,
+		NextID:   state.nextID,
+	}
+
+	var err error
+	for i, secret := range state.Secrets {
+		if fs.Secrets[i], err = proto.Marshal(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, op := range state.Pending {
+		fs.Pending[i].Kind = op.Kind
+
+		if fs.Pending[i].Secret, err = proto.Marshal(op.Secret); err != nil {
+			return nil, err
+		}
+
+		if op.Base != nil {
+			if fs.Pending[i].Base, err = proto.Marshal(op.Base); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return json.Marshal(fs)
+}
+
+func decodeState(data []byte) (*State, error) {
+	var fs fileState
+	if err := json.Unmarshal(data, &fs); err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		Secrets:  make([]*pb.SecretData, len(fs.Secrets)),
+		Pending:  make([]Operation, len(fs.Pending)),
+		Revision: fs.Revision,
+		nextID:   fs.NextID,
+	}
+
+	for i, data := range fs.Secrets {
+		state.Secrets[i] = &pb.SecretData{}
+		if err := proto.Unmarshal(data, state.Secrets[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, op := range fs.Pending {
+		state.Pending[i] = Operation{Kind: op.Kind, Secret: &pb.SecretData{}}
+		if err := proto.Unmarshal(op.Secret, state.Pending[i].Secret); err != nil {
+			return nil, err
+		}
+
+		if op.Base != nil {
+			state.Pending[i].Base = &pb.SecretData{}
+			if err := proto.Unmarshal(op.Base, state.Pending[i].Base); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return state, nil
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func testState() *State {
+	secret := &pb.SecretData{
+		Id:       1,
+		Type:     pb.SecretType_TEXT,
+		MetaData: "meta",
+		Version:  2,
+		Payload:  &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "text"}}},
+	}
+
+	state := &State{Secrets: []*pb.SecretData{secret}, Revision: 5}
+	state.Queue(Operation{Kind: OpUpdate, Secret: secret, Base: secret})
+	state.Queue(Operation{Kind: OpCreate, Secret: &pb.SecretData{Type: pb.SecretType_TEXT}})
+
+	return state
+}
+
+func TestCache_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir, "user@server", "password")
+	assert.NoError(t, err)
+
+	_, err = c.Load()
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	state := testState()
+	assert.NoError(t, c.Save(state))
+
+	reopened, err := Open(dir, "user@server", "password")
+	assert.NoError(t, err)
+
+	loaded, err := reopened.Load()
+	if assert.NoError(t, err) {
+		assert.Equal(t, state.Revision, loaded.Revision)
+		assert.Equal(t, state.nextID, loaded.nextID)
+		assert.Len(t, loaded.Secrets, 1)
+		assert.True(t, proto.Equal(state.Secrets[0], loaded.Secrets[0]))
+		assert.Len(t, loaded.Pending, 2)
+		assert.True(t, proto.Equal(state.Pending[0].Base, loaded.Pending[0].Base))
+		assert.True(t, proto.Equal(state.Pending[1].Secret, loaded.Pending[1].Secret))
+		assert.Nil(t, loaded.Pending[1].Base)
+	}
+
+	info, err := os.Stat(c.path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, os.FileMode(filePerm), info.Mode().Perm())
+	}
+}
+
+func TestCache_InvalidPassword(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir, "user@server", "password")
+	assert.NoError(t, err)
+	assert.NoError(t, c.Save(testState()))
+
+	wrong, err := Open(dir, "user@server", "wrong")
+	assert.NoError(t, err)
+
+	_, err = wrong.Load()
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	other, err := Open(dir, "other@server", "password")
+	assert.NoError(t, err)
+
+	_, err = other.Load()
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCache_Rekey(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := Open(dir, "user@server", "password")
+	assert.NoError(t, err)
+	assert.NoError(t, c.Save(testState()))
+
+	assert.NoError(t, c.Rekey("new-password"))
+	assert.NoError(t, c.Save(testState()))
+
+	old, err := Open(dir, "user@server", "password")
+	assert.NoError(t, err)
+
+	_, err = old.Load()
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+
+	rekeyed, err := Open(dir, "user@server", "new-password")
+	assert.NoError(t, err)
+
+	_, err = rekeyed.Load()
+	assert.NoError(t, err)
+
+	assert.NoError(t, rekeyed.Remove())
+	assert.NoError(t, rekeyed.Remove())
+
+	_, err = rekeyed.Load()
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// Kinds of the operations made while the server is unreachable.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Operation is a change of a secret made while the server was unreachable. Secret is the plain
+// secret the change results in, Base is the copy of the secret the update was made on. The version
+// of Base is the version the change is based on, so the change is rejected if the secret was
+// changed on another device in the meantime.
+type Operation struct {
+	Secret *pb.SecretData
+	Base   *pb.SecretData
+	Kind   string
+}
+
+// State is the copy of the user's secrets fetched at Revision and the operations
+// made on them since which are not sent to the server yet.
+type State struct {
+	Secrets  []*pb.SecretData
+	Pending  []Operation
+	Revision int64
+	nextID   int64
+}
+
+// ApplyChanges applies the changes fetched by sync to the secrets
+// and keeps them ordered by creation time.
+func (s *State) ApplyChanges(changes *pb.SyncResponse) {
+	byID := make(map[int64]*pb.SecretData, len(s.Secrets)+len(changes.Secrets))
+	for _, secret := range s.Secrets {
+		byID[secret.Id] = secret
+	}
+
+	for _, secret := range changes.Secrets {
+		byID[secret.Id] = secret
+	}
+
+	for _, id := range changes.DeletedIds {
+		delete(byID, id)
+	}
+
+	s.Secrets = make([]*pb.SecretData, 0, len(byID))
+	for _, secret := range byID {
+		s.Secrets = append(s.Secrets, secret)
+	}
+
+	sortSecrets(s.Secrets)
+	s.Revision = changes.Revision
+}
+
+// View returns the secrets with the pending operations applied.
+func (s *State) View() []*pb.SecretData {
+	view := make([]*pb.SecretData, 0, len(s.Secrets))
+	for _, secret := range s.Secrets {
+		view = append(view, secret)
+	}
+
+	for _, op := range s.Pending {
+		i := indexOf(view, op.Secret.Id)
+
+		switch {
+		case op.Kind == OpDelete && i >= 0:
+			view = append(view[:i], view[i+1:]...)
+		case op.Kind != OpDelete && i >= 0:
+			view[i] = op.Secret
+		case op.Kind != OpDelete:
+			view = append(view, op.Secret)
+		}
+	}
+
+	sortSecrets(view)
+
+	return view
+}
+
+// Queue records the operation to send it once the server is reachable. There is at most
+// one pending operation per secret: an update of a secret created offline changes its creation,
+// a deletion of it drops the creation, and repeated updates are merged into the first one.
+// Secrets created offline get negative IDs until they are sent.
+func (s *State) Queue(op Operation) {
+	op.Secret = proto.Clone(op.Secret).(*pb.SecretData)
+
+	if op.Kind == OpCreate {
+		s.nextID--
+		op.Secret.Id = s.nextID
+		s.Pending = append(s.Pending, op)
+
+		return
+	}
+
+	i := s.pendingIndex(op.Secret.Id)
+	if i < 0 {
+		s.Pending = append(s.Pending, op)
+
+		return
+	}
+
+	prev := s.Pending[i]
+
+	switch {
+	case prev.Kind == OpCreate && op.Kind == OpDelete:
+		s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+	case prev.Kind == OpCreate:
+		s.Pending[i].Secret = op.Secret
+	case op.Kind == OpDelete:
+		// The deletion is based on the same version as the dropped update.
+		op.Secret.Version = prev.Base.GetVersion()
+		s.Pending = append(s.Pending[:i], s.Pending[i+1:]...)
+		s.Pending = append(s.Pending, op)
+	default:
+		s.Pending[i].Secret = op.Secret
+	}
+}
+
+func (s *State) pendingIndex(id int64) int {
+	for i := range s.Pending {
+		if s.Pending[i].Secret.Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOf(secrets []*pb.SecretData, id int64) int {
+	for i := range secrets {
+		if secrets[i].Id == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func sortSecrets(secrets []*pb.SecretData) {
+	sort.Slice(secrets, func(i, j int) bool {
+		ti, tj := secrets[i].CreatedAt.AsTime(), secrets[j].CreatedAt.AsTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+
+		return secrets[i].Id < secrets[j].Id
+	})
+}
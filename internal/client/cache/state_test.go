@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func testSecret(id int64, version int64, createdAt time.Time) *pb.SecretData {
+	return &pb.SecretData{Id: id, Version: version, CreatedAt: timestamppb.New(createdAt)}
+}
+
+func TestState_ApplyChanges(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		changes *pb.SyncResponse
+		name    string
+		secrets []*pb.SecretData
+		want    []*pb.SecretData
+	}{
+		{
+			name: "first sync",
+			changes: &pb.SyncResponse{
+				Secrets:  []*pb.SecretData{testSecret(2, 1, now), testSecret(1, 1, now.Add(-time.Minute))},
+				Revision: 3,
+			},
+			want: []*pb.SecretData{testSecret(1, 1, now.Add(-time.Minute)), testSecret(2, 1, now)},
+		},
+		{
+			name:    "changed, created and deleted secrets",
+			secrets: []*pb.SecretData{testSecret(1, 1, now), testSecret(2, 1, now), testSecret(3, 1, now)},
+			changes: &pb.SyncResponse{
+				Secrets:    []*pb.SecretData{testSecret(2, 2, now), testSecret(4, 1, now)},
+				DeletedIds: []int64{3, 5},
+				Revision:   3,
+			},
+			want: []*pb.SecretData{testSecret(1, 1, now), testSecret(2, 2, now), testSecret(4, 1, now)},
+		},
+		{
+			name:    "no changes",
+			secrets: []*pb.SecretData{testSecret(1, 1, now)},
+			changes: &pb.SyncResponse{Revision: 3},
+			want:    []*pb.SecretData{testSecret(1, 1, now)},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			state := &State{Secrets: tt.secrets, Revision: 1}
+			state.ApplyChanges(tt.changes)
+
+			assert.Equal(t, tt.want, state.Secrets)
+			assert.Equal(t, int64(3), state.Revision)
+		})
+	}
+}
+
+func TestState_Queue(t *testing.T) {
+	now := time.Now()
+
+	secret := testSecret(1, 4, now)
+	updated := testSecret(1, 4, now)
+	updated.MetaData = "updated"
+	updatedTwice := testSecret(1, 4, now)
+	updatedTwice.MetaData = "updated twice"
+
+	created := testSecret(0, 0, now.Add(time.Minute))
+	createdView := testSecret(-1, 0, now.Add(time.Minute))
+	createdUpdated := testSecret(-1, 0, now.Add(time.Minute))
+	createdUpdated.MetaData = "updated"
+
+	tests := []struct {
+		name        string
+		ops         []Operation
+		wantPending []Operation
+		wantView    []*pb.SecretData
+	}{
+		{
+			name:        "create",
+			ops:         []Operation{{Kind: OpCreate, Secret: created}},
+			wantPending: []Operation{{Kind: OpCreate, Secret: createdView}},
+			wantView:    []*pb.SecretData{secret, createdView},
+		},
+		{
+			name: "update of created secret changes creation",
+			ops: []Operation{
+				{Kind: OpCreate, Secret: created},
+				{Kind: OpUpdate, Secret: createdUpdated, Base: createdView},
+			},
+			wantPending: []Operation{{Kind: OpCreate, Secret: createdUpdated}},
+			wantView:    []*pb.SecretData{secret, createdUpdated},
+		},
+		{
+			name: "deletion of created secret drops creation",
+			ops: []Operation{
+				{Kind: OpCreate, Secret: created},
+				{Kind: OpDelete, Secret: createdView},
+			},
+			wantView: []*pb.SecretData{secret},
+		},
+		{
+			name: "updates are merged",
+			ops: []Operation{
+				{Kind: OpUpdate, Secret: updated, Base: secret},
+				{Kind: OpUpdate, Secret: updatedTwice, Base: updated},
+			},
+			wantPending: []Operation{{Kind: OpUpdate, Secret: updatedTwice, Base: secret}},
+			wantView:    []*pb.SecretData{updatedTwice},
+		},
+		{
+			name: "deletion replaces update",
+			ops: []Operation{
+				{Kind: OpUpdate, Secret: updated, Base: secret},
+				{Kind: OpDelete, Secret: updated},
+			},
+			wantPending: []Operation{{Kind: OpDelete, Secret: updated}},
+			wantView:    []*pb.SecretData{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			state := &State{Secrets: []*pb.SecretData{secret}}
+			for _, op := range tt.ops {
+				state.Queue(op)
+			}
+
+			if assert.Len(t, state.Pending, len(tt.wantPending)) {
+				for i, op := range tt.wantPending {
+					assert.Equal(t, op.Kind, state.Pending[i].Kind)
+					assert.True(t, proto.Equal(op.Secret, state.Pending[i].Secret), "secret of operation %d", i)
+					assert.True(t, proto.Equal(op.Base, state.Pending[i].Base), "base of operation %d", i)
+				}
+			}
+
+			view := state.View()
+			if assert.Len(t, view, len(tt.wantView)) {
+				for i, secret := range tt.wantView {
+					assert.True(t, proto.Equal(secret, view[i]), "secret %d", i)
+				}
+			}
+		})
+	}
+}
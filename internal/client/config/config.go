@@ -3,6 +3,8 @@ package config
 
 import (
 	"log"
+	"os"
+	"path/filepath"
 
 	"github.com/caarlos0/env/v10"
 )
@@ -18,6 +20,9 @@ type Config struct {
 	Host        string `env:"GKEEPER_SERVER_HOST" envDefault:"localhost"`
 	Port        string `env:"GKEEPER_SERVER_PORT" envDefault:"8090"`
 	SSLCertPath string `env:"GKEEPER_SSL_CERT_PATH" envDefault:"cert/example.crt"`
+	// CacheDir is the directory of the encrypted copies of secrets for offline use.
+	// It defaults to the goph-keeper directory in the user's cache directory.
+	CacheDir string `env:"GKEEPER_CACHE_DIR"`
 }
 
 // LoadConfig parses the provided environment variables.
@@ -27,5 +32,14 @@ func LoadConfig() *Config {
 		log.Fatal(err)
 	}
 
+	if c.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c.CacheDir = filepath.Join(dir, "goph-keeper")
+	}
+
 	return c
 }
@@ -0,0 +1,274 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/client/vault"
+)
+
+// blobChunkSize is the size of the chunks files are uploaded in.
+const blobChunkSize = 256 << 10
+
+var (
+	errOfflineFile   = errors.New("files can be attached only while the server is reachable")
+	errFileCorrupted = errors.New("downloaded file does not match its size")
+)
+
+// attachFile uploads the file chosen in the form and attaches it to the binary secret.
+// For users with client-side encryption the file is encrypted with a new key, which is
+// kept in the payload of the secret. Secrets of other types have no file attached.
+func (a *Application) attachFile(secret *pb.SecretData, filePath string) error {
+	if secret.Type != pb.SecretType_BINARY {
+		secret.BlobId = ""
+		return nil
+	}
+
+	if filePath == "" {
+		return nil
+	}
+
+	if a.offlineAuth != nil {
+		return errOfflineFile
+	}
+
+	var key []byte
+	if a.vault != nil {
+		var err error
+		if key, err = vault.NewBlobKey(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		blobID string
+		size   int64
+	)
+
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		blobID, size, err = a.uploadFile(ctx, filePath, key)
+		return err
+	})
+	if status.Code(err) == codes.Unavailable {
+		return errOfflineFile
+	}
+
+	if err != nil {
+		return err
+	}
+
+	binary := secret.Payload.GetBinary()
+	binary.Bytes = nil
+	binary.Size = size
+	binary.Key = key
+	secret.BlobId = blobID
+
+	return nil
+}
+
+// uploadFile sends the file in chunks, encrypted with the key if it is set.
+// It returns the ID of the blob and the size of the file.
+func (a *Application) uploadFile(ctx context.Context, filePath string, key []byte) (string, int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	var blobCipher *vault.BlobCipher
+	if key != nil {
+		if blobCipher, err = vault.NewBlobCipher(key); err != nil {
+			return "", 0, err
+		}
+	}
+
+	stream, err := a.secretsClient.UploadBlob(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	reader := bufio.NewReaderSize(file, blobChunkSize)
+	buf := make([]byte, blobChunkSize)
+
+	var size int64
+
+	for seq := 0; ; seq++ {
+		n, err := io.ReadFull(reader, buf)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", 0, err
+		}
+
+		// The chunk is the last one if nothing follows it.
+		_, err = reader.Peek(1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", 0, err
+		}
+
+		last := err != nil
+		chunk := buf[:n]
+		size += int64(n)
+
+		if blobCipher != nil {
+			if chunk, err = blobCipher.Seal(seq, last, chunk); err != nil {
+				return "", 0, err
+			}
+		}
+
+		// The stream is ended by the server on failure, its status is returned by CloseAndRecv.
+		if err := stream.Send(&pb.UploadBlobRequest{Chunk: chunk}); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return "", 0, err
+		}
+
+		if last {
+			break
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return "", 0, err
+	}
+
+	return resp.BlobId, size, nil
+}
+
+// saveFile writes the file of the binary secret to the given path. The file is
+// downloaded and decrypted chunk by chunk unless it is stored inline.
+func (a *Application) saveFile(secret *pb.SecretData, filePath string) error {
+	binary := secret.GetPayload().GetBinary()
+
+	if secret.BlobId == "" {
+		return writeFile(filePath, func(w io.Writer) error {
+			_, err := w.Write(binary.GetBytes())
+			return err
+		})
+	}
+
+	if a.offlineAuth != nil {
+		return errOffline
+	}
+
+	return a.callWithRefresh(func(ctx context.Context) error {
+		return writeFile(filePath, func(w io.Writer) error {
+			return a.downloadFile(ctx, secret.BlobId, binary, w)
+		})
+	})
+}
+
+// downloadFile receives the chunks of the blob and writes them to w, decrypted with the key
+// of the binary payload if it is set. A chunk is decrypted once the next one arrives,
+// so it is known whether the chunk is the last one.
+func (a *Application) downloadFile(ctx context.Context, blobID string, binary *pb.Binary, w io.Writer) error {
+	var blobCipher *vault.BlobCipher
+	if len(binary.GetKey()) > 0 {
+		var err error
+		if blobCipher, err = vault.NewBlobCipher(binary.GetKey()); err != nil {
+			return err
+		}
+	}
+
+	stream, err := a.secretsClient.DownloadBlob(ctx, &pb.DownloadBlobRequest{BlobId: blobID})
+	if err != nil {
+		return err
+	}
+
+	var (
+		size    int64
+		pending []byte
+	)
+
+	write := func(seq int, last bool) error {
+		chunk := pending
+		if blobCipher != nil {
+			var err error
+			if chunk, err = blobCipher.Open(seq, last, pending); err != nil {
+				return err
+			}
+		}
+
+		size += int64(len(chunk))
+		_, err := w.Write(chunk)
+
+		return err
+	}
+
+	seq := 0
+
+	for ; ; seq++ {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if seq > 0 {
+			if err := write(seq-1, false); err != nil {
+				return err
+			}
+		}
+
+		pending = chunk.Data
+	}
+
+	if seq > 0 {
+		if err := write(seq-1, true); err != nil {
+			return err
+		}
+	}
+
+	if size != binary.GetSize() {
+		return errFileCorrupted
+	}
+
+	return nil
+}
+
+// writeFile writes the file through a temporary file, which replaces the file
+// only if write succeeds, so a failed download does not leave a partial file behind.
+func writeFile(filePath string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+"-*")
+	if err != nil {
+		return err
+	}
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return nil
+}
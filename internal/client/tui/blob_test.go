@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/client/vault"
+)
+
+// blobClient keeps the uploaded chunks in memory and returns them on download.
+type blobClient struct {
+	pb.SecretClient
+	chunks [][]byte
+}
+
+func (c *blobClient) UploadBlob(ctx context.Context, opts ...grpc.CallOption) (pb.Secret_UploadBlobClient, error) {
+	return &uploadStream{client: c}, nil
+}
+
+func (c *blobClient) DownloadBlob(
+	ctx context.Context,
+	in *pb.DownloadBlobRequest,
+	opts ...grpc.CallOption,
+) (pb.Secret_DownloadBlobClient, error) {
+	return &downloadStream{chunks: c.chunks}, nil
+}
+
+type uploadStream struct {
+	grpc.ClientStream
+	client *blobClient
+}
+
+func (s *uploadStream) Send(req *pb.UploadBlobRequest) error {
+	s.client.chunks = append(s.client.chunks, bytes.Clone(req.Chunk))
+
+	return nil
+}
+
+func (s *uploadStream) CloseAndRecv() (*pb.UploadBlobResponse, error) {
+	return &pb.UploadBlobResponse{BlobId: "blob"}, nil
+}
+
+type downloadStream struct {
+	grpc.ClientStream
+	chunks [][]byte
+}
+
+func (s *downloadStream) Recv() (*pb.BlobChunk, error) {
+	if len(s.chunks) == 0 {
+		return nil, io.EOF
+	}
+
+	chunk := s.chunks[0]
+	s.chunks = s.chunks[1:]
+
+	return &pb.BlobChunk{Data: chunk}, nil
+}
+
+func TestApplication_uploadDownloadFile(t *testing.T) {
+	key, err := vault.NewBlobKey()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		key     []byte
+		size    int
+		chunks  int
+		corrupt func(chunks [][]byte) [][]byte
+		wantErr bool
+	}{
+		{name: "empty file", size: 0, chunks: 0},
+		{name: "single chunk", size: 10, chunks: 1},
+		{name: "exact multiple of chunk size", size: 2 * blobChunkSize, chunks: 2},
+		{name: "encrypted chunks", key: key, size: 2*blobChunkSize + 1, chunks: 3},
+		{
+			name:   "error: truncated encrypted file",
+			key:    key,
+			size:   2*blobChunkSize + 1,
+			chunks: 3,
+			corrupt: func(chunks [][]byte) [][]byte {
+				return chunks[:2]
+			},
+			wantErr: true,
+		},
+		{
+			name:   "error: truncated file",
+			size:   2*blobChunkSize + 1,
+			chunks: 3,
+			corrupt: func(chunks [][]byte) [][]byte {
+				return chunks[:2]
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			content := bytes.Repeat([]byte("0123456789"), tt.size/10+1)[:tt.size]
+
+			source := filepath.Join(dir, "source")
+			assert.NoError(t, os.WriteFile(source, content, 0o600))
+
+			client := &blobClient{}
+			a := &Application{secretsClient: client}
+
+			blobID, size, err := a.uploadFile(context.Background(), source, tt.key)
+			assert.NoError(t, err)
+			assert.Equal(t, "blob", blobID)
+			assert.Equal(t, int64(tt.size), size)
+			assert.Len(t, client.chunks, tt.chunks)
+
+			if tt.corrupt != nil {
+				client.chunks = tt.corrupt(client.chunks)
+			}
+
+			var downloaded bytes.Buffer
+
+			err = a.downloadFile(context.Background(), blobID, &pb.Binary{Size: size, Key: tt.key}, &downloaded)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, string(content), downloaded.String())
+		})
+	}
+}
+
+func Test_writeFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "file")
+
+	assert.NoError(t, writeFile(target, func(w io.Writer) error {
+		_, err := w.Write([]byte("content"))
+		return err
+	}))
+
+	data, err := os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("content"), data)
+
+	err = writeFile(target, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errFileCorrupted
+	})
+	assert.ErrorIs(t, err, errFileCorrupted)
+
+	data, err = os.ReadFile(target)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("content"), data, "failed write must keep the file")
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file must be removed")
+}
@@ -15,21 +15,47 @@ const (
 	createPageName       = "CreatePageName"
 	editPageName         = "EditPageName"
 	deleteWindowName     = "DeleteWindow"
+	sessionsPageName     = "SessionsPage"
+	totpPageName         = "TOTPPage"
+	accountPageName      = "AccountPage"
+	filePageName         = "FilePage"
+	trashPageName        = "TrashPage"
+	sharePageName        = "SharePage"
 )
 
 const (
-	loginLabel  = "Login"
-	signUpLabel = "Sign Up"
-	okLabel     = "OK"
-	submitLabel = "Submit"
-	backLabel   = "Back"
-	quitLabel   = "Quit"
-	updateLabel = "Update"
-	saveLabel   = "Save"
-	createLabel = "Create"
-	syncLabel   = "Sync"
-	deleteLabel = "Delete"
-	editLabel   = "Edit"
+	loginLabel     = "Login"
+	signUpLabel    = "Sign Up"
+	okLabel        = "OK"
+	submitLabel    = "Submit"
+	backLabel      = "Back"
+	quitLabel      = "Quit"
+	updateLabel    = "Update"
+	saveLabel      = "Save"
+	createLabel    = "Create"
+	syncLabel      = "Sync"
+	deleteLabel    = "Delete"
+	editLabel      = "Edit"
+	logoutLabel    = "Logout"
+	sessionsLabel  = "Sessions"
+	revokeLabel    = "Revoke"
+	totpLabel      = "2FA"
+	enableLabel    = "Enable"
+	disableLabel   = "Disable"
+	confirmLabel   = "Confirm"
+	accountLabel   = "Account"
+	mergeLabel     = "Merge"
+	overwriteLabel = "Overwrite"
+	saveFileLabel  = "Save file"
+	historyLabel   = "History"
+	restoreLabel   = "Restore"
+	trashLabel     = "Trash"
+	purgeLabel     = "Delete forever"
+	shareLabel     = "Share"
+	unshareLabel   = "Unshare"
+
+	changePasswordLabel = "Change password"
+	deleteAccountLabel  = "Delete account"
 )
 
 func newButton(label string, selectedFunc func()) *tview.Button {
@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+var errHistoryOffline = errors.New("history of secrets is not available offline")
+
+// addHistory shows the previous versions of the selected secret in the details pane.
+// A version is shown when it is highlighted and can be restored.
+func (a *Application) addHistory() {
+	secret := a.selectedSecret
+
+	if a.offlineAuth != nil || a.unreachable {
+		a.addErrorWindow(errHistoryOffline.Error(), secretsPanelPageName)
+		return
+	}
+
+	var resp *pb.ListVersionsResponse
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		resp, err = a.secretsClient.ListVersions(ctx, &pb.ListVersionsRequest{SecretId: secret.Id})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
+		return
+	}
+
+	versions := resp.Versions
+
+	if a.vault != nil {
+		for _, version := range versions {
+			if err := a.vault.DecryptSecret(version); err != nil {
+				a.addErrorWindow(fmt.Sprintf("failed to decrypt secret: %s", err), secretsPanelPageName)
+				return
+			}
+		}
+	}
+
+	versionsList := tview.NewList()
+	versionText := tview.NewTextView().SetDynamicColors(true)
+
+	versionsList.SetChangedFunc(func(index int, mainText string, secondaryText string, shortcut rune) {
+		if index < len(versions) {
+			versionText.SetText(secretDetailsText(versions[index]))
+		}
+	})
+
+	for _, version := range versions {
+		versionsList.AddItem(versionTitle(version), tview.Escape(secretTitle(version)), 0, nil)
+	}
+
+	if len(versions) == 0 {
+		versionsList.AddItem("No previous versions", "", 0, nil)
+	} else {
+		versionText.SetText(secretDetailsText(versions[0]))
+	}
+
+	buttons := tview.NewFlex().
+		AddItem(newButton(backLabel, func() {
+			a.showSecretDetails(secret)
+		}), 0, 1, false)
+
+	if len(versions) > 0 {
+		buttons.AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(newButton(restoreLabel, func() {
+				a.restoreVersion(secret, versions[versionsList.GetCurrentItem()])
+			}), 0, 1, false)
+	}
+
+	a.secretsDetails.Clear()
+	a.secretsDetails.AddItem(buttons.
+		AddItem(tview.NewBox(), 0, 4, false), 1, 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(versionsList, 0, 1, true).
+			AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(versionText, 0, 2, false), 0, 10, true)
+
+	a.App.SetFocus(versionsList)
+}
+
+// restoreVersion makes the previous version of the secret current again. The restore
+// is based on the current version of the secret and is rejected if the secret was changed
+// since, which is not merged: the user opens the history of the changed secret again.
+func (a *Application) restoreVersion(secret *pb.SecretData, version *pb.SecretData) {
+	if len(a.state.Pending) > 0 {
+		a.addErrorWindow(errPendingChanges.Error(), secretsPanelPageName)
+		return
+	}
+
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.secretsClient.RestoreVersion(ctx, &pb.RestoreVersionRequest{
+			SecretId:       secret.Id,
+			Version:        version.Version,
+			CurrentVersion: secret.Version,
+		})
+		return err
+	})
+
+	if _, ok := a.conflictSecret(err); ok {
+		a.addSecretsList()
+		a.addErrorWindow("The secret was changed on another device. Open its history again.", secretsPanelPageName)
+
+		return
+	}
+
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
+		return
+	}
+
+	a.addSecretsList()
+	a.Pages.SwitchToPage(secretsPanelPageName)
+}
+
+// versionTitle returns the version of the secret and the time it was saved for the list of versions.
+func versionTitle(version *pb.SecretData) string {
+	return fmt.Sprintf("Version %d · %s", version.Version, version.UpdatedAt.AsTime().Local().Format(time.DateTime))
+}
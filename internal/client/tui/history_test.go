@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func Test_versionTitle(t *testing.T) {
+	savedAt := time.Date(2026, 10, 17, 12, 30, 0, 0, time.Local)
+
+	title := versionTitle(&pb.SecretData{Version: 3, UpdatedAt: timestamppb.New(savedAt)})
+	assert.Equal(t, "Version 3 · 2026-10-17 12:30:00", title)
+}
+
+func Test_secretDetailsText(t *testing.T) {
+	text := secretDetailsText(&pb.SecretData{
+		Type:     pb.SecretType_TEXT,
+		Name:     "Note",
+		Payload:  &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "old body"}}},
+		Folder:   "Work",
+		Tags:     []string{"a", "b"},
+		MetaData: "meta",
+	})
+
+	for _, s := range []string{"Note", "TEXT", "old body", "Work", "a, b", "meta"} {
+		assert.Contains(t, text, s)
+	}
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// splitTags returns the comma separated tags entered in a form.
+func splitTags(text string) []string {
+	var tags []string
+	for _, tag := range strings.Split(text, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// groupSecrets returns the rows of the secrets list: the secrets without a folder first,
+// followed by every folder with its secrets. The row of a folder heading is nil.
+// Within a group the secrets are sorted by name.
+func groupSecrets(secrets []*pb.SecretData) []*pb.SecretData {
+	sorted := make([]*pb.SecretData, len(secrets))
+	copy(sorted, secrets)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Folder != sorted[j].Folder {
+			return sorted[i].Folder < sorted[j].Folder
+		}
+
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+
+	rows := make([]*pb.SecretData, 0, len(sorted))
+	for i, secret := range sorted {
+		if secret.Folder != "" && (i == 0 || sorted[i-1].Folder != secret.Folder) {
+			rows = append(rows, nil)
+		}
+
+		rows = append(rows, secret)
+	}
+
+	return rows
+}
+
+// secretTitle returns the name of the secret for the secrets list. Secrets without
+// a name are titled after their type.
+func secretTitle(secret *pb.SecretData) string {
+	if secret.Name != "" {
+		return secret.Name
+	}
+
+	return fmt.Sprintf("Unnamed %s", strings.ToLower(secret.Type.String()))
+}
+
+// secretSubtitle returns the type, the short description and the tags of the secret for the secrets list.
+func secretSubtitle(secret *pb.SecretData) string {
+	subtitle := secret.Type.String()
+	if summary := payloadSummary(secret.Payload); summary != "" {
+		subtitle += " · " + summary
+	}
+
+	if secret.Shared {
+		subtitle += " · shared by " + secret.Owner
+	}
+
+	if secret.CollectionId != 0 {
+		subtitle += " · " + collectionText(secret)
+	}
+
+	for _, tag := range secret.Tags {
+		subtitle += " #" + tag
+	}
+
+	return subtitle
+}
@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func Test_splitTags(t *testing.T) {
+	assert.Equal(t, []string{"dev", "ci"}, splitTags(" dev, ,ci ,"))
+	assert.Nil(t, splitTags(" "))
+}
+
+func Test_groupSecrets(t *testing.T) {
+	github := &pb.SecretData{Id: 1, Name: "github", Folder: "Work"}
+	bank := &pb.SecretData{Id: 2, Name: "Bank", Folder: "Personal"}
+	note := &pb.SecretData{Id: 3, Name: "note"}
+	aws := &pb.SecretData{Id: 4, Name: "AWS", Folder: "Work"}
+
+	tests := []struct {
+		name     string
+		secrets  []*pb.SecretData
+		expected []*pb.SecretData
+	}{
+		{
+			name:     "secrets are grouped by folder and sorted by name",
+			secrets:  []*pb.SecretData{github, bank, note, aws},
+			expected: []*pb.SecretData{note, nil, bank, nil, aws, github},
+		},
+		{
+			name:     "no folders",
+			secrets:  []*pb.SecretData{note},
+			expected: []*pb.SecretData{note},
+		},
+		{
+			name:     "no secrets",
+			expected: []*pb.SecretData{},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, groupSecrets(tt.secrets))
+		})
+	}
+}
+
+func Test_secretTitle(t *testing.T) {
+	assert.Equal(t, "GitHub", secretTitle(&pb.SecretData{Name: "GitHub", Type: pb.SecretType_CREDENTIALS}))
+	assert.Equal(t, "Unnamed card", secretTitle(&pb.SecretData{Type: pb.SecretType_CARD}))
+}
+
+func Test_secretSubtitle(t *testing.T) {
+	secret := &pb.SecretData{
+		Type:    pb.SecretType_CREDENTIALS,
+		Payload: &pb.Payload{Kind: &pb.Payload_Credentials{Credentials: &pb.Credentials{Login: "john", Password: "pass"}}},
+		Tags:    []string{"dev", "ci"},
+	}
+
+	assert.Equal(t, "CREDENTIALS · john #dev #ci", secretSubtitle(secret))
+
+	secret.Shared = true
+	secret.Owner = "alice"
+
+	assert.Equal(t, "CREDENTIALS · john · shared by alice #dev #ci", secretSubtitle(secret))
+
+	secret.Shared = false
+	secret.CollectionId = 3
+	secret.Collection = "infra"
+	secret.Organization = "team"
+
+	assert.Equal(t, "CREDENTIALS · john · team / infra #dev #ci", secretSubtitle(secret))
+}
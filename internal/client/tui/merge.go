@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// mergeSecrets merges the local changes of a secret into its current copy on the server.
+// Base is the copy the local changes were made on. Every field changed locally
+// is taken from mine, every other field is taken from theirs.
+func mergeSecrets(base, mine, theirs *pb.SecretData) *pb.SecretData {
+	merged := proto.Clone(theirs).(*pb.SecretData)
+	mergeFields(base.ProtoReflect(), mine.ProtoReflect(), merged.ProtoReflect())
+
+	return merged
+}
+
+func mergeFields(base, mine, merged protoreflect.Message) {
+	fields := mine.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() &&
+			base.Has(fd) && mine.Has(fd) && merged.Has(fd) {
+			mergeFields(base.Get(fd).Message(), mine.Get(fd).Message(), merged.Mutable(fd).Message())
+			continue
+		}
+
+		if fieldEqual(fd, base, mine) {
+			continue
+		}
+
+		if mine.Has(fd) {
+			merged.Set(fd, mine.Get(fd))
+		} else {
+			merged.Clear(fd)
+		}
+	}
+}
+
+func fieldEqual(fd protoreflect.FieldDescriptor, a, b protoreflect.Message) bool {
+	return a.Has(fd) == b.Has(fd) && a.Get(fd).Equal(b.Get(fd))
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func credentialsSecret(version int64, metaData string, creds *pb.Credentials) *pb.SecretData {
+	return &pb.SecretData{
+		Id:       1,
+		Type:     pb.SecretType_CREDENTIALS,
+		MetaData: metaData,
+		Version:  version,
+		Payload:  &pb.Payload{Kind: &pb.Payload_Credentials{Credentials: creds}},
+	}
+}
+
+func TestMergeSecrets(t *testing.T) {
+	base := credentialsSecret(1, "meta", &pb.Credentials{Login: "login", Password: "pass", Url: "url"})
+
+	tests := []struct {
+		name   string
+		mine   *pb.SecretData
+		theirs *pb.SecretData
+		want   *pb.SecretData
+	}{
+		{
+			name:   "different fields are merged",
+			mine:   credentialsSecret(1, "meta", &pb.Credentials{Login: "login", Password: "new", Url: "url"}),
+			theirs: credentialsSecret(2, "changed", &pb.Credentials{Login: "user", Password: "pass", Url: "url"}),
+			want:   credentialsSecret(2, "changed", &pb.Credentials{Login: "user", Password: "new", Url: "url"}),
+		},
+		{
+			name:   "local change wins on the same field",
+			mine:   credentialsSecret(1, "mine", &pb.Credentials{Login: "login", Password: "pass", Url: "url"}),
+			theirs: credentialsSecret(2, "theirs", &pb.Credentials{Login: "login", Password: "pass", Url: "url"}),
+			want:   credentialsSecret(2, "mine", &pb.Credentials{Login: "login", Password: "pass", Url: "url"}),
+		},
+		{
+			name:   "cleared field is cleared",
+			mine:   credentialsSecret(1, "meta", &pb.Credentials{Login: "login", Password: "pass"}),
+			theirs: credentialsSecret(2, "meta", &pb.Credentials{Login: "login", Password: "other", Url: "url"}),
+			want:   credentialsSecret(2, "meta", &pb.Credentials{Login: "login", Password: "other"}),
+		},
+		{
+			name: "changed type replaces payload",
+			mine: &pb.SecretData{
+				Id:       1,
+				Type:     pb.SecretType_TEXT,
+				MetaData: "meta",
+				Version:  1,
+				Payload:  &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "text"}}},
+			},
+			theirs: credentialsSecret(2, "changed", &pb.Credentials{Login: "user", Password: "pass", Url: "url"}),
+			want: &pb.SecretData{
+				Id:       1,
+				Type:     pb.SecretType_TEXT,
+				MetaData: "changed",
+				Version:  2,
+				Payload:  &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "text"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			theirs := proto.Clone(tt.theirs)
+
+			got := mergeSecrets(base, tt.mine, tt.theirs)
+
+			assert.True(t, proto.Equal(tt.want, got), "got %v", got)
+			assert.True(t, proto.Equal(theirs, tt.theirs))
+		})
+	}
+}
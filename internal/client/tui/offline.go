@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rivo/tview"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/client/cache"
+)
+
+var errOffline = errors.New("server is unreachable")
+
+// apply makes the change of a secret. The change is sent to the server right away unless
+// the server is unreachable or earlier changes are still waiting to be sent. In that case
+// it is saved to the cache and sent by the next sync, so the order of changes is kept.
+func (a *Application) apply(op cache.Operation) error {
+	if a.offlineAuth == nil && len(a.state.Pending) == 0 {
+		err := a.send(op)
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+	}
+
+	a.state.Queue(op)
+	a.saveCache()
+
+	return nil
+}
+
+// send sends the change of a secret to the server.
+func (a *Application) send(op cache.Operation) error {
+	if a.offlineAuth != nil {
+		return status.Error(codes.Unavailable, errOffline.Error())
+	}
+
+	switch op.Kind {
+	case cache.OpCreate:
+		return a.createSecret(op.Secret)
+	case cache.OpUpdate:
+		return a.updateSecret(op.Secret)
+	default:
+		return a.callWithRefresh(func(ctx context.Context) error {
+			_, err := a.secretsClient.Delete(ctx, &pb.DeleteRequest{
+				SecretId: op.Secret.Id,
+				Version:  op.Secret.Version,
+			})
+			return err
+		})
+	}
+}
+
+// replay sends the changes made while the server was unreachable in the order they were made.
+// A change of a secret which was changed on another device in the meantime is handed over
+// to the user, who decides how to resolve the conflict. Replay stops at the first change
+// which is not sent and returns its error, so the rest is sent by the next sync.
+func (a *Application) replay() error {
+	for len(a.state.Pending) > 0 {
+		op := a.state.Pending[0]
+
+		err := a.send(op)
+		if status.Code(err) == codes.Unavailable {
+			return err
+		}
+
+		// The secret was deleted on another device, the change is kept as a new secret.
+		if op.Kind == cache.OpUpdate && status.Code(err) == codes.NotFound {
+			a.state.Pending[0].Kind = cache.OpCreate
+			continue
+		}
+
+		a.state.Pending = a.state.Pending[1:]
+		a.saveCache()
+
+		if op.Kind == cache.OpDelete && status.Code(err) == codes.NotFound {
+			continue
+		}
+
+		if current, ok := a.conflictSecret(err); ok {
+			if op.Kind == cache.OpDelete {
+				a.addDeleteConflictWindow(current)
+			} else {
+				a.addConflictWindow(op.Base, op.Secret, current)
+			}
+
+			return err
+		}
+
+		if err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("failed to send a change made offline: %s", s.Message()), secretsPanelPageName)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loginOffline opens the cached secrets of the user when the server is unreachable.
+// The password is checked by decrypting the cache. The login is repeated with
+// the same request once the server is reachable again.
+func (a *Application) loginOffline(req *pb.AuthRequest, password string) {
+	c, err := cache.Open(a.cacheDir, a.cacheName(req.Login), password)
+	if err != nil {
+		a.addErrorWindow(err.Error(), authPageName)
+		return
+	}
+
+	state, err := c.Load()
+	if err != nil {
+		switch {
+		case errors.Is(err, cache.ErrNotFound):
+			a.addErrorWindow("server is unreachable and there is no offline copy of the secrets", authPageName)
+		case errors.Is(err, cache.ErrInvalidPassword):
+			a.addErrorWindow("login or password is invalid", authPageName)
+		default:
+			a.addErrorWindow(err.Error(), authPageName)
+		}
+
+		return
+	}
+
+	a.cache = c
+	a.state = state
+	a.offlineAuth = proto.Clone(req).(*pb.AuthRequest)
+
+	a.showSecrets()
+	a.Pages.SwitchToPage(secretsPanelPageName)
+	a.startWatch()
+	a.startOTPRefresh()
+}
+
+// reconnect repeats the login made offline. On success the session continues online
+// and the changes made offline are sent.
+func (a *Application) reconnect() error {
+	resp, err := a.authClient.Login(context.Background(), a.offlineAuth)
+	if status.Code(err) == codes.Unavailable {
+		return err
+	}
+
+	if err != nil {
+		a.resetSession()
+
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), startMenuPageName)
+
+		return err
+	}
+
+	if resp.TotpChallenge != "" {
+		a.addLoginTOTPForm(resp.TotpChallenge)
+		return nil
+	}
+
+	a.startSession(resp)
+
+	return nil
+}
+
+// openCache opens the cache of the user after an online login. The secrets and the changes
+// which were not sent are restored from it. If it cannot be read, e.g. because the password
+// was changed on another device, it is replaced with the secrets fetched from the server.
+func (a *Application) openCache(login, password string) {
+	a.state = &cache.State{}
+
+	c, err := cache.Open(a.cacheDir, a.cacheName(login), password)
+	if err != nil {
+		a.cache = nil
+		return
+	}
+
+	a.cache = c
+
+	if state, err := c.Load(); err == nil {
+		a.state = state
+	}
+}
+
+// saveCache saves the secrets and the changes which were not sent to the cache.
+func (a *Application) saveCache() {
+	if a.cache == nil {
+		return
+	}
+
+	if err := a.cache.Save(a.state); err != nil {
+		a.addErrorWindow(fmt.Sprintf("failed to save secrets for offline use: %s", err), secretsPanelPageName)
+	}
+}
+
+// cacheName identifies the cache of the user of the server.
+func (a *Application) cacheName(login string) string {
+	return fmt.Sprintf("%s@%s", login, a.server)
+}
+
+// showSecrets shows the secrets matching the search query with the changes which were not sent yet.
+func (a *Application) showSecrets() {
+	a.secretsList.Clear()
+	a.secretText.Clear()
+	a.secretsDetails.Clear()
+
+	a.secrets = groupSecrets(a.searchSecrets())
+
+	var shortcut rune = '1'
+	for i, s := range a.secrets {
+		if s == nil {
+			a.secretsList.AddItem(fmt.Sprintf("[yellow]▸ %s", tview.Escape(a.secrets[i+1].Folder)), "", 0, nil)
+			continue
+		}
+
+		a.secretsList.AddItem(tview.Escape(secretTitle(s)), tview.Escape(secretSubtitle(s)), shortcut, nil)
+		shortcut++
+	}
+
+	switch {
+	case a.offlineAuth != nil || a.unreachable:
+		a.syncStatus.SetText(fmt.Sprintf("Offline, %d changes not synced", len(a.state.Pending)))
+	case len(a.state.Pending) > 0:
+		a.syncStatus.SetText(fmt.Sprintf("%d changes not synced", len(a.state.Pending)))
+	default:
+		a.syncStatus.SetText("")
+	}
+}
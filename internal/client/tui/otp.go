@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/pkg/totp"
+)
+
+// otpRefreshInterval is the period of time between the updates of the code of the selected OTP secret.
+const otpRefreshInterval = time.Second
+
+// startOTPRefresh starts updating the code and the countdown of the selected OTP secret.
+// The refresh runs until the session ends.
+func (a *Application) startOTPRefresh() {
+	a.stopOTPRefresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.otpCancel = cancel
+
+	go a.refreshOTP(ctx)
+}
+
+// stopOTPRefresh stops updating the code of the selected OTP secret.
+func (a *Application) stopOTPRefresh() {
+	if a.otpCancel != nil {
+		a.otpCancel()
+		a.otpCancel = nil
+	}
+}
+
+// refreshOTP redraws the details of the selected secret every second while it is an OTP
+// secret shown on the secrets panel. It runs in its own goroutine, so the state of the
+// application is accessed through QueueUpdateDraw.
+func (a *Application) refreshOTP(ctx context.Context) {
+	ticker := time.NewTicker(otpRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.App.QueueUpdateDraw(func() {
+			if name, _ := a.Pages.GetFrontPage(); name != secretsPanelPageName {
+				return
+			}
+
+			if a.selectedSecret.GetType() == pb.SecretType_OTP {
+				a.setSecretText(a.selectedSecret)
+			}
+		})
+	}
+}
+
+// otpText returns the current code of the OTP secret and the time it stays valid
+// formatted for the secret text view. Counter-based codes show their counter instead.
+func otpText(otp *pb.Otp, now time.Time) string {
+	key, err := totp.ParseURI(otp.GetUri())
+	if err != nil {
+		return fmt.Sprintf("[red]%s[white]\n\n", err)
+	}
+
+	code, err := key.Code(now)
+	if err != nil {
+		return fmt.Sprintf("[red]%s[white]\n\n", err)
+	}
+
+	text := fmt.Sprintf("[green]ISSUER[white]\n%s\n\n", tview.Escape(key.Issuer)) +
+		fmt.Sprintf("[green]ACCOUNT[white]\n%s\n\n", tview.Escape(key.Account)) +
+		fmt.Sprintf("[green]CODE[white]\n[yellow]%s[white]\n\n", code)
+
+	if key.Type == totp.TypeHOTP {
+		return text + fmt.Sprintf("[green]COUNTER[white]\n%d\n\n", key.Counter)
+	}
+
+	return text + fmt.Sprintf("[green]EXPIRES IN[white]\n%ds\n\n", int(key.Remaining(now)/time.Second))
+}
+
+// otpSummary returns the issuer and the account of the OTP secret for the secrets list.
+func otpSummary(otp *pb.Otp) string {
+	key, err := totp.ParseURI(otp.GetUri())
+	if err != nil {
+		return ""
+	}
+
+	if key.Issuer == "" {
+		return key.Account
+	}
+
+	return fmt.Sprintf("%s: %s", key.Issuer, key.Account)
+}
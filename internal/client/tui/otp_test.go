@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// rfcURI holds the secret of the RFC 6238 test vectors.
+const rfcURI = "otpauth://totp/ACME:john?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=8"
+
+func Test_otpText(t *testing.T) {
+	tests := []struct {
+		now      time.Time
+		name     string
+		uri      string
+		contains []string
+	}{
+		{
+			name:     "time-based code with countdown",
+			uri:      rfcURI,
+			now:      time.Unix(59, 0),
+			contains: []string{"ACME", "john", "94287082", "EXPIRES IN[white]\n1s"},
+		},
+		{
+			name:     "counter-based code",
+			uri:      "otpauth://hotp/john?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=1",
+			now:      time.Unix(59, 0),
+			contains: []string{"287082", "COUNTER[white]\n1"},
+		},
+		{
+			name:     "invalid uri",
+			uri:      "https://example.com",
+			now:      time.Unix(59, 0),
+			contains: []string{"otpauth uri is invalid"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			text := otpText(&pb.Otp{Uri: tt.uri}, tt.now)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, text, s)
+			}
+		})
+	}
+}
+
+func Test_otpSummary(t *testing.T) {
+	assert.Equal(t, "ACME: john", otpSummary(&pb.Otp{Uri: rfcURI}))
+	assert.Equal(t, "john", otpSummary(&pb.Otp{Uri: "otpauth://totp/john?secret=GEZDGNBVGY3TQOJQ"}))
+	assert.Empty(t, otpSummary(&pb.Otp{Uri: "invalid"}))
+}
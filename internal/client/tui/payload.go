@@ -0,0 +1,254 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rivo/tview"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/pkg/totp"
+)
+
+var errRequiredFields = errors.New("you have to fill all required fields")
+
+var secretTypes = []string{
+	pb.SecretType_CREDENTIALS.String(),
+	pb.SecretType_TEXT.String(),
+	pb.SecretType_CARD.String(),
+	pb.SecretType_BINARY.String(),
+	pb.SecretType_OTP.String(),
+}
+
+// payloadInput collects the type-specific fields of a secret entered in a form.
+type payloadInput struct {
+	credentials *pb.Credentials
+	card        *pb.Card
+	text        *pb.Text
+	binary      *pb.Binary
+	otp         *pb.Otp
+	filePath    string
+}
+
+func newPayloadInput(payload *pb.Payload) *payloadInput {
+	p := &payloadInput{
+		credentials: &pb.Credentials{},
+		card:        &pb.Card{},
+		text:        &pb.Text{},
+		binary:      &pb.Binary{},
+		otp:         &pb.Otp{},
+	}
+
+	switch kind := payload.GetKind().(type) {
+	case *pb.Payload_Credentials:
+		p.credentials = proto.Clone(kind.Credentials).(*pb.Credentials)
+	case *pb.Payload_Card:
+		p.card = proto.Clone(kind.Card).(*pb.Card)
+	case *pb.Payload_Text:
+		p.text = proto.Clone(kind.Text).(*pb.Text)
+	case *pb.Payload_Binary:
+		p.binary = proto.Clone(kind.Binary).(*pb.Binary)
+	case *pb.Payload_Otp:
+		p.otp = proto.Clone(kind.Otp).(*pb.Otp)
+	}
+
+	return p
+}
+
+// addFields adds the input fields of the given secret type to the form.
+func (p *payloadInput) addFields(form *tview.Form, secretType pb.SecretType) {
+	switch secretType {
+	case pb.SecretType_CREDENTIALS:
+		form.AddInputField("Login *", p.credentials.Login, 40, nil, func(text string) {
+			p.credentials.Login = text
+		})
+		form.AddPasswordField("Password *", p.credentials.Password, 40, '*', func(text string) {
+			p.credentials.Password = text
+		})
+		form.AddInputField("URL", p.credentials.Url, 40, nil, func(text string) {
+			p.credentials.Url = text
+		})
+	case pb.SecretType_CARD:
+		form.AddInputField("Number *", p.card.Number, 20, nil, func(text string) {
+			p.card.Number = text
+		})
+		form.AddInputField("Holder", p.card.Holder, 40, nil, func(text string) {
+			p.card.Holder = text
+		})
+		form.AddInputField("Expiry (MM/YY)", p.card.Expiry, 6, nil, func(text string) {
+			p.card.Expiry = text
+		})
+		form.AddPasswordField("CVV", p.card.Cvv, 4, '*', func(text string) {
+			p.card.Cvv = text
+		})
+	case pb.SecretType_TEXT:
+		form.AddTextArea("Text *", p.text.Body, 40, 0, 0, func(text string) {
+			p.text.Body = text
+		})
+	case pb.SecretType_BINARY:
+		label := "File path *"
+		if hasFile(p.binary) {
+			label = fmt.Sprintf("File path (current: %s)", p.binary.Filename)
+		}
+
+		form.AddInputField(label, p.filePath, 40, nil, func(text string) {
+			p.filePath = text
+		})
+	case pb.SecretType_OTP:
+		form.AddPasswordField("otpauth URI *", p.otp.Uri, 40, '*', func(text string) {
+			p.otp.Uri = text
+		})
+	}
+}
+
+// build returns the payload of the given secret type assembled from the entered fields.
+func (p *payloadInput) build(secretType pb.SecretType) (*pb.Payload, error) {
+	switch secretType {
+	case pb.SecretType_CREDENTIALS:
+		if p.credentials.Login == "" || p.credentials.Password == "" {
+			return nil, errRequiredFields
+		}
+
+		return &pb.Payload{Kind: &pb.Payload_Credentials{Credentials: p.credentials}}, nil
+	case pb.SecretType_CARD:
+		if p.card.Number == "" {
+			return nil, errRequiredFields
+		}
+
+		return &pb.Payload{Kind: &pb.Payload_Card{Card: p.card}}, nil
+	case pb.SecretType_TEXT:
+		if p.text.Body == "" {
+			return nil, errRequiredFields
+		}
+
+		return &pb.Payload{Kind: &pb.Payload_Text{Text: p.text}}, nil
+	case pb.SecretType_BINARY:
+		if p.filePath != "" {
+			if err := p.describeFile(); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.filePath == "" && !hasFile(p.binary) {
+			return nil, errRequiredFields
+		}
+
+		return &pb.Payload{Kind: &pb.Payload_Binary{Binary: p.binary}}, nil
+	case pb.SecretType_OTP:
+		if p.otp.Uri == "" {
+			return nil, errRequiredFields
+		}
+
+		if _, err := totp.ParseURI(p.otp.Uri); err != nil {
+			return nil, err
+		}
+
+		return &pb.Payload{Kind: &pb.Payload_Otp{Otp: p.otp}}, nil
+	default:
+		return nil, errRequiredFields
+	}
+}
+
+// describeFile fills the name and MIME type of the chosen file. The file itself
+// is uploaded and attached to the secret when the secret is saved.
+func (p *payloadInput) describeFile() error {
+	file, err := os.Open(p.filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mimeType := mime.TypeByExtension(filepath.Ext(p.filePath))
+	if mimeType == "" {
+		head := make([]byte, 512)
+
+		n, err := io.ReadFull(file, head)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+
+		mimeType = http.DetectContentType(head[:n])
+	}
+
+	p.binary.Filename = filepath.Base(p.filePath)
+	p.binary.Mime = mimeType
+
+	return nil
+}
+
+// hasFile reports whether the binary payload holds a file, either inline or in an attached blob.
+func hasFile(binary *pb.Binary) bool {
+	return len(binary.GetBytes()) > 0 || binary.GetSize() > 0
+}
+
+// fileSize returns the size of the file of the binary payload.
+func fileSize(binary *pb.Binary) int64 {
+	if binary.GetSize() > 0 {
+		return binary.GetSize()
+	}
+
+	return int64(len(binary.GetBytes()))
+}
+
+// payloadText returns the details of the payload formatted for the secret text view.
+func payloadText(payload *pb.Payload) string {
+	switch kind := payload.GetKind().(type) {
+	case *pb.Payload_Credentials:
+		return fmt.Sprintf("[green]LOGIN[white]\n%s\n\n", kind.Credentials.Login) +
+			fmt.Sprintf("[green]PASSWORD[white]\n%s\n\n", kind.Credentials.Password) +
+			fmt.Sprintf("[green]URL[white]\n%s\n\n", kind.Credentials.Url)
+	case *pb.Payload_Card:
+		return fmt.Sprintf("[green]NUMBER[white]\n%s\n\n", kind.Card.Number) +
+			fmt.Sprintf("[green]HOLDER[white]\n%s\n\n", kind.Card.Holder) +
+			fmt.Sprintf("[green]EXPIRY[white]\n%s\n\n", kind.Card.Expiry) +
+			fmt.Sprintf("[green]CVV[white]\n%s\n\n", kind.Card.Cvv)
+	case *pb.Payload_Text:
+		return fmt.Sprintf("[green]TEXT[white]\n%s\n\n", tview.Escape(kind.Text.Body))
+	case *pb.Payload_Binary:
+		return fmt.Sprintf("[green]FILE[white]\n%s\n\n", kind.Binary.Filename) +
+			fmt.Sprintf("[green]MIME[white]\n%s\n\n", kind.Binary.Mime) +
+			fmt.Sprintf("[green]SIZE[white]\n%d bytes\n\n", fileSize(kind.Binary))
+	case *pb.Payload_Otp:
+		return otpText(kind.Otp, time.Now())
+	default:
+		return ""
+	}
+}
+
+// payloadSummary returns a short description of the payload that does not reveal
+// passwords or card details, suitable for the secrets list.
+func payloadSummary(payload *pb.Payload) string {
+	switch kind := payload.GetKind().(type) {
+	case *pb.Payload_Credentials:
+		if kind.Credentials.Url != "" {
+			return fmt.Sprintf("%s @ %s", kind.Credentials.Login, kind.Credentials.Url)
+		}
+
+		return kind.Credentials.Login
+	case *pb.Payload_Card:
+		number := kind.Card.Number
+		if len(number) > 4 {
+			number = number[len(number)-4:]
+		}
+
+		return fmt.Sprintf("**** %s %s", number, kind.Card.Holder)
+	case *pb.Payload_Text:
+		line, _, _ := strings.Cut(kind.Text.Body, "\n")
+
+		return line
+	case *pb.Payload_Binary:
+		return kind.Binary.Filename
+	case *pb.Payload_Otp:
+		return otpSummary(kind.Otp)
+	default:
+		return ""
+	}
+}
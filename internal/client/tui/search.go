@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// parseQuery parses the query of the search field into the filters of GetSecrets:
+//
+//	#tag folder:Bank type:card name words
+//
+// The words which are not filters form the name the secrets are searched by.
+// A folder with spaces is not supported by the query.
+func parseQuery(query string) *pb.GetSecretsRequest {
+	req := &pb.GetSecretsRequest{}
+
+	var name []string
+	for _, word := range strings.Fields(query) {
+		switch {
+		case strings.HasPrefix(word, "#") && len(word) > 1:
+			req.Tag = word[1:]
+		case strings.HasPrefix(word, "folder:"):
+			req.Folder = strings.TrimPrefix(word, "folder:")
+		case strings.HasPrefix(word, "type:"):
+			if v, ok := pb.SecretType_value[strings.ToUpper(strings.TrimPrefix(word, "type:"))]; ok {
+				req.Type = pb.SecretType(v)
+				continue
+			}
+
+			name = append(name, word)
+		default:
+			name = append(name, word)
+		}
+	}
+
+	req.Name = strings.Join(name, " ")
+
+	return req
+}
+
+// matchSecret reports whether the secret matches the filters of the request the same
+// way the server does. It is used to search the cached secrets while offline.
+func matchSecret(req *pb.GetSecretsRequest, secret *pb.SecretData) bool {
+	if req.Type != pb.SecretType_UNSPECIFIED && req.Type != secret.Type {
+		return false
+	}
+
+	if req.Folder != "" && req.Folder != secret.Folder {
+		return false
+	}
+
+	if req.Tag != "" && !hasTag(secret, req.Tag) {
+		return false
+	}
+
+	return strings.Contains(strings.ToLower(secret.Name), strings.ToLower(req.Name))
+}
+
+func hasTag(secret *pb.SecretData, tag string) bool {
+	for _, t := range secret.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// search shows the secrets matching the query of the search field. An empty query shows all secrets.
+func (a *Application) search(query string) {
+	a.searchQuery = nil
+	if strings.TrimSpace(query) != "" {
+		a.searchQuery = parseQuery(query)
+	}
+
+	a.showSecrets()
+}
+
+// searchSecrets returns the secrets matching the search query. The server is searched
+// while it is reachable and there are no changes left to sync, the cached secrets otherwise.
+func (a *Application) searchSecrets() []*pb.SecretData {
+	if a.searchQuery == nil {
+		return a.state.View()
+	}
+
+	if a.offlineAuth == nil && !a.unreachable && len(a.state.Pending) == 0 {
+		if secrets, err := a.getSecrets(a.searchQuery, a.vault != nil); err == nil {
+			return secrets
+		}
+	}
+
+	var secrets []*pb.SecretData
+	for _, secret := range a.state.View() {
+		if matchSecret(a.searchQuery, secret) {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	return secrets
+}
+
+// getSecrets fetches all pages of the secrets matching the filters of the request.
+// The secrets are decrypted with the vault if decrypt is set.
+func (a *Application) getSecrets(filter *pb.GetSecretsRequest, decrypt bool) ([]*pb.SecretData, error) {
+	var secrets []*pb.SecretData
+
+	req := &pb.GetSecretsRequest{
+		Type:   filter.Type,
+		Tag:    filter.Tag,
+		Folder: filter.Folder,
+		Name:   filter.Name,
+	}
+
+	for {
+		var resp *pb.GetSecretsResponse
+		err := a.callWithRefresh(func(ctx context.Context) error {
+			var err error
+			resp, err = a.secretsClient.GetSecrets(ctx, req)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, secret := range resp.Secrets {
+			if decrypt {
+				if err := a.vault.DecryptSecret(secret); err != nil {
+					return nil, err
+				}
+			}
+
+			secrets = append(secrets, secret)
+		}
+
+		if resp.NextPageToken == "" {
+			return secrets, nil
+		}
+
+		req.PageToken = resp.NextPageToken
+	}
+}
@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func Test_parseQuery(t *testing.T) {
+	tests := []struct {
+		expected *pb.GetSecretsRequest
+		name     string
+		query    string
+	}{
+		{
+			name:     "name only",
+			query:    "  my bank  ",
+			expected: &pb.GetSecretsRequest{Name: "my bank"},
+		},
+		{
+			name:  "all filters",
+			query: "#work folder:Bank type:card visa",
+			expected: &pb.GetSecretsRequest{
+				Type:   pb.SecretType_CARD,
+				Tag:    "work",
+				Folder: "Bank",
+				Name:   "visa",
+			},
+		},
+		{
+			name:     "unknown type is part of the name",
+			query:    "type:car",
+			expected: &pb.GetSecretsRequest{Name: "type:car"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseQuery(tt.query))
+		})
+	}
+}
+
+func Test_matchSecret(t *testing.T) {
+	secret := &pb.SecretData{
+		Type:   pb.SecretType_CARD,
+		Name:   "Work Visa",
+		Folder: "Bank",
+		Tags:   []string{"work", "travel"},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{name: "empty query", query: "", expected: true},
+		{name: "name regardless of case", query: "visa", expected: true},
+		{name: "all filters", query: "#travel folder:Bank type:card work", expected: true},
+		{name: "other name", query: "mastercard", expected: false},
+		{name: "other tag", query: "#home", expected: false},
+		{name: "other folder", query: "folder:Mail", expected: false},
+		{name: "other type", query: "type:text", expected: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, matchSecret(parseQuery(tt.query), secret))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+var errShareOffline = errors.New("sharing is not available offline")
+
+// sharePermissions are the options of the permission drop-down in the order of their labels.
+var sharePermissions = []pb.SharePermission{pb.SharePermission_READ_ONLY, pb.SharePermission_READ_WRITE}
+
+// addShareForm lets the user share the selected secret with another user or revoke the access.
+func (a *Application) addShareForm() {
+	secret := a.selectedSecret
+
+	if a.offlineAuth != nil || a.unreachable {
+		a.addErrorWindow(errShareOffline.Error(), secretsPanelPageName)
+		return
+	}
+
+	a.shareForm.Clear(true)
+	a.shareForm.SetBorder(true).SetTitle(fmt.Sprintf("Share %s", secretTitle(secret)))
+	a.Pages.SwitchToPage(sharePageName)
+
+	var login string
+	permission := sharePermissions[0]
+
+	a.shareForm.AddInputField("User login", "", 20, nil, func(text string) {
+		login = text
+	})
+
+	a.shareForm.AddDropDown("Access", []string{"Read only", "Read and write"}, 0, func(option string, index int) {
+		if index >= 0 {
+			permission = sharePermissions[index]
+		}
+	})
+
+	a.shareForm.AddButton(shareLabel, func() {
+		a.shareSecret(func(ctx context.Context) error {
+			_, err := a.secretsClient.Share(ctx, &pb.ShareRequest{
+				SecretId:    secret.Id,
+				TargetLogin: login,
+				Permission:  permission,
+			})
+			return err
+		})
+	})
+
+	a.shareForm.AddButton(unshareLabel, func() {
+		a.shareSecret(func(ctx context.Context) error {
+			_, err := a.secretsClient.Unshare(ctx, &pb.UnshareRequest{SecretId: secret.Id, TargetLogin: login})
+			return err
+		})
+	})
+
+	a.shareForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+// shareSecret changes the access to the secret with the call. Sharing increments the version
+// of the secret, so the secrets are synced once the change is made.
+func (a *Application) shareSecret(call func(ctx context.Context) error) {
+	if err := a.callWithRefresh(call); err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), sharePageName)
+		return
+	}
+
+	a.addSecretsList()
+	a.Pages.SwitchToPage(secretsPanelPageName)
+}
+
+// shareText returns the owner of the secret shared with the user and the access granted to the user.
+func shareText(secret *pb.SecretData) string {
+	if secret.Permission == pb.SharePermission_READ_WRITE {
+		return fmt.Sprintf("%s (read and write)", secret.Owner)
+	}
+
+	return fmt.Sprintf("%s (read only)", secret.Owner)
+}
+
+// collectionText returns the organization and the collection the secret belongs to.
+func collectionText(secret *pb.SecretData) string {
+	return fmt.Sprintf("%s / %s", secret.Organization, secret.Collection)
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func Test_shareText(t *testing.T) {
+	tests := []struct {
+		secret   *pb.SecretData
+		name     string
+		expected string
+	}{
+		{
+			name:     "read only",
+			secret:   &pb.SecretData{Shared: true, Owner: "alice", Permission: pb.SharePermission_READ_ONLY},
+			expected: "alice (read only)",
+		},
+		{
+			name:     "read and write",
+			secret:   &pb.SecretData{Shared: true, Owner: "alice", Permission: pb.SharePermission_READ_WRITE},
+			expected: "alice (read and write)",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, shareText(tt.secret))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rivo/tview"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+var errTrashOffline = errors.New("trash is not available offline")
+
+// addTrashList shows the secrets in the trash. A selected secret can be restored or deleted forever.
+func (a *Application) addTrashList() {
+	if a.offlineAuth != nil || a.unreachable {
+		a.addErrorWindow(errTrashOffline.Error(), secretsPanelPageName)
+		return
+	}
+
+	a.trashList.Clear()
+	a.trashList.SetBorder(true).SetTitle("Trash")
+	a.Pages.SwitchToPage(trashPageName)
+
+	var resp *pb.ListTrashResponse
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		resp, err = a.secretsClient.ListTrash(ctx, &pb.ListTrashRequest{})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
+		return
+	}
+
+	for _, secret := range resp.Secrets {
+		secret := secret
+
+		if a.vault != nil {
+			if err := a.vault.DecryptSecret(secret); err != nil {
+				a.addErrorWindow(fmt.Sprintf("failed to decrypt secret: %s", err), secretsPanelPageName)
+				return
+			}
+		}
+
+		a.trashList.AddItem(tview.Escape(secretTitle(secret)), tview.Escape(trashSubtitle(secret)), 0, func() {
+			a.addTrashWindow(secret)
+		})
+	}
+
+	a.trashList.AddItem(backLabel, "", 'b', func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+// addTrashWindow asks whether to restore the secret in the trash or to delete it forever.
+func (a *Application) addTrashWindow(secret *pb.SecretData) {
+	a.deleteWindow.ClearButtons()
+	a.Pages.SwitchToPage(deleteWindowName)
+
+	a.deleteWindow.SetText(fmt.Sprintf("%s\n\nRestore the secret or delete it forever?", secretTitle(secret))).
+		AddButtons([]string{restoreLabel, purgeLabel, backLabel}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			switch buttonLabel {
+			case restoreLabel:
+				a.restoreSecret(secret)
+			case purgeLabel:
+				a.purgeSecret(secret)
+			default:
+				a.Pages.SwitchToPage(trashPageName)
+			}
+		})
+}
+
+// restoreSecret moves the secret out of the trash. The secret is back in the secrets list after the sync.
+func (a *Application) restoreSecret(secret *pb.SecretData) {
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.secretsClient.Restore(ctx, &pb.RestoreRequest{SecretId: secret.Id})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), trashPageName)
+		return
+	}
+
+	a.syncPending = true
+	a.addTrashList()
+}
+
+// purgeSecret removes the secret from the trash permanently.
+func (a *Application) purgeSecret(secret *pb.SecretData) {
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.secretsClient.Purge(ctx, &pb.PurgeRequest{SecretId: secret.Id})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), trashPageName)
+		return
+	}
+
+	a.addTrashList()
+}
+
+// trashSubtitle returns the type of the secret and the time it was deleted for the list of the trash.
+func trashSubtitle(secret *pb.SecretData) string {
+	return fmt.Sprintf("%s · deleted %s", secret.Type, secret.DeletedAt.AsTime().Local().Format(time.DateTime))
+}
@@ -0,0 +1,18 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func Test_trashSubtitle(t *testing.T) {
+	deletedAt := time.Date(2026, 10, 17, 12, 30, 0, 0, time.Local)
+
+	subtitle := trashSubtitle(&pb.SecretData{Type: pb.SecretType_CARD, DeletedAt: timestamppb.New(deletedAt)})
+	assert.Equal(t, "CARD · deleted 2026-10-17 12:30:00", subtitle)
+}
@@ -3,15 +3,29 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/client/cache"
 	"github.com/PrahaTurbo/goph-keeper/internal/client/config"
+	"github.com/PrahaTurbo/goph-keeper/internal/client/vault"
+)
+
+var (
+	errNoRefreshToken = errors.New("session has no refresh token")
+	errPendingChanges = errors.New("changes made offline have to be synced first")
 )
 
 // Application holds all the components necessary for the terminal interface of the application.
@@ -24,22 +38,44 @@ type Application struct {
 	errorWindow    *tview.Modal
 	secretsPanel   *tview.Flex
 	secretsList    *tview.List
+	sessionsList   *tview.List
+	trashList      *tview.List
 	startMenu      *tview.Modal
 	secretsDetails *tview.Flex
 	authForm       *tview.Form
 	editForm       *tview.Form
+	totpForm       *tview.Form
+	accountForm    *tview.Form
+	fileForm       *tview.Form
+	shareForm      *tview.Form
 	deleteWindow   *tview.Modal
+	syncStatus     *tview.TextView
+	searchField    *tview.InputField
+	searchQuery    *pb.GetSecretsRequest
 	selectedSecret *pb.SecretData
+	vault          *vault.Vault
+	cache          *cache.Cache
+	state          *cache.State
+	offlineAuth    *pb.AuthRequest
 	Pages          *tview.Pages
 	App            *tview.Application
 	authStatus     string
+	login          string
+	refreshToken   string
+	cacheDir       string
+	server         string
+	watchCancel    context.CancelFunc
+	otpCancel      context.CancelFunc
 	secrets        []*pb.SecretData
+	syncPending    bool
+	unreachable    bool
 }
 
 // NewApplication is a constructor function for Application.
 // It initializes Application with necessary tview and ProtoBuf clients. It also sets
-// up the pages and the menu in this function.
-func NewApplication(authClient pb.AuthClient, secretsClient pb.SecretClient) Application {
+// up the pages and the menu in this function. The secrets are cached for offline use
+// in the cache directory of the configuration.
+func NewApplication(authClient pb.AuthClient, secretsClient pb.SecretClient, cfg *config.Config) Application {
 	c := Application{
 		App:            tview.NewApplication(),
 		Pages:          tview.NewPages(),
@@ -49,12 +85,23 @@ func NewApplication(authClient pb.AuthClient, secretsClient pb.SecretClient) App
 		secretsPanel:   tview.NewFlex(),
 		secretsDetails: tview.NewFlex(),
 		secretsList:    tview.NewList(),
+		sessionsList:   tview.NewList(),
+		trashList:      tview.NewList(),
 		secretText:     tview.NewTextView(),
 		createForm:     tview.NewForm(),
 		editForm:       tview.NewForm(),
+		totpForm:       tview.NewForm(),
+		accountForm:    tview.NewForm(),
+		fileForm:       tview.NewForm(),
+		shareForm:      tview.NewForm(),
 		deleteWindow:   tview.NewModal(),
+		syncStatus:     tview.NewTextView(),
+		searchField:    tview.NewInputField(),
+		state:          &cache.State{},
 		authClient:     authClient,
 		secretsClient:  secretsClient,
+		cacheDir:       cfg.CacheDir,
+		server:         fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 	}
 
 	c.setupPages()
@@ -74,6 +121,18 @@ func (a *Application) setupPages() {
 	a.Pages.AddPage(createPageName, a.createForm, true, false)
 	a.Pages.AddPage(editPageName, a.editForm, true, false)
 	a.Pages.AddPage(deleteWindowName, a.deleteWindow, true, false)
+	a.Pages.AddPage(sessionsPageName, a.sessionsList, true, false)
+	a.Pages.AddPage(totpPageName, a.totpForm, true, false)
+	a.Pages.AddPage(accountPageName, a.accountForm, true, false)
+	a.Pages.AddPage(filePageName, a.fileForm, true, false)
+	a.Pages.AddPage(trashPageName, a.trashList, true, false)
+	a.Pages.AddPage(sharePageName, a.shareForm, true, false)
+
+	a.Pages.SetChangedFunc(func() {
+		if name, _ := a.Pages.GetFrontPage(); name == secretsPanelPageName && a.syncPending {
+			a.addSecretsList()
+		}
+	})
 }
 
 func (a *Application) setupStartMenu() {
@@ -103,15 +162,19 @@ func (a *Application) setupSecretsPanel() {
 	quitButton := newButton(quitLabel, a.App.Stop)
 	createButton := newButton(createLabel, a.addCreateForm)
 	syncButton := newButton(syncLabel, a.addSecretsList)
-	editButton := newButton(editLabel, a.addEditForm)
-	deleteButton := newButton(deleteLabel, a.addDeleteWindow)
-	deleteButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorRed))
+	trashButton := newButton(trashLabel, a.addTrashList)
+	sessionsButton := newButton(sessionsLabel, a.addSessionsList)
+	logoutButton := newButton(logoutLabel, a.logout)
+	totpButton := newButton(totpLabel, a.addTOTPForm)
+	accountButton := newButton(accountLabel, a.addAccountForm)
 
 	a.secretsPanel.SetDirection(tview.FlexRow).
 		AddItem(tview.NewFlex().
 			AddItem(secretsListFlex, 0, 2, true).
 			AddItem(a.secretsDetails, 0, 4, false), 0, 6, true).
-		AddItem(footer, 1, 0, true)
+		AddItem(tview.NewFlex().
+			AddItem(a.syncStatus, 0, 1, false).
+			AddItem(footer, 0, 1, false), 1, 0, true)
 
 	secretsListFlex.Box = tview.NewBox().SetBorder(true).SetTitle("Secrets")
 	secretsListFlex.AddItem(tview.NewFlex().
@@ -119,98 +182,218 @@ func (a *Application) setupSecretsPanel() {
 		AddItem(tview.NewBox(), 1, 0, false).
 		AddItem(createButton, 0, 1, false).
 		AddItem(tview.NewBox(), 1, 0, false).
-		AddItem(syncButton, 0, 1, false), 1, 0, false).
+		AddItem(syncButton, 0, 1, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(trashButton, 0, 1, false), 1, 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(sessionsButton, 0, 1, false).
+			AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(totpButton, 0, 1, false).
+			AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(accountButton, 0, 1, false).
+			AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(logoutButton, 0, 1, false), 1, 0, false).
+		AddItem(tview.NewBox(), 1, 0, false).
+		AddItem(a.searchField, 1, 0, false).
 		AddItem(a.secretsList, 0, 10, true)
 
+	a.searchField.SetLabel("Search ").
+		SetPlaceholder("name #tag folder:name type:card").
+		SetDoneFunc(func(key tcell.Key) {
+			if key == tcell.KeyEscape {
+				a.searchField.SetText("")
+			}
+
+			a.search(a.searchField.GetText())
+			a.App.SetFocus(a.secretsList)
+		})
+
 	a.secretsDetails.Box = tview.NewBox().SetBorder(true).SetTitle("Details")
 
 	a.secretText.SetDynamicColors(true)
 
 	a.secretsList.SetBorderPadding(1, 0, 0, 0)
 	a.secretsList.SetSelectedFunc(func(index int, name string, secondName string, shortcut rune) {
-		a.secretsDetails.Clear()
-		a.secretsDetails.AddItem(tview.NewFlex().
-			AddItem(editButton, 0, 1, false).
-			AddItem(tview.NewBox(), 1, 0, false).
-			AddItem(deleteButton, 0, 1, false).
-			AddItem(tview.NewBox(), 0, 4, false), 1, 0, false).
-			AddItem(tview.NewBox(), 1, 0, true).
-			AddItem(a.secretText, 0, 10, true)
+		// Folder headings of the list have no secret.
+		if a.secrets[index] == nil {
+			return
+		}
 
-		a.selectedSecret = a.secrets[index]
-		a.setSecretText(a.secrets[index])
+		a.showSecretDetails(a.secrets[index])
 	})
 }
 
+// showSecretDetails selects the secret and shows its details with the actions on it.
+// Secrets shared with the user can only be edited, if the user has write access to them.
+// Secrets of collections can be edited, deleted and restored from their history, if the role
+// of the user allows writing them, they cannot be shared.
+func (a *Application) showSecretDetails(secret *pb.SecretData) {
+	buttons := tview.NewFlex()
+
+	owned := !secret.Shared && secret.CollectionId == 0
+	writable := owned || secret.Permission == pb.SharePermission_READ_WRITE
+
+	if writable {
+		buttons.AddItem(newButton(editLabel, a.addEditForm), 0, 1, false)
+	}
+
+	if !secret.Shared && writable {
+		deleteButton := newButton(deleteLabel, a.addDeleteWindow)
+		deleteButton.SetStyle(tcell.StyleDefault.Background(tcell.ColorRed))
+
+		buttons.AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(deleteButton, 0, 1, false)
+	}
+
+	if owned || secret.CollectionId != 0 && writable {
+		buttons.AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(newButton(historyLabel, a.addHistory), 0, 1, false)
+	}
+
+	switch {
+	case secret.Type == pb.SecretType_BINARY:
+		buttons.AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(newButton(saveFileLabel, a.addFileForm), 0, 1, false)
+	case owned:
+		buttons.AddItem(tview.NewBox(), 1, 0, false).
+			AddItem(newButton(shareLabel, a.addShareForm), 0, 1, false)
+	}
+
+	a.secretsDetails.Clear()
+	a.secretsDetails.AddItem(buttons.
+		AddItem(tview.NewBox(), 0, 4, false), 1, 0, false).
+		AddItem(tview.NewBox(), 1, 0, true).
+		AddItem(a.secretText, 0, 10, true)
+
+	a.selectedSecret = secret
+	a.setSecretText(secret)
+}
+
 func (a *Application) addDeleteWindow() {
 	a.deleteWindow.ClearButtons()
 	a.Pages.SwitchToPage(deleteWindowName)
 
-	a.deleteWindow.SetText("Are you sure?").
+	a.deleteWindow.SetText("Move the secret to the trash?").
 		AddButtons([]string{deleteLabel, backLabel}).
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			switch buttonIndex {
 			case 0:
-				req := &pb.DeleteRequest{SecretId: a.selectedSecret.Id}
-				_, err := a.secretsClient.Delete(a.appContext, req)
-				if err != nil {
-					s := status.Convert(err)
-					a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
-					return
-				}
+				a.deleteSecret(a.selectedSecret.Id, a.selectedSecret.Version)
+			case 1:
+				a.Pages.SwitchToPage(secretsPanelPageName)
+			}
+		})
+}
+
+// deleteSecret deletes the given version of the secret. If the secret was changed on another
+// device in the meantime, the user is asked whether to delete it anyway.
+func (a *Application) deleteSecret(secretID int64, version int64) {
+	err := a.apply(cache.Operation{
+		Kind:   cache.OpDelete,
+		Secret: &pb.SecretData{Id: secretID, Version: version},
+	})
+
+	if current, ok := a.conflictSecret(err); ok {
+		a.addDeleteConflictWindow(current)
+		return
+	}
+
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
+		return
+	}
+
+	a.addSecretsList()
+	a.Pages.SwitchToPage(secretsPanelPageName)
+}
 
+// addDeleteConflictWindow asks whether to delete the secret which was changed on another device.
+func (a *Application) addDeleteConflictWindow(current *pb.SecretData) {
+	a.deleteWindow.ClearButtons()
+	a.Pages.SwitchToPage(deleteWindowName)
+
+	a.deleteWindow.SetText("The secret was changed on another device. Move it to the trash anyway?").
+		AddButtons([]string{deleteLabel, backLabel}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != deleteLabel {
 				a.addSecretsList()
 				a.Pages.SwitchToPage(secretsPanelPageName)
-			case 1:
-				a.Pages.SwitchToPage(secretsPanelPageName)
+				return
 			}
+
+			a.deleteSecret(current.Id, current.Version)
 		})
 }
 
 func (a *Application) addEditForm() {
+	a.showEditForm(a.selectedSecret, proto.Clone(a.selectedSecret).(*pb.SecretData))
+}
+
+// showEditForm shows the edit form filled with the draft. The update is based on
+// the version of the base secret and is rejected if the secret was changed since.
+func (a *Application) showEditForm(base *pb.SecretData, draft *pb.SecretData) {
 	a.editForm.Clear(true)
 	a.Pages.SwitchToPage(editPageName)
 
-	req := &pb.UpdateRequest{
-		SecretId: a.selectedSecret.Id,
-		Type:     a.selectedSecret.Type,
-		Content:  a.selectedSecret.Content,
-		MetaData: a.selectedSecret.MetaData,
+	edited := &pb.SecretData{
+		Id:        base.Id,
+		Type:      draft.Type,
+		MetaData:  draft.MetaData,
+		CreatedAt: base.CreatedAt,
+		Version:   base.Version,
+		BlobId:    draft.BlobId,
+		Name:      draft.Name,
+		Tags:      draft.Tags,
+		Folder:    draft.Folder,
 	}
 
-	typeList := []string{
-		pb.SecretType_CREDENTIALS.String(),
-		pb.SecretType_TEXT.String(),
-		pb.SecretType_CARD.String(),
-		pb.SecretType_BINARY.String(),
-	}
+	input := newPayloadInput(draft.Payload)
 
 	var initialOption int
-	for i := range typeList {
-		if typeList[i] == a.selectedSecret.Type.String() {
+	for i := range secretTypes {
+		if secretTypes[i] == draft.Type.String() {
 			initialOption = i
 		}
 	}
 
-	a.editForm.AddDropDown("Type", typeList, initialOption, func(option string, optionIndex int) {
+	a.editForm.AddDropDown("Type", secretTypes, initialOption, func(option string, optionIndex int) {
 		secretType := pb.SecretType_UNSPECIFIED
 		if v, ok := pb.SecretType_value[option]; ok {
 			secretType = pb.SecretType(v)
 		}
 
-		req.Type = secretType
-	})
+		if secretType == edited.Type || a.editForm.GetFormItemCount() == 0 {
+			return
+		}
 
-	a.editForm.AddTextArea("Content", a.selectedSecret.Content, 40, 0, 0, func(text string) {
-		req.Content = text
+		edited.Type = secretType
+		setPayloadFields(a.editForm, input, edited)
 	})
 
-	a.editForm.AddTextArea("Additional info", a.selectedSecret.MetaData, 40, 0, 0, func(text string) {
-		req.MetaData = text
-	})
+	setPayloadFields(a.editForm, input, edited)
 
 	a.editForm.AddButton(updateLabel, func() {
-		_, err := a.secretsClient.Update(a.appContext, req)
+		payload, err := input.build(edited.Type)
+		if err != nil {
+			a.addErrorWindow(err.Error(), editPageName)
+			return
+		}
+
+		edited.Payload = payload
+
+		if err := a.attachFile(edited, input.filePath); err != nil {
+			a.addErrorWindow(err.Error(), editPageName)
+			return
+		}
+
+		err = a.apply(cache.Operation{Kind: cache.OpUpdate, Secret: edited, Base: base})
+		if current, ok := a.conflictSecret(err); ok {
+			a.addConflictWindow(base, proto.Clone(edited).(*pb.SecretData), current)
+			return
+		}
+
 		if err != nil {
 			s := status.Convert(err)
 			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), editPageName)
@@ -226,42 +409,204 @@ func (a *Application) addEditForm() {
 	})
 }
 
+// createSecret sends the new plain secret to the server, encrypted with the vault if it is set.
+// The secret encrypted with the vault is bound to the ID reserved for it beforehand.
+func (a *Application) createSecret(secret *pb.SecretData) error {
+	req := &pb.CreateRequest{
+		Type:     secret.Type,
+		Payload:  secret.Payload,
+		MetaData: secret.MetaData,
+		BlobId:   secret.BlobId,
+		Name:     secret.Name,
+		Tags:     secret.Tags,
+		Folder:   secret.Folder,
+	}
+
+	if a.vault != nil {
+		err := a.callWithRefresh(func(ctx context.Context) error {
+			resp, err := a.secretsClient.ReserveID(ctx, &emptypb.Empty{})
+			if err != nil {
+				return err
+			}
+
+			req.SecretId = resp.SecretId
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if req.Payload, err = a.vault.EncryptPayload(req.SecretId, req.Type, secret.Payload); err != nil {
+			return err
+		}
+
+		if req.MetaData, err = a.vault.EncryptMetaData(req.SecretId, req.Type, secret.MetaData); err != nil {
+			return err
+		}
+	}
+
+	return a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.secretsClient.Create(ctx, req)
+		return err
+	})
+}
+
+// updateSecret sends the plain secret to the server, encrypted with the vault if it is set.
+func (a *Application) updateSecret(secret *pb.SecretData) error {
+	req := &pb.UpdateRequest{
+		SecretId: secret.Id,
+		Type:     secret.Type,
+		Payload:  secret.Payload,
+		MetaData: secret.MetaData,
+		Version:  secret.Version,
+		BlobId:   secret.BlobId,
+		Name:     secret.Name,
+		Tags:     secret.Tags,
+		Folder:   secret.Folder,
+	}
+
+	if a.vault != nil {
+		var err error
+		if req.Payload, err = a.vault.EncryptPayload(req.SecretId, req.Type, secret.Payload); err != nil {
+			return err
+		}
+
+		if req.MetaData, err = a.vault.EncryptMetaData(req.SecretId, req.Type, secret.MetaData); err != nil {
+			return err
+		}
+	}
+
+	return a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.secretsClient.Update(ctx, req)
+		return err
+	})
+}
+
+// addConflictWindow is shown when the secret was changed on another device while the user
+// was editing it. The user can merge both changes, overwrite the other change or continue editing.
+func (a *Application) addConflictWindow(base, mine, current *pb.SecretData) {
+	a.deleteWindow.ClearButtons()
+	a.Pages.SwitchToPage(deleteWindowName)
+
+	a.deleteWindow.SetText("The secret was changed on another device.\n" +
+		"Merge opens the form with both changes, Overwrite discards the other change.").
+		AddButtons([]string{mergeLabel, overwriteLabel, backLabel}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			switch buttonLabel {
+			case mergeLabel:
+				a.showEditForm(current, mergeSecrets(base, mine, current))
+			case overwriteLabel:
+				mine.Version = current.Version
+				a.showEditForm(current, mine)
+			default:
+				a.showEditForm(base, mine)
+			}
+		})
+}
+
+// conflictSecret returns the current copy of the secret if the server rejected
+// a write because the secret was changed since it was fetched.
+func (a *Application) conflictSecret(err error) (*pb.SecretData, bool) {
+	st := status.Convert(err)
+	if err == nil || st.Code() != codes.Aborted {
+		return nil, false
+	}
+
+	for _, detail := range st.Details() {
+		current, ok := detail.(*pb.SecretData)
+		if !ok {
+			continue
+		}
+
+		if a.vault != nil {
+			if err := a.vault.DecryptSecret(current); err != nil {
+				return nil, false
+			}
+		}
+
+		return current, true
+	}
+
+	return nil, false
+}
+
+// addFileForm asks where to save the file of the selected binary secret.
+func (a *Application) addFileForm() {
+	a.fileForm.Clear(true)
+	a.Pages.SwitchToPage(filePageName)
+
+	secret := a.selectedSecret
+	filePath := secret.GetPayload().GetBinary().GetFilename()
+
+	a.fileForm.AddInputField("Save to *", filePath, 40, nil, func(text string) {
+		filePath = text
+	})
+
+	a.fileForm.AddButton(saveLabel, func() {
+		if filePath == "" {
+			a.addErrorWindow(errRequiredFields.Error(), filePageName)
+			return
+		}
+
+		if err := a.saveFile(secret, filePath); err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("failed to save file: %s", s.Message()), filePageName)
+			return
+		}
+
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+
+	a.fileForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
 func (a *Application) addCreateForm() {
 	a.createForm.Clear(true)
 	a.Pages.SwitchToPage(createPageName)
 
-	req := &pb.CreateRequest{}
+	secret := &pb.SecretData{}
+	input := newPayloadInput(nil)
 
-	a.createForm.AddDropDown("Type *", []string{
-		pb.SecretType_CREDENTIALS.String(),
-		pb.SecretType_TEXT.String(),
-		pb.SecretType_CARD.String(),
-		pb.SecretType_BINARY.String(),
-	}, -1, func(option string, optionIndex int) {
+	a.createForm.AddDropDown("Type *", secretTypes, -1, func(option string, optionIndex int) {
 		secretType := pb.SecretType_UNSPECIFIED
 		if v, ok := pb.SecretType_value[option]; ok {
 			secretType = pb.SecretType(v)
 		}
 
-		req.Type = secretType
-	})
+		if secretType == secret.Type || a.createForm.GetFormItemCount() == 0 {
+			return
+		}
 
-	a.createForm.AddTextArea("Content *", "", 40, 0, 0, func(text string) {
-		req.Content = text
+		secret.Type = secretType
+		setPayloadFields(a.createForm, input, secret)
 	})
 
-	a.createForm.AddTextArea("Additional info", "", 40, 0, 0, func(text string) {
-		req.MetaData = text
-	})
+	setPayloadFields(a.createForm, input, secret)
 
 	a.createForm.AddButton(saveLabel, func() {
-		if req.Type == pb.SecretType_UNSPECIFIED || req.Content == "" {
-			a.addErrorWindow("You have to fill all required fields", createPageName)
+		if secret.Type == pb.SecretType_UNSPECIFIED {
+			a.addErrorWindow(errRequiredFields.Error(), createPageName)
 			return
 		}
 
-		_, err := a.secretsClient.Create(a.appContext, req)
+		payload, err := input.build(secret.Type)
 		if err != nil {
+			a.addErrorWindow(err.Error(), createPageName)
+			return
+		}
+
+		secret.Payload = payload
+		secret.CreatedAt = timestamppb.Now()
+
+		if err := a.attachFile(secret, input.filePath); err != nil {
+			a.addErrorWindow(err.Error(), createPageName)
+			return
+		}
+
+		if err := a.apply(cache.Operation{Kind: cache.OpCreate, Secret: secret}); err != nil {
 			s := status.Convert(err)
 			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), createPageName)
 			return
@@ -276,35 +621,120 @@ func (a *Application) addCreateForm() {
 	})
 }
 
+// setPayloadFields replaces the form items following the type drop-down with the name,
+// the fields of the type of the secret, the folder, the tags and the additional info text area.
+func setPayloadFields(form *tview.Form, input *payloadInput, secret *pb.SecretData) {
+	for form.GetFormItemCount() > 1 {
+		form.RemoveFormItem(1)
+	}
+
+	form.AddInputField("Name", secret.Name, 40, nil, func(text string) {
+		secret.Name = text
+	})
+
+	input.addFields(form, secret.Type)
+
+	form.AddInputField("Folder", secret.Folder, 40, nil, func(text string) {
+		secret.Folder = text
+	})
+	form.AddInputField("Tags (comma separated)", strings.Join(secret.Tags, ", "), 40, nil, func(text string) {
+		secret.Tags = splitTags(text)
+	})
+	form.AddTextArea("Additional info", secret.MetaData, 40, 0, 0, func(text string) {
+		secret.MetaData = text
+	})
+}
+
 func (a *Application) setSecretText(secret *pb.SecretData) {
 	a.secretText.Clear()
+	a.secretText.SetText(secretDetailsText(secret))
+}
+
+// secretDetailsText returns the details of the secret formatted for a text view.
+func secretDetailsText(secret *pb.SecretData) string {
+	var text string
+	if secret.Name != "" {
+		text += fmt.Sprintf("[green]NAME[white]\n%s\n\n", tview.Escape(secret.Name))
+	}
+
+	text += fmt.Sprintf("[green]TYPE[white]\n%s\n\n", secret.Type) +
+		payloadText(secret.Payload)
+
+	if secret.Shared {
+		text += fmt.Sprintf("[green]SHARED BY[white]\n%s\n\n", tview.Escape(shareText(secret)))
+	}
+
+	if secret.CollectionId != 0 {
+		text += fmt.Sprintf("[green]COLLECTION[white]\n%s\n\n", tview.Escape(collectionText(secret)))
+	}
 
-	text := fmt.Sprintf("[green]TYPE[white]\n%s\n\n", secret.Type) +
-		fmt.Sprintf("[green]CONTENT[white]\n%s\n\n", secret.Content)
+	if secret.Folder != "" {
+		text += fmt.Sprintf("[green]FOLDER[white]\n%s\n\n", tview.Escape(secret.Folder))
+	}
+
+	if len(secret.Tags) > 0 {
+		text += fmt.Sprintf("[green]TAGS[white]\n%s\n\n", tview.Escape(strings.Join(secret.Tags, ", ")))
+	}
 
 	if secret.MetaData != "" {
 		text += fmt.Sprintf("[green]META DATA[white]\n%s\n\n", secret.MetaData)
 	}
 
-	a.secretText.SetText(text)
+	return text
 }
 
+// addSecretsList sends the changes made offline, fetches the changes of the secrets made
+// since the last sync and shows the secrets. The cached secrets are shown while the server
+// is unreachable.
 func (a *Application) addSecretsList() {
-	a.secretsList.Clear()
-	a.secretText.Clear()
-	a.secretsDetails.Clear()
+	a.syncPending = false
+
+	if a.offlineAuth != nil {
+		if err := a.reconnect(); status.Code(err) == codes.Unavailable {
+			a.showSecrets()
+		}
+
+		return
+	}
+
+	if err := a.replay(); err != nil {
+		a.unreachable = status.Code(err) == codes.Unavailable
+		a.showSecrets()
+
+		return
+	}
+
+	var resp *pb.SyncResponse
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		resp, err = a.secretsClient.Sync(ctx, &pb.SyncRequest{SinceRevision: a.state.Revision})
+		return err
+	})
+
+	a.unreachable = status.Code(err) == codes.Unavailable
+	if a.unreachable {
+		a.showSecrets()
+		return
+	}
 
-	resp, err := a.secretsClient.GetSecrets(a.appContext, &pb.GetSecretsRequest{})
 	if err != nil {
 		s := status.Convert(err)
 		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
 		return
 	}
 
-	a.secrets = resp.Secrets
-	for i, s := range resp.Secrets {
-		a.secretsList.AddItem(s.Type.String(), s.Content, rune(49+i), nil)
+	if a.vault != nil {
+		for _, secret := range resp.Secrets {
+			if err := a.vault.DecryptSecret(secret); err != nil {
+				a.addErrorWindow(fmt.Sprintf("failed to decrypt secret: %s", err), secretsPanelPageName)
+				return
+			}
+		}
 	}
+
+	a.state.ApplyChanges(resp)
+	a.saveCache()
+	a.showSecrets()
 }
 
 func (a *Application) setupAuthForm() {
@@ -314,17 +744,45 @@ func (a *Application) setupAuthForm() {
 		req.Login = login
 	})
 
-	a.authForm.AddPasswordField("Password", "", 20, '*', func(password string) {
-		req.Password = password
+	var password string
+
+	a.authForm.AddPasswordField("Password", "", 20, '*', func(text string) {
+		password = text
+	})
+
+	a.authForm.AddCheckbox("Client-side encryption", false, func(checked bool) {
+		req.ClientSideEncryption = checked
 	})
 
 	a.authForm.AddButton(submitLabel, func() {
 		var resp *pb.AuthResponse
 		var err error
 
+		// In the client-side encryption mode the master password never leaves the client:
+		// the server gets a password derived from it, and secrets are encrypted with the vault key.
+		a.vault = nil
+		a.login = req.Login
+		req.Password = password
+
+		if req.ClientSideEncryption {
+			v, err := vault.NewVault(req.Login, password)
+			if err != nil {
+				a.addErrorWindow(err.Error(), authPageName)
+				return
+			}
+
+			a.vault = v
+			req.Password = v.AuthPassword()
+		}
+
 		switch a.authStatus {
 		case loginLabel:
 			resp, err = a.authClient.Login(context.Background(), req)
+			if status.Code(err) == codes.Unavailable {
+				a.loginOffline(req, password)
+				return
+			}
+
 			if err != nil {
 				s := status.Convert(err)
 				a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), authPageName)
@@ -339,11 +797,14 @@ func (a *Application) setupAuthForm() {
 			}
 		}
 
-		md := metadata.Pairs(authentication, fmt.Sprintf("%s %s", bearerSchema, resp.Token))
-		a.appContext = metadata.NewOutgoingContext(a.appContext, md)
+		a.openCache(req.Login, password)
 
-		a.addSecretsList()
-		a.Pages.SwitchToPage(secretsPanelPageName)
+		if resp.TotpChallenge != "" {
+			a.addLoginTOTPForm(resp.TotpChallenge)
+			return
+		}
+
+		a.startSession(resp)
 	})
 
 	a.authForm.AddButton(backLabel, func() {
@@ -351,6 +812,463 @@ func (a *Application) setupAuthForm() {
 	})
 }
 
+// addLoginTOTPForm asks for the second factor of a user with two-factor authentication
+// enabled and completes the login.
+func (a *Application) addLoginTOTPForm(challenge string) {
+	a.totpForm.Clear(true)
+	a.totpForm.SetBorder(true).SetTitle("Two-factor authentication")
+	a.Pages.SwitchToPage(totpPageName)
+
+	var code string
+
+	a.totpForm.AddInputField("Code or recovery code", "", 20, nil, func(text string) {
+		code = text
+	})
+
+	a.totpForm.AddButton(submitLabel, func() {
+		resp, err := a.authClient.LoginTOTP(context.Background(), &pb.LoginTOTPRequest{
+			Challenge: challenge,
+			Code:      code,
+		})
+		if err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), totpPageName)
+			return
+		}
+
+		a.startSession(resp)
+	})
+
+	a.totpForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(authPageName)
+	})
+}
+
+// addTOTPForm lets the user enroll an authenticator or disable two-factor authentication.
+func (a *Application) addTOTPForm() {
+	a.totpForm.Clear(true)
+	a.totpForm.SetBorder(true).SetTitle("Two-factor authentication")
+	a.Pages.SwitchToPage(totpPageName)
+
+	var code string
+
+	a.totpForm.AddInputField("Code to disable", "", 20, nil, func(text string) {
+		code = text
+	})
+
+	a.totpForm.AddButton(enableLabel, a.enableTOTP)
+
+	a.totpForm.AddButton(disableLabel, func() {
+		err := a.callWithRefresh(func(ctx context.Context) error {
+			_, err := a.authClient.DisableTOTP(ctx, &pb.DisableTOTPRequest{Code: code})
+			return err
+		})
+		if err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), totpPageName)
+			return
+		}
+
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+
+	a.totpForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+// enableTOTP shows the secret of a new authenticator and confirms the enrollment
+// with a code of the authenticator.
+func (a *Application) enableTOTP() {
+	var resp *pb.EnableTOTPResponse
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		resp, err = a.authClient.EnableTOTP(ctx, &emptypb.Empty{})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), totpPageName)
+		return
+	}
+
+	a.totpForm.Clear(true)
+
+	var code string
+
+	a.totpForm.AddTextView("Secret", resp.Secret, 0, 1, false, false)
+	a.totpForm.AddTextView("URI", resp.Uri, 0, 3, false, false)
+	a.totpForm.AddInputField("Code", "", 20, nil, func(text string) {
+		code = text
+	})
+
+	a.totpForm.AddButton(confirmLabel, func() {
+		var confirmation *pb.ConfirmTOTPResponse
+		err := a.callWithRefresh(func(ctx context.Context) error {
+			var err error
+			confirmation, err = a.authClient.ConfirmTOTP(ctx, &pb.ConfirmTOTPRequest{Code: code})
+			return err
+		})
+		if err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), totpPageName)
+			return
+		}
+
+		a.totpForm.Clear(true)
+		a.totpForm.AddTextView(
+			"Recovery codes",
+			strings.Join(confirmation.RecoveryCodes, "\n"),
+			0,
+			len(confirmation.RecoveryCodes),
+			false,
+			false,
+		)
+		a.totpForm.AddButton(okLabel, func() {
+			a.Pages.SwitchToPage(secretsPanelPageName)
+		})
+	})
+
+	a.totpForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+func (a *Application) addSessionsList() {
+	a.sessionsList.Clear()
+	a.sessionsList.SetBorder(true).SetTitle("Sessions")
+	a.Pages.SwitchToPage(sessionsPageName)
+
+	var resp *pb.ListSessionsResponse
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		var err error
+		resp, err = a.authClient.ListSessions(ctx, &emptypb.Empty{})
+		return err
+	})
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), secretsPanelPageName)
+		return
+	}
+
+	for _, session := range resp.Sessions {
+		session := session
+
+		title := fmt.Sprintf("Started %s", session.CreatedAt.AsTime().Local().Format(time.DateTime))
+		if session.Current {
+			title += " (current)"
+		}
+
+		description := fmt.Sprintf("Expires %s", session.ExpiresAt.AsTime().Local().Format(time.DateTime))
+
+		a.sessionsList.AddItem(title, description, 0, func() {
+			a.addRevokeWindow(session)
+		})
+	}
+
+	a.sessionsList.AddItem(backLabel, "", 'b', func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+func (a *Application) addRevokeWindow(session *pb.Session) {
+	a.deleteWindow.ClearButtons()
+	a.Pages.SwitchToPage(deleteWindowName)
+
+	text := "Revoke the session? The device will be logged out."
+	if session.Current {
+		text = "Revoke the current session? You will be logged out."
+	}
+
+	a.deleteWindow.SetText(text).
+		AddButtons([]string{revokeLabel, backLabel}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != revokeLabel {
+				a.Pages.SwitchToPage(sessionsPageName)
+				return
+			}
+
+			err := a.callWithRefresh(func(ctx context.Context) error {
+				_, err := a.authClient.RevokeSession(ctx, &pb.RevokeSessionRequest{SessionId: session.Id})
+				return err
+			})
+			if err != nil {
+				s := status.Convert(err)
+				a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), sessionsPageName)
+				return
+			}
+
+			if session.Current {
+				a.resetSession()
+				return
+			}
+
+			a.addSessionsList()
+		})
+}
+
+// addAccountForm lets the user change the password or delete the account.
+func (a *Application) addAccountForm() {
+	a.accountForm.Clear(true)
+	a.accountForm.SetBorder(true).SetTitle("Account")
+	a.Pages.SwitchToPage(accountPageName)
+
+	var password, newPassword string
+
+	a.accountForm.AddPasswordField("Current password", "", 20, '*', func(text string) {
+		password = text
+	})
+
+	a.accountForm.AddPasswordField("New password", "", 20, '*', func(text string) {
+		newPassword = text
+	})
+
+	a.accountForm.AddButton(changePasswordLabel, func() {
+		if err := a.changePassword(password, newPassword); err != nil {
+			s := status.Convert(err)
+			a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), accountPageName)
+			return
+		}
+
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+
+	a.accountForm.AddButton(deleteAccountLabel, func() {
+		a.addDeleteAccountWindow(password)
+	})
+
+	a.accountForm.AddButton(backLabel, func() {
+		a.Pages.SwitchToPage(secretsPanelPageName)
+	})
+}
+
+// changePassword changes the password of the user. In the client-side encryption mode
+// the secrets are encrypted with a key derived from the password, so all of them are
+// re-encrypted with the new password and sent along.
+func (a *Application) changePassword(password, newPassword string) error {
+	// Changes made offline are based on the versions the password change increments.
+	if len(a.state.Pending) > 0 {
+		return errPendingChanges
+	}
+
+	req := &pb.ChangePasswordRequest{
+		OldPassword: password,
+		NewPassword: newPassword,
+	}
+
+	var newVault *vault.Vault
+
+	if a.vault != nil {
+		oldVault, err := vault.NewVault(a.login, password)
+		if err != nil {
+			return err
+		}
+
+		newVault, err = vault.NewVault(a.login, newPassword)
+		if err != nil {
+			return err
+		}
+
+		req.OldPassword = oldVault.AuthPassword()
+		req.NewPassword = newVault.AuthPassword()
+
+		secrets, err := a.getSecrets(&pb.GetSecretsRequest{}, false)
+		if err != nil {
+			return err
+		}
+
+		// The secrets in the trash are encrypted with the password as well.
+		var trash *pb.ListTrashResponse
+		err = a.callWithRefresh(func(ctx context.Context) error {
+			var err error
+			trash, err = a.secretsClient.ListTrash(ctx, &pb.ListTrashRequest{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		secrets = append(secrets, trash.Secrets...)
+
+		for _, secret := range secrets {
+			if err := oldVault.DecryptSecret(secret); err != nil {
+				return err
+			}
+
+			if err := newVault.EncryptSecret(secret); err != nil {
+				return err
+			}
+
+			req.Secrets = append(req.Secrets, &pb.ReencryptedSecret{
+				Id:       secret.Id,
+				Payload:  secret.Payload.GetEncrypted(),
+				MetaData: secret.MetaData,
+			})
+		}
+	}
+
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.authClient.ChangePassword(ctx, req)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if newVault != nil {
+		a.vault = newVault
+	}
+
+	if a.cache != nil {
+		if err := a.cache.Rekey(newPassword); err != nil {
+			a.cache = nil
+			return nil
+		}
+
+		a.saveCache()
+	}
+
+	return nil
+}
+
+func (a *Application) addDeleteAccountWindow(password string) {
+	a.deleteWindow.ClearButtons()
+	a.Pages.SwitchToPage(deleteWindowName)
+
+	a.deleteWindow.SetText("Delete the account? All your secrets will be removed permanently.").
+		AddButtons([]string{deleteLabel, backLabel}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel != deleteLabel {
+				a.Pages.SwitchToPage(accountPageName)
+				return
+			}
+
+			if a.vault != nil {
+				v, err := vault.NewVault(a.login, password)
+				if err != nil {
+					a.addErrorWindow(err.Error(), accountPageName)
+					return
+				}
+
+				password = v.AuthPassword()
+			}
+
+			err := a.callWithRefresh(func(ctx context.Context) error {
+				_, err := a.authClient.DeleteAccount(ctx, &pb.DeleteAccountRequest{Password: password})
+				return err
+			})
+			if err != nil {
+				s := status.Convert(err)
+				a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), accountPageName)
+				return
+			}
+
+			if a.cache != nil {
+				_ = a.cache.Remove()
+			}
+
+			a.resetSession()
+		})
+}
+
+// logout revokes the current session on the server and returns to the start menu.
+func (a *Application) logout() {
+	err := a.callWithRefresh(func(ctx context.Context) error {
+		_, err := a.authClient.Logout(ctx, &emptypb.Empty{})
+		return err
+	})
+
+	a.resetSession()
+
+	if err != nil {
+		s := status.Convert(err)
+		a.addErrorWindow(fmt.Sprintf("%s: %s", s.Err(), s.Message()), startMenuPageName)
+	}
+}
+
+// resetSession forgets the tokens, the vault and the fetched secrets of the user
+// and returns to the start menu. The cache stays on disk for the next login.
+func (a *Application) resetSession() {
+	a.stopWatch()
+	a.stopOTPRefresh()
+
+	a.appContext = context.Background()
+	a.refreshToken = ""
+	a.login = ""
+	a.vault = nil
+	a.cache = nil
+	a.state = &cache.State{}
+	a.offlineAuth = nil
+	a.secrets = nil
+	a.syncPending = false
+	a.unreachable = false
+	a.selectedSecret = nil
+	a.searchQuery = nil
+	a.syncStatus.SetText("")
+	a.searchField.SetText("")
+
+	a.secretsList.Clear()
+	a.secretText.Clear()
+	a.secretsDetails.Clear()
+
+	a.Pages.SwitchToPage(startMenuPageName)
+}
+
+// startSession starts the session with the tokens of the auth response,
+// syncs the secrets and starts watching their changes.
+func (a *Application) startSession(resp *pb.AuthResponse) {
+	a.offlineAuth = nil
+	a.setTokens(resp)
+
+	a.addSecretsList()
+	a.Pages.SwitchToPage(secretsPanelPageName)
+	a.startWatch()
+	a.startOTPRefresh()
+}
+
+// setTokens stores the tokens of the auth response and authorizes further requests with the access token.
+func (a *Application) setTokens(resp *pb.AuthResponse) {
+	md := metadata.Pairs(authentication, fmt.Sprintf("%s %s", bearerSchema, resp.Token))
+	a.appContext = metadata.NewOutgoingContext(context.Background(), md)
+	a.refreshToken = resp.RefreshToken
+}
+
+// callWithRefresh calls the function with the authorized context. If the server rejects
+// the access token, the tokens are refreshed and the call is repeated once.
+func (a *Application) callWithRefresh(call func(ctx context.Context) error) error {
+	err := call(a.appContext)
+	if status.Code(err) != codes.Unauthenticated || a.refreshToken == "" {
+		return err
+	}
+
+	if a.refreshTokens() != nil {
+		return err
+	}
+
+	return call(a.appContext)
+}
+
+// refreshTokens exchanges the refresh token for a new pair of tokens. The refresh token
+// is forgotten if the server rejects it, so it is not presented again.
+func (a *Application) refreshTokens() error {
+	if a.refreshToken == "" {
+		return errNoRefreshToken
+	}
+
+	resp, err := a.authClient.Refresh(context.Background(), &pb.RefreshRequest{
+		RefreshToken: a.refreshToken,
+	})
+	if err != nil {
+		a.refreshToken = ""
+
+		return err
+	}
+
+	a.setTokens(resp)
+
+	return nil
+}
+
 func (a *Application) addErrorWindow(err string, parentPage string) {
 	a.errorWindow.ClearButtons()
 	a.errorWindow.SetBackgroundColor(tcell.ColorRed)
@@ -0,0 +1,116 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+// watchRetryDelay is the period of time to wait before watching again after the stream breaks.
+const watchRetryDelay = 5 * time.Second
+
+// startWatch starts watching the changes of the secrets made on other devices.
+// The watch runs until the session ends.
+func (a *Application) startWatch() {
+	a.stopWatch()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.watchCancel = cancel
+
+	go a.watch(ctx)
+}
+
+// stopWatch stops watching the changes of the secrets.
+func (a *Application) stopWatch() {
+	if a.watchCancel != nil {
+		a.watchCancel()
+		a.watchCancel = nil
+	}
+}
+
+// watch keeps the secrets list up to date with the changes made on other devices. It runs
+// in its own goroutine, so the state of the application is accessed through QueueUpdate.
+// The stream is opened again whenever it breaks, and the secrets are synced once it is open,
+// so the changes made while it was broken are not missed and the changes made offline are sent.
+func (a *Application) watch(ctx context.Context) {
+	for {
+		var md metadata.MD
+		a.App.QueueUpdate(func() {
+			md, _ = metadata.FromOutgoingContext(a.appContext)
+		})
+
+		err := a.receiveEvents(metadata.NewOutgoingContext(ctx, md))
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The session of an offline login is started once the server is reachable again.
+		if status.Code(err) == codes.Unauthenticated {
+			var offline bool
+			var retryErr error
+			a.App.QueueUpdateDraw(func() {
+				if offline = a.offlineAuth != nil; offline {
+					retryErr = a.reconnect()
+					return
+				}
+
+				retryErr = a.refreshTokens()
+			})
+
+			if retryErr == nil && !offline {
+				continue
+			}
+
+			if status.Code(retryErr) != codes.Unavailable {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// receiveEvents opens the stream of changes and syncs the secrets on every change
+// until the stream breaks.
+func (a *Application) receiveEvents(ctx context.Context) error {
+	stream, err := a.secretsClient.Watch(ctx, &pb.WatchRequest{})
+	if err != nil {
+		return err
+	}
+
+	// The server sends the headers once the stream is subscribed to the changes.
+	if _, err := stream.Header(); err != nil {
+		return err
+	}
+
+	a.App.QueueUpdateDraw(a.syncInBackground)
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+
+		a.App.QueueUpdateDraw(a.syncInBackground)
+	}
+}
+
+// syncInBackground refreshes the secrets list if it is shown. Otherwise the list
+// is refreshed once the user returns to it, so the page the user is on is not disturbed.
+func (a *Application) syncInBackground() {
+	if name, _ := a.Pages.GetFrontPage(); name != secretsPanelPageName {
+		a.syncPending = true
+
+		return
+	}
+
+	a.addSecretsList()
+}
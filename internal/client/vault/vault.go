@@ -0,0 +1,273 @@
+// Package vault implements client-side encryption of secrets for users with the
+// zero-knowledge mode, where the server only stores opaque encrypted blobs.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+const (
+	saltPrefix = "goph-keeper:"
+	authInfo   = "goph-keeper auth"
+	vaultInfo  = "goph-keeper vault"
+	keyLen     = 32
+
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// ErrNotEncrypted is returned when a payload received from the server is not encrypted.
+var ErrNotEncrypted = errors.New("payload is not encrypted")
+
+// Vault encrypts and decrypts secrets with a key derived from the master password.
+// The master password never leaves the client: the server only receives a separate
+// authentication password derived from the same master key.
+type Vault struct {
+	aead         cipher.AEAD
+	authPassword string
+}
+
+// NewVault derives the vault and authentication keys from the login and master password.
+// The master key is derived with Argon2id using a salt bound to the login, so the same
+// credentials produce the same keys on every device.
+func NewVault(login, password string) (*Vault, error) {
+	salt := sha256.Sum256([]byte(saltPrefix + login))
+	masterKey := argon2.IDKey([]byte(password), salt[:], argonTime, argonMemory, argonThreads, keyLen)
+
+	authKey, err := expandKey(masterKey, authInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	vaultKey, err := expandKey(masterKey, vaultInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Vault{
+		aead:         aead,
+		authPassword: hex.EncodeToString(authKey),
+	}, nil
+}
+
+// AuthPassword returns the password that is sent to the server instead of the master password.
+func (v *Vault) AuthPassword() string {
+	return v.authPassword
+}
+
+// Names of the secret fields which are encrypted separately.
+const (
+	fieldPayload  = "payload"
+	fieldMetaData = "meta_data"
+)
+
+// EncryptPayload encrypts the payload of the secret with the given ID and type. The ID, the type
+// and the field are authenticated, so the server cannot swap payloads between secrets or fields
+// unnoticed. New secrets get their IDs reserved before they are encrypted.
+func (v *Vault) EncryptPayload(secretID int64, secretType pb.SecretType, payload *pb.Payload) (*pb.Payload, error) {
+	plain, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := seal(v.aead, plain, additionalData(secretID, secretType, fieldPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Payload{Kind: &pb.Payload_Encrypted{Encrypted: sealed}}, nil
+}
+
+// DecryptPayload decrypts the payload of the secret with the given ID and type.
+func (v *Vault) DecryptPayload(secretID int64, secretType pb.SecretType, payload *pb.Payload) (*pb.Payload, error) {
+	kind, ok := payload.GetKind().(*pb.Payload_Encrypted)
+	if !ok {
+		return nil, ErrNotEncrypted
+	}
+
+	plain, err := open(v.aead, kind.Encrypted, additionalData(secretID, secretType, fieldPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := &pb.Payload{}
+	if err := proto.Unmarshal(plain, decrypted); err != nil {
+		return nil, err
+	}
+
+	return decrypted, nil
+}
+
+// EncryptMetaData encrypts the meta data of the secret with the given ID and type and encodes it
+// with base64. The meta data is bound to the secret like the payload. Empty meta data stays empty.
+func (v *Vault) EncryptMetaData(secretID int64, secretType pb.SecretType, metaData string) (string, error) {
+	if metaData == "" {
+		return "", nil
+	}
+
+	sealed, err := seal(v.aead, []byte(metaData), additionalData(secretID, secretType, fieldMetaData))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptMetaData decrypts the meta data produced by EncryptMetaData.
+func (v *Vault) DecryptMetaData(secretID int64, secretType pb.SecretType, metaData string) (string, error) {
+	if metaData == "" {
+		return "", nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(metaData)
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := open(v.aead, sealed, additionalData(secretID, secretType, fieldMetaData))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// EncryptSecret encrypts the payload and meta data of the secret in place.
+func (v *Vault) EncryptSecret(secret *pb.SecretData) error {
+	payload, err := v.EncryptPayload(secret.Id, secret.Type, secret.Payload)
+	if err != nil {
+		return err
+	}
+
+	metaData, err := v.EncryptMetaData(secret.Id, secret.Type, secret.MetaData)
+	if err != nil {
+		return err
+	}
+
+	secret.Payload = payload
+	secret.MetaData = metaData
+
+	return nil
+}
+
+// DecryptSecret decrypts the payload and meta data of the secret in place.
+func (v *Vault) DecryptSecret(secret *pb.SecretData) error {
+	payload, err := v.DecryptPayload(secret.Id, secret.Type, secret.Payload)
+	if err != nil {
+		return err
+	}
+
+	metaData, err := v.DecryptMetaData(secret.Id, secret.Type, secret.MetaData)
+	if err != nil {
+		return err
+	}
+
+	secret.Payload = payload
+	secret.MetaData = metaData
+
+	return nil
+}
+
+// BlobCipher encrypts the chunks of a file attached to a secret. Every file has a random key
+// of its own, which is kept in the encrypted payload of the secret, so the file does not have
+// to be encrypted again when the master password changes. Each chunk is bound to its position
+// and the last chunk is marked, so the server can neither reorder nor truncate the file unnoticed.
+type BlobCipher struct {
+	aead cipher.AEAD
+}
+
+// NewBlobKey generates a random key for a new file.
+func NewBlobKey() ([]byte, error) {
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// NewBlobCipher returns the cipher of the file with the given key.
+func NewBlobCipher(key []byte) (*BlobCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BlobCipher{aead: aead}, nil
+}
+
+// Seal encrypts the chunk with the given sequence number. Last reports whether it is the last chunk.
+func (c *BlobCipher) Seal(seq int, last bool, chunk []byte) ([]byte, error) {
+	return seal(c.aead, chunk, chunkAdditionalData(seq, last))
+}
+
+// Open decrypts the chunk produced by Seal with the same sequence number and last flag.
+func (c *BlobCipher) Open(seq int, last bool, sealed []byte) ([]byte, error) {
+	return open(c.aead, sealed, chunkAdditionalData(seq, last))
+}
+
+func chunkAdditionalData(seq int, last bool) []byte {
+	return []byte(fmt.Sprintf("chunk:%d;last:%t", seq, last))
+}
+
+// additionalData binds a field of a secret to the secret.
+func additionalData(secretID int64, secretType pb.SecretType, field string) []byte {
+	return []byte(fmt.Sprintf("secret:%d;type:%s;field:%s", secretID, secretType, field))
+}
+
+func seal(aead cipher.AEAD, plain, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plain, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+}
+
+func expandKey(masterKey []byte, info string) ([]byte, error) {
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, masterKey, nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+)
+
+func TestNewVault(t *testing.T) {
+	v1, err := NewVault("login", "password")
+	assert.NoError(t, err)
+
+	v2, err := NewVault("login", "password")
+	assert.NoError(t, err)
+
+	other, err := NewVault("other", "password")
+	assert.NoError(t, err)
+
+	assert.Equal(t, v1.AuthPassword(), v2.AuthPassword())
+	assert.NotEqual(t, v1.AuthPassword(), other.AuthPassword())
+	assert.NotEqual(t, "password", v1.AuthPassword())
+}
+
+func TestVault_Payload(t *testing.T) {
+	v, err := NewVault("login", "password")
+	assert.NoError(t, err)
+
+	payload := &pb.Payload{Kind: &pb.Payload_Card{Card: &pb.Card{
+		Number: "4111111111111111", Holder: "JOHN DOE", Expiry: "12/30", Cvv: "123",
+	}}}
+
+	encrypted, err := v.EncryptPayload(13, pb.SecretType_CARD, payload)
+	assert.NoError(t, err)
+	assert.IsType(t, &pb.Payload_Encrypted{}, encrypted.Kind)
+
+	decrypted, err := v.DecryptPayload(13, pb.SecretType_CARD, encrypted)
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(payload, decrypted))
+
+	_, err = v.DecryptPayload(13, pb.SecretType_TEXT, encrypted)
+	assert.Error(t, err, "payload must be bound to its secret type")
+
+	_, err = v.DecryptPayload(14, pb.SecretType_CARD, encrypted)
+	assert.Error(t, err, "payload must be bound to its secret")
+
+	other, err := NewVault("login", "another password")
+	assert.NoError(t, err)
+
+	_, err = other.DecryptPayload(13, pb.SecretType_CARD, encrypted)
+	assert.Error(t, err)
+
+	_, err = v.DecryptPayload(13, pb.SecretType_CARD, payload)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+}
+
+func TestVault_MetaData(t *testing.T) {
+	v, err := NewVault("login", "password")
+	assert.NoError(t, err)
+
+	encrypted, err := v.EncryptMetaData(13, pb.SecretType_TEXT, "meta")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "meta", encrypted)
+
+	decrypted, err := v.DecryptMetaData(13, pb.SecretType_TEXT, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "meta", decrypted)
+
+	_, err = v.DecryptMetaData(14, pb.SecretType_TEXT, encrypted)
+	assert.Error(t, err, "meta data must be bound to its secret")
+
+	// The meta data cannot be passed off as the payload of the secret.
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	assert.NoError(t, err)
+
+	_, err = v.DecryptPayload(13, pb.SecretType_TEXT, &pb.Payload{Kind: &pb.Payload_Encrypted{Encrypted: sealed}})
+	assert.Error(t, err, "meta data must be bound to its field")
+
+	empty, err := v.EncryptMetaData(13, pb.SecretType_TEXT, "")
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestVault_Secret(t *testing.T) {
+	oldVault, err := NewVault("login", "password")
+	assert.NoError(t, err)
+
+	newVault, err := NewVault("login", "new password")
+	assert.NoError(t, err)
+
+	payload := &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "text"}}}
+	secret := &pb.SecretData{Id: 13, Type: pb.SecretType_TEXT, Payload: payload, MetaData: "meta"}
+
+	assert.NoError(t, oldVault.EncryptSecret(secret))
+
+	// re-encryption with a new password
+	assert.NoError(t, oldVault.DecryptSecret(secret))
+	assert.NoError(t, newVault.EncryptSecret(secret))
+
+	assert.Error(t, oldVault.DecryptSecret(secret))
+	assert.NoError(t, newVault.DecryptSecret(secret))
+	assert.True(t, proto.Equal(payload, secret.Payload))
+	assert.Equal(t, "meta", secret.MetaData)
+}
+
+func TestBlobCipher(t *testing.T) {
+	key, err := NewBlobKey()
+	assert.NoError(t, err)
+
+	c, err := NewBlobCipher(key)
+	assert.NoError(t, err)
+
+	sealed, err := c.Seal(1, true, []byte("chunk"))
+	assert.NoError(t, err)
+
+	plain, err := c.Open(1, true, sealed)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("chunk"), plain)
+
+	_, err = c.Open(0, true, sealed)
+	assert.Error(t, err, "chunk must be bound to its position")
+
+	_, err = c.Open(1, false, sealed)
+	assert.Error(t, err, "last chunk must be marked")
+
+	otherKey, err := NewBlobKey()
+	assert.NoError(t, err)
+
+	other, err := NewBlobCipher(otherKey)
+	assert.NoError(t, err)
+
+	_, err = other.Open(1, true, sealed)
+	assert.Error(t, err)
+}
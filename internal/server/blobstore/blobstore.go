@@ -0,0 +1,37 @@
+// Package blobstore provides storages for the chunks of blobs which hold the files of binary secrets.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/config"
+)
+
+// ErrNotFound is returned when the requested chunk is not stored.
+var ErrNotFound = errors.New("chunk not found")
+
+// BlobStore is an interface that defines methods for storing the chunks of blobs.
+// Chunks are numbered from zero within their blob. Putting a chunk which is already stored replaces it.
+type BlobStore interface {
+	PutChunk(ctx context.Context, blobID string, seq int, data []byte) error
+	GetChunk(ctx context.Context, blobID string, seq int) ([]byte, error)
+	DeleteBlob(ctx context.Context, blobID string) error
+}
+
+// New creates the blob store selected in the server configuration.
+func New(ctx context.Context, cfg config.Server, pool *pgxpool.Pool) (BlobStore, error) {
+	switch cfg.BlobStore {
+	case config.BlobStorePostgres:
+		return NewPGStore(pool), nil
+	case config.BlobStoreFS:
+		return NewFSStore(cfg.BlobDir)
+	case config.BlobStoreS3:
+		return NewS3Store(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", cfg.BlobStore)
+	}
+}
@@ -0,0 +1,217 @@
+package blobstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/config"
+)
+
+// testStore checks the behavior shared by all blob stores.
+func testStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+
+	assert.NoError(t, store.PutChunk(ctx, "blob", 0, []byte("first")))
+	assert.NoError(t, store.PutChunk(ctx, "blob", 1, []byte("second")))
+	assert.NoError(t, store.PutChunk(ctx, "other", 0, []byte("other")))
+
+	data, err := store.GetChunk(ctx, "blob", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+
+	_, err = store.GetChunk(ctx, "blob", 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.DeleteBlob(ctx, "blob"))
+
+	_, err = store.GetChunk(ctx, "blob", 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	data, err = store.GetChunk(ctx, "other", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("other"), data, "other blobs must be kept")
+
+	assert.NoError(t, store.DeleteBlob(ctx, "missing"))
+}
+
+func TestFSStore(t *testing.T) {
+	store, err := NewFSStore(t.TempDir())
+	assert.NoError(t, err)
+
+	testStore(t, store)
+
+	for _, blobID := range []string{"", ".", "..", "../blob", "dir/blob"} {
+		err := store.PutChunk(context.Background(), blobID, 0, []byte("data"))
+		assert.ErrorIs(t, err, ErrInvalidBlobID, blobID)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	server := httptest.NewServer(&fakeS3{bucket: "blobs", objects: make(map[string][]byte)})
+	defer server.Close()
+
+	cfg := config.S3{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		Bucket:    "blobs",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+	}
+
+	store, err := NewS3Store(context.Background(), cfg)
+	assert.NoError(t, err)
+
+	testStore(t, store)
+
+	cfg.Bucket = "missing"
+	_, err = NewS3Store(context.Background(), cfg)
+	assert.Error(t, err, "bucket must exist")
+}
+
+// fakeS3 is an in-memory stand-in for an S3-compatible storage such as MinIO.
+// It serves the requests made by the S3 store with path-style addressing and ignores signatures.
+type fakeS3 struct {
+	objects map[string][]byte
+	bucket  string
+	mu      sync.Mutex
+}
+
+type listBucketResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Name     string   `xml:"Name"`
+	Prefix   string   `xml:"Prefix"`
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+	KeyCount    int  `xml:"KeyCount"`
+	MaxKeys     int  `xml:"MaxKeys"`
+	IsTruncated bool `xml:"IsTruncated"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+
+		f.objects[key] = data
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	result := listBucketResult{Name: f.bucket, Prefix: prefix, MaxKeys: 1000}
+
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		result.Contents = append(result.Contents, struct {
+			Key  string `xml:"Key"`
+			Size int    `xml:"Size"`
+		}{Key: key, Size: len(f.objects[key])})
+	}
+
+	result.KeyCount = len(keys)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+// readS3Body returns the object sent in the request, decoding the chunked upload
+// with streaming signatures used over plain HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data bytes.Buffer
+
+	reader := bufio.NewReader(r.Body)
+
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&data, reader, size); err != nil {
+			return nil, err
+		}
+
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, code int, s3Code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", s3Code, s3Code)
+}
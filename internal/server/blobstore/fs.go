@@ -0,0 +1,107 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ErrInvalidBlobID is returned when a blob ID cannot be used as a file name.
+var ErrInvalidBlobID = errors.New("invalid blob id")
+
+type fsStore struct {
+	dir string
+}
+
+// NewFSStore creates a BlobStore which keeps every blob in a directory of its own
+// inside dir on the local filesystem. The directory is created if it does not exist.
+func NewFSStore(dir string) (BlobStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &fsStore{
+		dir: dir,
+	}, nil
+}
+
+// PutChunk implements the PutChunk method of the BlobStore interface.
+// The chunk is written to a temporary file first, so a chunk is never read half-written.
+func (s *fsStore) PutChunk(_ context.Context, blobID string, seq int, data []byte) error {
+	blobDir, err := s.blobDir(blobID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(blobDir, 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(blobDir, fmt.Sprintf(".%d-*", seq))
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(blobDir, strconv.Itoa(seq))); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return nil
+}
+
+// GetChunk implements the GetChunk method of the BlobStore interface.
+func (s *fsStore) GetChunk(_ context.Context, blobID string, seq int) ([]byte, error) {
+	blobDir, err := s.blobDir(blobID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(blobDir, strconv.Itoa(seq)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// DeleteBlob implements the DeleteBlob method of the BlobStore interface.
+func (s *fsStore) DeleteBlob(_ context.Context, blobID string) error {
+	blobDir, err := s.blobDir(blobID)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(blobDir)
+}
+
+// blobDir returns the directory of the blob. The ID is checked, so it cannot point outside of the store.
+func (s *fsStore) blobDir(blobID string) (string, error) {
+	if blobID == "" || blobID == "." || blobID == ".." || filepath.Base(blobID) != blobID {
+		return "", ErrInvalidBlobID
+	}
+
+	return filepath.Join(s.dir, blobID), nil
+}
@@ -0,0 +1,67 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+type pgStore struct {
+	pg *pgxpool.Pool
+}
+
+// NewPGStore creates a BlobStore which keeps the chunks in the blob_chunks table of the PostgreSQL database.
+func NewPGStore(pg *pgxpool.Pool) BlobStore {
+	return &pgStore{
+		pg: pg,
+	}
+}
+
+// PutChunk implements the PutChunk method of the BlobStore interface.
+func (s *pgStore) PutChunk(ctx context.Context, blobID string, seq int, data []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO blob_chunks (blob_id, seq, data) VALUES ($1, $2, $3)
+ON CONFLICT (blob_id, seq) DO UPDATE SET data = EXCLUDED.data
+`
+
+	_, err := s.pg.Exec(timeoutCtx, stmt, blobID, seq, data)
+
+	return err
+}
+
+// GetChunk implements the GetChunk method of the BlobStore interface.
+func (s *pgStore) GetChunk(ctx context.Context, blobID string, seq int) ([]byte, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `SELECT data FROM blob_chunks WHERE blob_id = $1 AND seq = $2`
+
+	var data []byte
+	if err := s.pg.QueryRow(timeoutCtx, stmt, blobID, seq).Scan(&data); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// DeleteBlob implements the DeleteBlob method of the BlobStore interface.
+// The chunks are usually gone already, as they are removed along with their blob.
+func (s *pgStore) DeleteBlob(ctx context.Context, blobID string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	_, err := s.pg.Exec(timeoutCtx, `DELETE FROM blob_chunks WHERE blob_id = $1`, blobID)
+
+	return err
+}
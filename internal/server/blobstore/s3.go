@@ -0,0 +1,103 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/config"
+)
+
+type s3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store creates a BlobStore which keeps the chunks as objects named <blob id>/<seq>
+// in the bucket of an S3-compatible storage. The bucket must exist.
+func NewS3Store(ctx context.Context, cfg config.S3) (BlobStore, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("bucket %q does not exist", cfg.Bucket)
+	}
+
+	return &s3Store{
+		client: client,
+		bucket: cfg.Bucket,
+	}, nil
+}
+
+// PutChunk implements the PutChunk method of the BlobStore interface.
+func (s *s3Store) PutChunk(ctx context.Context, blobID string, seq int, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, chunkKey(blobID, seq), bytes.NewReader(data), int64(len(data)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+
+	return err
+}
+
+// GetChunk implements the GetChunk method of the BlobStore interface.
+func (s *s3Store) GetChunk(ctx context.Context, blobID string, seq int) ([]byte, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, chunkKey(blobID, seq), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, s3Error(err)
+	}
+
+	return data, nil
+}
+
+// DeleteBlob implements the DeleteBlob method of the BlobStore interface.
+func (s *s3Store) DeleteBlob(ctx context.Context, blobID string) error {
+	objects := s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    blobID + "/",
+		Recursive: true,
+	})
+
+	for object := range objects {
+		if object.Err != nil {
+			return object.Err
+		}
+
+		if err := s.client.RemoveObject(ctx, s.bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func chunkKey(blobID string, seq int) string {
+	return blobID + "/" + strconv.Itoa(seq)
+}
+
+// s3Error converts the error of a missing object into ErrNotFound.
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+
+	return err
+}
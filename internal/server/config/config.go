@@ -4,6 +4,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 	"gopkg.in/yaml.v3"
@@ -11,6 +12,13 @@ import (
 
 const path = "./server.config.yml"
 
+// Blob stores which can be selected with Server.BlobStore.
+const (
+	BlobStorePostgres = "postgres"
+	BlobStoreFS       = "fs"
+	BlobStoreS3       = "s3"
+)
+
 // LoadConfig loads the server and database configurations from a YAML file
 // and environment variables into Config struct.
 func LoadConfig() *Config {
@@ -28,23 +36,130 @@ func LoadConfig() *Config {
 		log.Fatal(err)
 	}
 
+	if cfg.Server.SecretVersion < 1 {
+		log.Fatal("secret key version must be positive")
+	}
+
+	if cfg.Server.JWTSecret == "" {
+		log.Fatal("jwt secret key must be set")
+	}
+
+	if cfg.Server.JWTSecret == cfg.Server.Secret || cfg.Server.JWTSecret == cfg.Server.PreviousSecret {
+		log.Fatal("jwt secret key must differ from the master secret keys")
+	}
+
+	if cfg.Server.AccessTokenTTL <= 0 || cfg.Server.RefreshTokenTTL <= 0 {
+		log.Fatal("token ttl must be positive")
+	}
+
+	if cfg.Server.IPRateLimit <= 0 || cfg.Server.IPRateBurst <= 0 ||
+		cfg.Server.LoginRateLimit <= 0 || cfg.Server.LoginRateBurst <= 0 {
+		log.Fatal("rate limits must be positive")
+	}
+
+	if cfg.Server.MaxFailedLogins <= 0 || cfg.Server.LockoutDuration <= 0 {
+		log.Fatal("lockout settings must be positive")
+	}
+
+	if cfg.Server.MaxBlobSize <= 0 {
+		log.Fatal("max blob size must be positive")
+	}
+
+	if cfg.Server.HistoryMaxAge <= 0 || cfg.Server.HistoryMaxVersions <= 0 {
+		log.Fatal("history retention must be positive")
+	}
+
+	if cfg.Server.TrashRetention <= 0 {
+		log.Fatal("trash retention must be positive")
+	}
+
+	switch cfg.Server.BlobStore {
+	case BlobStorePostgres:
+	case BlobStoreFS:
+		if cfg.Server.BlobDir == "" {
+			log.Fatal("blob directory must be set")
+		}
+	case BlobStoreS3:
+		if cfg.Server.S3.Endpoint == "" || cfg.Server.S3.Bucket == "" {
+			log.Fatal("s3 endpoint and bucket must be set")
+		}
+	default:
+		log.Fatalf("unknown blob store %q", cfg.Server.BlobStore)
+	}
+
 	return &cfg
 }
 
 // Config is a representation of the configuration in the YAML file.
 // It holds configurations for the server and PostgreSQL database.
 type Config struct {
-	Server Server `yaml:"goph-keeper"`
 	PG     PG     `yaml:"postgre"`
+	Server Server `yaml:"goph-keeper"`
 }
 
 // Server holds the server configurations.
+//
+// Secret is the current master secret and SecretVersion its version. To rotate the
+// master secret, move the old one to PreviousSecret, set a new Secret with the version
+// incremented by one and run the rotate-key command.
+//
+// LegacyCipherTexts keeps the secrets encrypted before the header and associated data were
+// introduced and the files encrypted before their last chunk was marked readable. Turn it off
+// once the upgrade-secrets command has completed.
+//
+// JWTSecret signs the access tokens and has no default, the server does not start without it.
+// It is kept apart from the master secret, so that rotating the master secret does not invalidate
+// the issued tokens and sessions.
+//
+// AccessTokenTTL and RefreshTokenTTL set the lifetime of the issued access and refresh tokens.
+//
+// IPRateLimit and LoginRateLimit set the number of authentication requests per minute allowed
+// from a single IP address and for a single account, IPRateBurst and LoginRateBurst the size of
+// a burst. After MaxFailedLogins wrong passwords or one-time codes in a row the account is locked
+// for LockoutDuration, which doubles with every further failure.
+//
+// MaxBlobSize limits the size in bytes of a file uploaded for a binary secret. BlobStore selects
+// where the files are stored: in the database, in BlobDir on the local filesystem or in a bucket
+// of an S3-compatible storage. Changing the store does not move the files stored before.
+//
+// HistoryMaxAge and HistoryMaxVersions limit how long the previous versions of a secret are kept
+// after they are replaced and how many of them are kept. TrashRetention limits how long deleted
+// secrets are kept in the trash before they are purged.
 type Server struct {
-	Host     string `yaml:"host"`
-	CertPath string `yaml:"cert_path"`
-	KeyPath  string `yaml:"key_path"`
-	Secret   string `env:"GKEEPER_SECRET_KEY" envDefault:"secret_key"`
-	Port     int    `yaml:"port"`
+	Host               string `yaml:"host"`
+	CertPath           string `yaml:"cert_path"`
+	KeyPath            string `yaml:"key_path"`
+	Secret             string `env:"GKEEPER_SECRET_KEY" envDefault:"secret_key"`
+	PreviousSecret     string `env:"GKEEPER_PREVIOUS_SECRET_KEY"`
+	JWTSecret          string `env:"GKEEPER_JWT_SECRET_KEY"`
+	BlobStore          string `env:"GKEEPER_BLOB_STORE" envDefault:"postgres"`
+	BlobDir            string `env:"GKEEPER_BLOB_DIR" envDefault:"blobs"`
+	S3                 S3
+	SecretVersion      int           `env:"GKEEPER_SECRET_KEY_VERSION" envDefault:"1"`
+	Port               int           `yaml:"port"`
+	AccessTokenTTL     time.Duration `env:"GKEEPER_ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL    time.Duration `env:"GKEEPER_REFRESH_TOKEN_TTL" envDefault:"720h"`
+	IPRateLimit        int           `env:"GKEEPER_IP_RATE_LIMIT" envDefault:"60"`
+	IPRateBurst        int           `env:"GKEEPER_IP_RATE_BURST" envDefault:"20"`
+	LoginRateLimit     int           `env:"GKEEPER_LOGIN_RATE_LIMIT" envDefault:"10"`
+	LoginRateBurst     int           `env:"GKEEPER_LOGIN_RATE_BURST" envDefault:"5"`
+	MaxFailedLogins    int           `env:"GKEEPER_MAX_FAILED_LOGINS" envDefault:"5"`
+	LockoutDuration    time.Duration `env:"GKEEPER_LOCKOUT_DURATION" envDefault:"1m"`
+	MaxBlobSize        int64         `env:"GKEEPER_MAX_BLOB_SIZE" envDefault:"104857600"`
+	HistoryMaxAge      time.Duration `env:"GKEEPER_HISTORY_MAX_AGE" envDefault:"2160h"`
+	HistoryMaxVersions int           `env:"GKEEPER_HISTORY_MAX_VERSIONS" envDefault:"20"`
+	TrashRetention     time.Duration `env:"GKEEPER_TRASH_RETENTION" envDefault:"720h"`
+	LegacyCipherTexts  bool          `env:"GKEEPER_LEGACY_CIPHER_TEXTS" envDefault:"true"`
+}
+
+// S3 holds the configurations of the S3-compatible storage of blobs.
+type S3 struct {
+	Endpoint  string `env:"GKEEPER_S3_ENDPOINT"`
+	Bucket    string `env:"GKEEPER_S3_BUCKET"`
+	Region    string `env:"GKEEPER_S3_REGION" envDefault:"us-east-1"`
+	AccessKey string `env:"GKEEPER_S3_ACCESS_KEY"`
+	SecretKey string `env:"GKEEPER_S3_SECRET_KEY"`
+	UseSSL    bool   `env:"GKEEPER_S3_USE_SSL" envDefault:"true"`
 }
 
 // PG holds the PostgreSQL database configurations.
@@ -1,6 +1,8 @@
+// Package encryption provides symmetric encryption of user secrets.
 package encryption
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -12,29 +14,201 @@ import (
 	"golang.org/x/crypto/pbkdf2"
 )
 
+const keySize = 32
+
+// masterKeySalt is the salt used to derive the key-encryption key from the server secret.
+var masterKeySalt = []byte("goph-keeper-master-key")
+
+// cipherTextHeader prefixes cipher texts produced by Encrypt. It identifies the format
+// version, so cipher texts written before associated data was introduced, which have
+// no header, can still be decrypted until they are sealed again with Reseal.
+var cipherTextHeader = []byte{'G', 'K', 1}
+
+// ErrUnknownKeyVersion is returned when data is wrapped by a master key which is not configured.
+var ErrUnknownKeyVersion = errors.New("unknown master key version")
+
+// ErrLegacyCipherText is returned when a cipher text without the format header is decrypted
+// and legacy cipher texts are not allowed.
+var ErrLegacyCipherText = errors.New("legacy cipher text")
+
+// ErrLegacyKeyUnavailable is returned when a legacy user key is requested but
+// the secret of the first master key version is not configured anymore.
+var ErrLegacyKeyUnavailable = errors.New("legacy key cannot be derived")
+
+// Encryption is an interface that defines methods for managing user keys and
+// encrypting or decrypting data with them. Implementations hold no per-user state
+// and are safe for concurrent use.
+//
+// User data is protected with envelope encryption: every user has a random data
+// encryption key which is stored wrapped by a versioned master key derived from the
+// server secret. Keys wrapped by the previous master key can still be unwrapped,
+// which allows rotating the master key without downtime.
 type Encryption interface {
-	GenerateKey(userID int)
-	Encrypt(plainText string) ([]byte, error)
-	Decrypt(cipherText []byte) (string, error)
+	GenerateKey() ([]byte, error)
+	DeriveKey(userID int) ([]byte, error)
+	KeyVersion() int
+	WrapKey(key []byte) ([]byte, int, error)
+	UnwrapKey(wrappedKey []byte, version int) ([]byte, error)
+	Encrypt(key []byte, plainText string, associatedData []byte) ([]byte, error)
+	Decrypt(key []byte, cipherText []byte, associatedData []byte) (string, error)
+	Reseal(key []byte, cipherText []byte, associatedData []byte) ([]byte, bool, error)
 }
 
 type cryptoService struct {
-	secret string
-	key    []byte
+	masterKeys        map[int][]byte
+	legacySecret      string
+	version           int
+	legacyCipherTexts bool
+}
+
+// NewCryptoService creates and returns an Encryption instance which derives
+// the master key of the given version from the provided server secret. The previous
+// secret, if not empty, is used for the master key of the preceding version.
+// Cipher texts without the format header are decrypted only if legacyCipherTexts is set.
+func NewCryptoService(secret string, version int, previousSecret string, legacyCipherTexts bool) Encryption {
+	e := &cryptoService{
+		masterKeys:        map[int][]byte{version: deriveMasterKey(secret)},
+		version:           version,
+		legacyCipherTexts: legacyCipherTexts,
+	}
+
+	if previousSecret != "" && version > 1 {
+		e.masterKeys[version-1] = deriveMasterKey(previousSecret)
+	}
+
+	switch {
+	case version == 1:
+		e.legacySecret = secret
+	case version == 2:
+		e.legacySecret = previousSecret
+	}
+
+	return e
 }
 
-func NewCryptoService(secret string) Encryption {
-	return &cryptoService{secret: secret}
+func deriveMasterKey(secret string) []byte {
+	return pbkdf2.Key([]byte(secret), masterKeySalt, 4096, keySize, sha256.New)
 }
 
-func (e *cryptoService) GenerateKey(userID int) {
+// GenerateKey generates a new random data encryption key.
+func (e *cryptoService) GenerateKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// DeriveKey derives the legacy encryption key of the user with the provided ID from
+// the server secret of the first master key version. It is only used for users whose
+// secrets were encrypted before random data encryption keys were introduced.
+func (e *cryptoService) DeriveKey(userID int) ([]byte, error) {
+	if e.legacySecret == "" {
+		return nil, ErrLegacyKeyUnavailable
+	}
+
 	salt := []byte(strconv.Itoa(userID))
-	key := pbkdf2.Key([]byte(e.secret), salt, 4096, 32, sha256.New)
-	e.key = key
+
+	return pbkdf2.Key([]byte(e.legacySecret), salt, 4096, keySize, sha256.New), nil
 }
 
-func (e *cryptoService) Encrypt(plainText string) ([]byte, error) {
-	block, err := aes.NewCipher(e.key)
+// KeyVersion returns the version of the current master key.
+func (e *cryptoService) KeyVersion() int {
+	return e.version
+}
+
+// WrapKey encrypts the data encryption key with the current master key
+// and returns the wrapped key along with the master key version.
+func (e *cryptoService) WrapKey(key []byte) ([]byte, int, error) {
+	wrappedKey, err := seal(e.masterKeys[e.version], key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return wrappedKey, e.version, nil
+}
+
+// UnwrapKey decrypts the data encryption key wrapped by the master key of the given version.
+func (e *cryptoService) UnwrapKey(wrappedKey []byte, version int) ([]byte, error) {
+	masterKey, ok := e.masterKeys[version]
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+
+	return open(masterKey, wrappedKey, nil)
+}
+
+// Encrypt encrypts the plain text with the provided key using AES-GCM. The associated
+// data is authenticated but not encrypted, so the cipher text can only be decrypted with
+// the same associated data. The returned cipher text consists of the format header,
+// the random nonce and the sealed data.
+func (e *cryptoService) Encrypt(key []byte, plainText string, associatedData []byte) ([]byte, error) {
+	sealed, err := seal(key, []byte(plainText), withHeader(associatedData))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]byte{}, cipherTextHeader...), sealed...), nil
+}
+
+// Decrypt decrypts the cipher text produced by Encrypt with the provided key and
+// associated data. Cipher texts without the format header were produced before
+// associated data was introduced and are decrypted without it, if legacy cipher
+// texts are allowed.
+func (e *cryptoService) Decrypt(key []byte, cipherText []byte, associatedData []byte) (string, error) {
+	plainText, err := openCurrent(key, cipherText, associatedData)
+	if err == nil || !e.legacyCipherTexts {
+		return string(plainText), err
+	}
+
+	// The random nonce of a legacy cipher text may start with the header bytes by chance.
+	plainText, err = open(key, cipherText, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plainText), nil
+}
+
+// Reseal encrypts the legacy cipher text without the format header again with the associated data
+// and reports whether it did so. Cipher texts in the current format are returned as is. Legacy
+// cipher texts are resealed even if they are not allowed anymore, so they can be migrated later.
+func (e *cryptoService) Reseal(key []byte, cipherText []byte, associatedData []byte) ([]byte, bool, error) {
+	if _, err := openCurrent(key, cipherText, associatedData); err == nil {
+		return cipherText, false, nil
+	}
+
+	plainText, err := open(key, cipherText, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resealed, err := e.Encrypt(key, string(plainText), associatedData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return resealed, true, nil
+}
+
+// openCurrent decrypts the cipher text produced by Encrypt.
+func openCurrent(key []byte, cipherText []byte, associatedData []byte) ([]byte, error) {
+	if !bytes.HasPrefix(cipherText, cipherTextHeader) {
+		return nil, ErrLegacyCipherText
+	}
+
+	return open(key, cipherText[len(cipherTextHeader):], withHeader(associatedData))
+}
+
+// withHeader binds the format header to the associated data, so the header cannot
+// be stripped or replaced without failing the authentication.
+func withHeader(associatedData []byte) []byte {
+	return append(append([]byte{}, cipherTextHeader...), associatedData...)
+}
+
+func seal(key []byte, plainText []byte, associatedData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
@@ -49,32 +223,32 @@ func (e *cryptoService) Encrypt(plainText string) ([]byte, error) {
 		return nil, err
 	}
 
-	ciphertext := aesgcm.Seal(nonce, nonce, []byte(plainText), nil)
+	ciphertext := aesgcm.Seal(nonce, nonce, plainText, associatedData)
 
 	return ciphertext, nil
 }
 
-func (e *cryptoService) Decrypt(cipherText []byte) (string, error) {
-	block, err := aes.NewCipher(e.key)
+func open(key []byte, cipherText []byte, associatedData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	aesgcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	nonceSize := aesgcm.NonceSize()
 	if len(cipherText) < nonceSize {
-		return "", errors.New("cipherText too short")
+		return nil, errors.New("cipherText too short")
 	}
 
 	nonce, cipherText := cipherText[:nonceSize], cipherText[nonceSize:]
-	plaintext, err := aesgcm.Open(nil, nonce, cipherText, nil)
+	plaintext, err := aesgcm.Open(nil, nonce, cipherText, associatedData)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return string(plaintext), nil
+	return plaintext, nil
 }
@@ -20,16 +20,158 @@ func TestCryptoService(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cryptoSrvc := NewCryptoService("secret")
-			cryptoSrvc.GenerateKey(tt.userID)
+			cryptoSrvc := NewCryptoService("secret", 1, "", true)
+			key, err := cryptoSrvc.DeriveKey(tt.userID)
+			assert.NoError(t, err)
 
-			encryptedData, err := cryptoSrvc.Encrypt(tt.input)
+			encryptedData, err := cryptoSrvc.Encrypt(key, tt.input, []byte("data"))
 			assert.NoError(t, err)
 
-			decryptedData, err := cryptoSrvc.Decrypt(encryptedData)
+			decryptedData, err := cryptoSrvc.Decrypt(key, encryptedData, []byte("data"))
 			assert.NoError(t, err)
 
 			assert.Equal(t, tt.input, decryptedData)
 		})
 	}
 }
+
+func TestCryptoService_DifferentUsers(t *testing.T) {
+	cryptoSrvc := NewCryptoService("secret", 1, "", true)
+
+	firstKey, err := cryptoSrvc.DeriveKey(1)
+	assert.NoError(t, err)
+
+	secondKey, err := cryptoSrvc.DeriveKey(2)
+	assert.NoError(t, err)
+
+	encryptedData, err := cryptoSrvc.Encrypt(firstKey, "test text", nil)
+	assert.NoError(t, err)
+
+	_, err = cryptoSrvc.Decrypt(secondKey, encryptedData, nil)
+	assert.Error(t, err)
+}
+
+func TestCryptoService_AssociatedData(t *testing.T) {
+	cryptoSrvc := NewCryptoService("secret", 1, "", true)
+
+	key, err := cryptoSrvc.GenerateKey()
+	assert.NoError(t, err)
+
+	encryptedData, err := cryptoSrvc.Encrypt(key, "test text", []byte("secret:1"))
+	assert.NoError(t, err)
+	assert.Equal(t, cipherTextHeader, encryptedData[:len(cipherTextHeader)])
+
+	_, err = cryptoSrvc.Decrypt(key, encryptedData, []byte("secret:2"))
+	assert.Error(t, err, "cipher text must not be decrypted with other associated data")
+
+	_, err = cryptoSrvc.Decrypt(key, encryptedData, nil)
+	assert.Error(t, err)
+
+	_, err = cryptoSrvc.Decrypt(key, encryptedData[len(cipherTextHeader):], []byte("secret:1"))
+	assert.Error(t, err, "cipher text must not be decrypted without its header")
+}
+
+func TestCryptoService_LegacyCipherText(t *testing.T) {
+	cryptoSrvc := NewCryptoService("secret", 1, "", true)
+
+	key, err := cryptoSrvc.DeriveKey(1)
+	assert.NoError(t, err)
+
+	legacyData, err := seal(key, []byte("test text"), nil)
+	assert.NoError(t, err)
+
+	decryptedData, err := cryptoSrvc.Decrypt(key, legacyData, []byte("secret:1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "test text", decryptedData)
+
+	_, err = NewCryptoService("secret", 1, "", false).Decrypt(key, legacyData, []byte("secret:1"))
+	assert.Error(t, err, "legacy cipher text must be rejected once legacy cipher texts are not allowed")
+}
+
+func TestCryptoService_Reseal(t *testing.T) {
+	cryptoSrvc := NewCryptoService("secret", 1, "", false)
+
+	key, err := cryptoSrvc.GenerateKey()
+	assert.NoError(t, err)
+
+	legacyData, err := seal(key, []byte("test text"), nil)
+	assert.NoError(t, err)
+
+	resealed, changed, err := cryptoSrvc.Reseal(key, legacyData, []byte("secret:1"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	decryptedData, err := cryptoSrvc.Decrypt(key, resealed, []byte("secret:1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "test text", decryptedData)
+
+	_, err = cryptoSrvc.Decrypt(key, resealed, []byte("secret:2"))
+	assert.Error(t, err, "resealed cipher text must be bound to its associated data")
+
+	same, changed, err := cryptoSrvc.Reseal(key, resealed, []byte("secret:1"))
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, resealed, same)
+
+	otherKey, err := cryptoSrvc.GenerateKey()
+	assert.NoError(t, err)
+
+	_, _, err = cryptoSrvc.Reseal(otherKey, legacyData, []byte("secret:1"))
+	assert.Error(t, err)
+}
+
+func TestCryptoService_WrapKey(t *testing.T) {
+	cryptoSrvc := NewCryptoService("secret", 1, "", true)
+
+	key, err := cryptoSrvc.GenerateKey()
+	assert.NoError(t, err)
+	assert.Len(t, key, keySize)
+
+	wrappedKey, version, err := cryptoSrvc.WrapKey(key)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, wrappedKey)
+	assert.Equal(t, 1, version)
+
+	unwrappedKey, err := cryptoSrvc.UnwrapKey(wrappedKey, version)
+	assert.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+
+	_, err = NewCryptoService("other secret", 1, "", true).UnwrapKey(wrappedKey, version)
+	assert.Error(t, err)
+}
+
+func TestCryptoService_KeyRotation(t *testing.T) {
+	oldCrypto := NewCryptoService("old secret", 1, "", true)
+	newCrypto := NewCryptoService("new secret", 2, "old secret", true)
+
+	key, err := oldCrypto.GenerateKey()
+	assert.NoError(t, err)
+
+	oldWrappedKey, oldVersion, err := oldCrypto.WrapKey(key)
+	assert.NoError(t, err)
+
+	unwrappedKey, err := newCrypto.UnwrapKey(oldWrappedKey, oldVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+
+	newWrappedKey, newVersion, err := newCrypto.WrapKey(unwrappedKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, newVersion)
+
+	unwrappedKey, err = newCrypto.UnwrapKey(newWrappedKey, newVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+
+	_, err = newCrypto.UnwrapKey(newWrappedKey, 3)
+	assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+
+	oldLegacyKey, err := oldCrypto.DeriveKey(1)
+	assert.NoError(t, err)
+
+	newLegacyKey, err := newCrypto.DeriveKey(1)
+	assert.NoError(t, err)
+	assert.Equal(t, oldLegacyKey, newLegacyKey)
+
+	_, err = NewCryptoService("newest secret", 3, "new secret", true).DeriveKey(1)
+	assert.ErrorIs(t, err, ErrLegacyKeyUnavailable)
+}
@@ -1,4 +1,4 @@
-// Package handlers provides the gRPC implementations for the Auth and Secret service.
+// Package handlers provides the gRPC implementations for the Auth, Secret and Organization services.
 package handlers
 
 import (
@@ -8,8 +8,11 @@ import (
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
 )
@@ -33,7 +36,7 @@ func NewAuthHandler(service services.AuthService, log *zerolog.Logger) *AuthHand
 // Register is a gRPC method that allows users to register to the system.
 // It returns the user's token or error.
 func (h *AuthHandler) Register(ctx context.Context, in *pb.AuthRequest) (*pb.AuthResponse, error) {
-	token, err := h.service.Register(ctx, in.Login, in.Password)
+	tokens, err := h.service.Register(ctx, in.Login, in.Password, in.ClientSideEncryption)
 	if err != nil {
 		if errors.Is(err, repository.ErrAlreadyExist) {
 			return nil, status.Error(codes.AlreadyExists, "login already exist")
@@ -42,20 +45,199 @@ func (h *AuthHandler) Register(ctx context.Context, in *pb.AuthRequest) (*pb.Aut
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	resp := pb.AuthResponse{Token: token}
-
-	return &resp, nil
+	return authResponse(tokens), nil
 }
 
 // Login is a gRPC method that allows users to authenticate themselves.
 // It returns the user's token or error.
 func (h *AuthHandler) Login(ctx context.Context, in *pb.AuthRequest) (*pb.AuthResponse, error) {
-	token, err := h.service.Login(ctx, in.Login, in.Password)
+	tokens, err := h.service.Login(ctx, in.Login, in.Password)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "login or password is invalid")
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return nil, status.Error(codes.Unauthenticated, "login or password is invalid")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
 	}
 
-	resp := pb.AuthResponse{Token: token}
+	return authResponse(tokens), nil
+}
+
+// Refresh is a gRPC method that exchanges a refresh token for a new pair of tokens.
+// It returns the user's tokens or error.
+func (h *AuthHandler) Refresh(ctx context.Context, in *pb.RefreshRequest) (*pb.AuthResponse, error) {
+	tokens, err := h.service.Refresh(ctx, in.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			return nil, status.Error(codes.Unauthenticated, "refresh token is invalid")
+		}
 
-	return &resp, nil
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return authResponse(tokens), nil
+}
+
+// Logout is a gRPC method that revokes the session of the current user's token.
+func (h *AuthHandler) Logout(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	if err := h.service.Logout(ctx); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to logout")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListSessions is a gRPC method that fetches the active sessions of the user.
+func (h *AuthHandler) ListSessions(ctx context.Context, _ *emptypb.Empty) (*pb.ListSessionsResponse, error) {
+	sessions, err := h.service.ListSessions(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list sessions")
+	}
+
+	protoSessions := make([]*pb.Session, len(sessions))
+	for i := range sessions {
+		protoSessions[i] = &pb.Session{
+			Id:        sessions[i].ID,
+			CreatedAt: timestamppb.New(sessions[i].CreatedAt),
+			ExpiresAt: timestamppb.New(sessions[i].ExpiresAt),
+			Current:   sessions[i].Current,
+		}
+	}
+
+	return &pb.ListSessionsResponse{Sessions: protoSessions}, nil
+}
+
+// RevokeSession is a gRPC method that revokes one of the user's sessions,
+// e.g. the session of a lost device.
+func (h *AuthHandler) RevokeSession(ctx context.Context, in *pb.RevokeSessionRequest) (*emptypb.Empty, error) {
+	if err := h.service.RevokeSession(ctx, in.SessionId); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "session not found")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to revoke session")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// LoginTOTP is a gRPC method that completes the login of a user with two-factor authentication
+// enabled using the challenge returned by Login and a one-time or recovery code.
+// It returns the user's tokens or error.
+func (h *AuthHandler) LoginTOTP(ctx context.Context, in *pb.LoginTOTPRequest) (*pb.AuthResponse, error) {
+	tokens, err := h.service.LoginTOTP(ctx, in.Challenge, in.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidChallenge) || errors.Is(err, services.ErrInvalidTOTPCode) {
+			return nil, status.Error(codes.Unauthenticated, "code or challenge is invalid")
+		}
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return authResponse(tokens), nil
+}
+
+// EnableTOTP is a gRPC method that starts the enrollment of an authenticator.
+// It returns the authenticator secret and its otpauth URI.
+func (h *AuthHandler) EnableTOTP(ctx context.Context, _ *emptypb.Empty) (*pb.EnableTOTPResponse, error) {
+	enrollment, err := h.service.EnableTOTP(ctx)
+	if err != nil {
+		if errors.Is(err, services.ErrTOTPAlreadyEnabled) {
+			return nil, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to enable two-factor authentication")
+	}
+
+	return &pb.EnableTOTPResponse{
+		Secret: enrollment.Secret,
+		Uri:    enrollment.URI,
+	}, nil
+}
+
+// ConfirmTOTP is a gRPC method that completes the enrollment of an authenticator with its code.
+// It returns the recovery codes of the user.
+func (h *AuthHandler) ConfirmTOTP(ctx context.Context, in *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	recoveryCodes, err := h.service.ConfirmTOTP(ctx, in.Code)
+	if err != nil {
+		return nil, totpError(err, "failed to confirm two-factor authentication")
+	}
+
+	return &pb.ConfirmTOTPResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableTOTP is a gRPC method that disables two-factor authentication of the user.
+// The request has to be confirmed with a one-time or recovery code.
+func (h *AuthHandler) DisableTOTP(ctx context.Context, in *pb.DisableTOTPRequest) (*emptypb.Empty, error) {
+	if err := h.service.DisableTOTP(ctx, in.Code); err != nil {
+		return nil, totpError(err, "failed to disable two-factor authentication")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ChangePassword is a gRPC method that changes the password of the user and logs out
+// the user's other sessions. Client-side encryption accounts pass their secrets
+// re-encrypted with the new password.
+func (h *AuthHandler) ChangePassword(ctx context.Context, in *pb.ChangePasswordRequest) (*emptypb.Empty, error) {
+	secrets := make([]models.Secret, len(in.Secrets))
+	for i, secret := range in.Secrets {
+		secrets[i] = models.Secret{
+			ID:       int(secret.Id),
+			Payload:  &models.Encrypted{Data: secret.Payload},
+			MetaData: secret.MetaData,
+		}
+	}
+
+	err := h.service.ChangePassword(ctx, in.OldPassword, in.NewPassword, secrets)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			return nil, status.Error(codes.PermissionDenied, "password is invalid")
+		case errors.Is(err, repository.ErrSecretsMismatch):
+			return nil, status.Error(codes.FailedPrecondition, "secrets do not match stored secrets")
+		default:
+			return nil, status.Error(codes.Internal, "failed to change password")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteAccount is a gRPC method that removes the account of the user with all the user's secrets.
+func (h *AuthHandler) DeleteAccount(ctx context.Context, in *pb.DeleteAccountRequest) (*emptypb.Empty, error) {
+	if err := h.service.DeleteAccount(ctx, in.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidPassword) {
+			return nil, status.Error(codes.PermissionDenied, "password is invalid")
+		}
+
+		return nil, status.Error(codes.Internal, "failed to delete account")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func totpError(err error, internalMsg string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTOTPCode):
+		return status.Error(codes.InvalidArgument, "code is invalid")
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled):
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled")
+	case errors.Is(err, services.ErrTOTPNotEnabled):
+		return status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled")
+	default:
+		return status.Error(codes.Internal, internalMsg)
+	}
+}
+
+func authResponse(tokens *models.Tokens) *pb.AuthResponse {
+	return &pb.AuthResponse{
+		Token:         tokens.AccessToken,
+		RefreshToken:  tokens.RefreshToken,
+		TotpChallenge: tokens.TOTPChallenge,
+	}
 }
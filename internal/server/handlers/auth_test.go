@@ -4,43 +4,58 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
 	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
 )
 
+var testTokens = &models.Tokens{AccessToken: "access-token", RefreshToken: "refresh-token"}
+
 func TestAuthHandler_Register(t *testing.T) {
 	log := logger.NewLogger()
 
 	tests := []struct {
-		err            error
-		expectedErr    error
-		expectedOutput *pb.AuthResponse
-		name           string
-		login          string
-		password       string
-		token          string
+		err                  error
+		expectedErr          error
+		expectedOutput       *pb.AuthResponse
+		tokens               *models.Tokens
+		name                 string
+		login                string
+		password             string
+		clientSideEncryption bool
 	}{
 		{
 			name:           "success: user registration",
 			login:          "test",
 			password:       "12345",
-			token:          "token_mock_1",
+			tokens:         testTokens,
 			err:            nil,
-			expectedOutput: &pb.AuthResponse{Token: "token_mock_1"},
+			expectedOutput: &pb.AuthResponse{Token: "access-token", RefreshToken: "refresh-token"},
 			expectedErr:    nil,
 		},
+		{
+			name:                 "success: user registration with client-side encryption",
+			login:                "test",
+			password:             "12345",
+			tokens:               testTokens,
+			clientSideEncryption: true,
+			expectedOutput:       &pb.AuthResponse{Token: "access-token", RefreshToken: "refresh-token"},
+		},
 		{
 			name:           "error: user already exists",
 			login:          "test",
 			password:       "12345",
-			token:          "",
 			err:            repository.ErrAlreadyExist,
 			expectedOutput: nil,
 			expectedErr:    status.Error(codes.AlreadyExists, "login already exist"),
@@ -49,7 +64,6 @@ func TestAuthHandler_Register(t *testing.T) {
 			name:           "error: internal error",
 			login:          "test",
 			password:       "12345",
-			token:          "",
 			err:            errors.New("internal error"),
 			expectedOutput: nil,
 			expectedErr:    status.Error(codes.Internal, "internal error"),
@@ -59,14 +73,15 @@ func TestAuthHandler_Register(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockAuthService := new(mocks.MockAuthService)
-			mockAuthService.On("Register", context.Background(), tt.login, tt.password).
-				Return(tt.token, tt.err).
+			mockAuthService.On("Register", context.Background(), tt.login, tt.password, tt.clientSideEncryption).
+				Return(tt.tokens, tt.err).
 				Times(1)
 
 			handler := NewAuthHandler(mockAuthService, &log)
 			output, err := handler.Register(context.Background(), &pb.AuthRequest{
-				Login:    tt.login,
-				Password: tt.password,
+				Login:                tt.login,
+				Password:             tt.password,
+				ClientSideEncryption: tt.clientSideEncryption,
 			})
 
 			assert.Equal(t, tt.expectedOutput, output)
@@ -82,28 +97,35 @@ func TestAuthHandler_Login(t *testing.T) {
 		err            error
 		expectedErr    error
 		expectedOutput *pb.AuthResponse
+		tokens         *models.Tokens
 		name           string
 		login          string
 		password       string
-		token          string
 	}{
 		{
 			name:           "success: user login",
 			login:          "test",
 			password:       "12345",
-			token:          "token_mock_1",
+			tokens:         testTokens,
 			err:            nil,
-			expectedOutput: &pb.AuthResponse{Token: "token_mock_1"},
+			expectedOutput: &pb.AuthResponse{Token: "access-token", RefreshToken: "refresh-token"},
 			expectedErr:    nil,
 		},
 		{
 			name:           "error: invalid password or login",
 			login:          "test",
 			password:       "12345",
-			token:          "",
+			err:            services.ErrInvalidCredentials,
+			expectedOutput: nil,
+			expectedErr:    status.Error(codes.Unauthenticated, "login or password is invalid"),
+		},
+		{
+			name:           "error: internal error",
+			login:          "test",
+			password:       "12345",
 			err:            errors.New("internal error"),
 			expectedOutput: nil,
-			expectedErr:    status.Error(codes.Internal, "login or password is invalid"),
+			expectedErr:    status.Error(codes.Internal, "internal error"),
 		},
 	}
 
@@ -111,7 +133,7 @@ func TestAuthHandler_Login(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockAuthService := new(mocks.MockAuthService)
 			mockAuthService.On("Login", context.Background(), tt.login, tt.password).
-				Return(tt.token, tt.err).
+				Return(tt.tokens, tt.err).
 				Times(1)
 
 			handler := NewAuthHandler(mockAuthService, &log)
@@ -125,3 +147,312 @@ func TestAuthHandler_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthHandler_Refresh(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err            error
+		expectedErr    error
+		expectedOutput *pb.AuthResponse
+		tokens         *models.Tokens
+		name           string
+	}{
+		{
+			name:           "success: tokens refreshed",
+			tokens:         testTokens,
+			expectedOutput: &pb.AuthResponse{Token: "access-token", RefreshToken: "refresh-token"},
+		},
+		{
+			name:        "error: invalid refresh token",
+			err:         services.ErrInvalidRefreshToken,
+			expectedErr: status.Error(codes.Unauthenticated, "refresh token is invalid"),
+		},
+		{
+			name:        "error: internal error",
+			err:         errors.New("internal error"),
+			expectedErr: status.Error(codes.Internal, "internal error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("Refresh", context.Background(), "refresh-token").
+				Return(tt.tokens, tt.err).
+				Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+			output, err := handler.Refresh(context.Background(), &pb.RefreshRequest{RefreshToken: "refresh-token"})
+
+			assert.Equal(t, tt.expectedOutput, output)
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestAuthHandler_ListSessions(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	tests := []struct {
+		err            error
+		expectedErr    error
+		expectedOutput *pb.ListSessionsResponse
+		name           string
+		sessions       []models.Session
+	}{
+		{
+			name: "success: sessions listed",
+			sessions: []models.Session{
+				{ID: "current", CreatedAt: now, ExpiresAt: now, Current: true},
+			},
+			expectedOutput: &pb.ListSessionsResponse{Sessions: []*pb.Session{
+				{Id: "current", CreatedAt: timestamppb.New(now), ExpiresAt: timestamppb.New(now), Current: true},
+			}},
+		},
+		{
+			name:        "error: internal error",
+			err:         errors.New("internal error"),
+			expectedErr: status.Error(codes.Internal, "failed to list sessions"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("ListSessions", context.Background()).
+				Return(tt.sessions, tt.err).
+				Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+			output, err := handler.ListSessions(context.Background(), &emptypb.Empty{})
+
+			assert.Equal(t, tt.expectedOutput, output)
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestAuthHandler_RevokeSession(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err               error
+		expectedErr       error
+		expectedLogoutErr error
+		name              string
+	}{
+		{
+			name: "success: session revoked",
+		},
+		{
+			name:              "error: session not found",
+			err:               repository.ErrNoRows,
+			expectedErr:       status.Error(codes.NotFound, "session not found"),
+			expectedLogoutErr: status.Error(codes.NotFound, "session not found"),
+		},
+		{
+			name:              "error: internal error",
+			err:               errors.New("internal error"),
+			expectedErr:       status.Error(codes.Internal, "failed to revoke session"),
+			expectedLogoutErr: status.Error(codes.Internal, "failed to logout"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("RevokeSession", context.Background(), "session").
+				Return(tt.err).
+				Times(1)
+			mockAuthService.On("Logout", context.Background()).
+				Return(tt.err).
+				Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+
+			_, err := handler.RevokeSession(context.Background(), &pb.RevokeSessionRequest{SessionId: "session"})
+			assert.Equal(t, tt.expectedErr, err)
+
+			_, err = handler.Logout(context.Background(), &emptypb.Empty{})
+			assert.Equal(t, tt.expectedLogoutErr, err)
+		})
+	}
+}
+
+func TestAuthHandler_LoginTOTP(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err            error
+		expectedErr    error
+		expectedOutput *pb.AuthResponse
+		tokens         *models.Tokens
+		name           string
+	}{
+		{
+			name:           "success: logged in",
+			tokens:         testTokens,
+			expectedOutput: &pb.AuthResponse{Token: "access-token", RefreshToken: "refresh-token"},
+		},
+		{
+			name:        "error: invalid code",
+			err:         services.ErrInvalidTOTPCode,
+			expectedErr: status.Error(codes.Unauthenticated, "code or challenge is invalid"),
+		},
+		{
+			name:        "error: invalid challenge",
+			err:         services.ErrInvalidChallenge,
+			expectedErr: status.Error(codes.Unauthenticated, "code or challenge is invalid"),
+		},
+		{
+			name:        "error: internal error",
+			err:         errors.New("internal error"),
+			expectedErr: status.Error(codes.Internal, "internal error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("LoginTOTP", context.Background(), "challenge", "123456").
+				Return(tt.tokens, tt.err).
+				Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+			output, err := handler.LoginTOTP(context.Background(), &pb.LoginTOTPRequest{
+				Challenge: "challenge",
+				Code:      "123456",
+			})
+
+			assert.Equal(t, tt.expectedOutput, output)
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestAuthHandler_TOTP(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockAuthService := new(mocks.MockAuthService)
+	mockAuthService.On("EnableTOTP", context.Background()).
+		Return(&models.TOTPEnrollment{Secret: "secret", URI: "uri"}, nil).Once()
+	mockAuthService.On("EnableTOTP", context.Background()).
+		Return(nil, services.ErrTOTPAlreadyEnabled).Once()
+	mockAuthService.On("ConfirmTOTP", context.Background(), "123456").
+		Return([]string{"abcde-12345"}, nil).Once()
+	mockAuthService.On("ConfirmTOTP", context.Background(), "000000").
+		Return(nil, services.ErrInvalidTOTPCode).Once()
+	mockAuthService.On("DisableTOTP", context.Background(), "123456").
+		Return(nil).Once()
+	mockAuthService.On("DisableTOTP", context.Background(), "000000").
+		Return(services.ErrTOTPNotEnabled).Once()
+
+	handler := NewAuthHandler(mockAuthService, &log)
+
+	enrollment, err := handler.EnableTOTP(context.Background(), &emptypb.Empty{})
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.EnableTOTPResponse{Secret: "secret", Uri: "uri"}, enrollment)
+
+	_, err = handler.EnableTOTP(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, status.Error(codes.FailedPrecondition, "two-factor authentication is already enabled"), err)
+
+	confirmation, err := handler.ConfirmTOTP(context.Background(), &pb.ConfirmTOTPRequest{Code: "123456"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abcde-12345"}, confirmation.RecoveryCodes)
+
+	_, err = handler.ConfirmTOTP(context.Background(), &pb.ConfirmTOTPRequest{Code: "000000"})
+	assert.Equal(t, status.Error(codes.InvalidArgument, "code is invalid"), err)
+
+	_, err = handler.DisableTOTP(context.Background(), &pb.DisableTOTPRequest{Code: "123456"})
+	assert.NoError(t, err)
+
+	_, err = handler.DisableTOTP(context.Background(), &pb.DisableTOTPRequest{Code: "000000"})
+	assert.Equal(t, status.Error(codes.FailedPrecondition, "two-factor authentication is not enabled"), err)
+
+	mockAuthService.AssertExpectations(t)
+}
+
+func TestAuthHandler_ChangePassword(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{
+			name: "success: password changed",
+		},
+		{
+			name:        "error: invalid password",
+			err:         services.ErrInvalidPassword,
+			expectedErr: status.Error(codes.PermissionDenied, "password is invalid"),
+		},
+		{
+			name:        "error: secrets mismatch",
+			err:         repository.ErrSecretsMismatch,
+			expectedErr: status.Error(codes.FailedPrecondition, "secrets do not match stored secrets"),
+		},
+		{
+			name:        "error: internal error",
+			err:         errors.New("internal error"),
+			expectedErr: status.Error(codes.Internal, "failed to change password"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("ChangePassword", context.Background(), "old", "new", []models.Secret{
+				{ID: 1, Payload: &models.Encrypted{Data: []byte("content")}, MetaData: "meta"},
+			}).Return(tt.err).Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+			_, err := handler.ChangePassword(context.Background(), &pb.ChangePasswordRequest{
+				OldPassword: "old",
+				NewPassword: "new",
+				Secrets:     []*pb.ReencryptedSecret{{Id: 1, Payload: []byte("content"), MetaData: "meta"}},
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestAuthHandler_DeleteAccount(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{
+			name: "success: account deleted",
+		},
+		{
+			name:        "error: invalid password",
+			err:         services.ErrInvalidPassword,
+			expectedErr: status.Error(codes.PermissionDenied, "password is invalid"),
+		},
+		{
+			name:        "error: internal error",
+			err:         errors.New("internal error"),
+			expectedErr: status.Error(codes.Internal, "failed to delete account"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAuthService := new(mocks.MockAuthService)
+			mockAuthService.On("DeleteAccount", context.Background(), "password").Return(tt.err).Times(1)
+
+			handler := NewAuthHandler(mockAuthService, &log)
+			_, err := handler.DeleteAccount(context.Background(), &pb.DeleteAccountRequest{Password: "password"})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
+)
+
+// rolePrefix is the prefix of the names of the OrganizationRole enum values,
+// which are scoped to the package of the API.
+const rolePrefix = "ROLE_"
+
+// OrganizationHandler implements the organization-related gRPC service.
+type OrganizationHandler struct {
+	pb.UnimplementedOrganizationServer
+
+	service services.OrganizationService
+	log     *zerolog.Logger
+}
+
+// NewOrganizationHandler is the constructor for the OrganizationHandler.
+func NewOrganizationHandler(service services.OrganizationService, log *zerolog.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		service: service,
+		log:     log,
+	}
+}
+
+// CreateOrganization is a gRPC method that creates an organization owned by the user.
+func (h *OrganizationHandler) CreateOrganization(
+	ctx context.Context,
+	in *pb.CreateOrganizationRequest,
+) (*pb.CreateOrganizationResponse, error) {
+	organizationID, err := h.service.CreateOrganization(ctx, in.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrganizationUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption")
+		case errors.Is(err, services.ErrInvalidName):
+			return nil, status.Errorf(codes.InvalidArgument, "organization name is invalid")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to create organization")
+		}
+	}
+
+	return &pb.CreateOrganizationResponse{OrganizationId: int64(organizationID)}, nil
+}
+
+// ListOrganizations is a gRPC method that fetches the organizations the user is a member of.
+func (h *OrganizationHandler) ListOrganizations(ctx context.Context, _ *emptypb.Empty) (*pb.ListOrganizationsResponse, error) {
+	orgs, err := h.service.ListOrganizations(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get organizations")
+	}
+
+	response := &pb.ListOrganizationsResponse{Organizations: make([]*pb.OrganizationData, len(orgs))}
+	for i, org := range orgs {
+		response.Organizations[i] = &pb.OrganizationData{
+			Id:        int64(org.ID),
+			Name:      org.Name,
+			Role:      roleToProto(org.Role),
+			CreatedAt: timestamppb.New(org.CreatedAt),
+		}
+	}
+
+	return response, nil
+}
+
+// DeleteOrganization is a gRPC method that removes an organization along with its secrets.
+func (h *OrganizationHandler) DeleteOrganization(
+	ctx context.Context,
+	in *pb.DeleteOrganizationRequest,
+) (*emptypb.Empty, error) {
+	if err := h.service.DeleteOrganization(ctx, int(in.OrganizationId)); err != nil {
+		return nil, organizationStatus(err, "failed to delete organization")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// InviteMember is a gRPC method that invites a user to an organization.
+func (h *OrganizationHandler) InviteMember(ctx context.Context, in *pb.InviteMemberRequest) (*emptypb.Empty, error) {
+	if err := h.service.InviteMember(ctx, int(in.OrganizationId), in.Login, roleFromProto(in.Role)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInviteeNotFound):
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		case errors.Is(err, services.ErrOrganizationUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption")
+		case errors.Is(err, repository.ErrAlreadyMember):
+			return nil, status.Errorf(codes.AlreadyExists, "user is already a member of organization")
+		default:
+			return nil, organizationStatus(err, "failed to invite member")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListInvites is a gRPC method that fetches the pending invites of the user.
+func (h *OrganizationHandler) ListInvites(ctx context.Context, _ *emptypb.Empty) (*pb.ListInvitesResponse, error) {
+	invites, err := h.service.ListInvites(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get invites")
+	}
+
+	response := &pb.ListInvitesResponse{Invites: make([]*pb.InviteData, len(invites))}
+	for i, invite := range invites {
+		response.Invites[i] = &pb.InviteData{
+			OrganizationId: int64(invite.OrganizationID),
+			Organization:   invite.Organization,
+			Role:           roleToProto(invite.Role),
+			InvitedBy:      invite.InvitedBy,
+			CreatedAt:      timestamppb.New(invite.CreatedAt),
+		}
+	}
+
+	return response, nil
+}
+
+// AcceptInvite is a gRPC method that makes the user a member of the organization the user is invited to.
+func (h *OrganizationHandler) AcceptInvite(ctx context.Context, in *pb.AcceptInviteRequest) (*emptypb.Empty, error) {
+	if err := h.service.AcceptInvite(ctx, int(in.OrganizationId)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrganizationUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption")
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "invite not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to accept invite")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeclineInvite is a gRPC method that removes an invite of the user.
+func (h *OrganizationHandler) DeclineInvite(ctx context.Context, in *pb.DeclineInviteRequest) (*emptypb.Empty, error) {
+	if err := h.service.DeclineInvite(ctx, int(in.OrganizationId)); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "invite not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to decline invite")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListMembers is a gRPC method that fetches the members of an organization.
+func (h *OrganizationHandler) ListMembers(ctx context.Context, in *pb.ListMembersRequest) (*pb.ListMembersResponse, error) {
+	members, err := h.service.ListMembers(ctx, int(in.OrganizationId))
+	if err != nil {
+		return nil, organizationStatus(err, "failed to get members")
+	}
+
+	response := &pb.ListMembersResponse{Members: make([]*pb.MemberData, len(members))}
+	for i, member := range members {
+		response.Members[i] = &pb.MemberData{
+			Login:     member.Login,
+			Role:      roleToProto(member.Role),
+			CreatedAt: timestamppb.New(member.CreatedAt),
+		}
+	}
+
+	return response, nil
+}
+
+// UpdateMemberRole is a gRPC method that changes the role of a member of an organization.
+func (h *OrganizationHandler) UpdateMemberRole(
+	ctx context.Context,
+	in *pb.UpdateMemberRoleRequest,
+) (*emptypb.Empty, error) {
+	err := h.service.UpdateMemberRole(ctx, int(in.OrganizationId), in.Login, roleFromProto(in.Role))
+	if err != nil {
+		return nil, memberStatus(err, "failed to update member role")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RemoveMember is a gRPC method that removes a member from an organization.
+func (h *OrganizationHandler) RemoveMember(ctx context.Context, in *pb.RemoveMemberRequest) (*emptypb.Empty, error) {
+	if err := h.service.RemoveMember(ctx, int(in.OrganizationId), in.Login); err != nil {
+		return nil, memberStatus(err, "failed to remove member")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// CreateCollection is a gRPC method that creates a collection in an organization.
+func (h *OrganizationHandler) CreateCollection(
+	ctx context.Context,
+	in *pb.CreateCollectionRequest,
+) (*pb.CreateCollectionResponse, error) {
+	collectionID, err := h.service.CreateCollection(ctx, int(in.OrganizationId), in.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidName):
+			return nil, status.Errorf(codes.InvalidArgument, "collection name is invalid")
+		case errors.Is(err, repository.ErrCollectionAlreadyExist):
+			return nil, status.Errorf(codes.AlreadyExists, "collection already exist")
+		default:
+			return nil, organizationStatus(err, "failed to create collection")
+		}
+	}
+
+	return &pb.CreateCollectionResponse{CollectionId: int64(collectionID)}, nil
+}
+
+// ListCollections is a gRPC method that fetches the collections of an organization.
+func (h *OrganizationHandler) ListCollections(
+	ctx context.Context,
+	in *pb.ListCollectionsRequest,
+) (*pb.ListCollectionsResponse, error) {
+	collections, err := h.service.ListCollections(ctx, int(in.OrganizationId))
+	if err != nil {
+		return nil, organizationStatus(err, "failed to get collections")
+	}
+
+	response := &pb.ListCollectionsResponse{Collections: make([]*pb.CollectionData, len(collections))}
+	for i, collection := range collections {
+		response.Collections[i] = &pb.CollectionData{
+			Id:        int64(collection.ID),
+			Name:      collection.Name,
+			CreatedAt: timestamppb.New(collection.CreatedAt),
+		}
+	}
+
+	return response, nil
+}
+
+// DeleteCollection is a gRPC method that removes a collection of an organization along with its secrets.
+func (h *OrganizationHandler) DeleteCollection(
+	ctx context.Context,
+	in *pb.DeleteCollectionRequest,
+) (*emptypb.Empty, error) {
+	if err := h.service.DeleteCollection(ctx, int(in.OrganizationId), int(in.CollectionId)); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "collection not found")
+		}
+
+		return nil, organizationStatus(err, "failed to delete collection")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// organizationStatus returns the status for the errors of the calls checking the role of the user
+// in the organization and the INTERNAL status with the message for other errors.
+func organizationStatus(err error, message string) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidRole):
+		return status.Errorf(codes.InvalidArgument, "organization role is invalid")
+	case errors.Is(err, services.ErrInsufficientRole):
+		return status.Errorf(codes.PermissionDenied, "organization role does not allow the operation")
+	case errors.Is(err, repository.ErrNoRows):
+		return status.Errorf(codes.NotFound, "organization not found")
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+// memberStatus is organizationStatus for the calls changing the members of an organization.
+func memberStatus(err error, message string) error {
+	switch {
+	case errors.Is(err, repository.ErrLastOwner):
+		return status.Errorf(codes.FailedPrecondition, "organization must keep an owner")
+	case errors.Is(err, services.ErrMemberNotFound):
+		return status.Errorf(codes.NotFound, "member not found")
+	default:
+		return organizationStatus(err, message)
+	}
+}
+
+// roleFromProto returns an empty role for unset roles.
+func roleFromProto(role pb.OrganizationRole) string {
+	if role == pb.OrganizationRole_ORGANIZATION_ROLE_UNSPECIFIED {
+		return ""
+	}
+
+	return strings.TrimPrefix(role.String(), rolePrefix)
+}
+
+func roleToProto(role string) pb.OrganizationRole {
+	return pb.OrganizationRole(pb.OrganizationRole_value[rolePrefix+role])
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func TestOrganizationHandler_CreateOrganization(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expected    *pb.CreateOrganizationResponse
+		expectedErr error
+		name        string
+	}{
+		{
+			name:     "success: organization created",
+			expected: &pb.CreateOrganizationResponse{OrganizationId: 5},
+		},
+		{
+			name:        "error: client-side encryption",
+			err:         services.ErrOrganizationUnavailable,
+			expectedErr: status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption"),
+		},
+		{
+			name:        "error: invalid name",
+			err:         services.ErrInvalidName,
+			expectedErr: status.Errorf(codes.InvalidArgument, "organization name is invalid"),
+		},
+		{
+			name:        "error: failed to create organization",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to create organization"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := 0
+			if tt.err == nil {
+				id = 5
+			}
+
+			mockOrgService := new(mocks.MockOrganizationService)
+			mockOrgService.On("CreateOrganization", context.Background(), "team").Return(id, tt.err).Times(1)
+
+			handler := NewOrganizationHandler(mockOrgService, &log)
+			response, err := handler.CreateOrganization(context.Background(), &pb.CreateOrganizationRequest{Name: "team"})
+
+			assert.Equal(t, tt.expected, response)
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestOrganizationHandler_ListOrganizations(t *testing.T) {
+	log := logger.NewLogger()
+	createdAt := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	mockOrgService := new(mocks.MockOrganizationService)
+	mockOrgService.On("ListOrganizations", context.Background()).Return([]models.Organization{
+		{ID: 5, Name: "team", Role: models.OrganizationRoleReadOnly, CreatedAt: createdAt},
+	}, nil).Times(1)
+
+	handler := NewOrganizationHandler(mockOrgService, &log)
+	response, err := handler.ListOrganizations(context.Background(), &emptypb.Empty{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.OrganizationData{
+		{Id: 5, Name: "team", Role: pb.OrganizationRole_ROLE_READ_ONLY, CreatedAt: timestamppb.New(createdAt)},
+	}, response.Organizations)
+}
+
+func TestOrganizationHandler_InviteMember(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		request     *pb.InviteMemberRequest
+		name        string
+		role        string
+	}{
+		{
+			name:    "success: member invited",
+			role:    models.OrganizationRoleAdmin,
+			request: &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_ADMIN},
+		},
+		{
+			name:        "error: unspecified role",
+			err:         services.ErrInvalidRole,
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob"},
+			expectedErr: status.Errorf(codes.InvalidArgument, "organization role is invalid"),
+		},
+		{
+			name:        "error: user not found",
+			role:        models.OrganizationRoleMember,
+			err:         services.ErrInviteeNotFound,
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_MEMBER},
+			expectedErr: status.Errorf(codes.NotFound, "user not found"),
+		},
+		{
+			name:        "error: already a member",
+			role:        models.OrganizationRoleMember,
+			err:         repository.ErrAlreadyMember,
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_MEMBER},
+			expectedErr: status.Errorf(codes.AlreadyExists, "user is already a member of organization"),
+		},
+		{
+			name:        "error: insufficient role",
+			role:        models.OrganizationRoleMember,
+			err:         services.ErrInsufficientRole,
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_MEMBER},
+			expectedErr: status.Errorf(codes.PermissionDenied, "organization role does not allow the operation"),
+		},
+		{
+			name:        "error: organization not found",
+			role:        models.OrganizationRoleMember,
+			err:         repository.ErrNoRows,
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_MEMBER},
+			expectedErr: status.Errorf(codes.NotFound, "organization not found"),
+		},
+		{
+			name:        "error: failed to invite",
+			role:        models.OrganizationRoleMember,
+			err:         errors.New("test"),
+			request:     &pb.InviteMemberRequest{OrganizationId: 5, Login: "bob", Role: pb.OrganizationRole_ROLE_MEMBER},
+			expectedErr: status.Errorf(codes.Internal, "failed to invite member"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgService := new(mocks.MockOrganizationService)
+			mockOrgService.On("InviteMember", context.Background(), 5, "bob", tt.role).Return(tt.err).Times(1)
+
+			handler := NewOrganizationHandler(mockOrgService, &log)
+			_, err := handler.InviteMember(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestOrganizationHandler_AcceptInvite(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: invite accepted"},
+		{
+			name:        "error: invite not found",
+			err:         repository.ErrNoRows,
+			expectedErr: status.Errorf(codes.NotFound, "invite not found"),
+		},
+		{
+			name:        "error: client-side encryption",
+			err:         services.ErrOrganizationUnavailable,
+			expectedErr: status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgService := new(mocks.MockOrganizationService)
+			mockOrgService.On("AcceptInvite", context.Background(), 5).Return(tt.err).Times(1)
+
+			handler := NewOrganizationHandler(mockOrgService, &log)
+			_, err := handler.AcceptInvite(context.Background(), &pb.AcceptInviteRequest{OrganizationId: 5})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestOrganizationHandler_RemoveMember(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: member removed"},
+		{
+			name:        "error: last owner",
+			err:         repository.ErrLastOwner,
+			expectedErr: status.Errorf(codes.FailedPrecondition, "organization must keep an owner"),
+		},
+		{
+			name:        "error: member not found",
+			err:         services.ErrMemberNotFound,
+			expectedErr: status.Errorf(codes.NotFound, "member not found"),
+		},
+		{
+			name:        "error: failed to remove member",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to remove member"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgService := new(mocks.MockOrganizationService)
+			mockOrgService.On("RemoveMember", context.Background(), 5, "bob").Return(tt.err).Times(1)
+
+			handler := NewOrganizationHandler(mockOrgService, &log)
+			_, err := handler.RemoveMember(context.Background(), &pb.RemoveMemberRequest{OrganizationId: 5, Login: "bob"})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestOrganizationHandler_CreateCollection(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: collection created"},
+		{
+			name:        "error: collection already exist",
+			err:         repository.ErrCollectionAlreadyExist,
+			expectedErr: status.Errorf(codes.AlreadyExists, "collection already exist"),
+		},
+		{
+			name:        "error: insufficient role",
+			err:         services.ErrInsufficientRole,
+			expectedErr: status.Errorf(codes.PermissionDenied, "organization role does not allow the operation"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockOrgService := new(mocks.MockOrganizationService)
+			mockOrgService.On("CreateCollection", context.Background(), 5, "infra").Return(3, tt.err).Times(1)
+
+			handler := NewOrganizationHandler(mockOrgService, &log)
+			_, err := handler.CreateCollection(context.Background(),
+				&pb.CreateCollectionRequest{OrganizationId: 5, Name: "infra"})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
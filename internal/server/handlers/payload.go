@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+// payloadFromProto converts the gRPC payload into its service-layer representation.
+// It returns nil if the payload or its kind is not set.
+func payloadFromProto(payload *pb.Payload) models.Payload {
+	switch kind := payload.GetKind().(type) {
+	case *pb.Payload_Credentials:
+		return &models.Credentials{
+			Login:    kind.Credentials.GetLogin(),
+			Password: kind.Credentials.GetPassword(),
+			URL:      kind.Credentials.GetUrl(),
+		}
+	case *pb.Payload_Card:
+		return &models.Card{
+			Number: kind.Card.GetNumber(),
+			Holder: kind.Card.GetHolder(),
+			Expiry: kind.Card.GetExpiry(),
+			CVV:    kind.Card.GetCvv(),
+		}
+	case *pb.Payload_Text:
+		return &models.Text{Body: kind.Text.GetBody()}
+	case *pb.Payload_Binary:
+		return &models.Binary{
+			Filename: kind.Binary.GetFilename(),
+			Mime:     kind.Binary.GetMime(),
+			Data:     kind.Binary.GetBytes(),
+			Size:     kind.Binary.GetSize(),
+		}
+	case *pb.Payload_Otp:
+		return &models.OTP{URI: kind.Otp.GetUri()}
+	case *pb.Payload_Encrypted:
+		return &models.Encrypted{Data: kind.Encrypted}
+	default:
+		return nil
+	}
+}
+
+// payloadToProto converts the service-layer payload into its gRPC representation.
+func payloadToProto(payload models.Payload) *pb.Payload {
+	switch p := payload.(type) {
+	case *models.Credentials:
+		return &pb.Payload{Kind: &pb.Payload_Credentials{Credentials: &pb.Credentials{
+			Login:    p.Login,
+			Password: p.Password,
+			Url:      p.URL,
+		}}}
+	case *models.Card:
+		return &pb.Payload{Kind: &pb.Payload_Card{Card: &pb.Card{
+			Number: p.Number,
+			Holder: p.Holder,
+			Expiry: p.Expiry,
+			Cvv:    p.CVV,
+		}}}
+	case *models.Text:
+		return &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: p.Body}}}
+	case *models.Binary:
+		return &pb.Payload{Kind: &pb.Payload_Binary{Binary: &pb.Binary{
+			Bytes:    p.Data,
+			Filename: p.Filename,
+			Mime:     p.Mime,
+			Size:     p.Size,
+		}}}
+	case *models.OTP:
+		return &pb.Payload{Kind: &pb.Payload_Otp{Otp: &pb.Otp{Uri: p.URI}}}
+	case *models.Encrypted:
+		return &pb.Payload{Kind: &pb.Payload_Encrypted{Encrypted: p.Data}}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+func TestPayloadConversion(t *testing.T) {
+	tests := []struct {
+		proto *pb.Payload
+		model models.Payload
+		name  string
+	}{
+		{
+			name: "credentials",
+			proto: &pb.Payload{Kind: &pb.Payload_Credentials{Credentials: &pb.Credentials{
+				Login: "login", Password: "password", Url: "example.com",
+			}}},
+			model: &models.Credentials{Login: "login", Password: "password", URL: "example.com"},
+		},
+		{
+			name: "card",
+			proto: &pb.Payload{Kind: &pb.Payload_Card{Card: &pb.Card{
+				Number: "4111111111111111", Holder: "JOHN DOE", Expiry: "12/30", Cvv: "123",
+			}}},
+			model: &models.Card{Number: "4111111111111111", Holder: "JOHN DOE", Expiry: "12/30", CVV: "123"},
+		},
+		{
+			name:  "text",
+			proto: &pb.Payload{Kind: &pb.Payload_Text{Text: &pb.Text{Body: "body"}}},
+			model: &models.Text{Body: "body"},
+		},
+		{
+			name: "binary",
+			proto: &pb.Payload{Kind: &pb.Payload_Binary{Binary: &pb.Binary{
+				Bytes: []byte{1, 2, 3}, Filename: "file.bin", Mime: "application/octet-stream",
+			}}},
+			model: &models.Binary{Data: []byte{1, 2, 3}, Filename: "file.bin", Mime: "application/octet-stream"},
+		},
+		{
+			name:  "otp",
+			proto: &pb.Payload{Kind: &pb.Payload_Otp{Otp: &pb.Otp{Uri: "otpauth://totp/john?secret=JBSWY3DPEHPK3PXP"}}},
+			model: &models.OTP{URI: "otpauth://totp/john?secret=JBSWY3DPEHPK3PXP"},
+		},
+		{
+			name:  "encrypted",
+			proto: &pb.Payload{Kind: &pb.Payload_Encrypted{Encrypted: []byte{1, 2, 3}}},
+			model: &models.Encrypted{Data: []byte{1, 2, 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.model, payloadFromProto(tt.proto))
+			assert.Equal(t, tt.proto, payloadToProto(tt.model))
+
+			_, err := proto.Marshal(tt.proto)
+			assert.NoError(t, err)
+		})
+	}
+
+	assert.Nil(t, payloadFromProto(nil))
+	assert.Nil(t, payloadToProto(nil))
+}
@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
 )
 
@@ -19,13 +23,15 @@ type SecretHandler struct {
 	pb.UnimplementedSecretServer
 
 	service services.SecretService
+	blobs   services.BlobService
 	log     *zerolog.Logger
 }
 
 // NewSecretHandler is the constructor for the SecretHandler.
-func NewSecretHandler(service services.SecretService, log *zerolog.Logger) *SecretHandler {
+func NewSecretHandler(service services.SecretService, blobs services.BlobService, log *zerolog.Logger) *SecretHandler {
 	return &SecretHandler{
 		service: service,
+		blobs:   blobs,
 		log:     log,
 	}
 }
@@ -33,44 +39,75 @@ func NewSecretHandler(service services.SecretService, log *zerolog.Logger) *Secr
 // Create is a gRPC method that allows users to create secrets.
 func (h *SecretHandler) Create(ctx context.Context, in *pb.CreateRequest) (*emptypb.Empty, error) {
 	secret := models.Secret{
-		Type:     in.Type.String(),
-		Content:  in.Content,
-		MetaData: in.MetaData,
+		Type:         in.Type.String(),
+		Payload:      payloadFromProto(in.Payload),
+		MetaData:     in.MetaData,
+		BlobID:       in.BlobId,
+		Name:         in.Name,
+		Tags:         in.Tags,
+		Folder:       in.Folder,
+		CollectionID: int(in.CollectionId),
+		ID:           int(in.SecretId),
 	}
 
 	if err := h.service.CreateSecret(ctx, &secret); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create secret")
+		switch {
+		case errors.Is(err, services.ErrInvalidPayload):
+			return nil, status.Errorf(codes.InvalidArgument, "payload does not match secret type")
+		case errors.Is(err, services.ErrInvalidSecretID):
+			return nil, status.Errorf(codes.InvalidArgument, "secret id is not reserved")
+		case errors.Is(err, services.ErrInvalidLabels):
+			return nil, status.Errorf(codes.InvalidArgument, "name, folder or tags are invalid")
+		case errors.Is(err, repository.ErrInvalidBlob):
+			return nil, status.Errorf(codes.InvalidArgument, "blob cannot be attached to secret")
+		case errors.Is(err, services.ErrBinaryCollection):
+			return nil, status.Errorf(codes.InvalidArgument, "binary secrets cannot be stored in collections")
+		case errors.Is(err, services.ErrOrganizationUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "organizations are not available with client-side encryption")
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "collection not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to create secret")
+		}
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
-// GetSecrets is a gRPC method that fetches the secrets of a user.
-func (h *SecretHandler) GetSecrets(ctx context.Context, in *pb.GetSecretsRequest) (*pb.GetSecretsResponse, error) {
-	secrets, err := h.service.GetUserSecrets(ctx)
+// ReserveID is a gRPC method that reserves the ID of a secret to create.
+func (h *SecretHandler) ReserveID(ctx context.Context, _ *emptypb.Empty) (*pb.ReserveIDResponse, error) {
+	secretID, err := h.service.ReserveSecretID(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to get user secrets")
+		return nil, status.Errorf(codes.Internal, "failed to reserve secret id")
 	}
 
-	protoSecrets := make([]*pb.SecretData, len(secrets))
-	for i := range secrets {
-		secretType := pb.SecretType_UNSPECIFIED
-		if v, ok := pb.SecretType_value[secrets[i].Type]; ok {
-			secretType = pb.SecretType(v)
-		}
+	return &pb.ReserveIDResponse{SecretId: int64(secretID)}, nil
+}
+
+// GetSecrets is a gRPC method that fetches a page of the secrets of a user matching the filters of the request.
+func (h *SecretHandler) GetSecrets(ctx context.Context, in *pb.GetSecretsRequest) (*pb.GetSecretsResponse, error) {
+	if in.PageSize < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "page size must not be negative")
+	}
 
-		secret := &pb.SecretData{
-			Id:        int64(secrets[i].ID),
-			Type:      secretType,
-			Content:   secrets[i].Content,
-			MetaData:  secrets[i].MetaData,
-			CreatedAt: timestamppb.New(secrets[i].CreatedAt),
+	page, err := h.service.GetUserSecrets(ctx, secretFilterFromProto(in))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPageToken):
+			return nil, status.Errorf(codes.InvalidArgument, "page token is invalid")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to get user secrets")
 		}
+	}
 
-		protoSecrets[i] = secret
+	protoSecrets := make([]*pb.SecretData, len(page.Secrets))
+	for i := range page.Secrets {
+		protoSecrets[i] = secretToProto(&page.Secrets[i])
 	}
 
-	response := pb.GetSecretsResponse{Secrets: protoSecrets}
+	response := pb.GetSecretsResponse{Secrets: protoSecrets, NextPageToken: page.NextPageToken}
 
 	return &response, nil
 }
@@ -80,22 +117,411 @@ func (h *SecretHandler) Update(ctx context.Context, in *pb.UpdateRequest) (*empt
 	secret := &models.Secret{
 		ID:       int(in.SecretId),
 		Type:     in.Type.String(),
-		Content:  in.Content,
+		Payload:  payloadFromProto(in.Payload),
 		MetaData: in.MetaData,
+		Version:  int(in.Version),
+		BlobID:   in.BlobId,
+		Name:     in.Name,
+		Tags:     in.Tags,
+		Folder:   in.Folder,
 	}
 
 	if err := h.service.UpdateSecret(ctx, secret); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update secret")
+		switch {
+		case errors.Is(err, services.ErrInvalidPayload):
+			return nil, status.Errorf(codes.InvalidArgument, "payload does not match secret type")
+		case errors.Is(err, services.ErrInvalidLabels):
+			return nil, status.Errorf(codes.InvalidArgument, "name, folder or tags are invalid")
+		case errors.Is(err, repository.ErrInvalidBlob):
+			return nil, status.Errorf(codes.InvalidArgument, "blob cannot be attached to secret")
+		case errors.Is(err, services.ErrBinaryShare):
+			return nil, status.Errorf(codes.InvalidArgument, "shared secret cannot be binary")
+		case errors.Is(err, services.ErrReadOnlyShare):
+			return nil, status.Errorf(codes.PermissionDenied, "secret is shared read-only")
+		case errors.Is(err, services.ErrBinaryCollection):
+			return nil, status.Errorf(codes.InvalidArgument, "binary secrets cannot be stored in collections")
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		case errors.Is(err, services.ErrVersionConflict):
+			return nil, conflictStatus(err)
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "secret not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to update secret")
+		}
 	}
 
 	return &emptypb.Empty{}, nil
 }
 
-// Delete is a gRPC method that allows users to delete secrets.
+// Delete is a gRPC method that allows users to move secrets to the trash.
 func (h *SecretHandler) Delete(ctx context.Context, in *pb.DeleteRequest) (*emptypb.Empty, error) {
-	if err := h.service.DeleteSecret(ctx, int(in.SecretId)); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to delete secret")
+	if err := h.service.DeleteSecret(ctx, int(in.SecretId), int(in.Version)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		case errors.Is(err, services.ErrVersionConflict):
+			return nil, conflictStatus(err)
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "secret not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to delete secret")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListTrash is a gRPC method that fetches the secrets of the user in the trash.
+func (h *SecretHandler) ListTrash(ctx context.Context, _ *pb.ListTrashRequest) (*pb.ListTrashResponse, error) {
+	secrets, err := h.service.ListTrash(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get trash")
+	}
+
+	response := &pb.ListTrashResponse{Secrets: make([]*pb.SecretData, len(secrets))}
+	for i := range secrets {
+		response.Secrets[i] = secretToProto(&secrets[i])
+	}
+
+	return response, nil
+}
+
+// Restore is a gRPC method that moves a secret out of the trash.
+func (h *SecretHandler) Restore(ctx context.Context, in *pb.RestoreRequest) (*emptypb.Empty, error) {
+	if err := h.service.RestoreSecret(ctx, int(in.SecretId)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "secret not found in trash")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to restore secret")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Purge is a gRPC method that permanently removes a secret from the trash.
+func (h *SecretHandler) Purge(ctx context.Context, in *pb.PurgeRequest) (*emptypb.Empty, error) {
+	if err := h.service.PurgeSecret(ctx, int(in.SecretId)); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "secret not found in trash")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to purge secret")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Sync is a gRPC method that fetches the changes of the user's secrets made since the given revision.
+func (h *SecretHandler) Sync(ctx context.Context, in *pb.SyncRequest) (*pb.SyncResponse, error) {
+	if in.SinceRevision < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "revision must not be negative")
+	}
+
+	changes, err := h.service.Sync(ctx, in.SinceRevision)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to sync secrets")
+	}
+
+	response := &pb.SyncResponse{
+		Secrets:    make([]*pb.SecretData, len(changes.Secrets)),
+		DeletedIds: make([]int64, len(changes.DeletedIDs)),
+		Revision:   changes.Revision,
+	}
+
+	for i := range changes.Secrets {
+		response.Secrets[i] = secretToProto(&changes.Secrets[i])
+	}
+
+	for i, id := range changes.DeletedIDs {
+		response.DeletedIds[i] = int64(id)
+	}
+
+	return response, nil
+}
+
+// Share is a gRPC method that grants another user access to a secret.
+func (h *SecretHandler) Share(ctx context.Context, in *pb.ShareRequest) (*emptypb.Empty, error) {
+	var permission string
+	if in.Permission != pb.SharePermission_SHARE_PERMISSION_UNSPECIFIED {
+		permission = in.Permission.String()
+	}
+
+	if err := h.service.ShareSecret(ctx, int(in.SecretId), in.TargetLogin, permission); err != nil {
+		switch {
+		case errors.Is(err, services.ErrSharingUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "sharing is not available with client-side encryption")
+		case errors.Is(err, services.ErrInvalidPermission):
+			return nil, status.Errorf(codes.InvalidArgument, "share permission is invalid")
+		case errors.Is(err, services.ErrInvalidRecipient):
+			return nil, status.Errorf(codes.InvalidArgument, "secret cannot be shared with its owner")
+		case errors.Is(err, services.ErrBinaryShare):
+			return nil, status.Errorf(codes.InvalidArgument, "binary secrets cannot be shared")
+		case errors.Is(err, services.ErrRecipientNotFound):
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		case errors.Is(err, services.ErrVersionConflict):
+			return nil, conflictStatus(err)
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "secret not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to share secret")
+		}
 	}
 
 	return &emptypb.Empty{}, nil
 }
+
+// Unshare is a gRPC method that revokes the access to a secret granted to another user.
+func (h *SecretHandler) Unshare(ctx context.Context, in *pb.UnshareRequest) (*emptypb.Empty, error) {
+	if err := h.service.UnshareSecret(ctx, int(in.SecretId), in.TargetLogin); err != nil {
+		switch {
+		case errors.Is(err, services.ErrSharingUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "sharing is not available with client-side encryption")
+		case errors.Is(err, services.ErrRecipientNotFound):
+			return nil, status.Errorf(codes.NotFound, "user not found")
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "share not found")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to unshare secret")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// ListVersions is a gRPC method that fetches the previous versions of a secret.
+func (h *SecretHandler) ListVersions(ctx context.Context, in *pb.ListVersionsRequest) (*pb.ListVersionsResponse, error) {
+	versions, err := h.service.ListVersions(ctx, int(in.SecretId))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get secret versions")
+	}
+
+	response := &pb.ListVersionsResponse{Versions: make([]*pb.SecretData, len(versions))}
+	for i := range versions {
+		response.Versions[i] = secretToProto(&versions[i])
+	}
+
+	return response, nil
+}
+
+// RestoreVersion is a gRPC method that makes a previous version of a secret current again.
+func (h *SecretHandler) RestoreVersion(ctx context.Context, in *pb.RestoreVersionRequest) (*emptypb.Empty, error) {
+	err := h.service.RestoreVersion(ctx, int(in.SecretId), int(in.Version), int(in.CurrentVersion))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrVersionConflict):
+			return nil, conflictStatus(err)
+		case errors.Is(err, repository.ErrNoRows):
+			return nil, status.Errorf(codes.NotFound, "version not found")
+		case errors.Is(err, repository.ErrInvalidBlob):
+			return nil, status.Errorf(codes.InvalidArgument, "blob cannot be attached to secret")
+		case errors.Is(err, services.ErrBinaryShare):
+			return nil, status.Errorf(codes.InvalidArgument, "shared secret cannot be binary")
+		case errors.Is(err, services.ErrInsufficientRole):
+			return nil, status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to restore secret version")
+		}
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// FindByDomain is a gRPC method that finds the credentials of the user for the website with the given domain.
+func (h *SecretHandler) FindByDomain(ctx context.Context, in *pb.FindByDomainRequest) (*pb.FindByDomainResponse, error) {
+	secrets, err := h.service.FindSecretsByDomain(ctx, in.Domain, in.Login)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDomain):
+			return nil, status.Errorf(codes.InvalidArgument, "domain is invalid")
+		case errors.Is(err, services.ErrBlindIndexUnavailable):
+			return nil, status.Errorf(codes.FailedPrecondition, "search by domain is not available with client-side encryption")
+		default:
+			return nil, status.Errorf(codes.Internal, "failed to find secrets")
+		}
+	}
+
+	response := &pb.FindByDomainResponse{Secrets: make([]*pb.SecretData, len(secrets))}
+	for i := range secrets {
+		response.Secrets[i] = secretToProto(&secrets[i])
+	}
+
+	return response, nil
+}
+
+// Watch is a gRPC method that streams the changes of the user's secrets until the client disconnects.
+func (h *SecretHandler) Watch(in *pb.WatchRequest, stream pb.Secret_WatchServer) error {
+	ctx := stream.Context()
+
+	events, stop, err := h.service.Watch(ctx)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to watch secrets")
+	}
+	defer stop()
+
+	// The headers tell the client that the stream is subscribed, so it can sync without missing changes.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Errorf(codes.Unavailable, "events may have been missed, sync and watch again")
+			}
+
+			err := stream.Send(&pb.SecretEvent{
+				Kind:     pb.SecretEvent_Kind(pb.SecretEvent_Kind_value[event.Kind]),
+				SecretId: int64(event.SecretID),
+				Revision: event.Revision,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// UploadBlob is a gRPC method that stores a file streamed in chunks and returns the ID of the blob.
+func (h *SecretHandler) UploadBlob(stream pb.Secret_UploadBlobServer) error {
+	blob, err := h.blobs.Upload(stream.Context(), func() ([]byte, error) {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		return req.Chunk, nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidChunk):
+			return status.Errorf(codes.InvalidArgument, "chunk must not be empty or exceed %d bytes", services.MaxBlobChunkSize)
+		case errors.Is(err, services.ErrBlobTooLarge):
+			return status.Errorf(codes.ResourceExhausted, "file is too large")
+		case stream.Context().Err() != nil:
+			return status.FromContextError(stream.Context().Err()).Err()
+		default:
+			return status.Errorf(codes.Internal, "failed to upload blob")
+		}
+	}
+
+	return stream.SendAndClose(&pb.UploadBlobResponse{BlobId: blob.ID, Size: blob.Size})
+}
+
+// DownloadBlob is a gRPC method that streams the chunks of a file in the order they were uploaded.
+func (h *SecretHandler) DownloadBlob(in *pb.DownloadBlobRequest, stream pb.Secret_DownloadBlobServer) error {
+	err := h.blobs.Download(stream.Context(), in.BlobId, func(data []byte) error {
+		return stream.Send(&pb.BlobChunk{Data: data})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNoRows):
+			return status.Errorf(codes.NotFound, "blob not found")
+		case errors.Is(err, services.ErrBlobCorrupted):
+			return status.Errorf(codes.DataLoss, "blob is corrupted")
+		case stream.Context().Err() != nil:
+			return status.FromContextError(stream.Context().Err()).Err()
+		default:
+			return status.Errorf(codes.Internal, "failed to download blob")
+		}
+	}
+
+	return nil
+}
+
+// conflictStatus returns the ABORTED status for a version conflict with the current
+// copy of the secret attached as the status details.
+func conflictStatus(err error) error {
+	st := status.New(codes.Aborted, "secret was modified concurrently")
+
+	var conflict *services.ConflictError
+	if !errors.As(err, &conflict) {
+		return st.Err()
+	}
+
+	withDetails, detailsErr := st.WithDetails(secretToProto(conflict.Current))
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
+
+func secretFilterFromProto(in *pb.GetSecretsRequest) *models.SecretFilter {
+	filter := &models.SecretFilter{
+		Tag:       in.Tag,
+		Folder:    in.Folder,
+		Name:      in.Name,
+		PageToken: in.PageToken,
+		PageSize:  int(in.PageSize),
+	}
+
+	if in.Type != pb.SecretType_UNSPECIFIED {
+		filter.Type = in.Type.String()
+	}
+
+	filter.CreatedAfter = timeFromProto(in.CreatedAfter)
+	filter.CreatedBefore = timeFromProto(in.CreatedBefore)
+	filter.UpdatedAfter = timeFromProto(in.UpdatedAfter)
+	filter.UpdatedBefore = timeFromProto(in.UpdatedBefore)
+
+	return filter
+}
+
+// timeFromProto returns the zero time for unset timestamps.
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
+
+func secretToProto(secret *models.Secret) *pb.SecretData {
+	secretType := pb.SecretType_UNSPECIFIED
+	if v, ok := pb.SecretType_value[secret.Type]; ok {
+		secretType = pb.SecretType(v)
+	}
+
+	data := &pb.SecretData{
+		Id:        int64(secret.ID),
+		Type:      secretType,
+		Payload:   payloadToProto(secret.Payload),
+		MetaData:  secret.MetaData,
+		CreatedAt: timestamppb.New(secret.CreatedAt),
+		UpdatedAt: timestamppb.New(secret.UpdatedAt),
+		Version:   int64(secret.Version),
+		BlobId:    secret.BlobID,
+		Name:      secret.Name,
+		Tags:      secret.Tags,
+		Folder:    secret.Folder,
+	}
+
+	if !secret.DeletedAt.IsZero() {
+		data.DeletedAt = timestamppb.New(secret.DeletedAt)
+	}
+
+	if secret.Permission != "" {
+		data.Shared = secret.CollectionID == 0
+		data.Permission = pb.SharePermission(pb.SharePermission_value[secret.Permission])
+		data.Owner = secret.Owner
+	}
+
+	if secret.CollectionID != 0 {
+		data.CollectionId = int64(secret.CollectionID)
+		data.Collection = secret.Collection
+		data.Organization = secret.Organization
+	}
+
+	return data
+}
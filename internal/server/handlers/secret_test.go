@@ -3,11 +3,14 @@ package handlers
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -15,9 +18,15 @@ import (
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/services"
 	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
 )
 
+var testPayload = &pb.Payload{Kind: &pb.Payload_Credentials{
+	Credentials: &pb.Credentials{Login: "login", Password: "password"},
+}}
+
 func TestSecretHandler_Create(t *testing.T) {
 	log := logger.NewLogger()
 
@@ -36,7 +45,7 @@ func TestSecretHandler_Create(t *testing.T) {
 			name: "success: created secret",
 			req: &pb.CreateRequest{
 				Type:     1,
-				Content:  "test",
+				Payload:  testPayload,
 				MetaData: "test",
 			},
 			err: nil,
@@ -49,7 +58,7 @@ func TestSecretHandler_Create(t *testing.T) {
 			name: "error: failed to create secret",
 			req: &pb.CreateRequest{
 				Type:     1,
-				Content:  "test",
+				Payload:  testPayload,
 				MetaData: "test",
 			},
 			err: errors.New("test"),
@@ -58,18 +67,104 @@ func TestSecretHandler_Create(t *testing.T) {
 				err:      status.Errorf(codes.Internal, "failed to create secret"),
 			},
 		},
+		{
+			name: "error: invalid payload",
+			req: &pb.CreateRequest{
+				Type:     1,
+				Payload:  testPayload,
+				MetaData: "test",
+			},
+			err: services.ErrInvalidPayload,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.InvalidArgument, "payload does not match secret type"),
+			},
+		},
+		{
+			name: "error: blob cannot be attached",
+			req: &pb.CreateRequest{
+				Type:    1,
+				Payload: testPayload,
+				BlobId:  "blob",
+			},
+			err: repository.ErrInvalidBlob,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.InvalidArgument, "blob cannot be attached to secret"),
+			},
+		},
+		{
+			name: "error: invalid labels",
+			req: &pb.CreateRequest{
+				Type:    1,
+				Payload: testPayload,
+				Name:    "name",
+				Tags:    []string{"tag"},
+				Folder:  "folder",
+			},
+			err: services.ErrInvalidLabels,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.InvalidArgument, "name, folder or tags are invalid"),
+			},
+		},
+		{
+			name: "error: secret id is not reserved",
+			req: &pb.CreateRequest{
+				Type:     1,
+				Payload:  testPayload,
+				SecretId: 21,
+			},
+			err: services.ErrInvalidSecretID,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.InvalidArgument, "secret id is not reserved"),
+			},
+		},
+		{
+			name: "error: read-only member of organization",
+			req: &pb.CreateRequest{
+				Type:         1,
+				Payload:      testPayload,
+				CollectionId: 3,
+			},
+			err: services.ErrInsufficientRole,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets"),
+			},
+		},
+		{
+			name: "error: collection not found",
+			req: &pb.CreateRequest{
+				Type:         1,
+				Payload:      testPayload,
+				CollectionId: 3,
+			},
+			err: repository.ErrNoRows,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.NotFound, "collection not found"),
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockSecretService := new(mocks.MockSecretService)
 			mockSecretService.On("CreateSecret", context.Background(), &models.Secret{
-				Type:     tt.req.Type.String(),
-				Content:  tt.req.Content,
-				MetaData: tt.req.MetaData,
+				Type:         tt.req.Type.String(),
+				Payload:      &models.Credentials{Login: "login", Password: "password"},
+				MetaData:     tt.req.MetaData,
+				BlobID:       tt.req.BlobId,
+				Name:         tt.req.Name,
+				Tags:         tt.req.Tags,
+				Folder:       tt.req.Folder,
+				CollectionID: int(tt.req.CollectionId),
+				ID:           int(tt.req.SecretId),
 			}).Return(tt.err).Times(1)
 
-			handler := NewSecretHandler(mockSecretService, &log)
+			handler := NewSecretHandler(mockSecretService, nil, &log)
 			response, err := handler.Create(context.Background(), tt.req)
 
 			assert.Equal(t, tt.expected.response, response)
@@ -78,6 +173,19 @@ func TestSecretHandler_Create(t *testing.T) {
 	}
 }
 
+func TestSecretHandler_ReserveID(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockSecretService := new(mocks.MockSecretService)
+	mockSecretService.On("ReserveSecretID", context.Background()).Return(21, nil).Times(1)
+
+	handler := NewSecretHandler(mockSecretService, nil, &log)
+	response, err := handler.ReserveID(context.Background(), &emptypb.Empty{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.ReserveIDResponse{SecretId: 21}, response)
+}
+
 func TestSecretHandler_GetSecrets(t *testing.T) {
 	log := logger.NewLogger()
 	now := time.Now()
@@ -89,22 +197,25 @@ func TestSecretHandler_GetSecrets(t *testing.T) {
 
 	tests := []struct {
 		expected expected
+		request  *pb.GetSecretsRequest
 		prepare  func(s *mocks.MockSecretService)
 		name     string
 	}{
 		{
-			name: "success: created secret",
+			name:    "success: created secret",
+			request: &pb.GetSecretsRequest{},
 			prepare: func(s *mocks.MockSecretService) {
-				s.On("GetUserSecrets", context.Background()).
-					Return([]models.Secret{
+				s.On("GetUserSecrets", context.Background(), &models.SecretFilter{}).
+					Return(&models.SecretPage{Secrets: []models.Secret{
 						{
 							ID:        10,
 							Type:      pb.SecretType_CREDENTIALS.String(),
-							Content:   "test",
+							Payload:   &models.Credentials{Login: "login", Password: "password"},
 							MetaData:  "test",
 							CreatedAt: now,
+							UpdatedAt: now,
 						},
-					}, nil).Times(1)
+					}}, nil).Times(1)
 			},
 			expected: expected{
 				response: &pb.GetSecretsResponse{
@@ -112,9 +223,10 @@ func TestSecretHandler_GetSecrets(t *testing.T) {
 						{
 							Id:        10,
 							Type:      pb.SecretType_CREDENTIALS,
-							Content:   "test",
+							Payload:   testPayload,
 							MetaData:  "test",
 							CreatedAt: timestamppb.New(now),
+							UpdatedAt: timestamppb.New(now),
 						},
 					},
 				},
@@ -122,9 +234,92 @@ func TestSecretHandler_GetSecrets(t *testing.T) {
 			},
 		},
 		{
-			name: "error: failed to create secret",
+			name:    "success: shared secret",
+			request: &pb.GetSecretsRequest{},
 			prepare: func(s *mocks.MockSecretService) {
-				s.On("GetUserSecrets", context.Background()).
+				s.On("GetUserSecrets", context.Background(), &models.SecretFilter{}).
+					Return(&models.SecretPage{Secrets: []models.Secret{
+						{
+							ID:         10,
+							Type:       pb.SecretType_CREDENTIALS.String(),
+							Payload:    &models.Credentials{Login: "login", Password: "password"},
+							CreatedAt:  now,
+							UpdatedAt:  now,
+							Permission: models.SharePermissionReadWrite,
+							Owner:      "alice",
+						},
+					}}, nil).Times(1)
+			},
+			expected: expected{
+				response: &pb.GetSecretsResponse{
+					Secrets: []*pb.SecretData{
+						{
+							Id:         10,
+							Type:       pb.SecretType_CREDENTIALS,
+							Payload:    testPayload,
+							CreatedAt:  timestamppb.New(now),
+							UpdatedAt:  timestamppb.New(now),
+							Shared:     true,
+							Permission: pb.SharePermission_READ_WRITE,
+							Owner:      "alice",
+						},
+					},
+				},
+				err: nil,
+			},
+		},
+		{
+			name: "success: filtered page",
+			request: &pb.GetSecretsRequest{
+				Type:          pb.SecretType_CARD,
+				Tag:           "work",
+				Folder:        "Bank",
+				Name:          "visa",
+				CreatedAfter:  timestamppb.New(now),
+				UpdatedBefore: timestamppb.New(now),
+				PageToken:     "token",
+				PageSize:      10,
+			},
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("GetUserSecrets", context.Background(), &models.SecretFilter{
+					Type:          pb.SecretType_CARD.String(),
+					Tag:           "work",
+					Folder:        "Bank",
+					Name:          "visa",
+					CreatedAfter:  timestamppb.New(now).AsTime(),
+					UpdatedBefore: timestamppb.New(now).AsTime(),
+					PageToken:     "token",
+					PageSize:      10,
+				}).Return(&models.SecretPage{NextPageToken: "next"}, nil).Times(1)
+			},
+			expected: expected{
+				response: &pb.GetSecretsResponse{Secrets: []*pb.SecretData{}, NextPageToken: "next"},
+			},
+		},
+		{
+			name:    "error: negative page size",
+			request: &pb.GetSecretsRequest{PageSize: -1},
+			prepare: func(s *mocks.MockSecretService) {},
+			expected: expected{
+				err: status.Errorf(codes.InvalidArgument, "page size must not be negative"),
+			},
+		},
+		{
+			name:    "error: invalid page token",
+			request: &pb.GetSecretsRequest{PageToken: "invalid"},
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("GetUserSecrets", context.Background(), &models.SecretFilter{PageToken: "invalid"}).
+					Return(nil, services.ErrInvalidPageToken).Times(1)
+			},
+			expected: expected{
+				err: status.Errorf(codes.InvalidArgument, "page token is invalid"),
+			},
+		},
+		{
+			name:    "error: failed to create secret",
+			request: &pb.GetSecretsRequest{},
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("GetUserSecrets", context.Background(), &models.SecretFilter{}).
 					Return(nil, errors.New("test")).
 					Times(1)
 			},
@@ -140,11 +335,770 @@ func TestSecretHandler_GetSecrets(t *testing.T) {
 			mockSecretService := new(mocks.MockSecretService)
 			tt.prepare(mockSecretService)
 
-			handler := NewSecretHandler(mockSecretService, &log)
-			response, err := handler.GetSecrets(context.Background(), &pb.GetSecretsRequest{})
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			response, err := handler.GetSecrets(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expected.response, response)
+			assert.Equal(t, tt.expected.err, err)
+		})
+	}
+}
+
+func TestSecretHandler_Sync(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	type expected struct {
+		response *pb.SyncResponse
+		err      error
+	}
+
+	tests := []struct {
+		expected expected
+		prepare  func(s *mocks.MockSecretService)
+		request  *pb.SyncRequest
+		name     string
+	}{
+		{
+			name:    "success: changed and deleted secrets",
+			request: &pb.SyncRequest{SinceRevision: 5},
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("Sync", context.Background(), int64(5)).
+					Return(&models.Changes{
+						Secrets: []models.Secret{
+							{
+								ID:        10,
+								Type:      pb.SecretType_CREDENTIALS.String(),
+								Payload:   &models.Credentials{Login: "login", Password: "password"},
+								CreatedAt: now,
+								UpdatedAt: now,
+								Version:   3,
+							},
+						},
+						DeletedIDs: []int{7},
+						Revision:   8,
+					}, nil).Times(1)
+			},
+			expected: expected{
+				response: &pb.SyncResponse{
+					Secrets: []*pb.SecretData{
+						{
+							Id:        10,
+							Type:      pb.SecretType_CREDENTIALS,
+							Payload:   testPayload,
+							CreatedAt: timestamppb.New(now),
+							UpdatedAt: timestamppb.New(now),
+							Version:   3,
+						},
+					},
+					DeletedIds: []int64{7},
+					Revision:   8,
+				},
+			},
+		},
+		{
+			name:    "error: negative revision",
+			request: &pb.SyncRequest{SinceRevision: -1},
+			prepare: func(s *mocks.MockSecretService) {},
+			expected: expected{
+				err: status.Errorf(codes.InvalidArgument, "revision must not be negative"),
+			},
+		},
+		{
+			name:    "error: failed to sync",
+			request: &pb.SyncRequest{},
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("Sync", context.Background(), int64(0)).
+					Return(nil, errors.New("test")).Times(1)
+			},
+			expected: expected{
+				err: status.Errorf(codes.Internal, "failed to sync secrets"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			tt.prepare(mockSecretService)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			response, err := handler.Sync(context.Background(), tt.request)
 
 			assert.Equal(t, tt.expected.response, response)
 			assert.Equal(t, tt.expected.err, err)
+			mockSecretService.AssertExpectations(t)
+		})
+	}
+}
+
+type mockWatchServer struct {
+	pb.Secret_WatchServer
+	ctx  context.Context
+	sent []*pb.SecretEvent
+}
+
+func (m *mockWatchServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockWatchServer) SendHeader(metadata.MD) error {
+	return nil
+}
+
+func (m *mockWatchServer) Send(event *pb.SecretEvent) error {
+	m.sent = append(m.sent, event)
+
+	return nil
+}
+
+func TestSecretHandler_Share(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+		permission  string
+		request     *pb.ShareRequest
+	}{
+		{
+			name:       "success: secret shared",
+			permission: models.SharePermissionReadOnly,
+			request:    &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+		},
+		{
+			name:        "error: unspecified permission",
+			err:         services.ErrInvalidPermission,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob"},
+			expectedErr: status.Errorf(codes.InvalidArgument, "share permission is invalid"),
+		},
+		{
+			name:        "error: client-side encryption",
+			permission:  models.SharePermissionReadWrite,
+			err:         services.ErrSharingUnavailable,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_WRITE},
+			expectedErr: status.Errorf(codes.FailedPrecondition, "sharing is not available with client-side encryption"),
+		},
+		{
+			name:        "error: user not found",
+			permission:  models.SharePermissionReadOnly,
+			err:         services.ErrRecipientNotFound,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+			expectedErr: status.Errorf(codes.NotFound, "user not found"),
+		},
+		{
+			name:        "error: binary secret",
+			permission:  models.SharePermissionReadOnly,
+			err:         services.ErrBinaryShare,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+			expectedErr: status.Errorf(codes.InvalidArgument, "binary secrets cannot be shared"),
+		},
+		{
+			name:        "error: secret not found",
+			permission:  models.SharePermissionReadOnly,
+			err:         repository.ErrNoRows,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+			expectedErr: status.Errorf(codes.NotFound, "secret not found"),
+		},
+		{
+			name:        "error: secret changed in the meantime",
+			permission:  models.SharePermissionReadOnly,
+			err:         services.ErrVersionConflict,
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+			expectedErr: status.Errorf(codes.Aborted, "secret was modified concurrently"),
+		},
+		{
+			name:        "error: failed to share",
+			permission:  models.SharePermissionReadOnly,
+			err:         errors.New("test"),
+			request:     &pb.ShareRequest{SecretId: 10, TargetLogin: "bob", Permission: pb.SharePermission_READ_ONLY},
+			expectedErr: status.Errorf(codes.Internal, "failed to share secret"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			mockSecretService.On("ShareSecret", context.Background(), 10, "bob", tt.permission).Return(tt.err).Times(1)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			_, err := handler.Share(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_Unshare(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: share revoked"},
+		{
+			name:        "error: user not found",
+			err:         services.ErrRecipientNotFound,
+			expectedErr: status.Errorf(codes.NotFound, "user not found"),
+		},
+		{
+			name:        "error: share not found",
+			err:         repository.ErrNoRows,
+			expectedErr: status.Errorf(codes.NotFound, "share not found"),
+		},
+		{
+			name:        "error: failed to unshare",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to unshare secret"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			mockSecretService.On("UnshareSecret", context.Background(), 10, "bob").Return(tt.err).Times(1)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			_, err := handler.Unshare(context.Background(), &pb.UnshareRequest{SecretId: 10, TargetLogin: "bob"})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_ListVersions(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	mockSecretService := new(mocks.MockSecretService)
+	mockSecretService.On("ListVersions", context.Background(), 10).
+		Return([]models.Secret{
+			{
+				ID:        10,
+				Type:      pb.SecretType_CREDENTIALS.String(),
+				Payload:   &models.Credentials{Login: "login", Password: "password"},
+				CreatedAt: now,
+				UpdatedAt: now,
+				Version:   1,
+			},
+		}, nil).Times(1)
+	mockSecretService.On("ListVersions", context.Background(), 11).
+		Return(nil, errors.New("test")).Times(1)
+
+	handler := NewSecretHandler(mockSecretService, nil, &log)
+
+	response, err := handler.ListVersions(context.Background(), &pb.ListVersionsRequest{SecretId: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.ListVersionsResponse{
+		Versions: []*pb.SecretData{
+			{
+				Id:        10,
+				Type:      pb.SecretType_CREDENTIALS,
+				Payload:   testPayload,
+				CreatedAt: timestamppb.New(now),
+				UpdatedAt: timestamppb.New(now),
+				Version:   1,
+			},
+		},
+	}, response)
+
+	response, err = handler.ListVersions(context.Background(), &pb.ListVersionsRequest{SecretId: 11})
+	assert.Nil(t, response)
+	assert.Equal(t, status.Errorf(codes.Internal, "failed to get secret versions"), err)
+}
+
+func TestSecretHandler_RestoreVersion(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: version restored"},
+		{
+			name:        "error: version not found",
+			err:         repository.ErrNoRows,
+			expectedErr: status.Errorf(codes.NotFound, "version not found"),
+		},
+		{
+			name:        "error: blob cannot be attached",
+			err:         repository.ErrInvalidBlob,
+			expectedErr: status.Errorf(codes.InvalidArgument, "blob cannot be attached to secret"),
+		},
+		{
+			name:        "error: insufficient role",
+			err:         services.ErrInsufficientRole,
+			expectedErr: status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets"),
+		},
+		{
+			name:        "error: failed to restore",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to restore secret version"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			mockSecretService.On("RestoreVersion", context.Background(), 10, 1, 3).Return(tt.err).Times(1)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			_, err := handler.RestoreVersion(context.Background(), &pb.RestoreVersionRequest{
+				SecretId:       10,
+				Version:        1,
+				CurrentVersion: 3,
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_ListTrash(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	mockSecretService := new(mocks.MockSecretService)
+	mockSecretService.On("ListTrash", context.Background()).
+		Return([]models.Secret{
+			{
+				ID:        10,
+				Type:      pb.SecretType_CREDENTIALS.String(),
+				Payload:   &models.Credentials{Login: "login", Password: "password"},
+				CreatedAt: now,
+				UpdatedAt: now,
+				DeletedAt: now,
+				Version:   2,
+			},
+		}, nil).Once()
+	mockSecretService.On("ListTrash", context.Background()).
+		Return(nil, errors.New("test")).Once()
+
+	handler := NewSecretHandler(mockSecretService, nil, &log)
+
+	response, err := handler.ListTrash(context.Background(), &pb.ListTrashRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, &pb.ListTrashResponse{
+		Secrets: []*pb.SecretData{
+			{
+				Id:        10,
+				Type:      pb.SecretType_CREDENTIALS,
+				Payload:   testPayload,
+				CreatedAt: timestamppb.New(now),
+				UpdatedAt: timestamppb.New(now),
+				DeletedAt: timestamppb.New(now),
+				Version:   2,
+			},
+		},
+	}, response)
+
+	response, err = handler.ListTrash(context.Background(), &pb.ListTrashRequest{})
+	assert.Nil(t, response)
+	assert.Equal(t, status.Errorf(codes.Internal, "failed to get trash"), err)
+}
+
+func TestSecretHandler_Restore(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: secret restored"},
+		{
+			name:        "error: secret not in trash",
+			err:         repository.ErrNoRows,
+			expectedErr: status.Errorf(codes.NotFound, "secret not found in trash"),
+		},
+		{
+			name:        "error: insufficient role",
+			err:         services.ErrInsufficientRole,
+			expectedErr: status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets"),
+		},
+		{
+			name:        "error: failed to restore",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to restore secret"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			mockSecretService.On("RestoreSecret", context.Background(), 10).Return(tt.err).Times(1)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			_, err := handler.Restore(context.Background(), &pb.RestoreRequest{SecretId: 10})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_Purge(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err         error
+		expectedErr error
+		name        string
+	}{
+		{name: "success: secret purged"},
+		{
+			name:        "error: secret not in trash",
+			err:         repository.ErrNoRows,
+			expectedErr: status.Errorf(codes.NotFound, "secret not found in trash"),
+		},
+		{
+			name:        "error: insufficient role",
+			err:         services.ErrInsufficientRole,
+			expectedErr: status.Errorf(codes.PermissionDenied, "organization role does not allow writing secrets"),
+		},
+		{
+			name:        "error: failed to purge",
+			err:         errors.New("test"),
+			expectedErr: status.Errorf(codes.Internal, "failed to purge secret"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			mockSecretService.On("PurgeSecret", context.Background(), 10).Return(tt.err).Times(1)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			_, err := handler.Purge(context.Background(), &pb.PurgeRequest{SecretId: 10})
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_FindByDomain(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		expected    *pb.FindByDomainResponse
+		prepare     func(s *mocks.MockSecretService)
+		name        string
+	}{
+		{
+			name: "success: found credentials",
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("FindSecretsByDomain", context.Background(), "example.com", "login").
+					Return([]models.Secret{
+						{
+							ID:      10,
+							Type:    pb.SecretType_CREDENTIALS.String(),
+							Payload: &models.Credentials{Login: "login", Password: "password"},
+						},
+					}, nil).Times(1)
+			},
+			expected: &pb.FindByDomainResponse{
+				Secrets: []*pb.SecretData{
+					{
+						Id:        10,
+						Type:      pb.SecretType_CREDENTIALS,
+						Payload:   testPayload,
+						CreatedAt: timestamppb.New(time.Time{}),
+						UpdatedAt: timestamppb.New(time.Time{}),
+					},
+				},
+			},
+		},
+		{
+			name: "error: invalid domain",
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("FindSecretsByDomain", context.Background(), "example.com", "login").
+					Return(nil, services.ErrInvalidDomain).Times(1)
+			},
+			expectedErr: status.Errorf(codes.InvalidArgument, "domain is invalid"),
+		},
+		{
+			name: "error: client-side encryption",
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("FindSecretsByDomain", context.Background(), "example.com", "login").
+					Return(nil, services.ErrBlindIndexUnavailable).Times(1)
+			},
+			expectedErr: status.Errorf(codes.FailedPrecondition, "search by domain is not available with client-side encryption"),
+		},
+		{
+			name: "error: failed to find secrets",
+			prepare: func(s *mocks.MockSecretService) {
+				s.On("FindSecretsByDomain", context.Background(), "example.com", "login").
+					Return(nil, errors.New("test")).Times(1)
+			},
+			expectedErr: status.Errorf(codes.Internal, "failed to find secrets"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSecretService := new(mocks.MockSecretService)
+			tt.prepare(mockSecretService)
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			response, err := handler.FindByDomain(context.Background(), &pb.FindByDomainRequest{Domain: "example.com", Login: "login"})
+
+			assert.Equal(t, tt.expected, response)
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func TestSecretHandler_Watch(t *testing.T) {
+	log := logger.NewLogger()
+
+	events := []models.SecretEvent{
+		{Kind: models.SecretEventCreated, Revision: 2, SecretID: 10, UserID: 1},
+		{Kind: models.SecretEventDeleted, Revision: 3, SecretID: 11, UserID: 1},
+	}
+
+	expectedEvents := []*pb.SecretEvent{
+		{Kind: pb.SecretEvent_CREATED, Revision: 2, SecretId: 10},
+		{Kind: pb.SecretEvent_DELETED, Revision: 3, SecretId: 11},
+	}
+
+	tests := []struct {
+		err      error
+		ctx      func() context.Context
+		prepare  func(s *mocks.MockSecretService, stopped *bool)
+		name     string
+		expected []*pb.SecretEvent
+	}{
+		{
+			name: "events are sent until watch is interrupted",
+			ctx:  context.Background,
+			prepare: func(s *mocks.MockSecretService, stopped *bool) {
+				ch := make(chan models.SecretEvent, len(events))
+				for _, event := range events {
+					ch <- event
+				}
+				close(ch)
+
+				s.On("Watch", mock.Anything).
+					Return((<-chan models.SecretEvent)(ch), func() { *stopped = true }, nil).Times(1)
+			},
+			expected: expectedEvents,
+			err:      status.Errorf(codes.Unavailable, "events may have been missed, sync and watch again"),
+		},
+		{
+			name: "client disconnects",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return ctx
+			},
+			prepare: func(s *mocks.MockSecretService, stopped *bool) {
+				s.On("Watch", mock.Anything).
+					Return((<-chan models.SecretEvent)(make(chan models.SecretEvent)), func() { *stopped = true }, nil).
+					Times(1)
+			},
+			err: status.Error(codes.Canceled, context.Canceled.Error()),
+		},
+		{
+			name: "error: failed to watch",
+			ctx:  context.Background,
+			prepare: func(s *mocks.MockSecretService, stopped *bool) {
+				*stopped = true
+
+				s.On("Watch", mock.Anything).Return(nil, nil, errors.New("test")).Times(1)
+			},
+			err: status.Errorf(codes.Internal, "failed to watch secrets"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stopped bool
+
+			mockSecretService := new(mocks.MockSecretService)
+			tt.prepare(mockSecretService, &stopped)
+
+			stream := &mockWatchServer{ctx: tt.ctx()}
+
+			handler := NewSecretHandler(mockSecretService, nil, &log)
+			err := handler.Watch(&pb.WatchRequest{}, stream)
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, stream.sent)
+			assert.True(t, stopped)
+		})
+	}
+}
+
+type mockUploadServer struct {
+	pb.Secret_UploadBlobServer
+	ctx      context.Context
+	response *pb.UploadBlobResponse
+	chunks   [][]byte
+}
+
+func (m *mockUploadServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockUploadServer) Recv() (*pb.UploadBlobRequest, error) {
+	if len(m.chunks) == 0 {
+		return nil, io.EOF
+	}
+
+	chunk := m.chunks[0]
+	m.chunks = m.chunks[1:]
+
+	return &pb.UploadBlobRequest{Chunk: chunk}, nil
+}
+
+func (m *mockUploadServer) SendAndClose(response *pb.UploadBlobResponse) error {
+	m.response = response
+
+	return nil
+}
+
+func TestSecretHandler_UploadBlob(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err      error
+		prepare  func(s *mocks.MockBlobService)
+		expected *pb.UploadBlobResponse
+		name     string
+	}{
+		{
+			name: "chunks are passed to service",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Upload", mock.Anything, mock.Anything).
+					Return(func(ctx context.Context, next func() ([]byte, error)) (*models.Blob, error) {
+						var size int64
+						for {
+							chunk, err := next()
+							if err != nil {
+								return &models.Blob{ID: "blob", Size: size}, nil
+							}
+
+							size += int64(len(chunk))
+						}
+					}).Times(1)
+			},
+			expected: &pb.UploadBlobResponse{BlobId: "blob", Size: 11},
+		},
+		{
+			name: "error: invalid chunk",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Upload", mock.Anything, mock.Anything).Return(nil, services.ErrInvalidChunk).Times(1)
+			},
+			err: status.Errorf(codes.InvalidArgument, "chunk must not be empty or exceed %d bytes", services.MaxBlobChunkSize),
+		},
+		{
+			name: "error: blob too large",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Upload", mock.Anything, mock.Anything).Return(nil, services.ErrBlobTooLarge).Times(1)
+			},
+			err: status.Errorf(codes.ResourceExhausted, "file is too large"),
+		},
+		{
+			name: "error: failed to upload",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Upload", mock.Anything, mock.Anything).Return(nil, errors.New("test")).Times(1)
+			},
+			err: status.Errorf(codes.Internal, "failed to upload blob"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBlobService := new(mocks.MockBlobService)
+			tt.prepare(mockBlobService)
+
+			stream := &mockUploadServer{
+				ctx:    context.Background(),
+				chunks: [][]byte{[]byte("first"), []byte("second")},
+			}
+
+			handler := NewSecretHandler(nil, mockBlobService, &log)
+			err := handler.UploadBlob(stream)
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, stream.response)
+		})
+	}
+}
+
+type mockDownloadServer struct {
+	pb.Secret_DownloadBlobServer
+	ctx  context.Context
+	sent []*pb.BlobChunk
+}
+
+func (m *mockDownloadServer) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockDownloadServer) Send(chunk *pb.BlobChunk) error {
+	m.sent = append(m.sent, chunk)
+
+	return nil
+}
+
+func TestSecretHandler_DownloadBlob(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		err      error
+		prepare  func(s *mocks.MockBlobService)
+		name     string
+		expected []*pb.BlobChunk
+	}{
+		{
+			name: "chunks are sent in order",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Download", mock.Anything, "blob", mock.Anything).
+					Return(func(ctx context.Context, blobID string, send func([]byte) error) error {
+						if err := send([]byte("first")); err != nil {
+							return err
+						}
+
+						return send([]byte("second"))
+					}).Times(1)
+			},
+			expected: []*pb.BlobChunk{{Data: []byte("first")}, {Data: []byte("second")}},
+		},
+		{
+			name: "error: blob not found",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Download", mock.Anything, "blob", mock.Anything).Return(repository.ErrNoRows).Times(1)
+			},
+			err: status.Errorf(codes.NotFound, "blob not found"),
+		},
+		{
+			name: "error: blob is corrupted",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Download", mock.Anything, "blob", mock.Anything).Return(services.ErrBlobCorrupted).Times(1)
+			},
+			err: status.Errorf(codes.DataLoss, "blob is corrupted"),
+		},
+		{
+			name: "error: failed to download",
+			prepare: func(s *mocks.MockBlobService) {
+				s.On("Download", mock.Anything, "blob", mock.Anything).Return(errors.New("test")).Times(1)
+			},
+			err: status.Errorf(codes.Internal, "failed to download blob"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBlobService := new(mocks.MockBlobService)
+			tt.prepare(mockBlobService)
+
+			stream := &mockDownloadServer{ctx: context.Background()}
+
+			handler := NewSecretHandler(nil, mockBlobService, &log)
+			err := handler.DownloadBlob(&pb.DownloadBlobRequest{BlobId: "blob"}, stream)
+
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, stream.sent)
 		})
 	}
 }
@@ -168,8 +1122,9 @@ func TestSecretHandler_Update(t *testing.T) {
 			req: &pb.UpdateRequest{
 				SecretId: 10,
 				Type:     1,
-				Content:  "test",
+				Payload:  testPayload,
 				MetaData: "test",
+				Version:  2,
 			},
 			err: nil,
 			expected: expected{
@@ -182,8 +1137,9 @@ func TestSecretHandler_Update(t *testing.T) {
 			req: &pb.UpdateRequest{
 				SecretId: 10,
 				Type:     1,
-				Content:  "test",
+				Payload:  testPayload,
 				MetaData: "test",
+				Version:  2,
 			},
 			err: errors.New("test"),
 			expected: expected{
@@ -191,6 +1147,51 @@ func TestSecretHandler_Update(t *testing.T) {
 				err:      status.Errorf(codes.Internal, "failed to update secret"),
 			},
 		},
+		{
+			name: "error: secret not found",
+			req: &pb.UpdateRequest{
+				SecretId: 10,
+				Type:     1,
+				Payload:  testPayload,
+				MetaData: "test",
+				Version:  2,
+			},
+			err: repository.ErrNoRows,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.NotFound, "secret not found"),
+			},
+		},
+		{
+			name: "error: secret shared read-only",
+			req: &pb.UpdateRequest{
+				SecretId: 10,
+				Type:     1,
+				Payload:  testPayload,
+				MetaData: "test",
+				Version:  2,
+			},
+			err: services.ErrReadOnlyShare,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.PermissionDenied, "secret is shared read-only"),
+			},
+		},
+		{
+			name: "error: invalid payload",
+			req: &pb.UpdateRequest{
+				SecretId: 10,
+				Type:     1,
+				Payload:  testPayload,
+				MetaData: "test",
+				Version:  2,
+			},
+			err: services.ErrInvalidPayload,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.InvalidArgument, "payload does not match secret type"),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -199,11 +1200,12 @@ func TestSecretHandler_Update(t *testing.T) {
 			mockSecretService.On("UpdateSecret", context.Background(), &models.Secret{
 				ID:       int(tt.req.SecretId),
 				Type:     tt.req.Type.String(),
-				Content:  tt.req.Content,
+				Payload:  &models.Credentials{Login: "login", Password: "password"},
 				MetaData: tt.req.MetaData,
+				Version:  int(tt.req.Version),
 			}).Return(tt.err).Times(1)
 
-			handler := NewSecretHandler(mockSecretService, &log)
+			handler := NewSecretHandler(mockSecretService, nil, &log)
 			response, err := handler.Update(context.Background(), tt.req)
 
 			assert.Equal(t, tt.expected.response, response)
@@ -228,7 +1230,7 @@ func TestSecretHandler_Delete(t *testing.T) {
 	}{
 		{
 			name: "success: secret deleted",
-			req:  &pb.DeleteRequest{SecretId: 10},
+			req:  &pb.DeleteRequest{SecretId: 10, Version: 2},
 			err:  nil,
 			expected: expected{
 				response: &emptypb.Empty{},
@@ -237,22 +1239,31 @@ func TestSecretHandler_Delete(t *testing.T) {
 		},
 		{
 			name: "error: failed to delete secret",
-			req:  &pb.DeleteRequest{SecretId: 10},
+			req:  &pb.DeleteRequest{SecretId: 10, Version: 2},
 			err:  errors.New("test"),
 			expected: expected{
 				response: nil,
 				err:      status.Errorf(codes.Internal, "failed to delete secret"),
 			},
 		},
+		{
+			name: "error: secret not found",
+			req:  &pb.DeleteRequest{SecretId: 10, Version: 2},
+			err:  repository.ErrNoRows,
+			expected: expected{
+				response: nil,
+				err:      status.Errorf(codes.NotFound, "secret not found"),
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockSecretService := new(mocks.MockSecretService)
-			mockSecretService.On("DeleteSecret", context.Background(), int(tt.req.SecretId)).
+			mockSecretService.On("DeleteSecret", context.Background(), int(tt.req.SecretId), int(tt.req.Version)).
 				Return(tt.err).Times(1)
 
-			handler := NewSecretHandler(mockSecretService, &log)
+			handler := NewSecretHandler(mockSecretService, nil, &log)
 			response, err := handler.Delete(context.Background(), tt.req)
 
 			assert.Equal(t, tt.expected.response, response)
@@ -260,3 +1271,47 @@ func TestSecretHandler_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestSecretHandler_Conflict(t *testing.T) {
+	log := logger.NewLogger()
+
+	conflict := &services.ConflictError{Current: &models.Secret{
+		ID:       10,
+		Type:     pb.SecretType_CREDENTIALS.String(),
+		Payload:  &models.Credentials{Login: "login", Password: "new password"},
+		MetaData: "test",
+		Version:  3,
+	}}
+
+	mockSecretService := new(mocks.MockSecretService)
+	mockSecretService.On("UpdateSecret", context.Background(), mock.Anything).Return(conflict).Times(1)
+	mockSecretService.On("DeleteSecret", context.Background(), 10, 2).Return(conflict).Times(1)
+	mockSecretService.On("RestoreVersion", context.Background(), 10, 1, 2).Return(conflict).Times(1)
+
+	handler := NewSecretHandler(mockSecretService, nil, &log)
+
+	_, updateErr := handler.Update(context.Background(), &pb.UpdateRequest{
+		SecretId: 10,
+		Type:     pb.SecretType_CREDENTIALS,
+		Payload:  testPayload,
+		Version:  2,
+	})
+	_, deleteErr := handler.Delete(context.Background(), &pb.DeleteRequest{SecretId: 10, Version: 2})
+	_, restoreErr := handler.RestoreVersion(context.Background(), &pb.RestoreVersionRequest{
+		SecretId:       10,
+		Version:        1,
+		CurrentVersion: 2,
+	})
+
+	for _, err := range []error{updateErr, deleteErr, restoreErr} {
+		st := status.Convert(err)
+		assert.Equal(t, codes.Aborted, st.Code())
+
+		if assert.Len(t, st.Details(), 1) {
+			current, ok := st.Details()[0].(*pb.SecretData)
+			assert.True(t, ok)
+			assert.Equal(t, int64(3), current.GetVersion())
+			assert.Equal(t, "new password", current.GetPayload().GetCredentials().GetPassword())
+		}
+	}
+}
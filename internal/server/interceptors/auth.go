@@ -3,6 +3,7 @@ package interceptors
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"google.golang.org/grpc"
@@ -21,29 +22,44 @@ const (
 	authentication = "authorization"
 )
 
-const UserIDKey UserIDKeyType = "userID"
+const (
+	UserIDKey               UserIDKeyType = "userID"
+	SessionIDKey            UserIDKeyType = "sessionID"
+	ClientSideEncryptionKey UserIDKeyType = "clientSideEncryption"
+)
 
 var unprotectedPaths = map[string]bool{
-	pb.Auth_Login_FullMethodName:    true,
-	pb.Auth_Register_FullMethodName: true,
+	pb.Auth_Login_FullMethodName:     true,
+	pb.Auth_LoginTOTP_FullMethodName: true,
+	pb.Auth_Register_FullMethodName:  true,
+	pb.Auth_Refresh_FullMethodName:   true,
+}
+
+// SessionChecker reports whether the session a token was issued for is still active.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
 }
 
 // AuthInterceptor structure holds the JWT Manager which will be used to parse the token
-// from the context metadata for authenticated services.
+// from the context metadata for authenticated services, and the SessionChecker which
+// is used to reject the tokens of revoked sessions.
 type AuthInterceptor struct {
 	JWTManager *jwt.JWTManager
+	Sessions   SessionChecker
 }
 
 // NewAuthInterceptor is a constructor function that initializes AuthInterceptor.
-func NewAuthInterceptor(jwtManager *jwt.JWTManager) AuthInterceptor {
+func NewAuthInterceptor(jwtManager *jwt.JWTManager, sessions SessionChecker) AuthInterceptor {
 	return AuthInterceptor{
 		JWTManager: jwtManager,
+		Sessions:   sessions,
 	}
 }
 
 // UnaryServerInterceptor is a gRPC unary server interceptor function.
 // It intercepts each request and if it is not an unprotected path, it checks for Bearer token and uses JWTManager
-// To parse and validate the token. The parsed UserID from the token is then added to context.
+// To parse and validate the token, and SessionChecker to make sure the session of the token is not revoked.
+// The parsed UserID, session ID and encryption mode from the token are then added to context.
 // It then calls the underlying gRPC handler and returns its response and error.
 func (a *AuthInterceptor) UnaryServerInterceptor(
 	ctx context.Context,
@@ -55,33 +71,107 @@ func (a *AuthInterceptor) UnaryServerInterceptor(
 		return handler(ctx, req)
 	}
 
+	newCtx, _, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(newCtx, req)
+}
+
+// StreamServerInterceptor is a gRPC stream server interceptor function.
+// It authenticates the stream the same way UnaryServerInterceptor authenticates requests.
+// A stream outlives a single request, so it is ended with the Unauthenticated status once
+// the token expires, and the client has to refresh the token to open a new one.
+func (a *AuthInterceptor) StreamServerInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if _, ok := unprotectedPaths[info.FullMethod]; ok {
+		return handler(srv, ss)
+	}
+
+	newCtx, claims, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+
+	if claims.ExpiresAt != nil {
+		var cancel context.CancelFunc
+		newCtx, cancel = context.WithDeadline(newCtx, claims.ExpiresAt.Time)
+		defer cancel()
+	}
+
+	err = handler(srv, &authenticatedStream{ServerStream: ss, ctx: newCtx})
+	if errors.Is(newCtx.Err(), context.DeadlineExceeded) && ss.Context().Err() == nil {
+		return status.Errorf(codes.Unauthenticated, "the token is expired")
+	}
+
+	return err
+}
+
+// authenticate validates the Bearer token of the incoming context and returns the context
+// with the UserID, session ID and encryption mode of the token along with the token claims.
+func (a *AuthInterceptor) authenticate(ctx context.Context) (context.Context, *jwt.Claims, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return nil, status.Errorf(codes.Unauthenticated, "metadata is not provided")
+		return nil, nil, status.Errorf(codes.Unauthenticated, "metadata is not provided")
 	}
 
 	authHeader, ok := md[authentication]
 	if !ok {
-		return nil, status.Errorf(codes.Unauthenticated, "authorization token is not provided")
+		return nil, nil, status.Errorf(codes.Unauthenticated, "authorization token is not provided")
 	}
 
 	splits := strings.SplitN(authHeader[0], " ", 2)
 	if len(splits) < 2 {
-		return nil, status.Errorf(codes.Unauthenticated, "the token is not in the correct format")
+		return nil, nil, status.Errorf(codes.Unauthenticated, "the token is not in the correct format")
 	}
 
 	if strings.ToLower(splits[0]) != bearerSchema {
-		return nil, status.Errorf(codes.Unauthenticated, "the token is not a Bearer token")
+		return nil, nil, status.Errorf(codes.Unauthenticated, "the token is not a Bearer token")
 	}
 
 	tokenString := splits[1]
 
-	userID, err := a.JWTManager.Parse(tokenString)
+	claims, err := a.JWTManager.Parse(tokenString)
 	if err != nil {
-		return nil, status.Errorf(codes.Unauthenticated, "the token is invalid")
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, nil, status.Errorf(codes.Unauthenticated, "the token is expired")
+		}
+
+		return nil, nil, status.Errorf(codes.Unauthenticated, "the token is invalid")
 	}
 
-	newCtx := context.WithValue(ctx, UserIDKey, userID)
+	if claims.SessionID == "" {
+		return nil, nil, status.Errorf(codes.Unauthenticated, "the token is invalid")
+	}
 
-	return handler(newCtx, req)
+	active, err := a.Sessions.IsSessionActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "failed to check session")
+	}
+
+	if !active {
+		return nil, nil, status.Errorf(codes.Unauthenticated, "the session is revoked")
+	}
+
+	newCtx := context.WithValue(ctx, UserIDKey, claims.UserID)
+	newCtx = context.WithValue(newCtx, SessionIDKey, claims.SessionID)
+	newCtx = context.WithValue(newCtx, ClientSideEncryptionKey, claims.ClientSideEncryption)
+
+	return newCtx, claims, nil
+}
+
+// authenticatedStream is a server stream with the context of the authenticated user.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the authenticated user.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
 }
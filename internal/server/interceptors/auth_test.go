@@ -2,8 +2,10 @@ package interceptors
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
@@ -21,9 +23,27 @@ func (m mockHandler) Handle(ctx context.Context, req interface{}) (interface{},
 	return m(ctx, req)
 }
 
+type mockSessionChecker func(ctx context.Context, sessionID string) (bool, error)
+
+func (m mockSessionChecker) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return m(ctx, sessionID)
+}
+
 func TestAuthInterceptor_UnaryServerInterceptor(t *testing.T) {
-	jwtManager := jwt.NewJWTManager("test-secret")
-	token, _ := jwtManager.Generate(1)
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	token, _ := jwtManager.Generate(1, "session", false)
+	expiredToken, _ := jwt.NewJWTManager("test-secret", -time.Minute).Generate(1, "session", false)
+	revokedToken, _ := jwtManager.Generate(1, "revoked", false)
+	noSessionToken, _ := jwtManager.Generate(1, "", false)
+	brokenSessionToken, _ := jwtManager.Generate(1, "broken", false)
+
+	sessions := mockSessionChecker(func(ctx context.Context, sessionID string) (bool, error) {
+		if sessionID == "broken" {
+			return false, errors.New("internal error")
+		}
+
+		return sessionID == "session", nil
+	})
 
 	testCases := []struct {
 		ctx          context.Context
@@ -70,24 +90,136 @@ func TestAuthInterceptor_UnaryServerInterceptor(t *testing.T) {
 			})),
 			expectedCode: codes.Unauthenticated,
 		},
+		{
+			name: "expired token",
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": fmt.Sprintf("bearer %s", expiredToken),
+			})),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "revoked session",
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": fmt.Sprintf("bearer %s", revokedToken),
+			})),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "token without session",
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": fmt.Sprintf("bearer %s", noSessionToken),
+			})),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "failed to check session",
+			ctx: metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+				"authorization": fmt.Sprintf("bearer %s", brokenSessionToken),
+			})),
+			expectedCode: codes.Internal,
+		},
 	}
 
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
 			handler := mockHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+				assert.Equal(t, 1, ctx.Value(UserIDKey))
+				assert.Equal(t, "session", ctx.Value(SessionIDKey))
+
 				return nil, nil
 			})
 
-			a := NewAuthInterceptor(jwtManager)
+			a := NewAuthInterceptor(jwtManager, sessions)
 			info := &grpc.UnaryServerInfo{
 				FullMethod: pb.Secret_Create_FullMethodName,
 			}
 
 			_, err := a.UnaryServerInterceptor(tt.ctx, "request", info, handler.Handle)
 
-			if status.Code(err) != codes.OK {
-				assert.Equal(t, tt.expectedCode.String(), status.Code(err).String())
+			assert.Equal(t, tt.expectedCode.String(), status.Code(err).String())
+		})
+	}
+}
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ctx
+}
+
+func TestAuthInterceptor_StreamServerInterceptor(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	token, _ := jwtManager.Generate(1, "session", false)
+	shortToken, _ := jwt.NewJWTManager("test-secret", 2*time.Second).Generate(1, "session", false)
+
+	sessions := mockSessionChecker(func(ctx context.Context, sessionID string) (bool, error) {
+		return sessionID == "session", nil
+	})
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+			"authorization": fmt.Sprintf("bearer %s", token),
+		}))
+	}
+
+	testCases := []struct {
+		ctx          context.Context
+		handlerErr   error
+		name         string
+		expectedCode codes.Code
+		waitForEnd   bool
+	}{
+		{
+			name:         "successful stream",
+			ctx:          withToken(token),
+			expectedCode: codes.OK,
+		},
+		{
+			name:         "handler error",
+			ctx:          withToken(token),
+			handlerErr:   status.Error(codes.Unavailable, "unavailable"),
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name:         "missing metadata",
+			ctx:          context.Background(),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "token expires during stream",
+			ctx:          withToken(shortToken),
+			waitForEnd:   true,
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				assert.Equal(t, 1, stream.Context().Value(UserIDKey))
+				assert.Equal(t, "session", stream.Context().Value(SessionIDKey))
+
+				if tt.waitForEnd {
+					<-stream.Context().Done()
+
+					return status.FromContextError(stream.Context().Err()).Err()
+				}
+
+				return tt.handlerErr
 			}
+
+			a := NewAuthInterceptor(jwtManager, sessions)
+			info := &grpc.StreamServerInfo{
+				FullMethod:     pb.Secret_Watch_FullMethodName,
+				IsServerStream: true,
+			}
+
+			err := a.StreamServerInterceptor(nil, &mockServerStream{ctx: tt.ctx}, info, handler)
+
+			assert.Equal(t, tt.expectedCode.String(), status.Code(err).String())
 		})
 	}
 }
@@ -0,0 +1,178 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
+)
+
+// limiterIdleTTL is the period of time after which the bucket of an idle client is forgotten.
+const limiterIdleTTL = 10 * time.Minute
+
+// rateLimitedPaths are the methods which accept credentials and thus can be used to guess them.
+var rateLimitedPaths = map[string]bool{
+	pb.Auth_Login_FullMethodName:          true,
+	pb.Auth_LoginTOTP_FullMethodName:      true,
+	pb.Auth_Register_FullMethodName:       true,
+	pb.Auth_ChangePassword_FullMethodName: true,
+	pb.Auth_DeleteAccount_FullMethodName:  true,
+	pb.Auth_ConfirmTOTP_FullMethodName:    true,
+	pb.Auth_DisableTOTP_FullMethodName:    true,
+}
+
+// RateLimit holds the parameters of a token bucket: the number of requests
+// allowed per minute and the size of a burst.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimitInterceptor limits the rate of the authentication requests per client IP
+// and per account, so passwords and one-time codes cannot be brute-forced. The JWT Manager
+// is used to find the account of second factor challenges.
+type RateLimitInterceptor struct {
+	jwtManager *jwt.JWTManager
+	ip         *keyedLimiter
+	account    *keyedLimiter
+}
+
+// NewRateLimitInterceptor is a constructor function that initializes RateLimitInterceptor
+// with the limits per client IP and per account.
+func NewRateLimitInterceptor(jwtManager *jwt.JWTManager, ipLimit RateLimit, accountLimit RateLimit) *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		jwtManager: jwtManager,
+		ip:         newKeyedLimiter(ipLimit),
+		account:    newKeyedLimiter(accountLimit),
+	}
+}
+
+// UnaryServerInterceptor is a gRPC unary server interceptor function.
+// It rejects the authentication requests which exceed the limit of the client IP
+// or the limit of the account with ResourceExhausted. Other requests are passed as is.
+// The interceptor has to run after AuthInterceptor, so the requests of signed in users
+// are limited per user.
+func (r *RateLimitInterceptor) UnaryServerInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if !rateLimitedPaths[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	if !r.ip.allow(clientIP(ctx)) {
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	if key := r.accountKey(ctx, req); key != "" && !r.account.allow(key) {
+		return nil, status.Error(codes.ResourceExhausted, "too many requests")
+	}
+
+	return handler(ctx, req)
+}
+
+// accountKey returns the key of the account the request tries to authenticate as:
+// the login, the user of the second factor challenge or the signed in user. The attempts
+// of a user share a key across challenges, sessions and methods. Invalid challenges
+// are rejected by the service and limited per client IP only.
+func (r *RateLimitInterceptor) accountKey(ctx context.Context, req interface{}) string {
+	if userID, ok := ctx.Value(UserIDKey).(int); ok {
+		return userKey(userID)
+	}
+
+	if withChallenge, ok := req.(interface{ GetChallenge() string }); ok && withChallenge.GetChallenge() != "" {
+		claims, err := r.jwtManager.ParseChallenge(withChallenge.GetChallenge())
+		if err != nil {
+			return ""
+		}
+
+		return userKey(claims.UserID)
+	}
+
+	if withLogin, ok := req.(interface{ GetLogin() string }); ok && withLogin.GetLogin() != "" {
+		return "login:" + strings.ToLower(withLogin.GetLogin())
+	}
+
+	return ""
+}
+
+func userKey(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// clientIP returns the IP address of the client the request came from.
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
+
+type limiterEntry struct {
+	lastSeen time.Time
+	limiter  *rate.Limiter
+}
+
+// keyedLimiter keeps a token bucket per key.
+type keyedLimiter struct {
+	lastSweep time.Time
+	limiters  map[string]*limiterEntry
+	limit     RateLimit
+	mu        sync.Mutex
+}
+
+func newKeyedLimiter(limit RateLimit) *keyedLimiter {
+	return &keyedLimiter{
+		limiters: make(map[string]*limiterEntry),
+		limit:    limit,
+	}
+}
+
+func (k *keyedLimiter) allow(key string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(k.lastSweep) > limiterIdleTTL {
+		for key, entry := range k.limiters {
+			if now.Sub(entry.lastSeen) > limiterIdleTTL {
+				delete(k.limiters, key)
+			}
+		}
+
+		k.lastSweep = now
+	}
+
+	entry, ok := k.limiters[key]
+	if !ok {
+		entry = &limiterEntry{
+			limiter: rate.NewLimiter(rate.Limit(float64(k.limit.PerMinute)/time.Minute.Seconds()), k.limit.Burst),
+		}
+		k.limiters[key] = entry
+	}
+
+	entry.lastSeen = now
+
+	return entry.limiter.AllowN(now, 1)
+}
@@ -0,0 +1,101 @@
+package interceptors
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
+)
+
+var jwtManager = jwt.NewJWTManager("secret", time.Minute)
+
+func peerContext(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000},
+	})
+}
+
+func TestRateLimitInterceptor_UnaryServerInterceptor(t *testing.T) {
+	handler := mockHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	login := &grpc.UnaryServerInfo{FullMethod: pb.Auth_Login_FullMethodName}
+	call := func(r *RateLimitInterceptor, ctx context.Context, req interface{}, info *grpc.UnaryServerInfo) codes.Code {
+		_, err := r.UnaryServerInterceptor(ctx, req, info, handler.Handle)
+
+		return status.Code(err)
+	}
+
+	t.Run("limit per IP", func(t *testing.T) {
+		r := NewRateLimitInterceptor(jwtManager, RateLimit{PerMinute: 1, Burst: 2}, RateLimit{PerMinute: 100, Burst: 100})
+
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.1"), &pb.AuthRequest{Login: "a"}, login))
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.1"), &pb.AuthRequest{Login: "b"}, login))
+		assert.Equal(t, codes.ResourceExhausted, call(r, peerContext("10.0.0.1"), &pb.AuthRequest{Login: "c"}, login))
+
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.2"), &pb.AuthRequest{Login: "c"}, login),
+			"other clients must not be affected")
+	})
+
+	t.Run("limit per login", func(t *testing.T) {
+		r := NewRateLimitInterceptor(jwtManager, RateLimit{PerMinute: 100, Burst: 100}, RateLimit{PerMinute: 1, Burst: 1})
+
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.1"), &pb.AuthRequest{Login: "user"}, login))
+		assert.Equal(t, codes.ResourceExhausted, call(r, peerContext("10.0.0.2"), &pb.AuthRequest{Login: "USER"}, login),
+			"the limit of a login must be shared between clients")
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.2"), &pb.AuthRequest{Login: "other"}, login))
+	})
+
+	t.Run("limit per user of challenge", func(t *testing.T) {
+		r := NewRateLimitInterceptor(jwtManager, RateLimit{PerMinute: 100, Burst: 100}, RateLimit{PerMinute: 1, Burst: 1})
+		info := &grpc.UnaryServerInfo{FullMethod: pb.Auth_LoginTOTP_FullMethodName}
+		confirm := &grpc.UnaryServerInfo{FullMethod: pb.Auth_ConfirmTOTP_FullMethodName}
+		challenge := func(userID int) string {
+			c, err := jwtManager.GenerateChallenge(userID, time.Minute)
+			assert.NoError(t, err)
+
+			return c
+		}
+
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.1"), &pb.LoginTOTPRequest{Challenge: challenge(1), Code: "000000"}, info))
+		assert.Equal(t, codes.ResourceExhausted,
+			call(r, peerContext("10.0.0.2"), &pb.LoginTOTPRequest{Challenge: challenge(1), Code: "000001"}, info),
+			"the limit of a user must be shared between challenges and clients")
+		assert.Equal(t, codes.ResourceExhausted,
+			call(r, context.WithValue(peerContext("10.0.0.2"), UserIDKey, 1), &pb.ConfirmTOTPRequest{Code: "000001"}, confirm),
+			"the limit of a user must be shared between second factor methods")
+		assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.2"), &pb.LoginTOTPRequest{Challenge: challenge(2), Code: "000001"}, info))
+	})
+
+	t.Run("limit per signed in user", func(t *testing.T) {
+		r := NewRateLimitInterceptor(jwtManager, RateLimit{PerMinute: 100, Burst: 100}, RateLimit{PerMinute: 1, Burst: 1})
+		changePassword := &grpc.UnaryServerInfo{FullMethod: pb.Auth_ChangePassword_FullMethodName}
+		deleteAccount := &grpc.UnaryServerInfo{FullMethod: pb.Auth_DeleteAccount_FullMethodName}
+		userCtx := func(ip string, userID int) context.Context {
+			return context.WithValue(peerContext(ip), UserIDKey, userID)
+		}
+
+		assert.Equal(t, codes.OK, call(r, userCtx("10.0.0.1", 1), &pb.ChangePasswordRequest{}, changePassword))
+		assert.Equal(t, codes.ResourceExhausted, call(r, userCtx("10.0.0.2", 1), &pb.DeleteAccountRequest{}, deleteAccount),
+			"the limit of a user must be shared between methods and clients")
+		assert.Equal(t, codes.OK, call(r, userCtx("10.0.0.2", 2), &pb.DeleteAccountRequest{}, deleteAccount))
+	})
+
+	t.Run("other methods are not limited", func(t *testing.T) {
+		r := NewRateLimitInterceptor(jwtManager, RateLimit{PerMinute: 1, Burst: 1}, RateLimit{PerMinute: 1, Burst: 1})
+		info := &grpc.UnaryServerInfo{FullMethod: pb.Secret_GetSecrets_FullMethodName}
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, codes.OK, call(r, peerContext("10.0.0.1"), &pb.GetSecretsRequest{}, info))
+		}
+	})
+}
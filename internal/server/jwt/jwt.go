@@ -2,34 +2,103 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// purposeTOTPChallenge marks the tokens which are issued after a successful password check
+// and are exchanged for access tokens with a second factor code.
+const purposeTOTPChallenge = "totp"
+
+// ErrTokenExpired is returned by Parse when the token is expired.
+var ErrTokenExpired = jwt.ErrTokenExpired
+
+// ErrUnexpectedPurpose is returned when a token issued for one purpose is used for another,
+// e.g. a second factor challenge is presented as an access token.
+var ErrUnexpectedPurpose = errors.New("token has unexpected purpose")
+
 // Claims represents the structure of JWT claims. It consists of standard registered claims and
-// additional UserID which represents the identity of the user.
+// additional UserID which represents the identity of the user. SessionID identifies the session
+// the token was issued for. ClientSideEncryption reports whether the user's secrets are
+// encrypted by the client. Purpose is empty for access tokens.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID int
+	SessionID            string `json:"sid"`
+	Purpose              string `json:"pur,omitempty"`
+	UserID               int
+	ClientSideEncryption bool `json:",omitempty"`
 }
 
-// JWTManager is a struct that encapsulates the secret used for signing JWT tokens.
+// JWTManager is a struct that encapsulates the secret used for signing JWT tokens
+// and the lifetime of the issued tokens.
 type JWTManager struct {
 	secret string
+	ttl    time.Duration
 }
 
 // NewJWTManager creates a new JWTManager instance with the provided secret.
-func NewJWTManager(secret string) *JWTManager {
-	return &JWTManager{secret}
+// Issued tokens expire after the provided ttl.
+func NewJWTManager(secret string, ttl time.Duration) *JWTManager {
+	return &JWTManager{
+		secret: secret,
+		ttl:    ttl,
+	}
 }
 
-// Generate generates a new JWT token with the provided userID.
+// Generate generates a new JWT token with the provided userID, session ID and encryption mode.
+// The token has a unique ID and expires after the ttl of the manager.
 // The function returns the signed token string or error.
-func (m *JWTManager) Generate(userID int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
-		UserID: userID,
-	})
+func (m *JWTManager) Generate(userID int, sessionID string, clientSideEncryption bool) (string, error) {
+	return m.sign(Claims{
+		SessionID:            sessionID,
+		UserID:               userID,
+		ClientSideEncryption: clientSideEncryption,
+	}, m.ttl)
+}
+
+// GenerateChallenge generates a token which proves that the user with the provided userID
+// passed the password check and has to complete the login with a second factor.
+// The challenge expires after the provided ttl and is not accepted as an access token.
+func (m *JWTManager) GenerateChallenge(userID int, ttl time.Duration) (string, error) {
+	return m.sign(Claims{
+		UserID:  userID,
+		Purpose: purposeTOTPChallenge,
+	}, ttl)
+}
+
+// Parse validates and parses the provided JWT access token string. Tokens without an expiration
+// time are rejected, ErrTokenExpired is returned for expired tokens.
+// It returns the token claims.
+func (m *JWTManager) Parse(tokenString string) (*Claims, error) {
+	return m.parse(tokenString, "")
+}
+
+// ParseChallenge validates and parses the challenge produced by GenerateChallenge.
+// It returns the challenge claims.
+func (m *JWTManager) ParseChallenge(tokenString string) (*Claims, error) {
+	return m.parse(tokenString, purposeTOTPChallenge)
+}
+
+func (m *JWTManager) sign(claims Claims, ttl time.Duration) (string, error) {
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        hex.EncodeToString(tokenID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(m.secret))
 	if err != nil {
@@ -39,9 +108,7 @@ func (m *JWTManager) Generate(userID int) (string, error) {
 	return tokenString, nil
 }
 
-// Parse validates and parses the provided JWT token string.
-// It returns the userID from the token claims.
-func (m *JWTManager) Parse(tokenString string) (int, error) {
+func (m *JWTManager) parse(tokenString string, purpose string) (*Claims, error) {
 	claims := &Claims{}
 	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -49,11 +116,15 @@ func (m *JWTManager) Parse(tokenString string) (int, error) {
 		}
 
 		return []byte(m.secret), nil
-	})
+	}, jwt.WithExpirationRequired())
 
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, ErrUnexpectedPurpose
 	}
 
-	return claims.UserID, nil
+	return claims, nil
 }
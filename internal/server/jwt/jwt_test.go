@@ -2,7 +2,9 @@ package jwt
 
 import (
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -10,15 +12,22 @@ var secretKey = "secret"
 
 func TestJWTManager(t *testing.T) {
 	tests := []struct {
-		name        string
-		userID      int
-		expectError bool
+		name                 string
+		userID               int
+		clientSideEncryption bool
+		expectError          bool
 	}{
 		{
 			name:        "success: correct user ID",
 			userID:      123,
 			expectError: false,
 		},
+		{
+			name:                 "success: client-side encryption",
+			userID:               123,
+			clientSideEncryption: true,
+			expectError:          false,
+		},
 		{
 			name:        "error: invalid token",
 			userID:      0,
@@ -28,9 +37,9 @@ func TestJWTManager(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			manager := NewJWTManager(secretKey)
+			manager := NewJWTManager(secretKey, time.Minute)
 
-			token, err := manager.Generate(tt.userID)
+			token, err := manager.Generate(tt.userID, "session", tt.clientSideEncryption)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -39,7 +48,7 @@ func TestJWTManager(t *testing.T) {
 				token = "invalid.token"
 			}
 
-			userID, err := manager.Parse(token)
+			claims, err := manager.Parse(token)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -47,7 +56,67 @@ func TestJWTManager(t *testing.T) {
 			}
 
 			assert.NoError(t, err)
-			assert.Equal(t, tt.userID, userID)
+			assert.Equal(t, tt.userID, claims.UserID)
+			assert.Equal(t, "session", claims.SessionID)
+			assert.Equal(t, tt.clientSideEncryption, claims.ClientSideEncryption)
 		})
 	}
 }
+
+func TestJWTManager_Expiration(t *testing.T) {
+	manager := NewJWTManager(secretKey, time.Minute)
+
+	token, err := manager.Generate(1, "session", false)
+	assert.NoError(t, err)
+
+	claims, err := manager.Parse(token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), claims.ExpiresAt.Time, 5*time.Second)
+
+	otherToken, err := manager.Generate(1, "session", false)
+	assert.NoError(t, err)
+
+	otherClaims, err := manager.Parse(otherToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, claims.ID, otherClaims.ID)
+
+	expiredToken, err := NewJWTManager(secretKey, -time.Minute).Generate(1, "session", false)
+	assert.NoError(t, err)
+
+	_, err = manager.Parse(expiredToken)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	noExpToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{UserID: 1}).
+		SignedString([]byte(secretKey))
+	assert.NoError(t, err)
+
+	_, err = manager.Parse(noExpToken)
+	assert.Error(t, err, "tokens without expiration must be rejected")
+}
+
+func TestJWTManager_Challenge(t *testing.T) {
+	manager := NewJWTManager(secretKey, time.Minute)
+
+	challenge, err := manager.GenerateChallenge(1, time.Minute)
+	assert.NoError(t, err)
+
+	claims, err := manager.ParseChallenge(challenge)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claims.UserID)
+
+	_, err = manager.Parse(challenge)
+	assert.ErrorIs(t, err, ErrUnexpectedPurpose, "challenge must not be accepted as an access token")
+
+	token, err := manager.Generate(1, "session", false)
+	assert.NoError(t, err)
+
+	_, err = manager.ParseChallenge(token)
+	assert.ErrorIs(t, err, ErrUnexpectedPurpose, "access token must not be accepted as a challenge")
+
+	expired, err := manager.GenerateChallenge(1, -time.Minute)
+	assert.NoError(t, err)
+
+	_, err = manager.ParseChallenge(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
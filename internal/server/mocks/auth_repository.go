@@ -4,10 +4,12 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 
 	models "github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	repository "github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 )
 
 // MockAuthRepository is an autogenerated mock type for the AuthRepository type
@@ -15,6 +17,78 @@ type MockAuthRepository struct {
 	mock.Mock
 }
 
+// ChangePassword provides a mock function with given fields: ctx, userID, passwordHash, secrets
+func (_m *MockAuthRepository) ChangePassword(ctx context.Context, userID int, passwordHash string, secrets []repository.Secret) error {
+	ret := _m.Called(ctx, userID, passwordHash, secrets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, []repository.Secret) error); ok {
+		r0 = rf(ctx, userID, passwordHash, secrets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userID
+func (_m *MockAuthRepository) DeleteUser(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableTOTP provides a mock function with given fields: ctx, userID
+func (_m *MockAuthRepository) DisableTOTP(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableTOTP provides a mock function with given fields: ctx, userID, recoveryCodeHashes
+func (_m *MockAuthRepository) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes [][]byte) error {
+	ret := _m.Called(ctx, userID, recoveryCodeHashes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, [][]byte) error); ok {
+		r0 = rf(ctx, userID, recoveryCodeHashes)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetUser provides a mock function with given fields: ctx, login
 func (_m *MockAuthRepository) GetUser(ctx context.Context, login string) (*models.User, error) {
 	ret := _m.Called(ctx, login)
@@ -45,6 +119,100 @@ func (_m *MockAuthRepository) GetUser(ctx context.Context, login string) (*model
 	return r0, r1
 }
 
+// GetUserByID provides a mock function with given fields: ctx, userID
+func (_m *MockAuthRepository) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserByID")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*models.User, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *models.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LockUser provides a mock function with given fields: ctx, userID, until
+func (_m *MockAuthRepository) LockUser(ctx context.Context, userID int, until time.Time) error {
+	ret := _m.Called(ctx, userID, until)
+
+	if len(ret) == 0 {
+		panic("no return value specified for LockUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) error); ok {
+		r0 = rf(ctx, userID, until)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordLoginFailure provides a mock function with given fields: ctx, userID
+func (_m *MockAuthRepository) RecordLoginFailure(ctx context.Context, userID int) (int, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordLoginFailure")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetLoginFailures provides a mock function with given fields: ctx, userID
+func (_m *MockAuthRepository) ResetLoginFailures(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetLoginFailures")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SaveUser provides a mock function with given fields: ctx, user
 func (_m *MockAuthRepository) SaveUser(ctx context.Context, user models.User) (int, error) {
 	ret := _m.Called(ctx, user)
@@ -73,6 +241,60 @@ func (_m *MockAuthRepository) SaveUser(ctx context.Context, user models.User) (i
 	return r0, r1
 }
 
+// SetTOTPSecret provides a mock function with given fields: ctx, userID, secret
+func (_m *MockAuthRepository) SetTOTPSecret(ctx context.Context, userID int, secret []byte) error {
+	ret := _m.Called(ctx, userID, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTOTPSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []byte) error); ok {
+		r0 = rf(ctx, userID, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseRecoveryCode provides a mock function with given fields: ctx, userID, codeHash
+func (_m *MockAuthRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) error {
+	ret := _m.Called(ctx, userID, codeHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseRecoveryCode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []byte) error); ok {
+		r0 = rf(ctx, userID, codeHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UseTOTPCounter provides a mock function with given fields: ctx, userID, counter
+func (_m *MockAuthRepository) UseTOTPCounter(ctx context.Context, userID int, counter int64) error {
+	ret := _m.Called(ctx, userID, counter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UseTOTPCounter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) error); ok {
+		r0 = rf(ctx, userID, counter)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockAuthRepository creates a new instance of MockAuthRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockAuthRepository(t interface {
@@ -6,6 +6,8 @@ import (
 	context "context"
 
 	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/PrahaTurbo/goph-keeper/internal/server/models"
 )
 
 // MockAuthService is an autogenerated mock type for the AuthService type
@@ -13,23 +15,197 @@ type MockAuthService struct {
 	mock.Mock
 }
 
+// ChangePassword provides a mock function with given fields: ctx, oldPassword, newPassword, secrets
+func (_m *MockAuthService) ChangePassword(ctx context.Context, oldPassword string, newPassword string, secrets []models.Secret) error {
+	ret := _m.Called(ctx, oldPassword, newPassword, secrets)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ChangePassword")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []models.Secret) error); ok {
+		r0 = rf(ctx, oldPassword, newPassword, secrets)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ConfirmTOTP provides a mock function with given fields: ctx, code
+func (_m *MockAuthService) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ConfirmTOTP")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, code)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, code)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAccount provides a mock function with given fields: ctx, password
+func (_m *MockAuthService) DeleteAccount(ctx context.Context, password string) error {
+	ret := _m.Called(ctx, password)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteAccount")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DisableTOTP provides a mock function with given fields: ctx, code
+func (_m *MockAuthService) DisableTOTP(ctx context.Context, code string) error {
+	ret := _m.Called(ctx, code)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DisableTOTP")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, code)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EnableTOTP provides a mock function with given fields: ctx
+func (_m *MockAuthService) EnableTOTP(ctx context.Context) (*models.TOTPEnrollment, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EnableTOTP")
+	}
+
+	var r0 *models.TOTPEnrollment
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*models.TOTPEnrollment, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *models.TOTPEnrollment); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TOTPEnrollment)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsSessionActive provides a mock function with given fields: ctx, sessionID
+func (_m *MockAuthService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	ret := _m.Called(ctx, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsSessionActive")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, sessionID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, sessionID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListSessions provides a mock function with given fields: ctx
+func (_m *MockAuthService) ListSessions(ctx context.Context) ([]models.Session, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListSessions")
+	}
+
+	var r0 []models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Session, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []models.Session); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Login provides a mock function with given fields: ctx, login, password
-func (_m *MockAuthService) Login(ctx context.Context, login string, password string) (string, error) {
+func (_m *MockAuthService) Login(ctx context.Context, login string, password string) (*models.Tokens, error) {
 	ret := _m.Called(ctx, login, password)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Login")
 	}
 
-	var r0 string
+	var r0 *models.Tokens
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.Tokens, error)); ok {
 		return rf(ctx, login, password)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Tokens); ok {
 		r0 = rf(ctx, login, password)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tokens)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
@@ -41,27 +217,77 @@ func (_m *MockAuthService) Login(ctx context.Context, login string, password str
 	return r0, r1
 }
 
-// Register provides a mock function with given fields: ctx, login, password
-func (_m *MockAuthService) Register(ctx context.Context, login string, password string) (string, error) {
-	ret := _m.Called(ctx, login, password)
+// LoginTOTP provides a mock function with given fields: ctx, challenge, code
+func (_m *MockAuthService) LoginTOTP(ctx context.Context, challenge string, code string) (*models.Tokens, error) {
+	ret := _m.Called(ctx, challenge, code)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Register")
+		panic("no return value specified for LoginTOTP")
 	}
 
-	var r0 string
+	var r0 *models.Tokens
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, login, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.Tokens, error)); ok {
+		return rf(ctx, challenge, code)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, login, password)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Tokens); ok {
+		r0 = rf(ctx, challenge, code)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tokens)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, login, password)
+		r1 = rf(ctx, challenge, code)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Logout provides a mock function with given fields: ctx
+func (_m *MockAuthService) Logout(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Logout")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Refresh provides a mock function with given fields: ctx, refreshToken
+func (_m *MockAuthService) Refresh(ctx context.Context, refreshToken string) (*models.Tokens, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Refresh")
+	}
+
+	var r0 *models.Tokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Tokens, error)); ok {
+		return rf(ctx, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Tokens); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tokens)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, refreshToken)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -69,6 +295,54 @@ func (_m *MockAuthService) Register(ctx context.Context, login string, password
 	return r0, r1
 }
 
+// Register provides a mock function with given fields: ctx, login, password, clientSideEncryption
+func (_m *MockAuthService) Register(ctx context.Context, login string, password string, clientSideEncryption bool) (*models.Tokens, error) {
+	ret := _m.Called(ctx, login, password, clientSideEncryption)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Register")
+	}
+
+	var r0 *models.Tokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) (*models.Tokens, error)); ok {
+		return rf(ctx, login, password, clientSideEncryption)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) *models.Tokens); ok {
+		r0 = rf(ctx, login, password, clientSideEncryption)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Tokens)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, bool) error); ok {
+		r1 = rf(ctx, login, password, clientSideEncryption)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeSession provides a mock function with given fields: ctx, sessionID
+func (_m *MockAuthService) RevokeSession(ctx context.Context, sessionID string) error {
+	ret := _m.Called(ctx, sessionID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeSession")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, sessionID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewMockAuthService creates a new instance of MockAuthService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockAuthService(t interface {
@@ -0,0 +1,111 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+// MockBlobService is an autogenerated mock type for the BlobService type
+type MockBlobService struct {
+	mock.Mock
+}
+
+// Download provides a mock function with given fields: ctx, blobID, send
+func (_m *MockBlobService) Download(ctx context.Context, blobID string, send func([]byte) error) error {
+	ret := _m.Called(ctx, blobID, send)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Download")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, func([]byte) error) error); ok {
+		r0 = rf(ctx, blobID, send)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Run provides a mock function with given fields: ctx
+func (_m *MockBlobService) Run(ctx context.Context) {
+	_m.Called(ctx)
+}
+
+// UpgradeBlobs provides a mock function with given fields: ctx, batchSize
+func (_m *MockBlobService) UpgradeBlobs(ctx context.Context, batchSize int) (int, error) {
+	ret := _m.Called(ctx, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpgradeBlobs")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, batchSize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Upload provides a mock function with given fields: ctx, next
+func (_m *MockBlobService) Upload(ctx context.Context, next func() ([]byte, error)) (*models.Blob, error) {
+	ret := _m.Called(ctx, next)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upload")
+	}
+
+	var r0 *models.Blob
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, func() ([]byte, error)) (*models.Blob, error)); ok {
+		return rf(ctx, next)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, func() ([]byte, error)) *models.Blob); ok {
+		r0 = rf(ctx, next)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Blob)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, func() ([]byte, error)) error); ok {
+		r1 = rf(ctx, next)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockBlobService creates a new instance of MockBlobService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockBlobService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockBlobService {
+	mock := &MockBlobService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,94 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockBlobStore is an autogenerated mock type for the BlobStore type
+type MockBlobStore struct {
+	mock.Mock
+}
+
+// DeleteBlob provides a mock function with given fields: ctx, blobID
+func (_m *MockBlobStore) DeleteBlob(ctx context.Context, blobID string) error {
+	ret := _m.Called(ctx, blobID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteBlob")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, blobID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetChunk provides a mock function with given fields: ctx, blobID, seq
+func (_m *MockBlobStore) GetChunk(ctx context.Context, blobID string, seq int) ([]byte, error) {
+	ret := _m.Called(ctx, blobID, seq)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChunk")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]byte, error)); ok {
+		return rf(ctx, blobID, seq)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []byte); ok {
+		r0 = rf(ctx, blobID, seq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, blobID, seq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PutChunk provides a mock function with given fields: ctx, blobID, seq, data
+func (_m *MockBlobStore) PutChunk(ctx context.Context, blobID string, seq int, data []byte) error {
+	ret := _m.Called(ctx, blobID, seq, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PutChunk")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int, []byte) error); ok {
+		r0 = rf(ctx, blobID, seq, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockBlobStore creates a new instance of MockBlobStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockBlobStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockBlobStore {
+	mock := &MockBlobStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -9,9 +9,9 @@ type MockEncryption struct {
 	mock.Mock
 }
 
-// Decrypt provides a mock function with given fields: cipherText
-func (_m *MockEncryption) Decrypt(cipherText []byte) (string, error) {
-	ret := _m.Called(cipherText)
+// Decrypt provides a mock function with given fields: key, cipherText, associatedData
+func (_m *MockEncryption) Decrypt(key []byte, cipherText []byte, associatedData []byte) (string, error) {
+	ret := _m.Called(key, cipherText, associatedData)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Decrypt")
@@ -19,17 +19,17 @@ func (_m *MockEncryption) Decrypt(cipherText []byte) (string, error) {
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func([]byte) (string, error)); ok {
-		return rf(cipherText)
+	if rf, ok := ret.Get(0).(func([]byte, []byte, []byte) (string, error)); ok {
+		return rf(key, cipherText, associatedData)
 	}
-	if rf, ok := ret.Get(0).(func([]byte) string); ok {
-		r0 = rf(cipherText)
+	if rf, ok := ret.Get(0).(func([]byte, []byte, []byte) string); ok {
+		r0 = rf(key, cipherText, associatedData)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func([]byte) error); ok {
-		r1 = rf(cipherText)
+	if rf, ok := ret.Get(1).(func([]byte, []byte, []byte) error); ok {
+		r1 = rf(key, cipherText, associatedData)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -37,9 +37,39 @@ func (_m *MockEncryption) Decrypt(cipherText []byte) (string, error) {
 	return r0, r1
 }
 
-// Encrypt provides a mock function with given fields: plainText
-func (_m *MockEncryption) Encrypt(plainText string) ([]byte, error) {
-	ret := _m.Called(plainText)
+// DeriveKey provides a mock function with given fields: userID
+func (_m *MockEncryption) DeriveKey(userID int) ([]byte, error) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeriveKey")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) ([]byte, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(int) []byte); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Encrypt provides a mock function with given fields: key, plainText, associatedData
+func (_m *MockEncryption) Encrypt(key []byte, plainText string, associatedData []byte) ([]byte, error) {
+	ret := _m.Called(key, plainText, associatedData)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Encrypt")
@@ -47,19 +77,49 @@ func (_m *MockEncryption) Encrypt(plainText string) ([]byte, error) {
 
 	var r0 []byte
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) ([]byte, error)); ok {
-		return rf(plainText)
+	if rf, ok := ret.Get(0).(func([]byte, string, []byte) ([]byte, error)); ok {
+		return rf(key, plainText, associatedData)
+	}
+	if rf, ok := ret.Get(0).(func([]byte, string, []byte) []byte); ok {
+		r0 = rf(key, plainText, associatedData)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte, string, []byte) error); ok {
+		r1 = rf(key, plainText, associatedData)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GenerateKey provides a mock function with given fields:
+func (_m *MockEncryption) GenerateKey() ([]byte, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GenerateKey")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]byte, error)); ok {
+		return rf()
 	}
-	if rf, ok := ret.Get(0).(func(string) []byte); ok {
-		r0 = rf(plainText)
+	if rf, ok := ret.Get(0).(func() []byte); ok {
+		r0 = rf()
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]byte)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(plainText)
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -67,9 +127,126 @@ func (_m *MockEncryption) Encrypt(plainText string) ([]byte, error) {
 	return r0, r1
 }
 
-// GenerateKey provides a mock function with given fields: userID
-func (_m *MockEncryption) GenerateKey(userID int) {
-	_m.Called(userID)
+// KeyVersion provides a mock function with given fields:
+func (_m *MockEncryption) KeyVersion() int {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for KeyVersion")
+	}
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// Reseal provides a mock function with given fields: key, cipherText, associatedData
+func (_m *MockEncryption) Reseal(key []byte, cipherText []byte, associatedData []byte) ([]byte, bool, error) {
+	ret := _m.Called(key, cipherText, associatedData)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reseal")
+	}
+
+	var r0 []byte
+	var r1 bool
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]byte, []byte, []byte) ([]byte, bool, error)); ok {
+		return rf(key, cipherText, associatedData)
+	}
+	if rf, ok := ret.Get(0).(func([]byte, []byte, []byte) []byte); ok {
+		r0 = rf(key, cipherText, associatedData)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte, []byte, []byte) bool); ok {
+		r1 = rf(key, cipherText, associatedData)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	if rf, ok := ret.Get(2).(func([]byte, []byte, []byte) error); ok {
+		r2 = rf(key, cipherText, associatedData)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UnwrapKey provides a mock function with given fields: wrappedKey, version
+func (_m *MockEncryption) UnwrapKey(wrappedKey []byte, version int) ([]byte, error) {
+	ret := _m.Called(wrappedKey, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnwrapKey")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]byte, int) ([]byte, error)); ok {
+		return rf(wrappedKey, version)
+	}
+	if rf, ok := ret.Get(0).(func([]byte, int) []byte); ok {
+		r0 = rf(wrappedKey, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte, int) error); ok {
+		r1 = rf(wrappedKey, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WrapKey provides a mock function with given fields: key
+func (_m *MockEncryption) WrapKey(key []byte) ([]byte, int, error) {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WrapKey")
+	}
+
+	var r0 []byte
+	var r1 int
+	var r2 error
+	if rf, ok := ret.Get(0).(func([]byte) ([]byte, int, error)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func([]byte) []byte); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func([]byte) int); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	if rf, ok := ret.Get(2).(func([]byte) error); ok {
+		r2 = rf(key)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // NewMockEncryption creates a new instance of MockEncryption. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -0,0 +1,67 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+// MockSecretBroker is an autogenerated mock type for the SecretBroker type
+type MockSecretBroker struct {
+	mock.Mock
+}
+
+// Run provides a mock function with given fields: ctx
+func (_m *MockSecretBroker) Run(ctx context.Context) {
+	_m.Called(ctx)
+}
+
+// Subscribe provides a mock function with given fields: userID
+func (_m *MockSecretBroker) Subscribe(userID int) (<-chan models.SecretEvent, func()) {
+	ret := _m.Called(userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 <-chan models.SecretEvent
+	var r1 func()
+	if rf, ok := ret.Get(0).(func(int) (<-chan models.SecretEvent, func())); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(int) <-chan models.SecretEvent); ok {
+		r0 = rf(userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan models.SecretEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) func()); ok {
+		r1 = rf(userID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
+	}
+
+	return r0, r1
+}
+
+// NewMockSecretBroker creates a new instance of MockSecretBroker. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSecretBroker(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSecretBroker {
+	mock := &MockSecretBroker{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,48 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+// MockSecretListener is an autogenerated mock type for the SecretListener type
+type MockSecretListener struct {
+	mock.Mock
+}
+
+// Listen provides a mock function with given fields: ctx, handle
+func (_m *MockSecretListener) Listen(ctx context.Context, handle func(models.SecretEvent)) error {
+	ret := _m.Called(ctx, handle)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Listen")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(models.SecretEvent)) error); ok {
+		r0 = rf(ctx, handle)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMockSecretListener creates a new instance of MockSecretListener. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSecretListener(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSecretListener {
+	mock := &MockSecretListener{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
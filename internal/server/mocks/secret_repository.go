@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 
@@ -15,6 +16,24 @@ type MockSecretRepository struct {
 	mock.Mock
 }
 
+// ClaimSecretID provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) ClaimSecretID(ctx context.Context, secretID int, userID int) error {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimSecretID")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Create provides a mock function with given fields: ctx, secret
 func (_m *MockSecretRepository) Create(ctx context.Context, secret *repository.Secret) error {
 	ret := _m.Called(ctx, secret)
@@ -33,17 +52,35 @@ func (_m *MockSecretRepository) Create(ctx context.Context, secret *repository.S
 	return r0
 }
 
-// DeleteSecret provides a mock function with given fields: ctx, secretID, userID
-func (_m *MockSecretRepository) DeleteSecret(ctx context.Context, secretID int, userID int) error {
-	ret := _m.Called(ctx, secretID, userID)
+// CreateCollectionSecret provides a mock function with given fields: ctx, secret
+func (_m *MockSecretRepository) CreateCollectionSecret(ctx context.Context, secret *repository.Secret) error {
+	ret := _m.Called(ctx, secret)
 
 	if len(ret) == 0 {
-		panic("no return value specified for DeleteSecret")
+		panic("no return value specified for CreateCollectionSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Secret) error); ok {
+		r0 = rf(ctx, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteCollectionSecret provides a mock function with given fields: ctx, secretID, version
+func (_m *MockSecretRepository) DeleteCollectionSecret(ctx context.Context, secretID int, version int) error {
+	ret := _m.Called(ctx, secretID, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteCollectionSecret")
 	}
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
-		r0 = rf(ctx, secretID, userID)
+		r0 = rf(ctx, secretID, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -51,21 +88,245 @@ func (_m *MockSecretRepository) DeleteSecret(ctx context.Context, secretID int,
 	return r0
 }
 
-// GetUserSecrets provides a mock function with given fields: ctx, userID
-func (_m *MockSecretRepository) GetUserSecrets(ctx context.Context, userID int) ([]repository.Secret, error) {
-	ret := _m.Called(ctx, userID)
+// DeleteExpiredReservations provides a mock function with given fields: ctx, reservedBefore
+func (_m *MockSecretRepository) DeleteExpiredReservations(ctx context.Context, reservedBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, reservedBefore)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetUserSecrets")
+		panic("no return value specified for DeleteExpiredReservations")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, reservedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, reservedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, reservedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteExpiredVersions provides a mock function with given fields: ctx, archivedBefore, keep
+func (_m *MockSecretRepository) DeleteExpiredVersions(ctx context.Context, archivedBefore time.Time, keep int) (int64, error) {
+	ret := _m.Called(ctx, archivedBefore, keep)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteExpiredVersions")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, archivedBefore, keep)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, archivedBefore, keep)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, archivedBefore, keep)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteSecret provides a mock function with given fields: ctx, secretID, userID, version
+func (_m *MockSecretRepository) DeleteSecret(ctx context.Context, secretID int, userID int, version int) error {
+	ret := _m.Called(ctx, secretID, userID, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, secretID, userID, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindSecrets provides a mock function with given fields: ctx, userID, indexes
+func (_m *MockSecretRepository) FindSecrets(ctx context.Context, userID int, indexes []repository.BlindIndex) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, userID, indexes)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindSecrets")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []repository.BlindIndex) ([]repository.Secret, error)); ok {
+		return rf(ctx, userID, indexes)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, []repository.BlindIndex) []repository.Secret); ok {
+		r0 = rf(ctx, userID, indexes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, []repository.BlindIndex) error); ok {
+		r1 = rf(ctx, userID, indexes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetChanges provides a mock function with given fields: ctx, userID, sinceRevision
+func (_m *MockSecretRepository) GetChanges(ctx context.Context, userID int, sinceRevision int64) (*repository.Changes, error) {
+	ret := _m.Called(ctx, userID, sinceRevision)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetChanges")
+	}
+
+	var r0 *repository.Changes
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) (*repository.Changes, error)); ok {
+		return rf(ctx, userID, sinceRevision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) *repository.Changes); ok {
+		r0 = rf(ctx, userID, sinceRevision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Changes)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int64) error); ok {
+		r1 = rf(ctx, userID, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollectionMember provides a mock function with given fields: ctx, collectionID, userID
+func (_m *MockSecretRepository) GetCollectionMember(ctx context.Context, collectionID int, userID int) (*repository.Member, error) {
+	ret := _m.Called(ctx, collectionID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollectionMember")
+	}
+
+	var r0 *repository.Member
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*repository.Member, error)); ok {
+		return rf(ctx, collectionID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *repository.Member); ok {
+		r0 = rf(ctx, collectionID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Member)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, collectionID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollectionSecret provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) GetCollectionSecret(ctx context.Context, secretID int) (*repository.Secret, error) {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollectionSecret")
+	}
+
+	var r0 *repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*repository.Secret, error)); ok {
+		return rf(ctx, secretID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *repository.Secret); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, secretID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollectionVersion provides a mock function with given fields: ctx, secretID, version
+func (_m *MockSecretRepository) GetCollectionVersion(ctx context.Context, secretID int, version int) (*repository.Secret, error) {
+	ret := _m.Called(ctx, secretID, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollectionVersion")
+	}
+
+	var r0 *repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*repository.Secret, error)); ok {
+		return rf(ctx, secretID, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *repository.Secret); ok {
+		r0 = rf(ctx, secretID, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, secretID, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCollectionVersions provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) GetCollectionVersions(ctx context.Context, secretID int) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCollectionVersions")
 	}
 
 	var r0 []repository.Secret
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context, int) ([]repository.Secret, error)); ok {
-		return rf(ctx, userID)
+		return rf(ctx, secretID)
 	}
 	if rf, ok := ret.Get(0).(func(context.Context, int) []repository.Secret); ok {
-		r0 = rf(ctx, userID)
+		r0 = rf(ctx, secretID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]repository.Secret)
@@ -73,7 +334,97 @@ func (_m *MockSecretRepository) GetUserSecrets(ctx context.Context, userID int)
 	}
 
 	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
-		r1 = rf(ctx, userID)
+		r1 = rf(ctx, secretID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecipient provides a mock function with given fields: ctx, login
+func (_m *MockSecretRepository) GetRecipient(ctx context.Context, login string) (*repository.Recipient, error) {
+	ret := _m.Called(ctx, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRecipient")
+	}
+
+	var r0 *repository.Recipient
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*repository.Recipient, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *repository.Recipient); ok {
+		r0 = rf(ctx, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Recipient)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSealedSecrets provides a mock function with given fields: ctx, afterID, limit
+func (_m *MockSecretRepository) GetSealedSecrets(ctx context.Context, afterID int, limit int) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, afterID, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSealedSecrets")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]repository.Secret, error)); ok {
+		return rf(ctx, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []repository.Secret); ok {
+		r0 = rf(ctx, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSealedVersions provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) GetSealedVersions(ctx context.Context, secretID int) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSealedVersions")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]repository.Secret, error)); ok {
+		return rf(ctx, secretID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []repository.Secret); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, secretID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -81,6 +432,480 @@ func (_m *MockSecretRepository) GetUserSecrets(ctx context.Context, userID int)
 	return r0, r1
 }
 
+// GetSecret provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) GetSecret(ctx context.Context, secretID int, userID int) (*repository.Secret, error) {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSecret")
+	}
+
+	var r0 *repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*repository.Secret, error)); ok {
+		return rf(ctx, secretID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *repository.Secret); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, secretID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSecretMember provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) GetSecretMember(ctx context.Context, secretID int, userID int) (*repository.Member, error) {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSecretMember")
+	}
+
+	var r0 *repository.Member
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (*repository.Member, error)); ok {
+		return rf(ctx, secretID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) *repository.Member); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Member)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, secretID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShares provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) GetShares(ctx context.Context, secretID int) ([]repository.Share, error) {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShares")
+	}
+
+	var r0 []repository.Share
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]repository.Share, error)); ok {
+		return rf(ctx, secretID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []repository.Share); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Share)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, secretID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrash provides a mock function with given fields: ctx, userID
+func (_m *MockSecretRepository) GetTrash(ctx context.Context, userID int) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTrash")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]repository.Secret, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []repository.Secret); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserSecrets provides a mock function with given fields: ctx, userID, filter
+func (_m *MockSecretRepository) GetUserSecrets(ctx context.Context, userID int, filter repository.SecretFilter) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, userID, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetUserSecrets")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, repository.SecretFilter) ([]repository.Secret, error)); ok {
+		return rf(ctx, userID, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, repository.SecretFilter) []repository.Secret); ok {
+		r0 = rf(ctx, userID, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, repository.SecretFilter) error); ok {
+		r1 = rf(ctx, userID, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersion provides a mock function with given fields: ctx, secretID, userID, version
+func (_m *MockSecretRepository) GetVersion(ctx context.Context, secretID int, userID int, version int) (*repository.Secret, error) {
+	ret := _m.Called(ctx, secretID, userID, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersion")
+	}
+
+	var r0 *repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) (*repository.Secret, error)); ok {
+		return rf(ctx, secretID, userID, version)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) *repository.Secret); ok {
+		r0 = rf(ctx, secretID, userID, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int, int) error); ok {
+		r1 = rf(ctx, secretID, userID, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVersions provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) GetVersions(ctx context.Context, secretID int, userID int) ([]repository.Secret, error) {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVersions")
+	}
+
+	var r0 []repository.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) ([]repository.Secret, error)); ok {
+		return rf(ctx, secretID, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) []repository.Secret); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]repository.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, secretID, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IndexSecret provides a mock function with given fields: ctx, secret
+func (_m *MockSecretRepository) IndexSecret(ctx context.Context, secret *repository.Secret) error {
+	ret := _m.Called(ctx, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IndexSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Secret) error); ok {
+		r0 = rf(ctx, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NextSecretID provides a mock function with given fields: ctx
+func (_m *MockSecretRepository) NextSecretID(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NextSecretID")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeCollectionSecret provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) PurgeCollectionSecret(ctx context.Context, secretID int) error {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeCollectionSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeSecret provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) PurgeSecret(ctx context.Context, secretID int, userID int) error {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeTrash provides a mock function with given fields: ctx, deletedBefore
+func (_m *MockSecretRepository) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ret := _m.Called(ctx, deletedBefore)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeTrash")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, deletedBefore)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, deletedBefore)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, deletedBefore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResealSecret provides a mock function with given fields: ctx, secret, previousContent
+func (_m *MockSecretRepository) ResealSecret(ctx context.Context, secret *repository.Secret, previousContent []byte) error {
+	ret := _m.Called(ctx, secret, previousContent)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResealSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Secret, []byte) error); ok {
+		r0 = rf(ctx, secret, previousContent)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResealVersion provides a mock function with given fields: ctx, secret, previousContent
+func (_m *MockSecretRepository) ResealVersion(ctx context.Context, secret *repository.Secret, previousContent []byte) error {
+	ret := _m.Called(ctx, secret, previousContent)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResealVersion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Secret, []byte) error); ok {
+		r0 = rf(ctx, secret, previousContent)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveSecretID provides a mock function with given fields: ctx, userID
+func (_m *MockSecretRepository) ReserveSecretID(ctx context.Context, userID int) (int, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveSecretID")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreCollectionSecret provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretRepository) RestoreCollectionSecret(ctx context.Context, secretID int) error {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreCollectionSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreSecret provides a mock function with given fields: ctx, secretID, userID
+func (_m *MockSecretRepository) RestoreSecret(ctx context.Context, secretID int, userID int) error {
+	ret := _m.Called(ctx, secretID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, secretID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ShareSecret provides a mock function with given fields: ctx, share, version
+func (_m *MockSecretRepository) ShareSecret(ctx context.Context, share *repository.Share, version int) error {
+	ret := _m.Called(ctx, share, version)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ShareSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Share, int) error); ok {
+		r0 = rf(ctx, share, version)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnshareSecret provides a mock function with given fields: ctx, secretID, ownerID, userID
+func (_m *MockSecretRepository) UnshareSecret(ctx context.Context, secretID int, ownerID int, userID int) error {
+	ret := _m.Called(ctx, secretID, ownerID, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnshareSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, secretID, ownerID, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateCollectionSecret provides a mock function with given fields: ctx, secret
+func (_m *MockSecretRepository) UpdateCollectionSecret(ctx context.Context, secret *repository.Secret) error {
+	ret := _m.Called(ctx, secret)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateCollectionSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *repository.Secret) error); ok {
+		r0 = rf(ctx, secret)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateSecret provides a mock function with given fields: ctx, secret
 func (_m *MockSecretRepository) UpdateSecret(ctx context.Context, secret *repository.Secret) error {
 	ret := _m.Called(ctx, secret)
@@ -33,17 +33,17 @@ func (_m *MockSecretService) CreateSecret(ctx context.Context, req *models.Secre
 	return r0
 }
 
-// DeleteSecret provides a mock function with given fields: ctx, secretID
-func (_m *MockSecretService) DeleteSecret(ctx context.Context, secretID int) error {
-	ret := _m.Called(ctx, secretID)
+// DeleteSecret provides a mock function with given fields: ctx, secretID, version
+func (_m *MockSecretService) DeleteSecret(ctx context.Context, secretID int, version int) error {
+	ret := _m.Called(ctx, secretID, version)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DeleteSecret")
 	}
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
-		r0 = rf(ctx, secretID)
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) error); ok {
+		r0 = rf(ctx, secretID, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -51,14 +51,74 @@ func (_m *MockSecretService) DeleteSecret(ctx context.Context, secretID int) err
 	return r0
 }
 
-// GetUserSecrets provides a mock function with given fields: ctx
-func (_m *MockSecretService) GetUserSecrets(ctx context.Context) ([]models.Secret, error) {
-	ret := _m.Called(ctx)
+// FindSecretsByDomain provides a mock function with given fields: ctx, domain, login
+func (_m *MockSecretService) FindSecretsByDomain(ctx context.Context, domain string, login string) ([]models.Secret, error) {
+	ret := _m.Called(ctx, domain, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindSecretsByDomain")
+	}
+
+	var r0 []models.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]models.Secret, error)); ok {
+		return rf(ctx, domain, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.Secret); ok {
+		r0 = rf(ctx, domain, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, domain, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserSecrets provides a mock function with given fields: ctx, filter
+func (_m *MockSecretService) GetUserSecrets(ctx context.Context, filter *models.SecretFilter) (*models.SecretPage, error) {
+	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
 		panic("no return value specified for GetUserSecrets")
 	}
 
+	var r0 *models.SecretPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SecretFilter) (*models.SecretPage, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SecretFilter) *models.SecretPage); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SecretPage)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.SecretFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListTrash provides a mock function with given fields: ctx
+func (_m *MockSecretService) ListTrash(ctx context.Context) ([]models.Secret, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListTrash")
+	}
+
 	var r0 []models.Secret
 	var r1 error
 	if rf, ok := ret.Get(0).(func(context.Context) ([]models.Secret, error)); ok {
@@ -81,6 +141,189 @@ func (_m *MockSecretService) GetUserSecrets(ctx context.Context) ([]models.Secre
 	return r0, r1
 }
 
+// ListVersions provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretService) ListVersions(ctx context.Context, secretID int) ([]models.Secret, error) {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListVersions")
+	}
+
+	var r0 []models.Secret
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) ([]models.Secret, error)); ok {
+		return rf(ctx, secretID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) []models.Secret); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.Secret)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, secretID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeSecret provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretService) PurgeSecret(ctx context.Context, secretID int) error {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReserveSecretID provides a mock function with given fields: ctx
+func (_m *MockSecretService) ReserveSecretID(ctx context.Context) (int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReserveSecretID")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreSecret provides a mock function with given fields: ctx, secretID
+func (_m *MockSecretService) RestoreSecret(ctx context.Context, secretID int) error {
+	ret := _m.Called(ctx, secretID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, secretID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreVersion provides a mock function with given fields: ctx, secretID, version, currentVersion
+func (_m *MockSecretService) RestoreVersion(ctx context.Context, secretID int, version int, currentVersion int) error {
+	ret := _m.Called(ctx, secretID, version, currentVersion)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreVersion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int, int) error); ok {
+		r0 = rf(ctx, secretID, version, currentVersion)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Run provides a mock function with given fields: ctx
+func (_m *MockSecretService) Run(ctx context.Context) {
+	_m.Called(ctx)
+}
+
+// ShareSecret provides a mock function with given fields: ctx, secretID, login, permission
+func (_m *MockSecretService) ShareSecret(ctx context.Context, secretID int, login string, permission string) error {
+	ret := _m.Called(ctx, secretID, login, permission)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ShareSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string, string) error); ok {
+		r0 = rf(ctx, secretID, login, permission)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Sync provides a mock function with given fields: ctx, sinceRevision
+func (_m *MockSecretService) Sync(ctx context.Context, sinceRevision int64) (*models.Changes, error) {
+	ret := _m.Called(ctx, sinceRevision)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Sync")
+	}
+
+	var r0 *models.Changes
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.Changes, error)); ok {
+		return rf(ctx, sinceRevision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.Changes); ok {
+		r0 = rf(ctx, sinceRevision)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Changes)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UnshareSecret provides a mock function with given fields: ctx, secretID, login
+func (_m *MockSecretService) UnshareSecret(ctx context.Context, secretID int, login string) error {
+	ret := _m.Called(ctx, secretID, login)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnshareSecret")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, string) error); ok {
+		r0 = rf(ctx, secretID, login)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateSecret provides a mock function with given fields: ctx, secret
 func (_m *MockSecretService) UpdateSecret(ctx context.Context, secret *models.Secret) error {
 	ret := _m.Called(ctx, secret)
@@ -99,6 +342,73 @@ func (_m *MockSecretService) UpdateSecret(ctx context.Context, secret *models.Se
 	return r0
 }
 
+// UpgradeSecrets provides a mock function with given fields: ctx, batchSize
+func (_m *MockSecretService) UpgradeSecrets(ctx context.Context, batchSize int) (int, error) {
+	ret := _m.Called(ctx, batchSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpgradeSecrets")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int, error)); ok {
+		return rf(ctx, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int); ok {
+		r0 = rf(ctx, batchSize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Watch provides a mock function with given fields: ctx
+func (_m *MockSecretService) Watch(ctx context.Context) (<-chan models.SecretEvent, func(), error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Watch")
+	}
+
+	var r0 <-chan models.SecretEvent
+	var r1 func()
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context) (<-chan models.SecretEvent, func(), error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) <-chan models.SecretEvent); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan models.SecretEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) func()); ok {
+		r1 = rf(ctx)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(func())
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context) error); ok {
+		r2 = rf(ctx)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // NewMockSecretService creates a new instance of MockSecretService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockSecretService(t interface {
@@ -0,0 +1,78 @@
+// Code generated by mockery v2.38.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	repository "github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// MockTokenRepository is an autogenerated mock type for the TokenRepository type
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+// SaveRefreshToken provides a mock function with given fields: ctx, token
+func (_m *MockTokenRepository) SaveRefreshToken(ctx context.Context, token repository.RefreshToken) error {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveRefreshToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.RefreshToken) error); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TakeRefreshToken provides a mock function with given fields: ctx, tokenHash
+func (_m *MockTokenRepository) TakeRefreshToken(ctx context.Context, tokenHash []byte) (*repository.RefreshToken, error) {
+	ret := _m.Called(ctx, tokenHash)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TakeRefreshToken")
+	}
+
+	var r0 *repository.RefreshToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) (*repository.RefreshToken, error)); ok {
+		return rf(ctx, tokenHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) *repository.RefreshToken); ok {
+		r0 = rf(ctx, tokenHash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*repository.RefreshToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []byte) error); ok {
+		r1 = rf(ctx, tokenHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewMockTokenRepository creates a new instance of MockTokenRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockTokenRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockTokenRepository {
+	mock := &MockTokenRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -3,19 +3,253 @@ package models
 
 import "time"
 
+// Secret types supported by the service. The values match the names of the
+// SecretType enum in the API and the secret_type enum in the database.
+const (
+	SecretTypeCredentials = "CREDENTIALS"
+	SecretTypeText        = "TEXT"
+	SecretTypeBinary      = "BINARY"
+	SecretTypeCard        = "CARD"
+	SecretTypeOTP         = "OTP"
+)
+
+// Permissions granted to the users a secret is shared with. The values match the names
+// of the SharePermission enum in the API.
+const (
+	SharePermissionReadOnly  = "READ_ONLY"
+	SharePermissionReadWrite = "READ_WRITE"
+)
+
+// Roles of the members of an organization. The values match the names of the OrganizationRole
+// enum in the API without the ROLE_ prefix.
+const (
+	OrganizationRoleOwner    = "OWNER"
+	OrganizationRoleAdmin    = "ADMIN"
+	OrganizationRoleMember   = "MEMBER"
+	OrganizationRoleReadOnly = "READ_ONLY"
+)
+
 // User is a struct that represents a User in the system.
+// ClientSideEncryption reports whether the user's secrets are encrypted by the client,
+// in which case the server stores them as opaque blobs. TOTPSecret is the encrypted
+// seed of the user's authenticator, TOTPEnabled reports whether the enrollment is confirmed.
+// FailedLogins counts the failed password checks since the last successful login, the user
+// cannot log in until LockedUntil.
 type User struct {
-	Login        string
-	PasswordHash string
-	ID           int
+	LockedUntil          time.Time
+	Login                string
+	PasswordHash         string
+	TOTPSecret           []byte
+	ID                   int
+	FailedLogins         int
+	ClientSideEncryption bool
+	TOTPEnabled          bool
+}
+
+// Tokens is a pair of a short-lived access token and a long-lived refresh token
+// which is used to obtain a new pair once the access token expires.
+// When the user has two-factor authentication enabled, the login yields only
+// TOTPChallenge which has to be completed with a one-time code to get the tokens.
+type Tokens struct {
+	AccessToken   string
+	RefreshToken  string
+	TOTPChallenge string
+}
+
+// TOTPEnrollment holds the secret of a new authenticator and its otpauth URI
+// which can be rendered as a QR code.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// Session is a struct that represents a session of a User on one of the user's devices.
+// Current reports whether the session is the one the request was made from.
+type Session struct {
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	ID        string
+	Current   bool
 }
 
 // Secret is a struct that represents a Secret created by a User.
+// Version is incremented on every write and guards against lost updates:
+// a secret is updated or deleted only if the version known to the client is current.
+// BlobID refers to the Blob holding the file of a binary secret.
+// Name, Tags and Folder label the secret in lists and are stored unencrypted.
+// DeletedAt is the time the secret was moved to the trash, it is set only on secrets in the trash.
+// Permission and Owner are set only on secrets shared with the user by their owner.
+// CollectionID is set on secrets of the collections of organizations, which have no owning user.
+// Such secrets read by a member have the names of their Collection and Organization and
+// the Permission following the role of the member.
 type Secret struct {
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    time.Time
+	Payload      Payload
+	Type         string
+	MetaData     string
+	BlobID       string
+	Name         string
+	Folder       string
+	Permission   string
+	Owner        string
+	Collection   string
+	Organization string
+	Tags         []string
+	ID           int
+	UserID       int
+	Version      int
+	CollectionID int
+}
+
+// SecretFilter selects the secrets of a user by their type, labels and the time they were
+// created or updated. Empty fields do not filter. Name matches a substring of the name
+// regardless of case. The time ranges include their start and exclude their end.
+// PageToken continues the listing after the page it was returned with.
+type SecretFilter struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Type          string
+	Tag           string
+	Folder        string
+	Name          string
+	PageToken     string
+	PageSize      int
+}
+
+// SecretPage is a page of the secrets matching a SecretFilter. NextPageToken is empty on the last page.
+type SecretPage struct {
+	NextPageToken string
+	Secrets       []Secret
+}
+
+// Organization is a team vault whose members share the secrets of its collections.
+// Role is the role of the user the organization is read for.
+type Organization struct {
+	CreatedAt time.Time
+	Name      string
+	Role      string
+	ID        int
+}
+
+// Member is a user who is a member of an Organization with the given Role since CreatedAt.
+type Member struct {
+	CreatedAt time.Time
+	Login     string
+	Role      string
+}
+
+// Invite is an invitation of a user to join an Organization with the given Role.
+// Organization and InvitedBy are the names of the organization and of the member who sent it.
+type Invite struct {
+	CreatedAt      time.Time
+	Organization   string
+	Role           string
+	InvitedBy      string
+	OrganizationID int
+}
+
+// Collection is a named group of the secrets of an Organization.
+type Collection struct {
 	CreatedAt time.Time
-	Type      string
-	Content   string
-	MetaData  string
+	Name      string
 	ID        int
-	UserID    int
 }
+
+// Blob is a file uploaded in chunks which can be attached to a binary secret.
+type Blob struct {
+	ID   string
+	Size int64
+}
+
+// Changes holds the secrets changed and the IDs of the secrets deleted since some revision
+// of the user's secrets. Revision is the current revision, which the client passes to get
+// the next changes.
+type Changes struct {
+	Secrets    []Secret
+	DeletedIDs []int
+	Revision   int64
+}
+
+// Kinds of the changes of secrets reported by SecretEvent.
+const (
+	SecretEventCreated = "CREATED"
+	SecretEventUpdated = "UPDATED"
+	SecretEventDeleted = "DELETED"
+)
+
+// SecretEvent reports a change of a secret of a user. Revision is the revision
+// of the user's secrets the change was made in.
+type SecretEvent struct {
+	Kind     string `json:"kind"`
+	Revision int64  `json:"revision"`
+	SecretID int    `json:"secret_id"`
+	UserID   int    `json:"user_id"`
+}
+
+// Payload is the typed content of a Secret. Every secret type has its own
+// payload structure, SecretType reports which one it is.
+type Payload interface {
+	SecretType() string
+}
+
+// Credentials is a login/password pair, optionally bound to a website.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+	URL      string `json:"url,omitempty"`
+}
+
+// SecretType implements the Payload interface.
+func (c *Credentials) SecretType() string { return SecretTypeCredentials }
+
+// Card holds bank card details.
+type Card struct {
+	Number string `json:"number"`
+	Holder string `json:"holder"`
+	Expiry string `json:"expiry"`
+	CVV    string `json:"cvv"`
+}
+
+// SecretType implements the Payload interface.
+func (c *Card) SecretType() string { return SecretTypeCard }
+
+// Text holds arbitrary text data.
+type Text struct {
+	Body string `json:"body"`
+}
+
+// SecretType implements the Payload interface.
+func (t *Text) SecretType() string { return SecretTypeText }
+
+// Binary holds arbitrary binary data along with a file name and MIME type.
+// Larger files are kept in the blob attached to the secret, Size is the size of the blob then.
+type Binary struct {
+	Filename string `json:"filename,omitempty"`
+	Mime     string `json:"mime,omitempty"`
+	Data     []byte `json:"data"`
+	Size     int64  `json:"size,omitempty"`
+}
+
+// SecretType implements the Payload interface.
+func (b *Binary) SecretType() string { return SecretTypeBinary }
+
+// OTP holds the otpauth URI of a one-time password generator. The codes are generated by the client.
+type OTP struct {
+	URI string `json:"uri"`
+}
+
+// SecretType implements the Payload interface.
+func (o *OTP) SecretType() string { return SecretTypeOTP }
+
+// Encrypted holds a payload encrypted by the client. The server cannot read it
+// and therefore does not know its type, SecretType returns an empty string.
+type Encrypted struct {
+	Data []byte `json:"data"`
+}
+
+// SecretType implements the Payload interface.
+func (e *Encrypted) SecretType() string { return "" }
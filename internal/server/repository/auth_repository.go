@@ -4,7 +4,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
@@ -17,11 +19,26 @@ const uniqueViolationErrCode = "23505"
 // ErrAlreadyExist is returned when a user already exists in the database.
 var ErrAlreadyExist = errors.New("login already exist in database")
 
+// ErrSecretsMismatch is returned when the re-encrypted secrets passed along with
+// a new password do not match the secrets stored for the user.
+var ErrSecretsMismatch = errors.New("secrets do not match stored secrets")
+
 // AuthRepository is an interface that defines method to
 // interact with underlying User related database operations.
 type AuthRepository interface {
 	SaveUser(ctx context.Context, user models.User) (int, error)
 	GetUser(ctx context.Context, login string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID int) (*models.User, error)
+	SetTOTPSecret(ctx context.Context, userID int, secret []byte) error
+	EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes [][]byte) error
+	DisableTOTP(ctx context.Context, userID int) error
+	UseTOTPCounter(ctx context.Context, userID int, counter int64) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) error
+	ChangePassword(ctx context.Context, userID int, passwordHash string, secrets []Secret) error
+	DeleteUser(ctx context.Context, userID int) error
+	RecordLoginFailure(ctx context.Context, userID int) (int, error)
+	LockUser(ctx context.Context, userID int, until time.Time) error
+	ResetLoginFailures(ctx context.Context, userID int) error
 }
 
 type authRepo struct {
@@ -44,13 +61,13 @@ func (a *authRepo) SaveUser(ctx context.Context, user models.User) (int, error)
 	defer cancel()
 
 	stmt := `
-INSERT INTO users (login, password)
-VALUES ($1, $2)
+INSERT INTO users (login, password, client_side_encryption)
+VALUES ($1, $2, $3)
 RETURNING id
 `
 
 	var userID int
-	err := a.pg.QueryRow(timeoutCtx, stmt, user.Login, user.PasswordHash).Scan(&userID)
+	err := a.pg.QueryRow(timeoutCtx, stmt, user.Login, user.PasswordHash, user.ClientSideEncryption).Scan(&userID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if ok := errors.As(err, &pgErr); !ok {
@@ -69,22 +86,369 @@ RETURNING id
 
 // GetUser implements the GetUser method of the AuthRepository interface.
 // It retrieves a User record by login from a PostgreSQL database.
+// ErrNoRows is returned if there is no such user.
 func (a *authRepo) GetUser(ctx context.Context, login string) (*models.User, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
 	defer cancel()
 
 	stmt := `
-SELECT id, login, password
+SELECT id, login, password, client_side_encryption, totp_secret, totp_enabled, failed_logins, locked_until
 FROM users
 WHERE login = $1
 `
 
 	row := a.pg.QueryRow(timeoutCtx, stmt, login)
 
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByID implements the GetUserByID method of the AuthRepository interface.
+// It retrieves a User record by ID from a PostgreSQL database.
+func (a *authRepo) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT id, login, password, client_side_encryption, totp_secret, totp_enabled, failed_logins, locked_until
+FROM users
+WHERE id = $1
+`
+
+	row := a.pg.QueryRow(timeoutCtx, stmt, userID)
+
+	user, err := scanUser(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func scanUser(row pgx.Row) (*models.User, error) {
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Login, &user.PasswordHash); err != nil {
+	var lockedUntil *time.Time
+
+	err := row.Scan(
+		&user.ID,
+		&user.Login,
+		&user.PasswordHash,
+		&user.ClientSideEncryption,
+		&user.TOTPSecret,
+		&user.TOTPEnabled,
+		&user.FailedLogins,
+		&lockedUntil,
+	)
+	if err != nil {
 		return nil, err
 	}
 
+	if lockedUntil != nil {
+		user.LockedUntil = *lockedUntil
+	}
+
 	return &user, nil
 }
+
+// SetTOTPSecret implements the SetTOTPSecret method of the AuthRepository interface.
+// It stores the encrypted seed of a new authenticator of the user. The seed is not used
+// for login until the enrollment is confirmed with EnableTOTP.
+// ErrNoRows is returned if the user has two-factor authentication already enabled.
+func (a *authRepo) SetTOTPSecret(ctx context.Context, userID int, secret []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE users
+SET totp_secret = $2, totp_last_counter = 0
+WHERE id = $1 AND NOT totp_enabled
+`
+
+	tag, err := a.pg.Exec(timeoutCtx, stmt, userID, secret)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// EnableTOTP implements the EnableTOTP method of the AuthRepository interface.
+// It enables two-factor authentication of the user and replaces the user's
+// recovery codes with the provided ones in a single transaction.
+func (a *authRepo) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes [][]byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, a.pg, func(tx pgx.Tx) error {
+		stmt := `
+UPDATE users
+SET totp_enabled = TRUE
+WHERE id = $1 AND totp_secret IS NOT NULL
+`
+
+		tag, err := tx.Exec(timeoutCtx, stmt, userID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		if _, err := tx.Exec(timeoutCtx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+
+		rows := make([][]interface{}, len(recoveryCodeHashes))
+		for i, hash := range recoveryCodeHashes {
+			rows[i] = []interface{}{userID, hash}
+		}
+
+		_, err = tx.CopyFrom(
+			timeoutCtx,
+			pgx.Identifier{"recovery_codes"},
+			[]string{"user_id", "code_hash"},
+			pgx.CopyFromRows(rows),
+		)
+
+		return err
+	})
+}
+
+// DisableTOTP implements the DisableTOTP method of the AuthRepository interface.
+// It removes the authenticator and the recovery codes of the user.
+func (a *authRepo) DisableTOTP(ctx context.Context, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, a.pg, func(tx pgx.Tx) error {
+		stmt := `
+UPDATE users
+SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = 0
+WHERE id = $1
+`
+
+		if _, err := tx.Exec(timeoutCtx, stmt, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(timeoutCtx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+
+		return err
+	})
+}
+
+// UseTOTPCounter implements the UseTOTPCounter method of the AuthRepository interface.
+// It records the time step of an accepted one-time code, so the code cannot be replayed.
+// ErrNoRows is returned if a code of the same or a later time step has already been used.
+func (a *authRepo) UseTOTPCounter(ctx context.Context, userID int, counter int64) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE users
+SET totp_last_counter = $2
+WHERE id = $1 AND totp_last_counter < $2
+`
+
+	tag, err := a.pg.Exec(timeoutCtx, stmt, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// UseRecoveryCode implements the UseRecoveryCode method of the AuthRepository interface.
+// It removes the recovery code with the given hash, so every code can be used only once.
+// ErrNoRows is returned if the user has no such code.
+func (a *authRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM recovery_codes
+WHERE user_id = $1 AND code_hash = $2
+`
+
+	tag, err := a.pg.Exec(timeoutCtx, stmt, userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// ChangePassword implements the ChangePassword method of the AuthRepository interface.
+// It stores the new password hash of the user. Secrets of client-side encryption accounts are
+// re-encrypted with the new password, so their new content is stored in the same transaction.
+// In this case the secrets must cover every secret of the user, otherwise ErrSecretsMismatch
+// is returned and nothing is changed. The previous versions of the secrets are encrypted with
+// the old password and could not be decrypted anymore, so they are removed.
+func (a *authRepo) ChangePassword(ctx context.Context, userID int, passwordHash string, secrets []Secret) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, a.pg, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(timeoutCtx, `UPDATE users SET password = $2 WHERE id = $1`, userID, passwordHash)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		if len(secrets) == 0 {
+			return nil
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		stmt := `
+UPDATE secrets
+SET content = $3, meta_data = $4, version = version + 1, revision = $5
+WHERE id = $1 AND user_id = $2
+`
+
+		for _, secret := range secrets {
+			tag, err := tx.Exec(timeoutCtx, stmt, secret.ID, userID, secret.Content, secret.MetaData, revision)
+			if err != nil {
+				return err
+			}
+
+			if tag.RowsAffected() == 0 {
+				return ErrSecretsMismatch
+			}
+
+			err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+				Kind:     models.SecretEventUpdated,
+				Revision: revision,
+				SecretID: secret.ID,
+				UserID:   userID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var stored int
+		if err := tx.QueryRow(timeoutCtx, `SELECT COUNT(*) FROM secrets WHERE user_id = $1`, userID).
+			Scan(&stored); err != nil {
+			return err
+		}
+
+		if stored != len(secrets) {
+			return ErrSecretsMismatch
+		}
+
+		_, err = tx.Exec(timeoutCtx, `DELETE FROM secret_versions WHERE user_id = $1`, userID)
+
+		return err
+	})
+}
+
+// DeleteUser implements the DeleteUser method of the AuthRepository interface.
+// It removes the secrets of the user and the user in a single transaction.
+// The keys, sessions and tokens of the user are removed by the database cascade.
+func (a *authRepo) DeleteUser(ctx context.Context, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, a.pg, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(timeoutCtx, `DELETE FROM secrets WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `DELETE FROM users WHERE id = $1`, userID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+}
+
+// RecordLoginFailure implements the RecordLoginFailure method of the AuthRepository interface.
+// It increments the number of failed logins of the user and returns the new number.
+func (a *authRepo) RecordLoginFailure(ctx context.Context, userID int) (int, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE users
+SET failed_logins = failed_logins + 1
+WHERE id = $1
+RETURNING failed_logins
+`
+
+	var failedLogins int
+	if err := a.pg.QueryRow(timeoutCtx, stmt, userID).Scan(&failedLogins); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNoRows
+		}
+
+		return 0, err
+	}
+
+	return failedLogins, nil
+}
+
+// LockUser implements the LockUser method of the AuthRepository interface.
+// It forbids the user to log in until the given time.
+func (a *authRepo) LockUser(ctx context.Context, userID int, until time.Time) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	_, err := a.pg.Exec(timeoutCtx, `UPDATE users SET locked_until = $2 WHERE id = $1`, userID, until)
+
+	return err
+}
+
+// ResetLoginFailures implements the ResetLoginFailures method of the AuthRepository interface.
+// It clears the failed logins and the lock of the user after a successful login.
+func (a *authRepo) ResetLoginFailures(ctx context.Context, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE users
+SET failed_logins = 0, locked_until = NULL
+WHERE id = $1
+`
+
+	_, err := a.pg.Exec(timeoutCtx, stmt, userID)
+
+	return err
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+// ErrInvalidBlob is returned when a blob attached to a secret does not exist, belongs to
+// another user, is not completed or is already attached to another secret.
+var ErrInvalidBlob = errors.New("blob cannot be attached to secret")
+
+// BlobRepository is an interface that defines methods for
+// handling blob related operations in the database.
+//
+// The chunks of blobs are kept in a blob store, removing a blob queues its chunks for removal
+// from the store. The queued blobs are listed by GetDeletedBlobIDs until ConfirmBlobDeletion.
+type BlobRepository interface {
+	CreateBlob(ctx context.Context, blob *Blob) error
+	CompleteBlob(ctx context.Context, blob *Blob) error
+	GetBlob(ctx context.Context, blobID string, userID int) (*Blob, error)
+	DeleteBlob(ctx context.Context, blobID string) error
+	DeleteUnattachedBlobs(ctx context.Context, createdBefore time.Time) (int64, error)
+	GetDeletedBlobIDs(ctx context.Context, limit int) ([]string, error)
+	ConfirmBlobDeletion(ctx context.Context, blobID string) error
+	GetOutdatedBlobs(ctx context.Context, afterID string, limit int) ([]Blob, error)
+	UpgradeBlob(ctx context.Context, blob *Blob) error
+}
+
+type blobRepo struct {
+	pg *pgxpool.Pool
+}
+
+// NewBlobRepository creates and returns an instance of BlobRepository.
+func NewBlobRepository(pg *pgxpool.Pool) BlobRepository {
+	return &blobRepo{
+		pg: pg,
+	}
+}
+
+// CreateBlob implements the CreateBlob method of the BlobRepository interface.
+// It stores a new empty blob in the PostgreSQL database.
+func (b *blobRepo) CreateBlob(ctx context.Context, blob *Blob) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `INSERT INTO blobs (id, user_id) VALUES ($1, $2)`
+
+	_, err := b.pg.Exec(timeoutCtx, stmt, blob.ID, blob.UserID)
+
+	return err
+}
+
+// CompleteBlob implements the CompleteBlob method of the BlobRepository interface.
+// It stores the number of chunks, the size and the hash of the uploaded blob and marks it completed.
+func (b *blobRepo) CompleteBlob(ctx context.Context, blob *Blob) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE blobs
+SET size = $1, chunks = $2, hash = $3, completed = TRUE
+WHERE id = $4
+`
+
+	tag, err := b.pg.Exec(timeoutCtx, stmt, blob.Size, blob.Chunks, blob.Hash, blob.ID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	blob.Completed = true
+
+	return nil
+}
+
+// GetBlob implements the GetBlob method of the BlobRepository interface.
+// It retrieves a specific blob of the user from the PostgreSQL database along with the hash
+// kept by the secret or the version of a secret the blob is attached to. The hash of
+// the upload is returned for a blob which is not attached yet.
+// ErrNoRows is returned if the user has no such blob.
+func (b *blobRepo) GetBlob(ctx context.Context, blobID string, userID int) (*Blob, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT b.id,
+       b.user_id,
+       b.size,
+       b.chunks,
+       b.completed,
+       CASE
+           WHEN s.blob_id IS NOT NULL THEN s.blob_hash
+           WHEN v.blob_id IS NOT NULL THEN v.blob_hash
+           ELSE b.hash
+       END,
+       b.created_at,
+       b.legacy_chunks
+FROM blobs b
+LEFT JOIN secrets s ON s.blob_id = b.id
+LEFT JOIN LATERAL (
+    SELECT blob_id, blob_hash FROM secret_versions WHERE blob_id = b.id LIMIT 1
+) v ON TRUE
+WHERE b.id = $1 AND b.user_id = $2
+`
+
+	blob, err := scanBlob(b.pg.QueryRow(timeoutCtx, stmt, blobID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return blob, nil
+}
+
+// GetOutdatedBlobs implements the GetOutdatedBlobs method of the BlobRepository interface.
+// It retrieves up to limit completed blobs with IDs greater than afterID which have legacy
+// chunks or no hash, ordered by ID, along with the hash kept by the secret or the version
+// of a secret the blob is attached to.
+func (b *blobRepo) GetOutdatedBlobs(ctx context.Context, afterID string, limit int) ([]Blob, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT b.id,
+       b.user_id,
+       b.size,
+       b.chunks,
+       b.completed,
+       CASE
+           WHEN s.blob_id IS NOT NULL THEN s.blob_hash
+           WHEN v.blob_id IS NOT NULL THEN v.blob_hash
+           ELSE b.hash
+       END,
+       b.created_at,
+       b.legacy_chunks
+FROM blobs b
+LEFT JOIN secrets s ON s.blob_id = b.id
+LEFT JOIN LATERAL (
+    SELECT blob_id, blob_hash FROM secret_versions WHERE blob_id = b.id LIMIT 1
+) v ON TRUE
+WHERE b.id > $1 AND b.completed AND (b.legacy_chunks OR b.hash IS NULL)
+ORDER BY b.id
+LIMIT $2
+`
+
+	rows, err := b.pg.Query(timeoutCtx, stmt, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Blob, error) {
+		blob, err := scanBlob(row)
+		if err != nil {
+			return Blob{}, err
+		}
+
+		return *blob, nil
+	})
+}
+
+// UpgradeBlob implements the UpgradeBlob method of the BlobRepository interface.
+// It clears the legacy flag of the blob whose chunks were encrypted again and stores its new
+// hash on the blob and on the secret or the versions of a secret the blob is attached to.
+// ErrNoRows is returned if the blob was removed in the meantime.
+func (b *blobRepo) UpgradeBlob(ctx context.Context, blob *Blob) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, b.pg, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(timeoutCtx, `
+UPDATE blobs
+SET hash = $1, legacy_chunks = FALSE
+WHERE id = $2
+`, blob.Hash, blob.ID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		if _, err := tx.Exec(timeoutCtx, `UPDATE secrets SET blob_hash = $1 WHERE blob_id = $2`,
+			blob.Hash, blob.ID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(timeoutCtx, `UPDATE secret_versions SET blob_hash = $1 WHERE blob_id = $2`,
+			blob.Hash, blob.ID)
+
+		return err
+	})
+}
+
+func scanBlob(row pgx.Row) (*Blob, error) {
+	var blob Blob
+
+	err := row.Scan(
+		&blob.ID,
+		&blob.UserID,
+		&blob.Size,
+		&blob.Chunks,
+		&blob.Completed,
+		&blob.Hash,
+		&blob.CreatedAt,
+		&blob.LegacyChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blob, nil
+}
+
+// DeleteBlob implements the DeleteBlob method of the BlobRepository interface.
+// It removes the blob from the PostgreSQL database and queues its chunks for removal.
+func (b *blobRepo) DeleteBlob(ctx context.Context, blobID string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	_, err := b.pg.Exec(timeoutCtx, `DELETE FROM blobs WHERE id = $1`, blobID)
+
+	return err
+}
+
+// DeleteUnattachedBlobs implements the DeleteUnattachedBlobs method of the BlobRepository interface.
+// It removes the blobs created before the given time which are not attached to any secret
+// or its previous version, such as abandoned uploads, and returns the number of removed blobs.
+func (b *blobRepo) DeleteUnattachedBlobs(ctx context.Context, createdBefore time.Time) (int64, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM blobs
+WHERE created_at < $1
+  AND NOT EXISTS (SELECT 1 FROM secrets WHERE secrets.blob_id = blobs.id)
+  AND NOT EXISTS (SELECT 1 FROM secret_versions WHERE secret_versions.blob_id = blobs.id)
+`
+
+	tag, err := b.pg.Exec(timeoutCtx, stmt, createdBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// GetDeletedBlobIDs implements the GetDeletedBlobIDs method of the BlobRepository interface.
+// It retrieves up to limit IDs of the removed blobs whose chunks are still to be removed
+// from the blob store, the earliest removed first.
+func (b *blobRepo) GetDeletedBlobIDs(ctx context.Context, limit int) ([]string, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	rows, err := b.pg.Query(timeoutCtx, `SELECT blob_id FROM deleted_blobs ORDER BY deleted_at LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// ConfirmBlobDeletion implements the ConfirmBlobDeletion method of the BlobRepository interface.
+// It dequeues the removed blob once its chunks are removed from the blob store.
+func (b *blobRepo) ConfirmBlobDeletion(ctx context.Context, blobID string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	_, err := b.pg.Exec(timeoutCtx, `DELETE FROM deleted_blobs WHERE blob_id = $1`, blobID)
+
+	return err
+}
+
+// attachBlob checks within the transaction that the blob of the secret can be attached to it:
+// the blob belongs to the owner of the secret, is completed and is not attached to another secret
+// or a previous version of another secret. The hash of the blob is set on the secret.
+// The blob row stays locked until the transaction ends, so it is not removed in the meantime.
+func attachBlob(ctx context.Context, tx pgx.Tx, secret *Secret) error {
+	secret.BlobHash = nil
+
+	if secret.BlobID == "" {
+		return nil
+	}
+
+	stmt := `
+SELECT completed, hash
+FROM blobs
+WHERE id = $1 AND user_id = $2
+FOR UPDATE
+`
+
+	var (
+		completed bool
+		hash      []byte
+	)
+
+	if err := tx.QueryRow(ctx, stmt, secret.BlobID, secret.UserID).Scan(&completed, &hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidBlob
+		}
+
+		return err
+	}
+
+	if !completed {
+		return ErrInvalidBlob
+	}
+
+	var attached bool
+
+	err := tx.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM secrets WHERE blob_id = $1 AND id <> $2)
+    OR EXISTS (SELECT 1 FROM secret_versions WHERE blob_id = $1 AND secret_id <> $2)
+`, secret.BlobID, secret.ID).Scan(&attached)
+	if err != nil {
+		return err
+	}
+
+	if attached {
+		return ErrInvalidBlob
+	}
+
+	secret.BlobHash = hash
+
+	return nil
+}
@@ -0,0 +1,263 @@
+// Package repository provides an abstraction over users and secrets databases.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+// ErrKeyAlreadyExist is returned when the user already has a stored key.
+var ErrKeyAlreadyExist = errors.New("user key already exist in database")
+
+// KeyRepository is an interface that defines methods for
+// storing the wrapped data encryption keys of users and organizations.
+type KeyRepository interface {
+	SaveUserKey(ctx context.Context, key UserKey) error
+	GetUserKey(ctx context.Context, userID int) (*UserKey, error)
+	GetStaleUserKeys(ctx context.Context, version, limit int) ([]UserKey, error)
+	GetUsersWithoutKey(ctx context.Context, limit int) ([]int, error)
+	UpdateUserKeys(ctx context.Context, keys []UserKey) error
+	GetOrganizationKey(ctx context.Context, organizationID int) (*OrganizationKey, error)
+	GetStaleOrganizationKeys(ctx context.Context, version, limit int) ([]OrganizationKey, error)
+	UpdateOrganizationKeys(ctx context.Context, keys []OrganizationKey) error
+}
+
+type keyRepo struct {
+	pg *pgxpool.Pool
+}
+
+// NewKeyRepository creates and returns an instance of KeyRepository.
+func NewKeyRepository(pg *pgxpool.Pool) KeyRepository {
+	r := &keyRepo{
+		pg: pg,
+	}
+
+	return r
+}
+
+// SaveUserKey implements the SaveUserKey method of the KeyRepository interface.
+// It stores the wrapped key of the user unless the user already has one.
+func (k *keyRepo) SaveUserKey(ctx context.Context, key UserKey) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO user_keys (user_id, wrapped_key, key_version)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO NOTHING
+`
+
+	tag, err := k.pg.Exec(timeoutCtx, stmt, key.UserID, key.WrappedKey, key.Version)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrKeyAlreadyExist
+	}
+
+	return nil
+}
+
+// GetUserKey implements the GetUserKey method of the KeyRepository interface.
+// It retrieves the wrapped key of the user, ErrNoRows is returned if the user has no key.
+func (k *keyRepo) GetUserKey(ctx context.Context, userID int) (*UserKey, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT user_id, wrapped_key, key_version
+FROM user_keys
+WHERE user_id = $1
+`
+
+	var key UserKey
+	err := k.pg.QueryRow(timeoutCtx, stmt, userID).Scan(&key.UserID, &key.WrappedKey, &key.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetStaleUserKeys implements the GetStaleUserKeys method of the KeyRepository interface.
+// It retrieves up to limit keys wrapped by a master key other than the one of the given version.
+func (k *keyRepo) GetStaleUserKeys(ctx context.Context, version, limit int) ([]UserKey, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT user_id, wrapped_key, key_version
+FROM user_keys
+WHERE key_version <> $1
+ORDER BY user_id
+LIMIT $2
+`
+
+	rows, err := k.pg.Query(timeoutCtx, stmt, version, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []UserKey
+	for rows.Next() {
+		var key UserKey
+		if err := rows.Scan(&key.UserID, &key.WrappedKey, &key.Version); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetUsersWithoutKey implements the GetUsersWithoutKey method of the KeyRepository interface.
+// It retrieves up to limit IDs of users registered before data encryption keys were introduced.
+func (k *keyRepo) GetUsersWithoutKey(ctx context.Context, limit int) ([]int, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT u.id
+FROM users u
+LEFT JOIN user_keys k ON k.user_id = u.id
+WHERE k.user_id IS NULL
+ORDER BY u.id
+LIMIT $1
+`
+
+	rows, err := k.pg.Query(timeoutCtx, stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// UpdateUserKeys implements the UpdateUserKeys method of the KeyRepository interface.
+// It replaces the wrapped keys of the given users in a single transaction.
+func (k *keyRepo) UpdateUserKeys(ctx context.Context, keys []UserKey) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE user_keys
+SET wrapped_key = $1, key_version = $2
+WHERE user_id = $3
+`
+
+	return pgx.BeginFunc(timeoutCtx, k.pg, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for i := range keys {
+			batch.Queue(stmt, keys[i].WrappedKey, keys[i].Version, keys[i].UserID)
+		}
+
+		return tx.SendBatch(timeoutCtx, batch).Close()
+	})
+}
+
+// GetOrganizationKey implements the GetOrganizationKey method of the KeyRepository interface.
+// It retrieves the wrapped key of the organization, ErrNoRows is returned if there is no such organization.
+func (k *keyRepo) GetOrganizationKey(ctx context.Context, organizationID int) (*OrganizationKey, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT id, wrapped_key, key_version
+FROM organizations
+WHERE id = $1
+`
+
+	var key OrganizationKey
+	err := k.pg.QueryRow(timeoutCtx, stmt, organizationID).Scan(&key.OrganizationID, &key.WrappedKey, &key.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetStaleOrganizationKeys implements the GetStaleOrganizationKeys method of the KeyRepository interface.
+// It retrieves up to limit keys wrapped by a master key other than the one of the given version.
+func (k *keyRepo) GetStaleOrganizationKeys(ctx context.Context, version, limit int) ([]OrganizationKey, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT id, wrapped_key, key_version
+FROM organizations
+WHERE key_version <> $1
+ORDER BY id
+LIMIT $2
+`
+
+	rows, err := k.pg.Query(timeoutCtx, stmt, version, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (OrganizationKey, error) {
+		var key OrganizationKey
+		err := row.Scan(&key.OrganizationID, &key.WrappedKey, &key.Version)
+
+		return key, err
+	})
+}
+
+// UpdateOrganizationKeys implements the UpdateOrganizationKeys method of the KeyRepository interface.
+// It replaces the wrapped keys of the given organizations in a single transaction.
+func (k *keyRepo) UpdateOrganizationKeys(ctx context.Context, keys []OrganizationKey) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE organizations
+SET wrapped_key = $1, key_version = $2
+WHERE id = $3
+`
+
+	return pgx.BeginFunc(timeoutCtx, k.pg, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for i := range keys {
+			batch.Queue(stmt, keys[i].WrappedKey, keys[i].Version, keys[i].OrganizationID)
+		}
+
+		return tx.SendBatch(timeoutCtx, batch).Close()
+	})
+}
@@ -0,0 +1,612 @@
+// Package repository provides an abstraction over users and secrets databases.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+var (
+	// ErrLastOwner is returned when the only owner of an organization is removed or gets another role.
+	ErrLastOwner = errors.New("organization must keep an owner")
+	// ErrAlreadyMember is returned when a member of an organization is invited to it.
+	ErrAlreadyMember = errors.New("user is already a member of organization")
+	// ErrCollectionAlreadyExist is returned when the organization already has a collection with the name.
+	ErrCollectionAlreadyExist = errors.New("collection already exist in organization")
+)
+
+// OrganizationRepository is an interface that defines methods for
+// handling organizations, their members, invites and collections in the database.
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, org *Organization, ownerID int) error
+	GetOrganizations(ctx context.Context, userID int) ([]Organization, error)
+	DeleteOrganization(ctx context.Context, organizationID int) error
+	GetMember(ctx context.Context, organizationID, userID int) (*Member, error)
+	GetMemberByLogin(ctx context.Context, organizationID int, login string) (*Member, error)
+	GetMembers(ctx context.Context, organizationID int) ([]Member, error)
+	UpdateMemberRole(ctx context.Context, organizationID, userID int, role string) error
+	RemoveMember(ctx context.Context, organizationID, userID int) error
+	GetInvitee(ctx context.Context, login string) (*Recipient, error)
+	CreateInvite(ctx context.Context, invite *Invite) error
+	GetInvites(ctx context.Context, userID int) ([]Invite, error)
+	AcceptInvite(ctx context.Context, organizationID, userID int) error
+	DeleteInvite(ctx context.Context, organizationID, userID int) error
+	CreateCollection(ctx context.Context, collection *Collection) error
+	GetCollections(ctx context.Context, organizationID int) ([]Collection, error)
+	DeleteCollection(ctx context.Context, organizationID, collectionID int) error
+}
+
+type organizationRepo struct {
+	pg *pgxpool.Pool
+}
+
+// NewOrganizationRepository creates and returns an instance of OrganizationRepository.
+func NewOrganizationRepository(pg *pgxpool.Pool) OrganizationRepository {
+	r := &organizationRepo{
+		pg: pg,
+	}
+
+	return r
+}
+
+// CreateOrganization implements the CreateOrganization method of the OrganizationRepository interface.
+// It stores a new organization along with its wrapped key and makes the user its owner
+// in a single transaction, and sets the ID of the organization on it.
+func (o *organizationRepo) CreateOrganization(ctx context.Context, org *Organization, ownerID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		err := tx.QueryRow(timeoutCtx, `
+INSERT INTO organizations (name, wrapped_key, key_version)
+VALUES ($1, $2, $3)
+RETURNING id
+`, org.Name, org.WrappedKey, org.KeyVersion).Scan(&org.ID)
+		if err != nil {
+			return err
+		}
+
+		stmt := `
+INSERT INTO organization_members (organization_id, user_id, role)
+VALUES ($1, $2, $3)
+`
+
+		_, err = tx.Exec(timeoutCtx, stmt, org.ID, ownerID, models.OrganizationRoleOwner)
+
+		return err
+	})
+}
+
+// GetOrganizations implements the GetOrganizations method of the OrganizationRepository interface.
+// It retrieves the organizations the user is a member of along with the role of the user, ordered by name.
+func (o *organizationRepo) GetOrganizations(ctx context.Context, userID int) ([]Organization, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT o.id, o.name, m.role, o.created_at
+FROM organizations o
+JOIN organization_members m ON m.organization_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.name, o.id
+`
+
+	rows, err := o.pg.Query(timeoutCtx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Organization, error) {
+		var org Organization
+		err := row.Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt)
+
+		return org, err
+	})
+}
+
+// DeleteOrganization implements the DeleteOrganization method of the OrganizationRepository interface.
+// It leaves a tombstone of every secret of the collections of the organization for its members
+// and removes the organization, its members, invites, collections and their secrets are removed
+// by the database cascade. ErrNoRows is returned if there is no such organization.
+func (o *organizationRepo) DeleteOrganization(ctx context.Context, organizationID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		members, err := memberIDs(timeoutCtx, tx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		if err := lockUsers(timeoutCtx, tx, members); err != nil {
+			return err
+		}
+
+		err = notifyMembers(timeoutCtx, tx, organizationID, 0, members, models.SecretEventDeleted)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `DELETE FROM organizations WHERE id = $1`, organizationID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+}
+
+// GetMember implements the GetMember method of the OrganizationRepository interface.
+// It retrieves the membership of the user in the organization.
+// ErrNoRows is returned if the user is not a member of the organization.
+func (o *organizationRepo) GetMember(ctx context.Context, organizationID, userID int) (*Member, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = $1 AND m.user_id = $2
+`
+
+	return scanMember(o.pg.QueryRow(timeoutCtx, stmt, organizationID, userID))
+}
+
+// GetMemberByLogin implements the GetMemberByLogin method of the OrganizationRepository interface.
+// It retrieves the membership of the user with the login in the organization.
+// ErrNoRows is returned if there is no such member.
+func (o *organizationRepo) GetMemberByLogin(ctx context.Context, organizationID int, login string) (*Member, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = $1 AND u.login = $2
+`
+
+	return scanMember(o.pg.QueryRow(timeoutCtx, stmt, organizationID, login))
+}
+
+// GetMembers implements the GetMembers method of the OrganizationRepository interface.
+// It retrieves the members of the organization in the order they joined it.
+func (o *organizationRepo) GetMembers(ctx context.Context, organizationID int) ([]Member, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+FROM organization_members m
+JOIN users u ON u.id = m.user_id
+WHERE m.organization_id = $1
+ORDER BY m.created_at, u.login
+`
+
+	rows, err := o.pg.Query(timeoutCtx, stmt, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Member, error) {
+		var member Member
+		err := row.Scan(&member.OrganizationID, &member.UserID, &member.Login, &member.Role, &member.CreatedAt)
+
+		return member, err
+	})
+}
+
+// UpdateMemberRole implements the UpdateMemberRole method of the OrganizationRepository interface.
+// It changes the role of the member of the organization. The secrets of its collections do not
+// change, so the member is not notified about them, the role is checked on every access to them.
+// ErrNoRows is returned if the user is not a member of the organization, ErrLastOwner if the user
+// is its only owner and gets another role.
+func (o *organizationRepo) UpdateMemberRole(ctx context.Context, organizationID, userID int, role string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		if role != models.OrganizationRoleOwner {
+			if err := keepOwner(timeoutCtx, tx, organizationID, userID); err != nil {
+				return err
+			}
+		}
+
+		stmt := `
+UPDATE organization_members
+SET role = $3
+WHERE organization_id = $1 AND user_id = $2
+`
+
+		tag, err := tx.Exec(timeoutCtx, stmt, organizationID, userID, role)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+}
+
+// RemoveMember implements the RemoveMember method of the OrganizationRepository interface.
+// It removes the member from the organization and leaves the member a tombstone of every secret
+// of its collections. ErrNoRows is returned if the user is not a member of the organization,
+// ErrLastOwner if the user is its only owner.
+func (o *organizationRepo) RemoveMember(ctx context.Context, organizationID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		if err := lockUsers(timeoutCtx, tx, []int{userID}); err != nil {
+			return err
+		}
+
+		if err := keepOwner(timeoutCtx, tx, organizationID, userID); err != nil {
+			return err
+		}
+
+		stmt := `
+DELETE FROM organization_members
+WHERE organization_id = $1 AND user_id = $2
+`
+
+		tag, err := tx.Exec(timeoutCtx, stmt, organizationID, userID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		return notifyMembers(timeoutCtx, tx, organizationID, 0, []int{userID}, models.SecretEventDeleted)
+	})
+}
+
+// keepOwner returns ErrLastOwner if the user is the only owner of the organization.
+// The owners stay locked until the transaction ends, so concurrent changes of the owners
+// cannot leave the organization without one.
+func keepOwner(ctx context.Context, tx pgx.Tx, organizationID, userID int) error {
+	stmt := `
+SELECT user_id
+FROM organization_members
+WHERE organization_id = $1 AND role = $2
+FOR UPDATE
+`
+
+	rows, err := tx.Query(ctx, stmt, organizationID, models.OrganizationRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+
+	return nil
+}
+
+// GetInvitee implements the GetInvitee method of the OrganizationRepository interface.
+// It retrieves the user with the login who can be invited to an organization.
+// ErrNoRows is returned if there is no such user.
+func (o *organizationRepo) GetInvitee(ctx context.Context, login string) (*Recipient, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	var invitee Recipient
+
+	err := o.pg.QueryRow(timeoutCtx, `SELECT id, client_side_encryption FROM users WHERE login = $1`, login).
+		Scan(&invitee.ID, &invitee.ClientSideEncryption)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return &invitee, nil
+}
+
+// CreateInvite implements the CreateInvite method of the OrganizationRepository interface.
+// It stores the invite or replaces the invite sent to the same user before.
+// ErrAlreadyMember is returned if the user is already a member of the organization.
+func (o *organizationRepo) CreateInvite(ctx context.Context, invite *Invite) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO organization_invites (organization_id, user_id, invited_by, role)
+SELECT $1, $2, $3, $4
+WHERE NOT EXISTS (
+    SELECT 1 FROM organization_members WHERE organization_id = $1 AND user_id = $2
+)
+ON CONFLICT (organization_id, user_id) DO UPDATE
+SET invited_by = EXCLUDED.invited_by,
+    role = EXCLUDED.role,
+    created_at = CURRENT_TIMESTAMP
+`
+
+	tag, err := o.pg.Exec(timeoutCtx, stmt, invite.OrganizationID, invite.UserID, invite.InvitedBy, invite.Role)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyMember
+	}
+
+	return nil
+}
+
+// GetInvites implements the GetInvites method of the OrganizationRepository interface.
+// It retrieves the pending invites of the user along with the names of the organizations
+// and the logins of the users who sent them, the latest first.
+func (o *organizationRepo) GetInvites(ctx context.Context, userID int) ([]Invite, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT i.organization_id, i.user_id, i.invited_by, i.role, i.created_at, o.name, u.login
+FROM organization_invites i
+JOIN organizations o ON o.id = i.organization_id
+JOIN users u ON u.id = i.invited_by
+WHERE i.user_id = $1
+ORDER BY i.created_at DESC
+`
+
+	rows, err := o.pg.Query(timeoutCtx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Invite, error) {
+		var invite Invite
+		err := row.Scan(
+			&invite.OrganizationID,
+			&invite.UserID,
+			&invite.InvitedBy,
+			&invite.Role,
+			&invite.CreatedAt,
+			&invite.Organization,
+			&invite.Inviter)
+
+		return invite, err
+	})
+}
+
+// AcceptInvite implements the AcceptInvite method of the OrganizationRepository interface.
+// It removes the invite of the user and makes the user a member of the organization with
+// the role of the invite in a single transaction, so the member gets the secrets of the collections
+// of the organization on sync. ErrNoRows is returned if there is no such invite.
+func (o *organizationRepo) AcceptInvite(ctx context.Context, organizationID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		if err := lockUsers(timeoutCtx, tx, []int{userID}); err != nil {
+			return err
+		}
+
+		var role string
+
+		err := tx.QueryRow(timeoutCtx, `
+DELETE FROM organization_invites
+WHERE organization_id = $1 AND user_id = $2
+RETURNING role
+`, organizationID, userID).Scan(&role)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		stmt := `
+INSERT INTO organization_members (organization_id, user_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (organization_id, user_id) DO NOTHING
+`
+
+		tag, err := tx.Exec(timeoutCtx, stmt, organizationID, userID, role)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		return notifyMembers(timeoutCtx, tx, organizationID, 0, []int{userID}, models.SecretEventCreated)
+	})
+}
+
+// DeleteInvite implements the DeleteInvite method of the OrganizationRepository interface.
+// It removes the invite of the user to the organization. ErrNoRows is returned if there is no such invite.
+func (o *organizationRepo) DeleteInvite(ctx context.Context, organizationID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM organization_invites
+WHERE organization_id = $1 AND user_id = $2
+`
+
+	tag, err := o.pg.Exec(timeoutCtx, stmt, organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// CreateCollection implements the CreateCollection method of the OrganizationRepository interface.
+// It stores a new collection of the organization and sets its ID on it.
+// ErrCollectionAlreadyExist is returned if the organization has a collection with the same name.
+func (o *organizationRepo) CreateCollection(ctx context.Context, collection *Collection) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO collections (organization_id, name)
+VALUES ($1, $2)
+RETURNING id
+`
+
+	err := o.pg.QueryRow(timeoutCtx, stmt, collection.OrganizationID, collection.Name).Scan(&collection.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationErrCode {
+			return ErrCollectionAlreadyExist
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// GetCollections implements the GetCollections method of the OrganizationRepository interface.
+// It retrieves the collections of the organization ordered by name.
+func (o *organizationRepo) GetCollections(ctx context.Context, organizationID int) ([]Collection, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT id, organization_id, name, created_at
+FROM collections
+WHERE organization_id = $1
+ORDER BY name
+`
+
+	rows, err := o.pg.Query(timeoutCtx, stmt, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Collection, error) {
+		var collection Collection
+		err := row.Scan(&collection.ID, &collection.OrganizationID, &collection.Name, &collection.CreatedAt)
+
+		return collection, err
+	})
+}
+
+// DeleteCollection implements the DeleteCollection method of the OrganizationRepository interface.
+// It leaves a tombstone of every secret of the collection for the members of the organization
+// and removes the collection, its secrets are removed by the database cascade.
+// ErrNoRows is returned if the organization has no such collection.
+func (o *organizationRepo) DeleteCollection(ctx context.Context, organizationID, collectionID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, o.pg, func(tx pgx.Tx) error {
+		members, err := memberIDs(timeoutCtx, tx, organizationID)
+		if err != nil {
+			return err
+		}
+
+		if err := lockUsers(timeoutCtx, tx, members); err != nil {
+			return err
+		}
+
+		err = notifyMembers(timeoutCtx, tx, organizationID, collectionID, members, models.SecretEventDeleted)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `DELETE FROM collections WHERE id = $1 AND organization_id = $2`,
+			collectionID, organizationID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		return nil
+	})
+}
+
+// memberIDs returns the IDs of the members of the organization within the transaction.
+func memberIDs(ctx context.Context, tx pgx.Tx, organizationID int) ([]int, error) {
+	rows, err := tx.Query(ctx, `SELECT user_id FROM organization_members WHERE organization_id = $1 ORDER BY user_id`,
+		organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// notifyMembers notifies the users about every secret not in the trash of the collections
+// of the organization, or of a single collection if collectionID is not zero, within the transaction.
+// The users must be locked with lockUsers before the organization is changed.
+func notifyMembers(ctx context.Context, tx pgx.Tx, organizationID, collectionID int, userIDs []int, kind string) error {
+	stmt := `
+SELECT s.id
+FROM secrets s
+JOIN collections c ON c.id = s.collection_id
+WHERE c.organization_id = $1 AND ($2 = 0 OR c.id = $2) AND s.deleted_at IS NULL
+ORDER BY s.id
+`
+
+	rows, err := tx.Query(ctx, stmt, organizationID, collectionID)
+	if err != nil {
+		return err
+	}
+
+	secretIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+
+	for _, secretID := range secretIDs {
+		if err := notifyRecipients(ctx, tx, secretID, userIDs, kind); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func scanMember(row pgx.Row) (*Member, error) {
+	var member Member
+
+	err := row.Scan(&member.OrganizationID, &member.UserID, &member.Login, &member.Role, &member.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return &member, nil
+}
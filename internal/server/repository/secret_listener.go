@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+// secretChangesChannel is the PostgreSQL notification channel the changes of secrets are sent to.
+const secretChangesChannel = "secret_changes"
+
+// SecretListener is an interface that defines a method for
+// receiving the changes of secrets committed to the database.
+type SecretListener interface {
+	Listen(ctx context.Context, handle func(event models.SecretEvent)) error
+}
+
+type secretListener struct {
+	pg *pgxpool.Pool
+}
+
+// NewSecretListener creates and returns an instance of SecretListener.
+func NewSecretListener(pg *pgxpool.Pool) SecretListener {
+	return &secretListener{
+		pg: pg,
+	}
+}
+
+// Listen implements the Listen method of the SecretListener interface.
+// It takes a dedicated connection out of the pool, listens for the changes of secrets
+// committed by any server instance and calls handle for each of them. It blocks until
+// the context is done or the connection fails, and always returns a non-nil error.
+func (l *secretListener) Listen(ctx context.Context, handle func(event models.SecretEvent)) error {
+	poolConn, err := l.pg.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn := poolConn.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, `LISTEN `+secretChangesChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event models.SecretEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			continue
+		}
+
+		handle(event)
+	}
+}
@@ -3,23 +3,73 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
 )
 
 // ErrNoRows is returned when no rows are found for a query.
 var ErrNoRows = errors.New("no rows were found")
 
+// revisionTxRetries is the number of times a transaction changing the revisions of users
+// is retried after PostgreSQL aborted it to resolve a deadlock or a serialization failure.
+const revisionTxRetries = 3
+
+// PostgreSQL error codes of the aborted transactions which may succeed when retried.
+const (
+	serializationFailureErrCode = "40001"
+	deadlockDetectedErrCode     = "40P01"
+)
+
 // SecretRepository is an interface that defines methods for
 // handling secret related operations in the database.
 type SecretRepository interface {
+	NextSecretID(ctx context.Context) (int, error)
+	ReserveSecretID(ctx context.Context, userID int) (int, error)
+	ClaimSecretID(ctx context.Context, secretID, userID int) error
+	DeleteExpiredReservations(ctx context.Context, reservedBefore time.Time) (int64, error)
 	Create(ctx context.Context, secret *Secret) error
-	GetUserSecrets(ctx context.Context, userID int) ([]Secret, error)
+	GetUserSecrets(ctx context.Context, userID int, filter SecretFilter) ([]Secret, error)
+	GetSecret(ctx context.Context, secretID, userID int) (*Secret, error)
 	UpdateSecret(ctx context.Context, secret *Secret) error
-	DeleteSecret(ctx context.Context, secretID, userID int) error
+	DeleteSecret(ctx context.Context, secretID, userID, version int) error
+	GetChanges(ctx context.Context, userID int, sinceRevision int64) (*Changes, error)
+	FindSecrets(ctx context.Context, userID int, indexes []BlindIndex) ([]Secret, error)
+	GetVersions(ctx context.Context, secretID, userID int) ([]Secret, error)
+	GetVersion(ctx context.Context, secretID, userID, version int) (*Secret, error)
+	DeleteExpiredVersions(ctx context.Context, archivedBefore time.Time, keep int) (int64, error)
+	GetTrash(ctx context.Context, userID int) ([]Secret, error)
+	RestoreSecret(ctx context.Context, secretID, userID int) error
+	PurgeSecret(ctx context.Context, secretID, userID int) error
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetShares(ctx context.Context, secretID int) ([]Share, error)
+	GetRecipient(ctx context.Context, login string) (*Recipient, error)
+	ShareSecret(ctx context.Context, share *Share, version int) error
+	UnshareSecret(ctx context.Context, secretID, ownerID, userID int) error
+	GetCollectionMember(ctx context.Context, collectionID, userID int) (*Member, error)
+	GetSecretMember(ctx context.Context, secretID, userID int) (*Member, error)
+	CreateCollectionSecret(ctx context.Context, secret *Secret) error
+	GetCollectionSecret(ctx context.Context, secretID int) (*Secret, error)
+	UpdateCollectionSecret(ctx context.Context, secret *Secret) error
+	DeleteCollectionSecret(ctx context.Context, secretID, version int) error
+	RestoreCollectionSecret(ctx context.Context, secretID int) error
+	PurgeCollectionSecret(ctx context.Context, secretID int) error
+	GetCollectionVersions(ctx context.Context, secretID int) ([]Secret, error)
+	GetCollectionVersion(ctx context.Context, secretID, version int) (*Secret, error)
+	GetSealedSecrets(ctx context.Context, afterID, limit int) ([]Secret, error)
+	GetSealedVersions(ctx context.Context, secretID int) ([]Secret, error)
+	ResealSecret(ctx context.Context, secret *Secret, previousContent []byte) error
+	ResealVersion(ctx context.Context, secret *Secret, previousContent []byte) error
+	IndexSecret(ctx context.Context, secret *Secret) error
 }
 
 type secretRepo struct {
@@ -35,131 +85,1891 @@ func NewSecretRepository(pg *pgxpool.Pool) SecretRepository {
 	return r
 }
 
+// NextSecretID implements the NextSecretID method of the SecretRepository interface.
+// It reserves an ID for a new secret, so the secret can be bound to its ID before it is stored.
+func (s *secretRepo) NextSecretID(ctx context.Context) (int, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `SELECT nextval(pg_get_serial_sequence('secrets', 'id'))`
+
+	var secretID int
+	if err := s.pg.QueryRow(timeoutCtx, stmt).Scan(&secretID); err != nil {
+		return 0, err
+	}
+
+	return secretID, nil
+}
+
+// ReserveSecretID implements the ReserveSecretID method of the SecretRepository interface.
+// It reserves an ID for a new secret of the user until the user creates the secret with it.
+func (s *secretRepo) ReserveSecretID(ctx context.Context, userID int) (int, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO secret_reservations (id, user_id)
+VALUES (nextval(pg_get_serial_sequence('secrets', 'id')), $1)
+RETURNING id
+`
+
+	var secretID int
+	if err := s.pg.QueryRow(timeoutCtx, stmt, userID).Scan(&secretID); err != nil {
+		return 0, err
+	}
+
+	return secretID, nil
+}
+
+// ClaimSecretID implements the ClaimSecretID method of the SecretRepository interface.
+// It removes the reservation of the ID made by the user with ReserveSecretID, so the ID
+// can be used once. ErrNoRows is returned if the user has not reserved the ID.
+func (s *secretRepo) ClaimSecretID(ctx context.Context, secretID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `DELETE FROM secret_reservations WHERE id = $1 AND user_id = $2`
+
+	tag, err := s.pg.Exec(timeoutCtx, stmt, secretID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteExpiredReservations implements the DeleteExpiredReservations method of the SecretRepository
+// interface. It removes the reservations made before the given time and returns their number.
+func (s *secretRepo) DeleteExpiredReservations(ctx context.Context, reservedBefore time.Time) (int64, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pg.Exec(timeoutCtx, `DELETE FROM secret_reservations WHERE created_at < $1`, reservedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
 // Create implements the Create method of the SecretRepository interface.
-// It stores a new secret in the PostgreSQL database.
+// It stores a new secret with the ID reserved by NextSecretID or ReserveSecretID in the PostgreSQL database.
+// ErrInvalidBlob is returned if the blob of the secret cannot be attached to it.
 func (s *secretRepo) Create(ctx context.Context, secret *Secret) error {
 	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
 	defer cancel()
 
 	stmt := `
 INSERT INTO secrets 
-    (user_id, 
+    (id,
+     user_id, 
      type, 
      content, 
-     meta_data)
-VALUES ($1, $2, $3, $4)
+     meta_data,
+     revision,
+     blob_id,
+     name,
+     tags,
+     folder,
+     blob_hash)
+VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, COALESCE($9, '{}'::TEXT[]), $10, $11)
+`
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		revision, err := nextRevision(timeoutCtx, tx, secret.UserID)
+		if err != nil {
+			return err
+		}
+
+		if err := attachBlob(timeoutCtx, tx, secret); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(timeoutCtx, stmt,
+			secret.ID,
+			secret.UserID,
+			secret.Type,
+			secret.Content,
+			secret.MetaData,
+			revision,
+			secret.BlobID,
+			secret.Name,
+			secret.Tags,
+			secret.Folder,
+			secret.BlobHash)
+		if err != nil {
+			return err
+		}
+
+		if err := saveIndexes(timeoutCtx, tx, secret); err != nil {
+			return err
+		}
+
+		return notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventCreated,
+			Revision: revision,
+			SecretID: secret.ID,
+			UserID:   secret.UserID,
+		})
+	})
+}
+
+// sharedSecretsSelect selects the secrets s along with the share sh of the user $1, the owner u
+// of a secret shared with the user, the collection c of a secret, the organization o owning it and
+// the membership m of the user in the organization in the columns scanned by scanSharedSecret.
+const sharedSecretsSelect = `
+SELECT s.id, 
+       COALESCE(s.user_id, 0), 
+       s.type, 
+       COALESCE(sh.content, s.content),
+       CASE WHEN sh.user_id IS NULL THEN s.meta_data ELSE sh.meta_data END,
+       s.created_at,
+       s.updated_at,
+       s.version,
+       COALESCE(s.blob_id, ''),
+       s.name,
+       s.tags,
+       s.folder,
+       COALESCE(sh.user_id, 0),
+       CASE
+           WHEN m.role IS NULL THEN COALESCE(sh.permission, '')
+           WHEN m.role = 'READ_ONLY' THEN 'READ_ONLY'
+           ELSE 'READ_WRITE'
+       END,
+       COALESCE(u.login, ''),
+       COALESCE(s.collection_id, 0),
+       COALESCE(c.organization_id, 0),
+       COALESCE(c.name, ''),
+       COALESCE(o.name, '')
+FROM secrets s
+LEFT JOIN secret_shares sh ON sh.secret_id = s.id AND sh.user_id = $1
+LEFT JOIN users u ON u.id = s.user_id AND sh.user_id IS NOT NULL
+LEFT JOIN collections c ON c.id = s.collection_id
+LEFT JOIN organizations o ON o.id = c.organization_id
+LEFT JOIN organization_members m ON m.organization_id = c.organization_id AND m.user_id = $1
+`
+
+// GetUserSecrets implements the GetUserSecrets method of the SecretRepository interface.
+// It retrieves the secrets of a specific user, the secrets shared with the user and the secrets
+// of the collections of the organizations the user is a member of matching the filter from
+// the PostgreSQL database, ordered by their creation.
+func (s *secretRepo) GetUserSecrets(ctx context.Context, userID int, filter SecretFilter) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	where, args := filterConditions(userID, filter)
+
+	stmt := sharedSecretsSelect + `WHERE ` + where + `
+ORDER BY s.created_at, s.id
 `
 
-	_, err := s.pg.Exec(timeoutCtx, stmt,
-		secret.UserID,
-		secret.Type,
-		secret.Content,
-		secret.MetaData)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		stmt += fmt.Sprintf("LIMIT $%d", len(args))
+	}
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, args...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return collectSecrets(rows, scanSharedSecret)
 }
 
-// GetUserSecrets implements the GetUserSecrets method of the SecretRepository interface.
-// It retrieves all secrets related to a specific user from the PostgreSQL database.
-func (s *secretRepo) GetUserSecrets(ctx context.Context, userID int) ([]Secret, error) {
+// filterConditions returns the conditions of the WHERE clause selecting the secrets s of the user,
+// the secrets shared with the user by sh and the secrets of the collections of the organizations
+// the user is a member m of matching the filter and the arguments of the conditions.
+func filterConditions(userID int, filter SecretFilter) (string, []any) {
+	conditions := []string{
+		"(s.user_id = $1 OR sh.user_id IS NOT NULL OR m.user_id IS NOT NULL)",
+		"s.deleted_at IS NULL",
+	}
+	args := []any{userID}
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Type != "" {
+		add("s.type = $%d", filter.Type)
+	}
+
+	if filter.Tag != "" {
+		add("s.tags @> ARRAY[$%d]::TEXT[]", filter.Tag)
+	}
+
+	if filter.Folder != "" {
+		add("s.folder = $%d", filter.Folder)
+	}
+
+	if filter.Name != "" {
+		add(`s.name ILIKE '%%' || $%d || '%%'`, likeEscaper.Replace(filter.Name))
+	}
+
+	if !filter.CreatedAfter.IsZero() {
+		add("s.created_at >= $%d", filter.CreatedAfter)
+	}
+
+	if !filter.CreatedBefore.IsZero() {
+		add("s.created_at < $%d", filter.CreatedBefore)
+	}
+
+	if !filter.UpdatedAfter.IsZero() {
+		add("s.updated_at >= $%d", filter.UpdatedAfter)
+	}
+
+	if !filter.UpdatedBefore.IsZero() {
+		add("s.updated_at < $%d", filter.UpdatedBefore)
+	}
+
+	if filter.AfterID != 0 {
+		args = append(args, filter.AfterCreatedAt, filter.AfterID)
+		conditions = append(conditions, fmt.Sprintf("(s.created_at, s.id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, so they match literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// FindSecrets implements the FindSecrets method of the SecretRepository interface.
+// It retrieves the secrets of the user having all the fields of the indexes. A field given
+// by several indexes matches any of their values.
+func (s *secretRepo) FindSecrets(ctx context.Context, userID int, indexes []BlindIndex) ([]Secret, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
 	defer cancel()
 
+	var fields []string
+	values := make(map[string][][]byte)
+
+	for _, index := range indexes {
+		if _, ok := values[index.Field]; !ok {
+			fields = append(fields, index.Field)
+		}
+
+		values[index.Field] = append(values[index.Field], index.Value)
+	}
+
+	conditions := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []any{userID}
+
+	for _, field := range fields {
+		args = append(args, field, values[field])
+		conditions = append(conditions, fmt.Sprintf(`id IN (
+    SELECT secret_id
+    FROM secret_indexes
+    WHERE user_id = $1 AND field = $%d AND value = ANY($%d)
+)`, len(args)-1, len(args)))
+	}
+
 	stmt := `
 SELECT id, 
        user_id, 
        type, 
        content,
        meta_data,
-       created_at
+       created_at,
+       updated_at,
+       version,
+       COALESCE(blob_id, ''),
+       name,
+       tags,
+       folder
 FROM secrets
-WHERE user_id = $1
-ORDER BY created_at
+WHERE ` + strings.Join(conditions, " AND ") + `
+ORDER BY created_at, id
 `
 
-	rows, err := s.pg.Query(timeoutCtx, stmt, userID)
+	rows, err := s.pg.Query(timeoutCtx, stmt, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
+	return scanSecrets(rows)
+}
 
-	var secrets []Secret
-	for rows.Next() {
-		var secret Secret
+// GetVersions implements the GetVersions method of the SecretRepository interface.
+// It retrieves the previous versions of the secret of the user from the PostgreSQL
+// database, the latest first. UpdatedAt of a version is the time it was written.
+func (s *secretRepo) GetVersions(ctx context.Context, secretID, userID int) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
 
-		err := rows.Scan(
-			&secret.ID,
-			&secret.UserID,
-			&secret.Type,
-			&secret.Content,
-			&secret.MetaData,
-			&secret.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
+	stmt := `
+SELECT v.secret_id, 
+       v.user_id, 
+       v.type, 
+       v.content,
+       v.meta_data,
+       s.created_at,
+       v.updated_at,
+       v.version,
+       COALESCE(v.blob_id, ''),
+       v.name,
+       v.tags,
+       v.folder
+FROM secret_versions v
+JOIN secrets s ON s.id = v.secret_id
+WHERE v.secret_id = $1 AND v.user_id = $2 AND s.deleted_at IS NULL
+ORDER BY v.version DESC
+`
 
-		secrets = append(secrets, secret)
+	rows, err := s.pg.Query(timeoutCtx, stmt, secretID, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
+	return scanSecrets(rows)
+}
+
+// GetVersion implements the GetVersion method of the SecretRepository interface.
+// It retrieves a previous version of the secret of the user from the PostgreSQL database.
+// ErrNoRows is returned if the version is not kept.
+func (s *secretRepo) GetVersion(ctx context.Context, secretID, userID, version int) (*Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT v.secret_id, 
+       v.user_id, 
+       v.type, 
+       v.content,
+       v.meta_data,
+       s.created_at,
+       v.updated_at,
+       v.version,
+       COALESCE(v.blob_id, ''),
+       v.name,
+       v.tags,
+       v.folder
+FROM secret_versions v
+JOIN secrets s ON s.id = v.secret_id
+WHERE v.secret_id = $1 AND v.user_id = $2 AND v.version = $3 AND s.deleted_at IS NULL
+`
+
+	secret, err := scanSecret(s.pg.QueryRow(timeoutCtx, stmt, secretID, userID, version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
 		return nil, err
 	}
 
-	return secrets, nil
+	return secret, nil
 }
 
-// UpdateSecret implements the UpdateSecret method of the SecretRepository interface.
-// It updates an existing secret in the PostgreSQL database.
-func (s *secretRepo) UpdateSecret(ctx context.Context, secret *Secret) error {
+// DeleteExpiredVersions implements the DeleteExpiredVersions method of the SecretRepository interface.
+// It removes the previous versions of secrets replaced before the given time and the versions
+// beyond the latest keep versions of every secret, and returns the number of removed versions.
+// The blobs of the removed versions are left unattached and are removed along with abandoned uploads.
+func (s *secretRepo) DeleteExpiredVersions(ctx context.Context, archivedBefore time.Time, keep int) (int64, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
 	defer cancel()
 
 	stmt := `
-UPDATE secrets 
-SET type = $1, content = $2, meta_data = $3
-WHERE id = $4 AND user_id = $5
+DELETE FROM secret_versions v
+WHERE v.archived_at < $1
+   OR v.version <= (
+       SELECT w.version
+       FROM secret_versions w
+       WHERE w.secret_id = v.secret_id
+       ORDER BY w.version DESC
+       OFFSET $2 LIMIT 1
+   )
 `
 
-	tag, err := s.pg.Exec(timeoutCtx, stmt,
-		secret.Type,
-		secret.Content,
-		secret.MetaData,
-		secret.ID,
-		secret.UserID)
+	tag, err := s.pg.Exec(timeoutCtx, stmt, archivedBefore, keep)
 	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// saveIndexes replaces the blind indexes of the secret with its Indexes.
+func saveIndexes(ctx context.Context, tx pgx.Tx, secret *Secret) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM secret_indexes WHERE secret_id = $1`, secret.ID); err != nil {
 		return err
 	}
 
-	if tag.RowsAffected() == 0 {
-		return ErrNoRows
+	stmt := `
+INSERT INTO secret_indexes (secret_id, user_id, field, value)
+VALUES ($1, $2, $3, $4)
+`
+
+	for _, index := range secret.Indexes {
+		if _, err := tx.Exec(ctx, stmt, secret.ID, secret.UserID, index.Field, index.Value); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// DeleteSecret implements the DeleteSecret method of the SecretRepository interface.
-// It removes a specific secret associated with a User ID from the PostgreSQL database.
-func (s *secretRepo) DeleteSecret(ctx context.Context, secretID, userID int) error {
+// GetSecret implements the GetSecret method of the SecretRepository interface.
+// It retrieves a specific secret of the user from the PostgreSQL database.
+// ErrNoRows is returned if the user has no such secret.
+func (s *secretRepo) GetSecret(ctx context.Context, secretID, userID int) (*Secret, error) {
 	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
 	defer cancel()
 
 	stmt := `
-DELETE FROM secrets 
-WHERE id = $1 AND user_id = $2
+SELECT id, 
+       user_id, 
+       type, 
+       content,
+       meta_data,
+       created_at,
+       updated_at,
+       version,
+       COALESCE(blob_id, ''),
+       name,
+       tags,
+       folder
+FROM secrets
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
-	tag, err := s.pg.Exec(timeoutCtx, stmt, secretID, userID)
+	secret, err := scanSecret(s.pg.QueryRow(timeoutCtx, stmt, secretID, userID))
 	if err != nil {
-		return err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
 	}
 
-	if tag.RowsAffected() == 0 {
-		return ErrNoRows
+	return secret, nil
+}
+
+// UpdateSecret implements the UpdateSecret method of the SecretRepository interface.
+// It updates an existing secret in the PostgreSQL database if the stored version matches
+// the version of the provided secret, and sets the incremented version on the secret.
+// The replaced version is kept in the secret_versions table along with its blob.
+// The shared copies of the secret are replaced with its Shares and the users the secret
+// is shared with are notified about the change.
+// ErrNoRows is returned if there is no such secret or its version differs,
+// ErrInvalidBlob if the blob of the secret cannot be attached to it.
+func (s *secretRepo) UpdateSecret(ctx context.Context, secret *Secret) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE secrets 
+SET type = $1,
+    content = $2,
+    meta_data = $3,
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP,
+    revision = $6,
+    blob_id = NULLIF($7, ''),
+    name = $8,
+    tags = COALESCE($9, '{}'::TEXT[]),
+    folder = $10,
+    blob_hash = $11
+WHERE id = $4 AND user_id = $5
+RETURNING version
+`
+
+	version := secret.Version
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secret.ID, secret.UserID); err != nil {
+			return err
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, secret.UserID)
+		if err != nil {
+			return err
+		}
+
+		var locked int
+
+		err = tx.QueryRow(timeoutCtx, `
+SELECT id
+FROM secrets
+WHERE id = $1 AND user_id = $2 AND version = $3 AND deleted_at IS NULL
+FOR UPDATE
+`, secret.ID, secret.UserID, version).Scan(&locked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		if err := archiveVersion(timeoutCtx, tx, secret.ID); err != nil {
+			return err
+		}
+
+		if err := attachBlob(timeoutCtx, tx, secret); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(timeoutCtx, stmt,
+			secret.Type,
+			secret.Content,
+			secret.MetaData,
+			secret.ID,
+			secret.UserID,
+			revision,
+			secret.BlobID,
+			secret.Name,
+			secret.Tags,
+			secret.Folder,
+			secret.BlobHash).Scan(&secret.Version)
+		if err != nil {
+			return err
+		}
+
+		if err := saveIndexes(timeoutCtx, tx, secret); err != nil {
+			return err
+		}
+
+		if err := saveShares(timeoutCtx, tx, secret); err != nil {
+			return err
+		}
+
+		err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventUpdated,
+			Revision: revision,
+			SecretID: secret.ID,
+			UserID:   secret.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		recipients, err := secretRecipients(timeoutCtx, tx, secret.ID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secret.ID, recipients, models.SecretEventUpdated)
+	})
+}
+
+// DeleteSecret implements the DeleteSecret method of the SecretRepository interface.
+// It moves a specific secret associated with a User ID to the trash if the stored version
+// matches the provided one, and leaves a tombstone of the secret, so other devices of the user
+// and the users the secret is shared with learn about the deletion on sync. The secret keeps
+// its versions, shares and blob until it is purged.
+// ErrNoRows is returned if there is no such secret or its version differs.
+func (s *secretRepo) DeleteSecret(ctx context.Context, secretID, userID, version int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secretID, userID); err != nil {
+			return err
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `
+UPDATE secrets
+SET deleted_at = CURRENT_TIMESTAMP,
+    revision = $4
+WHERE id = $1 AND user_id = $2 AND version = $3 AND deleted_at IS NULL
+`, secretID, userID, version, revision)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		stmt := `
+INSERT INTO secret_tombstones (secret_id, user_id, revision)
+VALUES ($1, $2, $3)
+`
+
+		if _, err = tx.Exec(timeoutCtx, stmt, secretID, userID, revision); err != nil {
+			return err
+		}
+
+		err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventDeleted,
+			Revision: revision,
+			SecretID: secretID,
+			UserID:   userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		recipients, err := secretRecipients(timeoutCtx, tx, secretID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secretID, recipients, models.SecretEventDeleted)
+	})
+}
+
+// GetTrash implements the GetTrash method of the SecretRepository interface.
+// It retrieves the secrets of the user in the trash and the secrets in the trash of the collections
+// of the organizations the user is a member of with a role allowing to write secrets from
+// the PostgreSQL database, the latest deleted first.
+func (s *secretRepo) GetTrash(ctx context.Context, userID int) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT s.id, 
+       COALESCE(s.user_id, 0), 
+       s.type, 
+       s.content,
+       s.meta_data,
+       s.created_at,
+       s.updated_at,
+       s.version,
+       COALESCE(s.blob_id, ''),
+       s.name,
+       s.tags,
+       s.folder,
+       s.deleted_at,
+       COALESCE(s.collection_id, 0),
+       COALESCE(c.organization_id, 0),
+       COALESCE(c.name, ''),
+       COALESCE(o.name, '')
+FROM secrets s
+LEFT JOIN collections c ON c.id = s.collection_id
+LEFT JOIN organizations o ON o.id = c.organization_id
+LEFT JOIN organization_members m ON m.organization_id = c.organization_id AND m.user_id = $1
+WHERE (s.user_id = $1 OR m.role IN ('OWNER', 'ADMIN', 'MEMBER')) AND s.deleted_at IS NOT NULL
+ORDER BY s.deleted_at DESC, s.id
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return collectSecrets(rows, scanTrashedSecret)
+}
+
+// RestoreSecret implements the RestoreSecret method of the SecretRepository interface.
+// It moves a specific secret of the user out of the trash and removes its tombstones,
+// so other devices of the user and the users the secret is shared with get the secret back on sync.
+// ErrNoRows is returned if the user has no such secret in the trash.
+func (s *secretRepo) RestoreSecret(ctx context.Context, secretID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secretID, userID); err != nil {
+			return err
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `
+UPDATE secrets
+SET deleted_at = NULL,
+    revision = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`, secretID, userID, revision)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		_, err = tx.Exec(timeoutCtx, `DELETE FROM secret_tombstones WHERE secret_id = $1 AND user_id = $2`,
+			secretID, userID)
+		if err != nil {
+			return err
+		}
+
+		err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventCreated,
+			Revision: revision,
+			SecretID: secretID,
+			UserID:   userID,
+		})
+		if err != nil {
+			return err
+		}
+
+		recipients, err := secretRecipients(timeoutCtx, tx, secretID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secretID, recipients, models.SecretEventCreated)
+	})
+}
+
+// PurgeSecret implements the PurgeSecret method of the SecretRepository interface.
+// It permanently removes a specific secret of the user in the trash from the PostgreSQL
+// database along with its blob. The versions of the secret are removed in cascade, their
+// blobs are left unattached and are removed along with abandoned uploads.
+// ErrNoRows is returned if the user has no such secret in the trash.
+func (s *secretRepo) PurgeSecret(ctx context.Context, secretID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		var blobID string
+
+		err := tx.QueryRow(timeoutCtx, `
+DELETE FROM secrets
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING COALESCE(blob_id, '')
+`, secretID, userID).Scan(&blobID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		if blobID != "" {
+			if _, err := tx.Exec(timeoutCtx, `DELETE FROM blobs WHERE id = $1`, blobID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PurgeTrash implements the PurgeTrash method of the SecretRepository interface.
+// It permanently removes the secrets moved to the trash before the given time along with
+// their blobs, and returns the number of removed secrets.
+func (s *secretRepo) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	var purged int64
+
+	err := pgx.BeginFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		rows, err := tx.Query(timeoutCtx, `
+DELETE FROM secrets
+WHERE deleted_at < $1
+RETURNING COALESCE(blob_id, '')
+`, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		blobIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return err
+		}
+
+		purged = int64(len(blobIDs))
+
+		_, err = tx.Exec(timeoutCtx, `DELETE FROM blobs WHERE id = ANY($1)`, blobIDs)
+
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
+
+// GetSealedSecrets implements the GetSealedSecrets method of the SecretRepository interface.
+// It retrieves up to limit secrets with IDs greater than afterID which the server encrypts with
+// the keys of their owners, including the secrets in the trash, ordered by ID.
+func (s *secretRepo) GetSealedSecrets(ctx context.Context, afterID, limit int) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT s.id, 
+       s.user_id, 
+       s.type, 
+       s.content,
+       s.meta_data,
+       s.created_at,
+       s.updated_at,
+       s.version,
+       COALESCE(s.blob_id, ''),
+       s.name,
+       s.tags,
+       s.folder
+FROM secrets s
+JOIN users u ON u.id = s.user_id
+WHERE s.id > $1 AND NOT u.client_side_encryption
+ORDER BY s.id
+LIMIT $2
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSecrets(rows)
+}
+
+// GetSealedVersions implements the GetSealedVersions method of the SecretRepository interface.
+// It retrieves the kept versions of the secret of a user, including the versions of a secret in the trash.
+func (s *secretRepo) GetSealedVersions(ctx context.Context, secretID int) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT v.secret_id, 
+       v.user_id, 
+       v.type, 
+       v.content,
+       v.meta_data,
+       s.created_at,
+       v.updated_at,
+       v.version,
+       COALESCE(v.blob_id, ''),
+       v.name,
+       v.tags,
+       v.folder
+FROM secret_versions v
+JOIN secrets s ON s.id = v.secret_id
+WHERE v.secret_id = $1 AND v.user_id IS NOT NULL
+ORDER BY v.version
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSecrets(rows)
+}
+
+// ResealSecret implements the ResealSecret method of the SecretRepository interface.
+// It replaces the encrypted content and meta data of the secret with the same data encrypted
+// in the current format. The version and revision of the secret stay the same, as the data
+// does not change. ErrNoRows is returned if the secret was changed in the meantime.
+func (s *secretRepo) ResealSecret(ctx context.Context, secret *Secret, previousContent []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pg.Exec(timeoutCtx, `
+UPDATE secrets
+SET content = $1,
+    meta_data = $2
+WHERE id = $3 AND content = $4
+`, secret.Content, secret.MetaData, secret.ID, previousContent)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// ResealVersion implements the ResealVersion method of the SecretRepository interface.
+// It replaces the encrypted content and meta data of the version of a secret with the same
+// data encrypted in the current format. ErrNoRows is returned if the version was removed
+// in the meantime.
+func (s *secretRepo) ResealVersion(ctx context.Context, secret *Secret, previousContent []byte) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pg.Exec(timeoutCtx, `
+UPDATE secret_versions
+SET content = $1,
+    meta_data = $2
+WHERE secret_id = $3 AND version = $4 AND content = $5
+`, secret.Content, secret.MetaData, secret.ID, secret.Version, previousContent)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// IndexSecret implements the IndexSecret method of the SecretRepository interface.
+// It stores the blind indexes of a secret which has none yet. ErrNoRows is returned if the
+// secret was changed or indexed in the meantime. The owner is locked first like on a change
+// of the secret, see lockUsers.
+func (s *secretRepo) IndexSecret(ctx context.Context, secret *Secret) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockUsers(timeoutCtx, tx, []int{secret.UserID}); err != nil {
+			return err
+		}
+
+		var secretID int
+
+		err := tx.QueryRow(timeoutCtx, `
+SELECT id
+FROM secrets
+WHERE id = $1 AND content = $2
+  AND NOT EXISTS (SELECT 1 FROM secret_indexes WHERE secret_id = $1)
+FOR UPDATE
+`, secret.ID, secret.Content).Scan(&secretID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNoRows
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return saveIndexes(timeoutCtx, tx, secret)
+	})
+}
+
+// GetShares implements the GetShares method of the SecretRepository interface.
+// It retrieves the shares of the secret without the shared copies.
+func (s *secretRepo) GetShares(ctx context.Context, secretID int) ([]Share, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT sh.secret_id, sh.user_id, s.user_id, sh.permission
+FROM secret_shares sh
+JOIN secrets s ON s.id = sh.secret_id
+WHERE sh.secret_id = $1
+ORDER BY sh.user_id
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (Share, error) {
+		var share Share
+		err := row.Scan(&share.SecretID, &share.UserID, &share.OwnerID, &share.Permission)
+
+		return share, err
+	})
+}
+
+// GetRecipient implements the GetRecipient method of the SecretRepository interface.
+// It retrieves the user with the login a secret can be shared with.
+// ErrNoRows is returned if there is no such user.
+func (s *secretRepo) GetRecipient(ctx context.Context, login string) (*Recipient, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	var recipient Recipient
+
+	err := s.pg.QueryRow(timeoutCtx, `SELECT id, client_side_encryption FROM users WHERE login = $1`, login).
+		Scan(&recipient.ID, &recipient.ClientSideEncryption)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return &recipient, nil
+}
+
+// ShareSecret implements the ShareSecret method of the SecretRepository interface.
+// It stores the share or replaces the share granted to the same user before if the stored
+// version of the secret matches the provided one, and notifies the user about the secret.
+// The version of the secret is incremented and the replaced version is kept like on update,
+// so a write based on the shares read before is rejected as a write of an outdated version.
+// ErrNoRows is returned if the owner has no such secret or its version differs.
+func (s *secretRepo) ShareSecret(ctx context.Context, share *Share, version int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockUsers(timeoutCtx, tx, []int{share.OwnerID, share.UserID}); err != nil {
+			return err
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, share.OwnerID)
+		if err != nil {
+			return err
+		}
+
+		var locked int
+
+		err = tx.QueryRow(timeoutCtx, `
+SELECT id
+FROM secrets
+WHERE id = $1 AND user_id = $2 AND version = $3 AND deleted_at IS NULL
+FOR UPDATE
+`, share.SecretID, share.OwnerID, version).Scan(&locked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		if err := archiveVersion(timeoutCtx, tx, share.SecretID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(timeoutCtx, `UPDATE secrets SET version = version + 1, revision = $2 WHERE id = $1`,
+			share.SecretID, revision)
+		if err != nil {
+			return err
+		}
+
+		stmt := `
+INSERT INTO secret_shares (secret_id, user_id, permission, content, meta_data)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (secret_id, user_id) DO UPDATE
+SET permission = EXCLUDED.permission,
+    content = EXCLUDED.content,
+    meta_data = EXCLUDED.meta_data
+RETURNING xmax = 0
+`
+
+		var inserted bool
+
+		err = tx.QueryRow(timeoutCtx, stmt, share.SecretID, share.UserID, share.Permission, share.Content, share.MetaData).
+			Scan(&inserted)
+		if err != nil {
+			return err
+		}
+
+		err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventUpdated,
+			Revision: revision,
+			SecretID: share.SecretID,
+			UserID:   share.OwnerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		kind := models.SecretEventUpdated
+		if inserted {
+			kind = models.SecretEventCreated
+		}
+
+		return notifyRecipients(timeoutCtx, tx, share.SecretID, []int{share.UserID}, kind)
+	})
+}
+
+// UnshareSecret implements the UnshareSecret method of the SecretRepository interface.
+// It removes the share of the secret of the owner granted to the user along with the shared
+// copy, leaves a tombstone of the secret for the user and increments the version of the secret,
+// see ShareSecret.
+// ErrNoRows is returned if the secret of the owner is not shared with the user.
+func (s *secretRepo) UnshareSecret(ctx context.Context, secretID, ownerID, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockUsers(timeoutCtx, tx, []int{ownerID, userID}); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `
+DELETE FROM secret_shares sh
+USING secrets s
+WHERE sh.secret_id = $1 AND sh.user_id = $3 AND s.id = sh.secret_id AND s.user_id = $2
+`, secretID, ownerID, userID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		revision, err := nextRevision(timeoutCtx, tx, ownerID)
+		if err != nil {
+			return err
+		}
+
+		if err := archiveVersion(timeoutCtx, tx, secretID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(timeoutCtx, `UPDATE secrets SET version = version + 1, revision = $2 WHERE id = $1`,
+			secretID, revision)
+		if err != nil {
+			return err
+		}
+
+		err = notifyChange(timeoutCtx, tx, models.SecretEvent{
+			Kind:     models.SecretEventUpdated,
+			Revision: revision,
+			SecretID: secretID,
+			UserID:   ownerID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secretID, []int{userID}, models.SecretEventDeleted)
+	})
+}
+
+// saveShares replaces the shared copies of the secret with the copies of its Shares.
+func saveShares(ctx context.Context, tx pgx.Tx, secret *Secret) error {
+	stmt := `
+UPDATE secret_shares
+SET content = $3,
+    meta_data = $4
+WHERE secret_id = $1 AND user_id = $2
+`
+
+	for _, share := range secret.Shares {
+		if _, err := tx.Exec(ctx, stmt, secret.ID, share.UserID, share.Content, share.MetaData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCollectionMember implements the GetCollectionMember method of the SecretRepository interface.
+// It retrieves the membership of the user in the organization owning the collection.
+// ErrNoRows is returned if there is no such collection or the user is not a member of its organization.
+func (s *secretRepo) GetCollectionMember(ctx context.Context, collectionID, userID int) (*Member, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+FROM collections c
+JOIN organization_members m ON m.organization_id = c.organization_id
+JOIN users u ON u.id = m.user_id
+WHERE c.id = $1 AND m.user_id = $2
+`
+
+	return scanMember(s.pg.QueryRow(timeoutCtx, stmt, collectionID, userID))
+}
+
+// GetSecretMember implements the GetSecretMember method of the SecretRepository interface.
+// It retrieves the membership of the user in the organization owning the collection of the secret.
+// ErrNoRows is returned if the secret is not in a collection or the user is not a member
+// of its organization.
+func (s *secretRepo) GetSecretMember(ctx context.Context, secretID, userID int) (*Member, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT m.organization_id, m.user_id, u.login, m.role, m.created_at
+FROM secrets s
+JOIN collections c ON c.id = s.collection_id
+JOIN organization_members m ON m.organization_id = c.organization_id
+JOIN users u ON u.id = m.user_id
+WHERE s.id = $1 AND m.user_id = $2
+`
+
+	return scanMember(s.pg.QueryRow(timeoutCtx, stmt, secretID, userID))
+}
+
+// CreateCollectionSecret implements the CreateCollectionSecret method of the SecretRepository interface.
+// It stores a new secret of the collection with the ID reserved by NextSecretID and notifies
+// the members of the organization owning the collection about it. Secrets of collections belong
+// to no user, so they get no revision of their own, but a revision of every member, and no blind indexes.
+func (s *secretRepo) CreateCollectionSecret(ctx context.Context, secret *Secret) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO secrets 
+    (id,
+     collection_id, 
+     type, 
+     content, 
+     meta_data,
+     revision,
+     name,
+     tags,
+     folder)
+VALUES ($1, $2, $3, $4, $5, 0, $6, COALESCE($7, '{}'::TEXT[]), $8)
+`
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		_, err := tx.Exec(timeoutCtx, stmt,
+			secret.ID,
+			secret.CollectionID,
+			secret.Type,
+			secret.Content,
+			secret.MetaData,
+			secret.Name,
+			secret.Tags,
+			secret.Folder)
+		if err != nil {
+			return err
+		}
+
+		members, err := secretRecipients(timeoutCtx, tx, secret.ID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secret.ID, members, models.SecretEventCreated)
+	})
+}
+
+// GetCollectionSecret implements the GetCollectionSecret method of the SecretRepository interface.
+// It retrieves a specific secret of a collection along with the organization owning the collection.
+// ErrNoRows is returned if there is no such secret in a collection.
+func (s *secretRepo) GetCollectionSecret(ctx context.Context, secretID int) (*Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT s.id, 
+       0, 
+       s.type, 
+       s.content,
+       s.meta_data,
+       s.created_at,
+       s.updated_at,
+       s.version,
+       '',
+       s.name,
+       s.tags,
+       s.folder,
+       s.collection_id,
+       c.organization_id
+FROM secrets s
+JOIN collections c ON c.id = s.collection_id
+WHERE s.id = $1 AND s.deleted_at IS NULL
+`
+
+	secret, err := scanCollectionSecret(s.pg.QueryRow(timeoutCtx, stmt, secretID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// UpdateCollectionSecret implements the UpdateCollectionSecret method of the SecretRepository interface.
+// It updates an existing secret of a collection if the stored version matches the version
+// of the provided secret, sets the incremented version on the secret and notifies the members
+// of the organization owning the collection about the change. The replaced version is kept
+// in the secret_versions table. ErrNoRows is returned if there is no such secret in a collection
+// or its version differs.
+func (s *secretRepo) UpdateCollectionSecret(ctx context.Context, secret *Secret) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE secrets 
+SET type = $1,
+    content = $2,
+    meta_data = $3,
+    version = version + 1,
+    updated_at = CURRENT_TIMESTAMP,
+    name = $5,
+    tags = COALESCE($6, '{}'::TEXT[]),
+    folder = $7
+WHERE id = $4
+RETURNING version
+`
+
+	version := secret.Version
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secret.ID); err != nil {
+			return err
+		}
+
+		var locked int
+
+		err := tx.QueryRow(timeoutCtx, `
+SELECT id
+FROM secrets
+WHERE id = $1 AND collection_id IS NOT NULL AND version = $2 AND deleted_at IS NULL
+FOR UPDATE
+`, secret.ID, version).Scan(&locked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		if err := archiveVersion(timeoutCtx, tx, secret.ID); err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(timeoutCtx, stmt,
+			secret.Type,
+			secret.Content,
+			secret.MetaData,
+			secret.ID,
+			secret.Name,
+			secret.Tags,
+			secret.Folder).Scan(&secret.Version)
+		if err != nil {
+			return err
+		}
+
+		members, err := secretRecipients(timeoutCtx, tx, secret.ID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secret.ID, members, models.SecretEventUpdated)
+	})
+}
+
+// DeleteCollectionSecret implements the DeleteCollectionSecret method of the SecretRepository interface.
+// It moves a specific secret of a collection to the trash if the stored version matches the provided one,
+// and leaves a tombstone of the secret for every member of the organization owning the collection.
+// The secret keeps its versions until it is purged.
+// ErrNoRows is returned if there is no such secret in a collection or its version differs.
+func (s *secretRepo) DeleteCollectionSecret(ctx context.Context, secretID, version int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secretID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `
+UPDATE secrets
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND collection_id IS NOT NULL AND version = $2 AND deleted_at IS NULL
+`, secretID, version)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		members, err := secretRecipients(timeoutCtx, tx, secretID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secretID, members, models.SecretEventDeleted)
+	})
+}
+
+// RestoreCollectionSecret implements the RestoreCollectionSecret method of the SecretRepository interface.
+// It moves a specific secret of a collection out of the trash, so the members of the organization
+// owning the collection get the secret back on sync.
+// ErrNoRows is returned if there is no such secret of a collection in the trash.
+func (s *secretRepo) RestoreCollectionSecret(ctx context.Context, secretID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return beginRevisionFunc(timeoutCtx, s.pg, func(tx pgx.Tx) error {
+		if err := lockSecretUsers(timeoutCtx, tx, secretID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(timeoutCtx, `
+UPDATE secrets
+SET deleted_at = NULL
+WHERE id = $1 AND collection_id IS NOT NULL AND deleted_at IS NOT NULL
+`, secretID)
+		if err != nil {
+			return err
+		}
+
+		if tag.RowsAffected() == 0 {
+			return ErrNoRows
+		}
+
+		members, err := secretRecipients(timeoutCtx, tx, secretID)
+		if err != nil {
+			return err
+		}
+
+		return notifyRecipients(timeoutCtx, tx, secretID, members, models.SecretEventCreated)
+	})
+}
+
+// PurgeCollectionSecret implements the PurgeCollectionSecret method of the SecretRepository interface.
+// It permanently removes a specific secret of a collection in the trash from the PostgreSQL database.
+// The versions of the secret are removed in cascade.
+// ErrNoRows is returned if there is no such secret of a collection in the trash.
+func (s *secretRepo) PurgeCollectionSecret(ctx context.Context, secretID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	tag, err := s.pg.Exec(timeoutCtx, `
+DELETE FROM secrets
+WHERE id = $1 AND collection_id IS NOT NULL AND deleted_at IS NOT NULL
+`, secretID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// GetCollectionVersions implements the GetCollectionVersions method of the SecretRepository interface.
+// It retrieves the previous versions of a secret of a collection along with the organization
+// owning the collection from the PostgreSQL database, the latest first.
+func (s *secretRepo) GetCollectionVersions(ctx context.Context, secretID int) ([]Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := collectionVersionsSelect + `
+WHERE v.secret_id = $1 AND s.deleted_at IS NULL
+ORDER BY v.version DESC
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectSecrets(rows, scanCollectionSecret)
+}
+
+// GetCollectionVersion implements the GetCollectionVersion method of the SecretRepository interface.
+// It retrieves a previous version of a secret of a collection along with the organization owning
+// the collection from the PostgreSQL database. ErrNoRows is returned if the version is not kept.
+func (s *secretRepo) GetCollectionVersion(ctx context.Context, secretID, version int) (*Secret, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := collectionVersionsSelect + `
+WHERE v.secret_id = $1 AND v.version = $2 AND s.deleted_at IS NULL
+`
+
+	secret, err := scanCollectionSecret(s.pg.QueryRow(timeoutCtx, stmt, secretID, version))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// collectionVersionsSelect selects the versions v of the secrets s of the collections c
+// in the columns scanned by scanCollectionSecret.
+const collectionVersionsSelect = `
+SELECT v.secret_id, 
+       0, 
+       v.type, 
+       v.content,
+       v.meta_data,
+       s.created_at,
+       v.updated_at,
+       v.version,
+       '',
+       v.name,
+       v.tags,
+       v.folder,
+       s.collection_id,
+       c.organization_id
+FROM secret_versions v
+JOIN secrets s ON s.id = v.secret_id
+JOIN collections c ON c.id = s.collection_id`
+
+// GetChanges implements the GetChanges method of the SecretRepository interface.
+// It retrieves the secrets of the user, the secrets shared with the user and the secrets of
+// the collections of the user's organizations changed after the given revision, the IDs of
+// the secrets deleted or made unavailable to the user after it and the current revision of the user.
+// All of them are read from the same snapshot, so no change is missed by the next call with
+// the returned revision.
+func (s *secretRepo) GetChanges(ctx context.Context, userID int, sinceRevision int64) (*Changes, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	txOptions := pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	}
+
+	var changes Changes
+
+	err := pgx.BeginTxFunc(timeoutCtx, s.pg, txOptions, func(tx pgx.Tx) error {
+		err := tx.QueryRow(timeoutCtx, `SELECT revision FROM users WHERE id = $1`, userID).
+			Scan(&changes.Revision)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoRows
+			}
+
+			return err
+		}
+
+		stmt := sharedSecretsSelect + `
+LEFT JOIN secret_revisions r ON r.secret_id = s.id AND r.user_id = $1
+WHERE s.deleted_at IS NULL
+  AND ((s.user_id = $1 AND s.revision > $2)
+    OR ((sh.user_id IS NOT NULL OR m.user_id IS NOT NULL) AND r.revision > $2))
+ORDER BY s.created_at, s.id
+`
+
+		rows, err := tx.Query(timeoutCtx, stmt, userID, sinceRevision)
+		if err != nil {
+			return err
+		}
+
+		if changes.Secrets, err = collectSecrets(rows, scanSharedSecret); err != nil {
+			return err
+		}
+
+		stmt = `
+SELECT secret_id
+FROM secret_tombstones
+WHERE user_id = $1 AND revision > $2
+`
+
+		rows, err = tx.Query(timeoutCtx, stmt, userID, sinceRevision)
+		if err != nil {
+			return err
+		}
+
+		changes.DeletedIDs, err = pgx.CollectRows(rows, pgx.RowTo[int])
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &changes, nil
+}
+
+// beginRevisionFunc runs fn in a transaction like pgx.BeginFunc and retries the transaction
+// if PostgreSQL aborted it to resolve a deadlock or a serialization failure. It is used by
+// the transactions changing the revisions of users, fn must not keep any state between
+// the attempts.
+func beginRevisionFunc(ctx context.Context, db *pgxpool.Pool, fn func(pgx.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := pgx.BeginFunc(ctx, db, fn)
+
+		var pgErr *pgconn.PgError
+		if attempt == revisionTxRetries || !errors.As(err, &pgErr) ||
+			(pgErr.Code != deadlockDetectedErrCode && pgErr.Code != serializationFailureErrCode) {
+			return err
+		}
+	}
+}
+
+// lockUsers locks the rows of the users within the transaction in the order of their IDs.
+// A transaction changing the revisions of several users locks all of them at once before
+// it locks anything else, so the transactions changing the revisions of the same users
+// wait for each other instead of deadlocking.
+func lockUsers(ctx context.Context, tx pgx.Tx, userIDs []int) error {
+	_, err := tx.Exec(ctx, `SELECT id FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE`, userIDs)
+
+	return err
+}
+
+// lockSecretUsers locks the rows of the users the secret is available to within the transaction
+// along with the rows of the given users, see lockUsers.
+func lockSecretUsers(ctx context.Context, tx pgx.Tx, secretID int, userIDs ...int) error {
+	recipients, err := secretRecipients(ctx, tx, secretID)
+	if err != nil {
+		return err
+	}
+
+	return lockUsers(ctx, tx, append(recipients, userIDs...))
+}
+
+// nextRevision increments the revision of the user within the transaction and returns it.
+// The user row stays locked until the transaction ends, so the changes of the user
+// are committed in the order of their revisions. The users whose revisions are changed
+// together must be locked with lockUsers first.
+func nextRevision(ctx context.Context, tx pgx.Tx, userID int) (int64, error) {
+	var revision int64
+
+	err := tx.QueryRow(ctx, `UPDATE users SET revision = revision + 1 WHERE id = $1 RETURNING revision`, userID).
+		Scan(&revision)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNoRows
+		}
+
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+// archiveVersion keeps the current version of the secret in the secret_versions table
+// within the transaction, before the version is incremented.
+func archiveVersion(ctx context.Context, tx pgx.Tx, secretID int) error {
+	_, err := tx.Exec(ctx, `
+INSERT INTO secret_versions
+    (secret_id, user_id, version, type, content, meta_data, blob_id, blob_hash, name, tags, folder, updated_at)
+SELECT id, user_id, version, type, content, meta_data, blob_id, blob_hash, name, tags, folder, updated_at
+FROM secrets
+WHERE id = $1
+`, secretID)
+
+	return err
+}
+
+// secretRecipients returns the users the secret is available to besides its owner within
+// the transaction: the users the secret is shared with or the members of the organization
+// owning the collection of the secret.
+func secretRecipients(ctx context.Context, tx pgx.Tx, secretID int) ([]int, error) {
+	stmt := `
+SELECT user_id
+FROM secret_shares
+WHERE secret_id = $1
+UNION
+SELECT m.user_id
+FROM secrets s
+JOIN collections c ON c.id = s.collection_id
+JOIN organization_members m ON m.organization_id = c.organization_id
+WHERE s.id = $1
+ORDER BY user_id
+`
+
+	rows, err := tx.Query(ctx, stmt, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[int])
+}
+
+// notifyRecipients records the change of the secret for every one of the users other than
+// its owner: each user gets a new revision of the secret, or a tombstone if the change
+// is a deletion, and is notified about the change like about a change of the user's own secret.
+// The users who are not locked yet are locked first, see lockUsers.
+func notifyRecipients(ctx context.Context, tx pgx.Tx, secretID int, userIDs []int, kind string) error {
+	if err := lockUsers(ctx, tx, userIDs); err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		revision, err := nextRevision(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if kind == models.SecretEventDeleted {
+			_, err = tx.Exec(ctx, `DELETE FROM secret_revisions WHERE secret_id = $1 AND user_id = $2`, secretID, userID)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, `
+INSERT INTO secret_tombstones (secret_id, user_id, revision)
+VALUES ($1, $2, $3)
+ON CONFLICT (secret_id, user_id) DO UPDATE
+SET revision = EXCLUDED.revision,
+    deleted_at = CURRENT_TIMESTAMP
+`, secretID, userID, revision)
+		} else {
+			_, err = tx.Exec(ctx, `DELETE FROM secret_tombstones WHERE secret_id = $1 AND user_id = $2`, secretID, userID)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx, `
+INSERT INTO secret_revisions (secret_id, user_id, revision)
+VALUES ($1, $2, $3)
+ON CONFLICT (secret_id, user_id) DO UPDATE
+SET revision = EXCLUDED.revision
+`, secretID, userID, revision)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = notifyChange(ctx, tx, models.SecretEvent{
+			Kind:     kind,
+			Revision: revision,
+			SecretID: secretID,
+			UserID:   userID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifyChange notifies the listeners of secretChangesChannel about the change of the secret.
+// The notification is delivered only if the transaction is committed.
+func notifyChange(ctx context.Context, tx pgx.Tx, event models.SecretEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `SELECT pg_notify($1, $2)`, secretChangesChannel, string(payload))
+
+	return err
+}
+
+func scanSecret(row pgx.Row) (*Secret, error) {
+	var secret Secret
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.UserID,
+		&secret.Type,
+		&secret.Content,
+		&secret.MetaData,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+		&secret.Version,
+		&secret.BlobID,
+		&secret.Name,
+		&secret.Tags,
+		&secret.Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+func scanSecrets(rows pgx.Rows) ([]Secret, error) {
+	return collectSecrets(rows, scanSecret)
+}
+
+// scanTrashedSecret scans the columns of scanSecret followed by deleted_at and the collection
+// columns, which are the collection, its organization and their names.
+func scanTrashedSecret(row pgx.Row) (*Secret, error) {
+	var secret Secret
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.UserID,
+		&secret.Type,
+		&secret.Content,
+		&secret.MetaData,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+		&secret.Version,
+		&secret.BlobID,
+		&secret.Name,
+		&secret.Tags,
+		&secret.Folder,
+		&secret.DeletedAt,
+		&secret.CollectionID,
+		&secret.OrganizationID,
+		&secret.Collection,
+		&secret.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// scanSharedSecret scans the columns of scanSecret followed by the share columns, which are
+// the user the secret is shared with, the permission and the owner login, and the collection
+// columns, which are the collection, its organization and their names.
+func scanSharedSecret(row pgx.Row) (*Secret, error) {
+	var secret Secret
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.UserID,
+		&secret.Type,
+		&secret.Content,
+		&secret.MetaData,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+		&secret.Version,
+		&secret.BlobID,
+		&secret.Name,
+		&secret.Tags,
+		&secret.Folder,
+		&secret.SharedWith,
+		&secret.Permission,
+		&secret.Owner,
+		&secret.CollectionID,
+		&secret.OrganizationID,
+		&secret.Collection,
+		&secret.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// scanCollectionSecret scans the columns of scanSecret followed by the collection
+// of the secret and the organization owning it.
+func scanCollectionSecret(row pgx.Row) (*Secret, error) {
+	var secret Secret
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.UserID,
+		&secret.Type,
+		&secret.Content,
+		&secret.MetaData,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+		&secret.Version,
+		&secret.BlobID,
+		&secret.Name,
+		&secret.Tags,
+		&secret.Folder,
+		&secret.CollectionID,
+		&secret.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secret, nil
+}
+
+// collectSecrets scans every row with the scan function and closes the rows.
+func collectSecrets(rows pgx.Rows, scan func(row pgx.Row) (*Secret, error)) ([]Secret, error) {
+	defer rows.Close()
+
+	var secrets []Secret
+	for rows.Next() {
+		secret, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		secrets = append(secrets, *secret)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
 }
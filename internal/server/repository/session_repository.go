@@ -0,0 +1,175 @@
+// Package repository provides an abstraction over users and secrets databases.
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+// SessionRepository is an interface that defines methods for
+// storing the sessions of users.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session Session) error
+	ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	GetUserSessions(ctx context.Context, userID int) ([]Session, error)
+	DeleteSession(ctx context.Context, sessionID string, userID int) error
+	DeleteOtherSessions(ctx context.Context, userID int, keepSessionID string) error
+}
+
+type sessionRepo struct {
+	pg *pgxpool.Pool
+}
+
+// NewSessionRepository creates and returns an instance of SessionRepository.
+func NewSessionRepository(pg *pgxpool.Pool) SessionRepository {
+	r := &sessionRepo{
+		pg: pg,
+	}
+
+	return r
+}
+
+// CreateSession implements the CreateSession method of the SessionRepository interface.
+// It stores a new session in the PostgreSQL database.
+func (s *sessionRepo) CreateSession(ctx context.Context, session Session) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+INSERT INTO sessions (id, user_id, expires_at)
+VALUES ($1, $2, $3)
+`
+
+	_, err := s.pg.Exec(timeoutCtx, stmt, session.ID, session.UserID, session.ExpiresAt)
+
+	return err
+}
+
+// ExtendSession implements the ExtendSession method of the SessionRepository interface.
+// It moves the expiration time of an active session, ErrNoRows is returned if the
+// session is revoked or expired.
+func (s *sessionRepo) ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+UPDATE sessions
+SET expires_at = $1
+WHERE id = $2 AND expires_at > CURRENT_TIMESTAMP
+`
+
+	tag, err := s.pg.Exec(timeoutCtx, stmt, expiresAt, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// IsSessionActive implements the IsSessionActive method of the SessionRepository interface.
+// It reports whether the session exists and is not expired.
+func (s *sessionRepo) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT EXISTS (
+    SELECT 1 FROM sessions
+    WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP
+)
+`
+
+	var active bool
+	if err := s.pg.QueryRow(timeoutCtx, stmt, sessionID).Scan(&active); err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
+
+// GetUserSessions implements the GetUserSessions method of the SessionRepository interface.
+// It retrieves the active sessions of the user from the PostgreSQL database.
+func (s *sessionRepo) GetUserSessions(ctx context.Context, userID int) ([]Session, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+SELECT id, user_id, expires_at, created_at
+FROM sessions
+WHERE user_id = $1 AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at
+`
+
+	rows, err := s.pg.Query(timeoutCtx, stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(&session.ID, &session.UserID, &session.ExpiresAt, &session.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession implements the DeleteSession method of the SessionRepository interface.
+// It removes the session of the user along with its refresh tokens.
+func (s *sessionRepo) DeleteSession(ctx context.Context, sessionID string, userID int) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM sessions
+WHERE id = $1 AND user_id = $2
+`
+
+	tag, err := s.pg.Exec(timeoutCtx, stmt, sessionID, userID)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteOtherSessions implements the DeleteOtherSessions method of the SessionRepository interface.
+// It removes all sessions of the user except the given one.
+func (s *sessionRepo) DeleteOtherSessions(ctx context.Context, userID int, keepSessionID string) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM sessions
+WHERE user_id = $1 AND id <> $2
+`
+
+	_, err := s.pg.Exec(timeoutCtx, stmt, userID, keepSessionID)
+
+	return err
+}
@@ -0,0 +1,91 @@
+// Package repository provides an abstraction over users and secrets databases.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository/pg"
+)
+
+// TokenRepository is an interface that defines methods for
+// storing the refresh tokens of users.
+type TokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token RefreshToken) error
+	TakeRefreshToken(ctx context.Context, tokenHash []byte) (*RefreshToken, error)
+}
+
+type tokenRepo struct {
+	pg *pgxpool.Pool
+}
+
+// NewTokenRepository creates and returns an instance of TokenRepository.
+func NewTokenRepository(pg *pgxpool.Pool) TokenRepository {
+	r := &tokenRepo{
+		pg: pg,
+	}
+
+	return r
+}
+
+// SaveRefreshToken implements the SaveRefreshToken method of the TokenRepository interface.
+// It stores the refresh token and removes the expired refresh tokens of the same user.
+func (t *tokenRepo) SaveRefreshToken(ctx context.Context, token RefreshToken) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	return pgx.BeginFunc(timeoutCtx, t.pg, func(tx pgx.Tx) error {
+		stmt := `
+DELETE FROM refresh_tokens
+WHERE user_id = $1 AND expires_at <= CURRENT_TIMESTAMP
+`
+
+		if _, err := tx.Exec(timeoutCtx, stmt, token.UserID); err != nil {
+			return err
+		}
+
+		stmt = `
+INSERT INTO refresh_tokens (user_id, session_id, token_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+`
+
+		_, err := tx.Exec(timeoutCtx, stmt, token.UserID, token.SessionID, token.TokenHash, token.ExpiresAt)
+
+		return err
+	})
+}
+
+// TakeRefreshToken implements the TakeRefreshToken method of the TokenRepository interface.
+// It removes the refresh token with the given hash and returns it, so every refresh token
+// can be used only once. ErrNoRows is returned if there is no such token or it is expired.
+func (t *tokenRepo) TakeRefreshToken(ctx context.Context, tokenHash []byte) (*RefreshToken, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, pg.DefaultQueryTimeout)
+	defer cancel()
+
+	stmt := `
+DELETE FROM refresh_tokens
+WHERE token_hash = $1
+RETURNING user_id, session_id, token_hash, expires_at
+`
+
+	var token RefreshToken
+	err := t.pg.QueryRow(timeoutCtx, stmt, tokenHash).
+		Scan(&token.UserID, &token.SessionID, &token.TokenHash, &token.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoRows
+		}
+
+		return nil, err
+	}
+
+	if !token.ExpiresAt.After(time.Now()) {
+		return nil, ErrNoRows
+	}
+
+	return &token, nil
+}
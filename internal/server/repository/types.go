@@ -3,11 +3,183 @@ package repository
 import "time"
 
 // Secret is a struct that represents a Secret created by a User.
+// Version is incremented on every write of the secret. BlobID is the ID of the
+// attached Blob or an empty string, BlobHash the hash of the blob's content which is
+// set when the blob is attached. Name, Tags and Folder are stored unencrypted.
+// Indexes replace the blind indexes of the secret on every write, they are not read back.
+// DeletedAt is the time the secret was moved to the trash, it is read only along with the trash.
+// Shares replace the copies of the secret shared with other users on every write.
+// A secret read for a user it is shared with has the ID of the user in SharedWith, the Permission
+// granted to the user and the login of its Owner, its Content and MetaData are the copy of the user.
+// A secret of a collection has no UserID, but the CollectionID and the OrganizationID
+// owning the collection. Read for a member, it has the names of its Collection and
+// Organization and the Permission following the role of the member.
 type Secret struct {
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      time.Time
+	Type           string
+	BlobID         string
+	Name           string
+	Folder         string
+	Permission     string
+	Owner          string
+	Collection     string
+	Organization   string
+	Content        []byte
+	MetaData       []byte
+	BlobHash       []byte
+	Tags           []string
+	Indexes        []BlindIndex
+	Shares         []Share
+	ID             int
+	UserID         int
+	Version        int
+	SharedWith     int
+	CollectionID   int
+	OrganizationID int
+}
+
+// Share is a struct that represents the access to a Secret granted by its owner to another User.
+// Content and MetaData are the copy of the secret encrypted with the key of the User,
+// they are not read back along with the shares of a secret.
+type Share struct {
+	Permission string
+	Content    []byte
+	MetaData   []byte
+	SecretID   int
+	UserID     int
+	OwnerID    int
+}
+
+// Recipient is a struct that represents a User a Secret can be shared with
+// or who can be invited to an Organization.
+type Recipient struct {
+	ID                   int
+	ClientSideEncryption bool
+}
+
+// Organization is a struct that represents a team vault owning collections of secrets.
+// WrappedKey is the data encryption key of the organization wrapped by the master key of KeyVersion,
+// it is not read back along with organizations. Role is the role of the User the organization is read for.
+type Organization struct {
+	CreatedAt  time.Time
+	Name       string
+	Role       string
+	WrappedKey []byte
+	ID         int
+	KeyVersion int
+}
+
+// Member is a struct that represents the membership of a User in an Organization with the given Role.
+type Member struct {
+	CreatedAt      time.Time
+	Login          string
+	Role           string
+	OrganizationID int
+	UserID         int
+}
+
+// Invite is a struct that represents an invitation of a User to an Organization sent by InvitedBy.
+// Organization and Inviter are the names of the organization and of the inviting user,
+// they are only read back.
+type Invite struct {
+	CreatedAt      time.Time
+	Organization   string
+	Inviter        string
+	Role           string
+	OrganizationID int
+	UserID         int
+	InvitedBy      int
+}
+
+// Collection is a struct that represents a named group of the secrets of an Organization.
+type Collection struct {
+	CreatedAt      time.Time
+	Name           string
+	ID             int
+	OrganizationID int
+}
+
+// BlindIndex is a struct that represents a keyed hash of an encrypted field of a Secret,
+// which allows exact-match lookups of the field.
+type BlindIndex struct {
+	Field string
+	Value []byte
+}
+
+// SecretFilter is a struct that selects the secrets of a User. Empty fields do not filter,
+// Name matches a substring of the name regardless of case, the time ranges include their
+// start and exclude their end. The secrets are selected in the order of their creation
+// after the secret given by AfterCreatedAt and AfterID, up to Limit secrets if it is positive.
+type SecretFilter struct {
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	UpdatedAfter   time.Time
+	UpdatedBefore  time.Time
+	AfterCreatedAt time.Time
+	Type           string
+	Tag            string
+	Folder         string
+	Name           string
+	AfterID        int
+	Limit          int
+}
+
+// Blob is a struct that represents a file of a User stored in Chunks numbered from zero.
+// Size is the total size of the chunks and Hash the SHA-256 hash of their content.
+// A blob read back carries the hash kept by the secret it is attached to.
+// A blob can be attached to a secret once it is Completed. LegacyChunks are encrypted by the server
+// without the mark of the last chunk.
+type Blob struct {
+	CreatedAt    time.Time
+	ID           string
+	Hash         []byte
+	Size         int64
+	UserID       int
+	Chunks       int
+	Completed    bool
+	LegacyChunks bool
+}
+
+// Changes is a struct that represents the changes of the secrets of a User made after some revision.
+// Revision is the current revision of the User, which covers all the changes.
+type Changes struct {
+	Secrets    []Secret
+	DeletedIDs []int
+	Revision   int64
+}
+
+// UserKey is a struct that represents the data encryption key of a User
+// wrapped by the master key of the given version.
+type UserKey struct {
+	WrappedKey []byte
+	UserID     int
+	Version    int
+}
+
+// OrganizationKey is a struct that represents the data encryption key of an Organization
+// wrapped by the master key of the given version.
+type OrganizationKey struct {
+	WrappedKey     []byte
+	OrganizationID int
+	Version        int
+}
+
+// RefreshToken is a struct that represents a refresh token issued to a User.
+// Only the hash of the token is stored.
+type RefreshToken struct {
+	ExpiresAt time.Time
+	SessionID string
+	TokenHash []byte
+	UserID    int
+}
+
+// Session is a struct that represents a session of a User started by login or registration.
+// Refresh tokens belong to a session, so revoking the session revokes all its tokens.
+type Session struct {
 	CreatedAt time.Time
-	Type      string
-	Content   []byte
-	MetaData  []byte
-	ID        int
+	ExpiresAt time.Time
+	ID        string
 	UserID    int
 }
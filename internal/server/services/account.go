@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// ErrInvalidPassword is returned when the password confirming an account operation is wrong.
+var ErrInvalidPassword = errors.New("password is invalid")
+
+// ChangePassword replaces the password of the user after checking the old one and revokes
+// all other sessions of the user. Wrong old passwords count toward the account lockout. The secrets of client-side encryption accounts are encrypted
+// with a key derived from the password, so they have to be re-encrypted by the client and passed
+// along. repository.ErrSecretsMismatch is returned unless they cover every secret of the user.
+func (a *authService) ChangePassword(
+	ctx context.Context,
+	oldPassword string,
+	newPassword string,
+	secrets []models.Secret,
+) error {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessionID, err := extractSessionIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract session from context")
+
+		return err
+	}
+
+	if err := a.checkPassword(ctx, user, oldPassword); err != nil {
+		return err
+	}
+
+	if !user.ClientSideEncryption && len(secrets) > 0 {
+		return repository.ErrSecretsMismatch
+	}
+
+	reencrypted := make([]repository.Secret, len(secrets))
+	for i := range secrets {
+		payload, ok := secrets[i].Payload.(*models.Encrypted)
+		if !ok {
+			return ErrInvalidPayload
+		}
+
+		reencrypted[i] = repository.Secret{
+			ID:      secrets[i].ID,
+			UserID:  user.ID,
+			Content: payload.Data,
+		}
+
+		if secrets[i].MetaData != "" {
+			reencrypted[i].MetaData = []byte(secrets[i].MetaData)
+		}
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to create hash from password")
+
+		return err
+	}
+
+	if err := a.repo.ChangePassword(ctx, user.ID, string(passHash), reencrypted); err != nil {
+		if !errors.Is(err, repository.ErrSecretsMismatch) {
+			a.log.Error().Err(err).Int("user", user.ID).Msg("failed to change password")
+		}
+
+		return err
+	}
+
+	if err := a.sessions.DeleteOtherSessions(ctx, user.ID, sessionID); err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to revoke other sessions")
+
+		return err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("password was changed")
+
+	return nil
+}
+
+// DeleteAccount removes the user along with all the user's secrets, keys and sessions
+// after checking the password. Wrong passwords count toward the account lockout.
+func (a *authService) DeleteAccount(ctx context.Context, password string) error {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := a.checkPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if err := a.repo.DeleteUser(ctx, user.ID); err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to delete user")
+
+		return err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("user was deleted")
+
+	return nil
+}
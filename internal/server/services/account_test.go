@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func Test_authService_ChangePassword(t *testing.T) {
+	log := logger.NewLogger()
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+	ctx = context.WithValue(ctx, interceptors.SessionIDKey, "current")
+
+	user := &models.User{ID: 1, PasswordHash: testPasswordHash}
+	clientSideUser := &models.User{ID: 1, PasswordHash: testPasswordHash, ClientSideEncryption: true}
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository)
+		name        string
+		oldPassword string
+		secrets     []models.Secret
+	}{
+		{
+			name:        "success: password changed",
+			oldPassword: "test",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("ChangePassword", ctx, 1, mock.Anything, []repository.Secret{}).Return(nil).Times(1)
+				s.On("DeleteOtherSessions", ctx, 1, "current").Return(nil).Times(1)
+			},
+		},
+		{
+			name:        "success: client-side encryption secrets re-encrypted",
+			oldPassword: "test",
+			secrets: []models.Secret{
+				{ID: 2, Payload: &models.Encrypted{Data: []byte("content")}, MetaData: "meta"},
+				{ID: 3, Payload: &models.Encrypted{Data: []byte("content")}},
+			},
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(clientSideUser, nil).Times(1)
+				r.On("ChangePassword", ctx, 1, mock.Anything, []repository.Secret{
+					{ID: 2, UserID: 1, Content: []byte("content"), MetaData: []byte("meta")},
+					{ID: 3, UserID: 1, Content: []byte("content")},
+				}).Return(nil).Times(1)
+				s.On("DeleteOtherSessions", ctx, 1, "current").Return(nil).Times(1)
+			},
+		},
+		{
+			name:        "error: old password is wrong",
+			oldPassword: "wrong",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:        "error: wrong old password locks account",
+			oldPassword: "wrong",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(3, nil).Times(1)
+				r.On("LockUser", ctx, 1, mock.Anything).Return(nil).Times(1)
+			},
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:        "error: account is locked",
+			oldPassword: "test",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{
+					ID:           1,
+					PasswordHash: testPasswordHash,
+					LockedUntil:  time.Now().Add(time.Minute),
+				}, nil).Times(1)
+			},
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:        "error: secrets passed for server-side encryption account",
+			oldPassword: "test",
+			secrets:     []models.Secret{{ID: 2, Payload: &models.Encrypted{Data: []byte("content")}}},
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+			},
+			expectedErr: repository.ErrSecretsMismatch,
+		},
+		{
+			name:        "error: not every secret re-encrypted",
+			oldPassword: "test",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(clientSideUser, nil).Times(1)
+				r.On("ChangePassword", ctx, 1, mock.Anything, mock.Anything).
+					Return(repository.ErrSecretsMismatch).Times(1)
+			},
+			expectedErr: repository.ErrSecretsMismatch,
+		},
+		{
+			name:        "error: failed to revoke other sessions",
+			oldPassword: "test",
+			prepare: func(r *mocks.MockAuthRepository, s *mocks.MockSessionRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("ChangePassword", ctx, 1, mock.Anything, mock.Anything).Return(nil).Times(1)
+				s.On("DeleteOtherSessions", ctx, 1, "current").Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			mockSessions := new(mocks.MockSessionRepository)
+			tt.prepare(mockRepo, mockSessions)
+
+			authService := NewAuthService(
+				mockRepo,
+				new(mocks.MockTokenRepository),
+				mockSessions,
+				&log,
+				jwtManager,
+				new(mocks.MockKeyService),
+				new(mocks.MockEncryption),
+				refreshTokenTTL,
+				testLockout,
+			)
+			err := authService.ChangePassword(ctx, tt.oldPassword, "new", tt.secrets)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_authService_DeleteAccount(t *testing.T) {
+	log := logger.NewLogger()
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+	user := &models.User{ID: 1, PasswordHash: testPasswordHash}
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository)
+		name        string
+		password    string
+	}{
+		{
+			name:     "success: account deleted",
+			password: "test",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("DeleteUser", ctx, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			name:     "success: failed logins reset",
+			password: "test",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).
+					Return(&models.User{ID: 1, PasswordHash: testPasswordHash, FailedLogins: 2}, nil).Times(1)
+				r.On("ResetLoginFailures", ctx, 1).Return(nil).Times(1)
+				r.On("DeleteUser", ctx, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			name:     "error: password is wrong",
+			password: "wrong",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:     "error: account is locked",
+			password: "test",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{
+					ID:           1,
+					PasswordHash: testPasswordHash,
+					LockedUntil:  time.Now().Add(time.Minute),
+				}, nil).Times(1)
+			},
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:     "error: failed to delete user",
+			password: "test",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+				r.On("DeleteUser", ctx, 1).Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			tt.prepare(mockRepo)
+
+			authService := NewAuthService(
+				mockRepo,
+				new(mocks.MockTokenRepository),
+				new(mocks.MockSessionRepository),
+				&log,
+				jwtManager,
+				new(mocks.MockKeyService),
+				new(mocks.MockEncryption),
+				refreshTokenTTL,
+				testLockout,
+			)
+			err := authService.DeleteAccount(ctx, tt.password)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
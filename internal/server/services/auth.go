@@ -3,92 +3,469 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
 
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 )
 
-// AuthService is an interface that defines methods for user registration and login functionalities.
+const (
+	refreshTokenSize = 32
+	sessionIDSize    = 16
+	maxLockout       = 24 * time.Hour
+)
+
+// dummyPasswordHash is compared with the password of unknown logins,
+// so that they take as long to check as the existing ones.
+const dummyPasswordHash = "$2a$10$Ra9/0S8tT.L1S/pOGFrLx.4OxVODS5/5o8yhwifitQ.4Urj/X0UD6"
+
+var (
+	// ErrInvalidRefreshToken is returned when the refresh token is unknown, already used or expired.
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")
+	// ErrInvalidCredentials is returned when the login fails. The same error is returned for unknown
+	// logins, wrong passwords and locked accounts, so the reason is not disclosed.
+	ErrInvalidCredentials = errors.New("login or password is invalid")
+)
+
+// LockoutPolicy defines when an account is locked after failed logins. Once MaxFailedLogins
+// passwords in a row are wrong, the account is locked for Duration, and every further failure
+// doubles the lock up to a day.
+type LockoutPolicy struct {
+	MaxFailedLogins int
+	Duration        time.Duration
+}
+
+// AuthService is an interface that defines methods for user registration, login,
+// token refresh, session management, two-factor authentication and account management functionalities.
 type AuthService interface {
-	Register(ctx context.Context, login string, password string) (string, error)
-	Login(ctx context.Context, login string, password string) (string, error)
+	Register(ctx context.Context, login string, password string, clientSideEncryption bool) (*models.Tokens, error)
+	Login(ctx context.Context, login string, password string) (*models.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.Tokens, error)
+	Logout(ctx context.Context) error
+	ListSessions(ctx context.Context) ([]models.Session, error)
+	RevokeSession(ctx context.Context, sessionID string) error
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+	EnableTOTP(ctx context.Context) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, code string) error
+	LoginTOTP(ctx context.Context, challenge string, code string) (*models.Tokens, error)
+	ChangePassword(ctx context.Context, oldPassword string, newPassword string, secrets []models.Secret) error
+	DeleteAccount(ctx context.Context, password string) error
 }
 
 type authService struct {
-	repo       repository.AuthRepository
-	log        *zerolog.Logger
-	jwtManager *jwt.JWTManager
+	repo            repository.AuthRepository
+	tokens          repository.TokenRepository
+	sessions        repository.SessionRepository
+	log             *zerolog.Logger
+	jwtManager      *jwt.JWTManager
+	keys            KeyService
+	crypt           encryption.Encryption
+	lockout         LockoutPolicy
+	refreshTokenTTL time.Duration
 }
 
 // NewAuthService creates and returns a new AuthService instance.
+// Issued refresh tokens and idle sessions expire after the provided refreshTokenTTL,
+// failed logins lock the account according to the lockout policy.
 func NewAuthService(
 	repo repository.AuthRepository,
+	tokens repository.TokenRepository,
+	sessions repository.SessionRepository,
 	log *zerolog.Logger,
 	jwtManager *jwt.JWTManager,
+	keys KeyService,
+	crypt encryption.Encryption,
+	refreshTokenTTL time.Duration,
+	lockout LockoutPolicy,
 ) AuthService {
 	return &authService{
-		repo:       repo,
-		log:        log,
-		jwtManager: jwtManager,
+		repo:            repo,
+		tokens:          tokens,
+		sessions:        sessions,
+		log:             log,
+		jwtManager:      jwtManager,
+		keys:            keys,
+		crypt:           crypt,
+		lockout:         lockout,
+		refreshTokenTTL: refreshTokenTTL,
 	}
 }
 
-// Register registers a new user with the given login and password, and returns a JWT token.
-func (a *authService) Register(ctx context.Context, login string, password string) (string, error) {
+// Register registers a new user with the given login and password, creates the user's
+// data encryption key, starts a new session and returns a pair of tokens. Users with client-side
+// encryption get the key as well, it protects their account data such as the authenticator secret.
+func (a *authService) Register(
+	ctx context.Context,
+	login string,
+	password string,
+	clientSideEncryption bool,
+) (*models.Tokens, error) {
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		a.log.Error().Err(err).Str("login", login).Msg("failed to create hash from password")
 
-		return "", err
+		return nil, err
 	}
 
 	user := models.User{
-		Login:        login,
-		PasswordHash: string(passHash),
+		Login:                login,
+		PasswordHash:         string(passHash),
+		ClientSideEncryption: clientSideEncryption,
 	}
 
-	userID, err := a.repo.SaveUser(ctx, user)
+	user.ID, err = a.repo.SaveUser(ctx, user)
 	if err != nil {
 		a.log.Error().Err(err).Str("login", login).Msg("failed to register user")
 
-		return "", err
+		return nil, err
+	}
+
+	if err := a.keys.CreateUserKey(ctx, user.ID); err != nil {
+		return nil, err
 	}
 
-	token, err := a.jwtManager.Generate(userID)
+	tokens, err := a.startSession(ctx, &user)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	a.log.Info().Int("user", userID).Msg("user was created")
+	a.log.Info().Int("user", user.ID).Msg("user was created")
 
-	return token, nil
+	return tokens, nil
 }
 
-// Login checks if the given login and password match a user account, and returns a JWT token.
-func (a *authService) Login(ctx context.Context, login string, password string) (string, error) {
+// Login checks if the given login and password match a user account, starts a new session
+// and returns a pair of tokens. If the user has two-factor authentication enabled, only
+// a challenge is returned which has to be completed with LoginTOTP. Failed logins are counted
+// and lock the account according to the lockout policy. ErrInvalidCredentials is returned
+// for unknown logins, wrong passwords and locked accounts alike.
+func (a *authService) Login(ctx context.Context, login string, password string) (*models.Tokens, error) {
 	savedUser, err := a.repo.GetUser(ctx, login)
+	if errors.Is(err, repository.ErrNoRows) {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+
+		return nil, ErrInvalidCredentials
+	}
+
 	if err != nil {
 		a.log.Error().Err(err).Str("login", login).Msg("cannot find user in database")
 
-		return "", err
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(savedUser.PasswordHash), []byte(password))
+
+	if time.Now().Before(savedUser.LockedUntil) {
+		a.log.Warn().Int("user", savedUser.ID).Msg("login attempt to locked account")
+
+		return nil, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		a.log.Warn().Int("user", savedUser.ID).Msg("hash and password mismatch")
+
+		if err := a.recordLoginFailure(ctx, savedUser.ID); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidCredentials
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(savedUser.PasswordHash), []byte(password)); err != nil {
-		a.log.Error().Err(err).Str("login", login).Msg("hash and password mismatch")
+	if err := a.resetLoginFailures(ctx, savedUser); err != nil {
+		return nil, err
+	}
 
-		return "", err
+	if savedUser.TOTPEnabled {
+		return a.totpChallenge(savedUser)
 	}
 
-	token, err := a.jwtManager.Generate(savedUser.ID)
+	tokens, err := a.startSession(ctx, savedUser)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	a.log.Info().Int("user", savedUser.ID).Msg("user logged in")
 
-	return token, nil
+	return tokens, nil
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens of the same session and
+// extends the session. Every refresh token can be used only once, ErrInvalidRefreshToken
+// is returned for used or expired tokens and tokens of revoked sessions.
+func (a *authService) Refresh(ctx context.Context, refreshToken string) (*models.Tokens, error) {
+	token, err := a.tokens.TakeRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+
+		a.log.Error().Err(err).Msg("failed to get refresh token")
+
+		return nil, err
+	}
+
+	if err := a.sessions.ExtendSession(ctx, token.SessionID, time.Now().Add(a.refreshTokenTTL)); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+
+		a.log.Error().Err(err).Str("session", token.SessionID).Msg("failed to extend session")
+
+		return nil, err
+	}
+
+	user, err := a.repo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		a.log.Error().Err(err).Int("user", token.UserID).Msg("cannot find user in database")
+
+		return nil, err
+	}
+
+	return a.issueTokens(ctx, user, token.SessionID)
+}
+
+// Logout revokes the session the request was made from.
+func (a *authService) Logout(ctx context.Context) error {
+	sessionID, err := extractSessionIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract session from context")
+
+		return err
+	}
+
+	return a.RevokeSession(ctx, sessionID)
+}
+
+// ListSessions returns the active sessions of the user.
+func (a *authService) ListSessions(ctx context.Context) ([]models.Session, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	currentSessionID, err := extractSessionIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract session from context")
+
+		return nil, err
+	}
+
+	sessions, err := a.sessions.GetUserSessions(ctx, userID)
+	if err != nil {
+		a.log.Error().Err(err).Int("user", userID).Msg("failed to get user sessions")
+
+		return nil, err
+	}
+
+	modelSessions := make([]models.Session, len(sessions))
+	for i, session := range sessions {
+		modelSessions[i] = models.Session{
+			ID:        session.ID,
+			CreatedAt: session.CreatedAt,
+			ExpiresAt: session.ExpiresAt,
+			Current:   session.ID == currentSessionID,
+		}
+	}
+
+	return modelSessions, nil
+}
+
+// RevokeSession revokes the session of the user along with all its tokens.
+// Access tokens of the session are rejected right away. repository.ErrNoRows is
+// returned if the user has no such session.
+func (a *authService) RevokeSession(ctx context.Context, sessionID string) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	if err := a.sessions.DeleteSession(ctx, sessionID, userID); err != nil {
+		a.log.Error().Err(err).Int("user", userID).Str("session", sessionID).Msg("failed to revoke session")
+
+		return err
+	}
+
+	a.log.Info().Int("user", userID).Str("session", sessionID).Msg("session was revoked")
+
+	return nil
+}
+
+// IsSessionActive reports whether the session exists and is neither revoked nor expired.
+func (a *authService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	active, err := a.sessions.IsSessionActive(ctx, sessionID)
+	if err != nil {
+		a.log.Error().Err(err).Str("session", sessionID).Msg("failed to check session")
+
+		return false, err
+	}
+
+	return active, nil
+}
+
+// recordLoginFailure counts the failed login of the user and locks the account once
+// the number of failures reaches the limit of the lockout policy.
+func (a *authService) recordLoginFailure(ctx context.Context, userID int) error {
+	failedLogins, err := a.repo.RecordLoginFailure(ctx, userID)
+	if err != nil {
+		a.log.Error().Err(err).Int("user", userID).Msg("failed to record failed login")
+
+		return err
+	}
+
+	if failedLogins < a.lockout.MaxFailedLogins {
+		return nil
+	}
+
+	lockout := a.lockout.Duration
+	for i := a.lockout.MaxFailedLogins; i < failedLogins && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+
+	lockout = min(lockout, maxLockout)
+
+	if err := a.repo.LockUser(ctx, userID, time.Now().Add(lockout)); err != nil {
+		a.log.Error().Err(err).Int("user", userID).Msg("failed to lock user")
+
+		return err
+	}
+
+	a.log.Warn().Int("user", userID).Int("failed_logins", failedLogins).Dur("lockout", lockout).Msg("user was locked")
+
+	return nil
+}
+
+// resetLoginFailures clears the failed logins of the user after a successful login.
+func (a *authService) resetLoginFailures(ctx context.Context, user *models.User) error {
+	if user.FailedLogins == 0 {
+		return nil
+	}
+
+	if err := a.repo.ResetLoginFailures(ctx, user.ID); err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to reset failed logins")
+
+		return err
+	}
+
+	return nil
+}
+
+// checkPassword confirms an account operation with the password of the user. Wrong passwords
+// count toward the lockout just like failed logins, and ErrInvalidPassword is returned
+// while the account is locked.
+func (a *authService) checkPassword(ctx context.Context, user *models.User, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
+
+	if time.Now().Before(user.LockedUntil) {
+		a.log.Warn().Int("user", user.ID).Msg("password check of locked account")
+
+		return ErrInvalidPassword
+	}
+
+	if err != nil {
+		a.log.Warn().Int("user", user.ID).Msg("hash and password mismatch")
+
+		if err := a.recordLoginFailure(ctx, user.ID); err != nil {
+			return err
+		}
+
+		return ErrInvalidPassword
+	}
+
+	return a.resetLoginFailures(ctx, user)
+}
+
+// currentUser returns the user the request was made by.
+func (a *authService) currentUser(ctx context.Context) (*models.User, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		a.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	user, err := a.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		a.log.Error().Err(err).Int("user", userID).Msg("cannot find user in database")
+
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// startSession starts a new session of the user and issues its first pair of tokens.
+func (a *authService) startSession(ctx context.Context, user *models.User) (*models.Tokens, error) {
+	sessionID := make([]byte, sessionIDSize)
+	if _, err := rand.Read(sessionID); err != nil {
+		return nil, err
+	}
+
+	session := repository.Session{
+		ID:        hex.EncodeToString(sessionID),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+	}
+
+	if err := a.sessions.CreateSession(ctx, session); err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to create session")
+
+		return nil, err
+	}
+
+	return a.issueTokens(ctx, user, session.ID)
+}
+
+// issueTokens generates an access token of the session and stores a new refresh token.
+func (a *authService) issueTokens(ctx context.Context, user *models.User, sessionID string) (*models.Tokens, error) {
+	accessToken, err := a.jwtManager.Generate(user.ID, sessionID, user.ClientSideEncryption)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(refreshToken); err != nil {
+		return nil, err
+	}
+
+	tokens := &models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: base64.RawURLEncoding.EncodeToString(refreshToken),
+	}
+
+	err = a.tokens.SaveRefreshToken(ctx, repository.RefreshToken{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		TokenHash: hashRefreshToken(tokens.RefreshToken),
+		ExpiresAt: time.Now().Add(a.refreshTokenTTL),
+	})
+	if err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to save refresh token")
+
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// hashRefreshToken returns the hash under which the refresh token is stored,
+// so a leaked database does not expose usable refresh tokens.
+func hashRefreshToken(refreshToken string) []byte {
+	hash := sha256.Sum256([]byte(refreshToken))
+
+	return hash[:]
 }
@@ -4,57 +4,79 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"golang.org/x/crypto/bcrypt"
 
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
 )
 
 var errInternal = errors.New("test")
 
+var testLockout = LockoutPolicy{MaxFailedLogins: 3, Duration: time.Minute}
+
+const (
+	testPasswordHash = "$2a$10$lSQ88TSGNM6cR6UAdZWzK.eqUP7GYGk3EmmAzgU5vwFSj5OFnYUKa"
+	refreshTokenTTL  = time.Hour
+)
+
 func Test_authService_Register(t *testing.T) {
 	log := logger.NewLogger()
-	jwtManager := jwt.NewJWTManager("test-secret")
-
-	type expected struct {
-		err   error
-		token string
-	}
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
 
 	tests := []struct {
-		expected expected
-		err      error
-		name     string
-		login    string
-		password string
-		userID   int
+		err                  error
+		keyErr               error
+		tokenErr             error
+		expectedErr          error
+		name                 string
+		login                string
+		password             string
+		userID               int
+		clientSideEncryption bool
 	}{
 		{
 			name:     "success: user created",
 			login:    "test",
 			password: "test",
 			userID:   1,
-			err:      nil,
-			expected: expected{
-				token: createToken(jwtManager, 1),
-				err:   nil,
-			},
 		},
 		{
-			name:     "error: failed to create user",
-			login:    "test",
-			password: "test",
-			userID:   0,
-			err:      errors.New("test"),
-			expected: expected{
-				token: "",
-				err:   errors.New("test"),
-			},
+			name:                 "success: user with client-side encryption created",
+			login:                "test",
+			password:             "test",
+			userID:               1,
+			clientSideEncryption: true,
+		},
+		{
+			name:        "error: failed to create user",
+			login:       "test",
+			password:    "test",
+			userID:      0,
+			err:         errInternal,
+			expectedErr: errInternal,
+		},
+		{
+			name:        "error: failed to create user key",
+			login:       "test",
+			password:    "test",
+			userID:      1,
+			keyErr:      errInternal,
+			expectedErr: errInternal,
+		},
+		{
+			name:        "error: failed to save refresh token",
+			login:       "test",
+			password:    "test",
+			userID:      1,
+			tokenErr:    errInternal,
+			expectedErr: errInternal,
 		},
 	}
 
@@ -64,33 +86,62 @@ func Test_authService_Register(t *testing.T) {
 			mockRepo.On("SaveUser", context.Background(), mock.Anything).
 				Return(tt.userID, tt.err).Times(1)
 
-			authService := NewAuthService(mockRepo, &log, jwtManager)
-			token, err := authService.Register(context.Background(), tt.login, tt.password)
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("CreateUserKey", context.Background(), tt.userID).
+				Return(tt.keyErr).Times(1)
+
+			mockTokens := new(mocks.MockTokenRepository)
+			mockTokens.On("SaveRefreshToken", context.Background(), mock.Anything).
+				Return(tt.tokenErr).Times(1)
+
+			mockSessions := new(mocks.MockSessionRepository)
+			mockSessions.On("CreateSession", context.Background(), mock.Anything).
+				Return(nil).Times(1)
+
+			authService := NewAuthService(
+				mockRepo,
+				mockTokens,
+				mockSessions,
+				&log,
+				jwtManager,
+				mockKeys,
+				new(mocks.MockEncryption),
+				refreshTokenTTL,
+				testLockout,
+			)
+			tokens, err := authService.Register(context.Background(), tt.login, tt.password, tt.clientSideEncryption)
 
-			assert.Equal(t, tt.expected.token, token)
-			assert.Equal(t, tt.expected.err, err)
+			assert.Equal(t, tt.expectedErr, err)
+
+			if tt.expectedErr != nil {
+				assert.Nil(t, tokens)
+				return
+			}
+
+			assertTokens(t, jwtManager, mockTokens, tokens, tt.userID, tt.clientSideEncryption)
+			mockSessions.AssertCalled(t, "CreateSession", context.Background(), mock.MatchedBy(
+				func(session repository.Session) bool {
+					return session.UserID == tt.userID && session.ID != ""
+				}))
 		})
 	}
 }
 
 func Test_authService_Login(t *testing.T) {
 	log := logger.NewLogger()
-	jwtManager := jwt.NewJWTManager("test-secret")
-
-	type expected struct {
-		err   error
-		token string
-	}
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
 
 	tests := []struct {
-		expected expected
-		prepare  func(s *mocks.MockAuthRepository)
-		name     string
-		login    string
-		password string
+		expectedErr          error
+		prepare              func(s *mocks.MockAuthRepository)
+		name                 string
+		login                string
+		password             string
+		clientSideEncryption bool
+		totpChallenge        bool
 	}{
 		{
-			name:     "success: user created",
+			name:     "success: user logged in",
 			login:    "login",
 			password: "test",
 			prepare: func(s *mocks.MockAuthRepository) {
@@ -98,11 +149,54 @@ func Test_authService_Login(t *testing.T) {
 					Return(&models.User{
 						ID:           1,
 						Login:        "login",
-						PasswordHash: "$2a$10$lSQ88TSGNM6cR6UAdZWzK.eqUP7GYGk3EmmAzgU5vwFSj5OFnYUKa",
+						PasswordHash: testPasswordHash,
 					}, nil).Times(1)
 			},
-			expected: expected{
-				token: createToken(jwtManager, 1),
+		},
+		{
+			name:     "success: client-side encryption flag is put into token",
+			login:    "login",
+			password: "test",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "login").
+					Return(&models.User{
+						ID:                   1,
+						Login:                "login",
+						PasswordHash:         testPasswordHash,
+						ClientSideEncryption: true,
+					}, nil).Times(1)
+			},
+			clientSideEncryption: true,
+		},
+		{
+			name:     "success: second factor challenge is returned",
+			login:    "login",
+			password: "test",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "login").
+					Return(&models.User{
+						ID:           1,
+						Login:        "login",
+						PasswordHash: testPasswordHash,
+						TOTPEnabled:  true,
+					}, nil).Times(1)
+			},
+			totpChallenge: true,
+		},
+		{
+			name:     "success: failed logins are reset",
+			login:    "login",
+			password: "test",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "login").
+					Return(&models.User{
+						ID:           1,
+						Login:        "login",
+						PasswordHash: testPasswordHash,
+						FailedLogins: 2,
+						LockedUntil:  time.Now().Add(-time.Minute),
+					}, nil).Times(1)
+				s.On("ResetLoginFailures", context.Background(), 1).Return(nil).Times(1)
 			},
 		},
 		{
@@ -114,12 +208,57 @@ func Test_authService_Login(t *testing.T) {
 					Return(&models.User{
 						ID:           1,
 						Login:        "login",
-						PasswordHash: "$2a$10$lSQ88TSGNM6cR6UAdZWzK.eqUP7GYGk3EmmAzgU5vwFSj5OFnYUKa",
+						PasswordHash: testPasswordHash,
 					}, nil).Times(1)
+				s.On("RecordLoginFailure", context.Background(), 1).Return(1, nil).Times(1)
 			},
-			expected: expected{
-				err: bcrypt.ErrMismatchedHashAndPassword,
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "error: account is locked after too many failures",
+			login:    "login",
+			password: "test2",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "login").
+					Return(&models.User{
+						ID:           1,
+						Login:        "login",
+						PasswordHash: testPasswordHash,
+					}, nil).Times(1)
+				s.On("RecordLoginFailure", context.Background(), 1).Return(4, nil).Times(1)
+				s.On("LockUser", context.Background(), 1, mock.MatchedBy(func(until time.Time) bool {
+					// the second failure over the limit doubles the lockout
+					lockout := time.Until(until)
+					return lockout > time.Minute+50*time.Second && lockout <= 2*time.Minute
+				})).Return(nil).Times(1)
 			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "error: locked account rejects valid password",
+			login:    "login",
+			password: "test",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "login").
+					Return(&models.User{
+						ID:           1,
+						Login:        "login",
+						PasswordHash: testPasswordHash,
+						FailedLogins: 3,
+						LockedUntil:  time.Now().Add(time.Minute),
+					}, nil).Times(1)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "error: unknown login",
+			login:    "unknown",
+			password: "test",
+			prepare: func(s *mocks.MockAuthRepository) {
+				s.On("GetUser", context.Background(), "unknown").
+					Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrInvalidCredentials,
 		},
 		{
 			name:     "error: failed to get user",
@@ -129,9 +268,7 @@ func Test_authService_Login(t *testing.T) {
 				s.On("GetUser", context.Background(), "login").
 					Return(nil, errInternal).Times(1)
 			},
-			expected: expected{
-				err: errInternal,
-			},
+			expectedErr: errInternal,
 		},
 	}
 
@@ -140,17 +277,233 @@ func Test_authService_Login(t *testing.T) {
 			mockRepo := new(mocks.MockAuthRepository)
 			tt.prepare(mockRepo)
 
-			authService := NewAuthService(mockRepo, &log, jwtManager)
-			token, err := authService.Login(context.Background(), tt.login, tt.password)
+			mockTokens := new(mocks.MockTokenRepository)
+			mockTokens.On("SaveRefreshToken", context.Background(), mock.Anything).
+				Return(nil)
+
+			mockSessions := new(mocks.MockSessionRepository)
+			mockSessions.On("CreateSession", context.Background(), mock.Anything).
+				Return(nil)
+
+			authService := NewAuthService(
+				mockRepo,
+				mockTokens,
+				mockSessions,
+				&log,
+				jwtManager,
+				new(mocks.MockKeyService),
+				new(mocks.MockEncryption),
+				refreshTokenTTL,
+				testLockout,
+			)
+			tokens, err := authService.Login(context.Background(), tt.login, tt.password)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+
+			if tt.expectedErr != nil {
+				assert.Nil(t, tokens)
+				return
+			}
+
+			if tt.totpChallenge {
+				assert.Empty(t, tokens.AccessToken)
+				assert.Empty(t, tokens.RefreshToken)
+
+				claims, err := jwtManager.ParseChallenge(tokens.TOTPChallenge)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, claims.UserID)
+				mockSessions.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+
+				return
+			}
+
+			assertTokens(t, jwtManager, mockTokens, tokens, 1, tt.clientSideEncryption)
+		})
+	}
+}
+
+func Test_authService_Refresh(t *testing.T) {
+	log := logger.NewLogger()
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository)
+		name        string
+	}{
+		{
+			name: "success: tokens refreshed",
+			prepare: func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository) {
+				tr.On("TakeRefreshToken", context.Background(), hashRefreshToken("refresh-token")).
+					Return(&repository.RefreshToken{UserID: 1, SessionID: "session"}, nil).Times(1)
+				sr.On("ExtendSession", context.Background(), "session", mock.Anything).
+					Return(nil).Times(1)
+				tr.On("SaveRefreshToken", context.Background(), mock.Anything).
+					Return(nil).Times(1)
+				r.On("GetUserByID", context.Background(), 1).
+					Return(&models.User{ID: 1, ClientSideEncryption: true}, nil).Times(1)
+			},
+		},
+		{
+			name: "error: unknown or used refresh token",
+			prepare: func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository) {
+				tr.On("TakeRefreshToken", context.Background(), hashRefreshToken("refresh-token")).
+					Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "error: session is revoked",
+			prepare: func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository) {
+				tr.On("TakeRefreshToken", context.Background(), hashRefreshToken("refresh-token")).
+					Return(&repository.RefreshToken{UserID: 1, SessionID: "session"}, nil).Times(1)
+				sr.On("ExtendSession", context.Background(), "session", mock.Anything).
+					Return(repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "error: failed to take refresh token",
+			prepare: func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository) {
+				tr.On("TakeRefreshToken", context.Background(), hashRefreshToken("refresh-token")).
+					Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to get user",
+			prepare: func(r *mocks.MockAuthRepository, tr *mocks.MockTokenRepository, sr *mocks.MockSessionRepository) {
+				tr.On("TakeRefreshToken", context.Background(), hashRefreshToken("refresh-token")).
+					Return(&repository.RefreshToken{UserID: 1, SessionID: "session"}, nil).Times(1)
+				sr.On("ExtendSession", context.Background(), "session", mock.Anything).
+					Return(nil).Times(1)
+				r.On("GetUserByID", context.Background(), 1).
+					Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			mockTokens := new(mocks.MockTokenRepository)
+			mockSessions := new(mocks.MockSessionRepository)
+			tt.prepare(mockRepo, mockTokens, mockSessions)
+
+			authService := NewAuthService(
+				mockRepo,
+				mockTokens,
+				mockSessions,
+				&log,
+				jwtManager,
+				new(mocks.MockKeyService),
+				new(mocks.MockEncryption),
+				refreshTokenTTL,
+				testLockout,
+			)
+			tokens, err := authService.Refresh(context.Background(), "refresh-token")
+
+			assert.Equal(t, tt.expectedErr, err)
 
-			assert.Equal(t, tt.expected.err, err)
-			assert.Equal(t, tt.expected.token, token)
+			if tt.expectedErr != nil {
+				assert.Nil(t, tokens)
+				return
+			}
+
+			assertTokens(t, jwtManager, mockTokens, tokens, 1, true)
+			assert.NotEqual(t, "refresh-token", tokens.RefreshToken)
+
+			claims, err := jwtManager.Parse(tokens.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, "session", claims.SessionID)
 		})
 	}
 }
 
-func createToken(m *jwt.JWTManager, userID int) string {
-	token, _ := m.Generate(userID)
+func Test_authService_Sessions(t *testing.T) {
+	log := logger.NewLogger()
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	now := time.Now()
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+	ctx = context.WithValue(ctx, interceptors.SessionIDKey, "current")
+
+	mockSessions := new(mocks.MockSessionRepository)
+	mockSessions.On("GetUserSessions", ctx, 1).
+		Return([]repository.Session{
+			{ID: "current", UserID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			{ID: "other", UserID: 1, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		}, nil).Times(1)
+	mockSessions.On("DeleteSession", ctx, "other", 1).Return(nil).Times(1)
+	mockSessions.On("DeleteSession", ctx, "current", 1).Return(nil).Times(1)
+	mockSessions.On("DeleteSession", ctx, "unknown", 1).Return(repository.ErrNoRows).Times(1)
+	mockSessions.On("IsSessionActive", ctx, "current").Return(true, nil).Times(1)
+
+	authService := NewAuthService(
+		new(mocks.MockAuthRepository),
+		new(mocks.MockTokenRepository),
+		mockSessions,
+		&log,
+		jwtManager,
+		new(mocks.MockKeyService),
+		new(mocks.MockEncryption),
+		refreshTokenTTL,
+		testLockout,
+	)
+
+	sessions, err := authService.ListSessions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Session{
+		{ID: "current", CreatedAt: now, ExpiresAt: now.Add(time.Hour), Current: true},
+		{ID: "other", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	}, sessions)
+
+	active, err := authService.IsSessionActive(ctx, "current")
+	assert.NoError(t, err)
+	assert.True(t, active)
+
+	assert.NoError(t, authService.RevokeSession(ctx, "other"))
+	assert.Equal(t, repository.ErrNoRows, authService.RevokeSession(ctx, "unknown"))
+	assert.NoError(t, authService.Logout(ctx))
+
+	assert.Equal(t, ErrExtractFromContext, authService.Logout(context.Background()))
+
+	_, err = authService.ListSessions(context.Background())
+	assert.Equal(t, ErrExtractFromContext, err)
+
+	mockSessions.AssertExpectations(t)
+}
+
+// assertTokens checks that the access token belongs to the user and that the hash of the
+// refresh token was stored.
+func assertTokens(
+	t *testing.T,
+	jwtManager *jwt.JWTManager,
+	mockTokens *mocks.MockTokenRepository,
+	tokens *models.Tokens,
+	userID int,
+	clientSideEncryption bool,
+) {
+	t.Helper()
+
+	if !assert.NotNil(t, tokens) {
+		return
+	}
+
+	claims, err := jwtManager.Parse(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, clientSideEncryption, claims.ClientSideEncryption)
+	assert.NotEmpty(t, claims.SessionID)
 
-	return token
+	assert.NotEmpty(t, tokens.RefreshToken)
+	mockTokens.AssertCalled(t, "SaveRefreshToken", context.Background(), mock.MatchedBy(
+		func(token repository.RefreshToken) bool {
+			return token.UserID == userID &&
+				token.SessionID == claims.SessionID &&
+				assert.ObjectsAreEqual(hashRefreshToken(tokens.RefreshToken), token.TokenHash) &&
+				token.ExpiresAt.After(time.Now())
+		}))
 }
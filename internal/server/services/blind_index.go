@@ -0,0 +1,110 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// Fields of credentials which are indexed with blind indexes.
+const (
+	indexFieldDomain = "domain"
+	indexFieldLogin  = "login"
+)
+
+// blindIndexInfo separates the key of the blind indexes from the other uses of the user key.
+const blindIndexInfo = "goph-keeper blind index"
+
+var (
+	// ErrInvalidDomain is returned when the domain to find the secrets by is empty or malformed.
+	ErrInvalidDomain = errors.New("domain is invalid")
+	// ErrBlindIndexUnavailable is returned when the secrets of the user are encrypted by the client,
+	// so the server cannot index them.
+	ErrBlindIndexUnavailable = errors.New("blind indexes are not available with client-side encryption")
+)
+
+// blindIndexKey derives the key of the blind indexes of the user from the user key.
+// The indexes of different users differ even for the same values.
+func blindIndexKey(userKey []byte) []byte {
+	mac := hmac.New(sha256.New, userKey)
+	mac.Write([]byte(blindIndexInfo))
+
+	return mac.Sum(nil)
+}
+
+// blindIndex returns the blind index of the value of the field.
+func blindIndex(key []byte, field, value string) repository.BlindIndex {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return repository.BlindIndex{Field: field, Value: mac.Sum(nil)}
+}
+
+// secretIndexes returns the blind indexes of the domain and the login of credentials.
+// Secrets of other types are not indexed.
+func secretIndexes(key []byte, payload models.Payload) []repository.BlindIndex {
+	credentials, ok := payload.(*models.Credentials)
+	if !ok {
+		return nil
+	}
+
+	var indexes []repository.BlindIndex
+
+	if domain, err := normalizeDomain(credentials.URL); err == nil {
+		indexes = append(indexes, blindIndex(key, indexFieldDomain, domain))
+	}
+
+	if login := normalizeLogin(credentials.Login); login != "" {
+		indexes = append(indexes, blindIndex(key, indexFieldLogin, login))
+	}
+
+	return indexes
+}
+
+// normalizeDomain returns the lower-cased host name of the URL without the www prefix.
+// The URL may omit the scheme, so both example.com and https://www.example.com/login
+// yield example.com.
+func normalizeDomain(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidDomain
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	domain = strings.TrimPrefix(domain, "www.")
+
+	if domain == "" {
+		return "", ErrInvalidDomain
+	}
+
+	return domain, nil
+}
+
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// parentDomains returns the domain followed by its parent domains down to
+// the second level: a.example.com yields a.example.com and example.com.
+func parentDomains(domain string) []string {
+	domains := []string{domain}
+
+	for strings.Count(domain, ".") > 1 {
+		_, domain, _ = strings.Cut(domain, ".")
+		domains = append(domains, domain)
+	}
+
+	return domains
+}
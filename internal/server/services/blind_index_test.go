@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+func Test_normalizeDomain(t *testing.T) {
+	tests := []struct {
+		expectedErr error
+		name        string
+		url         string
+		expected    string
+	}{
+		{name: "success: domain", url: "example.com", expected: "example.com"},
+		{name: "success: url", url: " https://WWW.Example.com:8443/login?next=/ ", expected: "example.com"},
+		{name: "success: subdomain", url: "login.example.com.", expected: "login.example.com"},
+		{name: "error: empty", url: " ", expectedErr: ErrInvalidDomain},
+		{name: "error: malformed", url: "https://exa mple.com", expectedErr: ErrInvalidDomain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain, err := normalizeDomain(tt.url)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, domain)
+		})
+	}
+}
+
+func Test_parentDomains(t *testing.T) {
+	assert.Equal(t, []string{"a.b.example.com", "b.example.com", "example.com"}, parentDomains("a.b.example.com"))
+	assert.Equal(t, []string{"localhost"}, parentDomains("localhost"))
+}
+
+func Test_secretIndexes(t *testing.T) {
+	key := blindIndexKey(testKey)
+
+	indexes := secretIndexes(key, &models.Credentials{
+		Login:    " John@Example.com ",
+		Password: "password",
+		URL:      "https://www.example.com/login",
+	})
+	assert.Equal(t, []repository.BlindIndex{
+		blindIndex(key, indexFieldDomain, "example.com"),
+		blindIndex(key, indexFieldLogin, "john@example.com"),
+	}, indexes)
+
+	// The indexes of the same values differ between users and fields.
+	otherKey := blindIndexKey([]byte("other-key"))
+	assert.NotEqual(t, blindIndex(key, indexFieldDomain, "example.com"), blindIndex(otherKey, indexFieldDomain, "example.com"))
+	assert.NotEqual(t, blindIndex(key, indexFieldDomain, "example.com").Value, blindIndex(key, indexFieldLogin, "example.com").Value)
+
+	assert.Nil(t, secretIndexes(key, &models.Credentials{}))
+	assert.Nil(t, secretIndexes(key, &models.Text{Body: "example.com"}))
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/blobstore"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+const (
+	// MaxBlobChunkSize is the maximum size of a chunk of an uploaded blob.
+	MaxBlobChunkSize = 1 << 20
+
+	blobIDSize = 16
+	// unattachedBlobTTL is the period of time a blob may stay unattached to any secret.
+	unattachedBlobTTL = 24 * time.Hour
+	// blobCleanupInterval is the period of time between the removals of unattached and deleted blobs.
+	blobCleanupInterval = 10 * time.Minute
+	// blobPurgeBatchSize is the number of deleted blobs removed from the blob store at once.
+	blobPurgeBatchSize = 100
+)
+
+var (
+	// ErrInvalidChunk is returned when an uploaded chunk is empty or exceeds MaxBlobChunkSize.
+	ErrInvalidChunk = errors.New("blob chunk is empty or too large")
+	// ErrBlobTooLarge is returned when an uploaded blob exceeds the maximum blob size.
+	ErrBlobTooLarge = errors.New("blob exceeds maximum size")
+	// ErrBlobCorrupted is returned when the stored chunks of a blob are missing or do not match its hash.
+	ErrBlobCorrupted = errors.New("blob is corrupted")
+)
+
+// BlobService is an interface that defines methods for storing the files of binary secrets.
+type BlobService interface {
+	Upload(ctx context.Context, next func() ([]byte, error)) (*models.Blob, error)
+	Download(ctx context.Context, blobID string, send func([]byte) error) error
+	UpgradeBlobs(ctx context.Context, batchSize int) (int, error)
+	Run(ctx context.Context)
+}
+
+type blobService struct {
+	repo         repository.BlobRepository
+	store        blobstore.BlobStore
+	log          *zerolog.Logger
+	crypt        encryption.Encryption
+	keys         KeyService
+	maxSize      int64
+	legacyChunks bool
+}
+
+// NewBlobService creates and returns a new BlobService instance.
+// The chunks of blobs are kept in the store. Uploaded blobs may not exceed maxSize bytes.
+// The chunks of blobs encrypted without the mark of the last chunk are decrypted only
+// if legacyChunks is set.
+func NewBlobService(
+	repo repository.BlobRepository,
+	store blobstore.BlobStore,
+	log *zerolog.Logger,
+	crypt encryption.Encryption,
+	keys KeyService,
+	maxSize int64,
+	legacyChunks bool,
+) BlobService {
+	return &blobService{
+		repo:         repo,
+		store:        store,
+		log:          log,
+		crypt:        crypt,
+		keys:         keys,
+		maxSize:      maxSize,
+		legacyChunks: legacyChunks,
+	}
+}
+
+// Upload stores the chunks returned by next until it returns io.EOF and returns the new blob.
+// Chunks of users with client-side encryption are already encrypted and are stored as is,
+// all other chunks are encrypted with the user's key and bound to their blob and position,
+// the last chunk is marked as such.
+// The hash of the stored chunks is kept with the blob. The blob is removed if the upload fails.
+func (s *blobService) Upload(ctx context.Context, next func() ([]byte, error)) (*models.Blob, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	var key []byte
+	if !isClientSideEncryption(ctx) {
+		key, err = s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blobID := make([]byte, blobIDSize)
+	if _, err := rand.Read(blobID); err != nil {
+		return nil, err
+	}
+
+	blob := &repository.Blob{
+		ID:     hex.EncodeToString(blobID),
+		UserID: userID,
+	}
+
+	if err := s.repo.CreateBlob(ctx, blob); err != nil {
+		s.log.Error().Err(err).Msg("failed to create blob")
+
+		return nil, err
+	}
+
+	err = s.receiveChunks(ctx, key, blob, next)
+	if err == nil {
+		err = s.repo.CompleteBlob(ctx, blob)
+	}
+
+	if err != nil {
+		// The incomplete blob is removed even if the client has gone away,
+		// its stored chunks are removed from the blob store later.
+		if deleteErr := s.repo.DeleteBlob(context.WithoutCancel(ctx), blob.ID); deleteErr != nil {
+			s.log.Error().Err(deleteErr).Str("blob", blob.ID).Msg("failed to delete incomplete blob")
+		}
+
+		return nil, err
+	}
+
+	return &models.Blob{ID: blob.ID, Size: blob.Size}, nil
+}
+
+// receiveChunks stores the chunks returned by next and counts them, their size and hash in the blob.
+// A chunk is stored once the next one is received, so it is known whether the chunk is the last one.
+func (s *blobService) receiveChunks(
+	ctx context.Context,
+	key []byte,
+	blob *repository.Blob,
+	next func() ([]byte, error),
+) error {
+	hash := sha256.New()
+
+	var pending []byte
+
+	for {
+		chunk, err := next()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		last := errors.Is(err, io.EOF)
+
+		if !last {
+			if len(chunk) == 0 || len(chunk) > MaxBlobChunkSize {
+				return ErrInvalidChunk
+			}
+
+			blob.Size += int64(len(chunk))
+			if blob.Size > s.maxSize {
+				return ErrBlobTooLarge
+			}
+		}
+
+		if pending != nil {
+			data, err := s.putChunk(ctx, key, blob, pending, last)
+			if err != nil {
+				return err
+			}
+
+			hash.Write(data)
+			blob.Chunks++
+		}
+
+		if last {
+			blob.Hash = hash.Sum(nil)
+
+			return nil
+		}
+
+		pending = chunk
+	}
+}
+
+// putChunk stores the next chunk of the blob, encrypted with the key if it is set,
+// and returns the stored data.
+func (s *blobService) putChunk(
+	ctx context.Context,
+	key []byte,
+	blob *repository.Blob,
+	chunk []byte,
+	last bool,
+) ([]byte, error) {
+	data := chunk
+	if key != nil {
+		encrypted, err := s.crypt.Encrypt(key, string(chunk), blobAssociatedData(blob, blob.Chunks, last))
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to encrypt blob chunk")
+
+			return nil, err
+		}
+
+		data = encrypted
+	}
+
+	if err := s.store.PutChunk(ctx, blob.ID, blob.Chunks, data); err != nil {
+		s.log.Error().Err(err).Msg("failed to store blob chunk")
+
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Download passes the chunks of the user's blob to send in the order they were uploaded.
+// repository.ErrNoRows is returned if the user has no such completed blob. The chunks encrypted
+// by the server are authenticated one by one, so a modified, moved, missing or extra chunk fails
+// the download before it is sent. The chunks encrypted by the client are checked by the client
+// in the same way. ErrBlobCorrupted is also returned after the last chunk if the stored chunks do
+// not match the hash kept by the secret the blob is attached to.
+func (s *blobService) Download(ctx context.Context, blobID string, send func([]byte) error) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	blob, err := s.repo.GetBlob(ctx, blobID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get blob")
+		}
+
+		return err
+	}
+
+	if !blob.Completed {
+		return repository.ErrNoRows
+	}
+
+	var key []byte
+	if !isClientSideEncryption(ctx) {
+		key, err = s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	hash := sha256.New()
+
+	for seq := 0; seq < blob.Chunks; seq++ {
+		data, err := s.store.GetChunk(ctx, blob.ID, seq)
+		if err != nil {
+			s.log.Error().Err(err).Str("blob", blob.ID).Int("seq", seq).Msg("failed to get blob chunk")
+
+			if errors.Is(err, blobstore.ErrNotFound) {
+				return ErrBlobCorrupted
+			}
+
+			return err
+		}
+
+		hash.Write(data)
+
+		if key != nil {
+			if data, err = s.openChunk(key, blob, seq, data); err != nil {
+				return err
+			}
+		}
+
+		if err := send(data); err != nil {
+			return err
+		}
+	}
+
+	// Blobs uploaded before their hashes were kept have no hash until upgrade-secrets is run.
+	if blob.Hash != nil && !bytes.Equal(hash.Sum(nil), blob.Hash) {
+		s.log.Error().Str("blob", blob.ID).Msg("blob does not match its hash")
+
+		return ErrBlobCorrupted
+	}
+
+	return nil
+}
+
+// Run periodically removes the blobs which were not attached to a secret in time,
+// such as abandoned uploads, and the chunks of removed blobs from the blob store
+// until the context is done.
+func (s *blobService) Run(ctx context.Context) {
+	ticker := time.NewTicker(blobCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		removed, err := s.repo.DeleteUnattachedBlobs(ctx, time.Now().Add(-unattachedBlobTTL))
+		if err != nil {
+			if ctx.Err() == nil {
+				s.log.Error().Err(err).Msg("failed to delete unattached blobs")
+			}
+		} else if removed > 0 {
+			s.log.Info().Int64("count", removed).Msg("deleted unattached blobs")
+		}
+
+		if err := s.purgeDeletedBlobs(ctx); err != nil && ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to purge deleted blobs")
+		}
+	}
+}
+
+// purgeDeletedBlobs removes the chunks of the removed blobs from the blob store.
+// A blob stays queued for removal until its chunks are removed, so failures are retried later.
+func (s *blobService) purgeDeletedBlobs(ctx context.Context) error {
+	for {
+		blobIDs, err := s.repo.GetDeletedBlobIDs(ctx, blobPurgeBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, blobID := range blobIDs {
+			if err := s.store.DeleteBlob(ctx, blobID); err != nil {
+				return err
+			}
+
+			if err := s.repo.ConfirmBlobDeletion(ctx, blobID); err != nil {
+				return err
+			}
+		}
+
+		if len(blobIDs) < blobPurgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// openChunk decrypts the chunk of the blob with the given sequence number. The legacy chunks
+// of the blob are decrypted without the mark of the last chunk while legacy chunks are allowed.
+func (s *blobService) openChunk(key []byte, blob *repository.Blob, seq int, data []byte) ([]byte, error) {
+	chunk, err := s.crypt.Decrypt(key, data, blobAssociatedData(blob, seq, seq == blob.Chunks-1))
+	if err != nil && blob.LegacyChunks && s.legacyChunks {
+		chunk, err = s.crypt.Decrypt(key, data, legacyBlobAssociatedData(blob, seq))
+	}
+
+	if err != nil {
+		s.log.Error().Err(err).Str("blob", blob.ID).Int("seq", seq).Msg("failed to decrypt blob chunk")
+
+		return nil, err
+	}
+
+	return []byte(chunk), nil
+}
+
+// blobAssociatedData returns the data authenticated along with the encrypted chunk of the blob.
+// It prevents moving chunks between users and blobs, reordering the chunks of a blob and,
+// as the last chunk is marked, truncating or extending the blob.
+func blobAssociatedData(blob *repository.Blob, seq int, last bool) []byte {
+	return []byte(fmt.Sprintf("user:%d;blob:%s;chunk:%d;last:%t", blob.UserID, blob.ID, seq, last))
+}
+
+// legacyBlobAssociatedData returns the data authenticated along with the chunks of the blobs
+// encrypted before the last chunk was marked.
+func legacyBlobAssociatedData(blob *repository.Blob, seq int) []byte {
+	return []byte(fmt.Sprintf("user:%d;blob:%s;chunk:%d", blob.UserID, blob.ID, seq))
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/blobstore"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func chunkReader(chunks ...[]byte) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+
+		chunk := chunks[0]
+		chunks = chunks[1:]
+
+		return chunk, nil
+	}
+}
+
+func hashOf(chunks ...string) []byte {
+	hash := sha256.New()
+	for _, chunk := range chunks {
+		hash.Write([]byte(chunk))
+	}
+
+	return hash.Sum(nil)
+}
+
+func Test_blobService_Upload(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr       error
+		expected          *models.Blob
+		next              func() ([]byte, error)
+		prepareRepo       func(r *mocks.MockBlobRepository)
+		prepareStore      func(s *mocks.MockBlobStore)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+		clientSide        bool
+	}{
+		{
+			name: "success: chunks are encrypted with user key",
+			next: chunkReader([]byte("first"), []byte("second")),
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("CompleteBlob", mock.Anything, mock.MatchedBy(func(b *repository.Blob) bool {
+					return b.Chunks == 2 && b.Size == 11 &&
+						assert.ObjectsAreEqual(hashOf("encrypted-first", "encrypted-second"), b.Hash)
+				})).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("PutChunk", mock.Anything, mock.Anything, 0, []byte("encrypted-first")).Return(nil).Times(1)
+				s.On("PutChunk", mock.Anything, mock.Anything, 1, []byte("encrypted-second")).Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, "first", mock.MatchedBy(func(ad []byte) bool {
+					return strings.HasSuffix(string(ad), ";chunk:0;last:false")
+				})).Return([]byte("encrypted-first"), nil).Times(1)
+				e.On("Encrypt", testKey, "second", mock.MatchedBy(func(ad []byte) bool {
+					return strings.HasSuffix(string(ad), ";chunk:1;last:true")
+				})).Return([]byte("encrypted-second"), nil).Times(1)
+			},
+			expected: &models.Blob{Size: 11},
+		},
+		{
+			name:       "success: chunks encrypted by client are stored as is",
+			next:       chunkReader([]byte("sealed")),
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("CompleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("PutChunk", mock.Anything, mock.Anything, 0, []byte("sealed")).Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected:          &models.Blob{Size: 6},
+		},
+		{
+			name:       "error: empty chunk",
+			next:       chunkReader([]byte{}),
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("DeleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore:      func(s *mocks.MockBlobStore) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrInvalidChunk,
+		},
+		{
+			name:       "error: chunk too large",
+			next:       chunkReader(make([]byte, MaxBlobChunkSize+1)),
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("DeleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore:      func(s *mocks.MockBlobStore) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrInvalidChunk,
+		},
+		{
+			name:       "error: blob exceeds maximum size",
+			next:       chunkReader([]byte("0123456789"), []byte("0123456789"), []byte("0")),
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("DeleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("PutChunk", mock.Anything, mock.Anything, 0, mock.Anything).Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrBlobTooLarge,
+		},
+		{
+			name: "error: failed to receive chunk",
+			next: func() ([]byte, error) {
+				return nil, errInternal
+			},
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("DeleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore:      func(s *mocks.MockBlobStore) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       errInternal,
+		},
+		{
+			name: "error: failed to store chunk",
+			next: chunkReader([]byte("first")),
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("DeleteBlob", mock.Anything, mock.Anything).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("PutChunk", mock.Anything, mock.Anything, 0, mock.Anything).Return(errInternal).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, "first", mock.Anything).Return([]byte("encrypted-first"), nil).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockBlobRepository)
+			mockStore := new(mocks.MockBlobStore)
+			mockEncryption := new(mocks.MockEncryption)
+			mockKeys := new(mocks.MockKeyService)
+
+			mockRepo.On("CreateBlob", mock.Anything, mock.MatchedBy(func(b *repository.Blob) bool {
+				return b.UserID == 1 && len(b.ID) == 2*blobIDSize
+			})).Return(nil).Times(1)
+			tt.prepareRepo(mockRepo)
+			tt.prepareStore(mockStore)
+			tt.prepareEncryption(mockEncryption)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSide)
+
+			blobService := NewBlobService(mockRepo, mockStore, &log, mockEncryption, mockKeys, 20, false)
+			blob, err := blobService.Upload(ctx, tt.next)
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expected != nil && assert.NotNil(t, blob) {
+				assert.Equal(t, tt.expected.Size, blob.Size)
+				assert.NotEmpty(t, blob.ID)
+			}
+
+			mockRepo.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+			mockEncryption.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_blobService_Download(t *testing.T) {
+	log := logger.NewLogger()
+
+	blob := &repository.Blob{
+		ID:        "blob",
+		UserID:    1,
+		Chunks:    2,
+		Size:      11,
+		Hash:      hashOf("encrypted-first", "encrypted-second"),
+		Completed: true,
+	}
+
+	legacyBlob := &repository.Blob{
+		ID:           "blob",
+		UserID:       1,
+		Chunks:       2,
+		Size:         11,
+		Hash:         hashOf("encrypted-first", "encrypted-second"),
+		Completed:    true,
+		LegacyChunks: true,
+	}
+
+	tests := []struct {
+		expectedErr       error
+		prepareRepo       func(r *mocks.MockBlobRepository)
+		prepareStore      func(s *mocks.MockBlobStore)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+		expected          []string
+		clientSide        bool
+		legacyChunks      bool
+	}{
+		{
+			name: "success: chunks are decrypted in order",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(blob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("encrypted-second"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-first"), []byte("user:1;blob:blob;chunk:0;last:false")).
+					Return("first", nil).Times(1)
+				e.On("Decrypt", testKey, []byte("encrypted-second"), []byte("user:1;blob:blob;chunk:1;last:true")).
+					Return("second", nil).Times(1)
+			},
+			expected: []string{"first", "second"},
+		},
+		{
+			name:         "success: legacy chunks are decrypted while allowed",
+			legacyChunks: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(legacyBlob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("encrypted-second"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, mock.Anything, mock.MatchedBy(func(ad []byte) bool {
+					return strings.Contains(string(ad), ";last:")
+				})).Return("", errInternal).Times(2)
+				e.On("Decrypt", testKey, []byte("encrypted-first"), []byte("user:1;blob:blob;chunk:0")).
+					Return("first", nil).Times(1)
+				e.On("Decrypt", testKey, []byte("encrypted-second"), []byte("user:1;blob:blob;chunk:1")).
+					Return("second", nil).Times(1)
+			},
+			expected: []string{"first", "second"},
+		},
+		{
+			name: "error: legacy chunks are not allowed",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(legacyBlob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-first"), []byte("user:1;blob:blob;chunk:0;last:false")).
+					Return("", errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: blob is truncated",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).
+					Return(&repository.Blob{ID: "blob", UserID: 1, Chunks: 1, Completed: true}, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-first"), []byte("user:1;blob:blob;chunk:0;last:true")).
+					Return("", errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name:       "success: chunks encrypted by client are sent as is",
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).
+					Return(&repository.Blob{ID: "blob", UserID: 1, Chunks: 2, Completed: true}, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("sealed-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("sealed-second"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected:          []string{"sealed-first", "sealed-second"},
+		},
+		{
+			name: "error: blob not found",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			prepareStore:      func(s *mocks.MockBlobStore) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       repository.ErrNoRows,
+		},
+		{
+			name: "error: blob is not completed",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).
+					Return(&repository.Blob{ID: "blob", UserID: 1, Chunks: 1}, nil).Times(1)
+			},
+			prepareStore:      func(s *mocks.MockBlobStore) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       repository.ErrNoRows,
+		},
+		{
+			name:       "error: chunk is missing from store",
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(blob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return(nil, blobstore.ErrNotFound).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrBlobCorrupted,
+		},
+		{
+			name:       "error: chunks do not match hash",
+			clientSide: true,
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(blob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("tampered"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected:          []string{"encrypted-first", "tampered"},
+			expectedErr:       ErrBlobCorrupted,
+		},
+		{
+			name: "error: failed to decrypt chunk",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetBlob", mock.Anything, "blob", 1).Return(blob, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("encrypted-first"), nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, mock.Anything, mock.Anything).Return("", errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockBlobRepository)
+			mockStore := new(mocks.MockBlobStore)
+			mockEncryption := new(mocks.MockEncryption)
+			mockKeys := new(mocks.MockKeyService)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareStore(mockStore)
+			tt.prepareEncryption(mockEncryption)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSide)
+
+			var sent []string
+
+			blobService := NewBlobService(mockRepo, mockStore, &log, mockEncryption, mockKeys, 20, tt.legacyChunks)
+			err := blobService.Download(ctx, "blob", func(data []byte) error {
+				sent = append(sent, string(data))
+
+				return nil
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, sent)
+			mockRepo.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_blobService_purgeDeletedBlobs(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr  error
+		prepareRepo  func(r *mocks.MockBlobRepository)
+		prepareStore func(s *mocks.MockBlobStore)
+		name         string
+	}{
+		{
+			name: "success: chunks of deleted blobs are removed",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetDeletedBlobIDs", mock.Anything, blobPurgeBatchSize).
+					Return([]string{"first", "second"}, nil).Times(1)
+				r.On("ConfirmBlobDeletion", mock.Anything, "first").Return(nil).Times(1)
+				r.On("ConfirmBlobDeletion", mock.Anything, "second").Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("DeleteBlob", mock.Anything, "first").Return(nil).Times(1)
+				s.On("DeleteBlob", mock.Anything, "second").Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: blob stays queued if store fails",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetDeletedBlobIDs", mock.Anything, blobPurgeBatchSize).
+					Return([]string{"first", "second"}, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("DeleteBlob", mock.Anything, "first").Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to get deleted blobs",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetDeletedBlobIDs", mock.Anything, blobPurgeBatchSize).Return(nil, errInternal).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {},
+			expectedErr:  errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockBlobRepository)
+			mockStore := new(mocks.MockBlobStore)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareStore(mockStore)
+
+			s := &blobService{repo: mockRepo, store: mockStore, log: &log}
+			err := s.purgeDeletedBlobs(context.Background())
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+const (
+	// watcherBufferSize is the number of events a watcher may lag behind before it is dropped.
+	watcherBufferSize = 64
+	// listenRetryDelay is the period of time to wait before listening again after a failure.
+	listenRetryDelay = time.Second
+)
+
+// SecretBroker is an interface that defines methods for delivering
+// the changes of secrets to the watchers of their owners.
+type SecretBroker interface {
+	Run(ctx context.Context)
+	Subscribe(userID int) (<-chan models.SecretEvent, func())
+}
+
+type secretBroker struct {
+	listener repository.SecretListener
+	log      *zerolog.Logger
+	watchers map[int]map[chan models.SecretEvent]struct{}
+	mu       sync.Mutex
+	stopped  bool
+}
+
+// NewSecretBroker creates and returns a new SecretBroker instance.
+func NewSecretBroker(listener repository.SecretListener, log *zerolog.Logger) SecretBroker {
+	return &secretBroker{
+		listener: listener,
+		log:      log,
+		watchers: make(map[int]map[chan models.SecretEvent]struct{}),
+	}
+}
+
+// Run listens for the changes of secrets and publishes them to the watchers until the context is done.
+// Events may be lost while the listener is down, so all watchers are dropped when it fails
+// and once the broker stops. A dropped watcher has its channel closed and should sync again.
+func (b *secretBroker) Run(ctx context.Context) {
+	defer func() {
+		b.mu.Lock()
+		b.stopped = true
+		b.mu.Unlock()
+
+		b.dropAll()
+	}()
+
+	for {
+		err := b.listener.Listen(ctx, b.publish)
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.log.Error().Err(err).Msg("failed to listen for secret changes")
+		b.dropAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// Subscribe registers a watcher of the user's secrets. It returns the channel of events
+// and the function which unregisters the watcher. The channel is closed if the watcher
+// falls behind or the broker cannot guarantee the delivery of further events.
+func (b *secretBroker) Subscribe(userID int) (<-chan models.SecretEvent, func()) {
+	events := make(chan models.SecretEvent, watcherBufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		close(events)
+
+		return events, func() {}
+	}
+
+	if b.watchers[userID] == nil {
+		b.watchers[userID] = make(map[chan models.SecretEvent]struct{})
+	}
+
+	b.watchers[userID][events] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.drop(userID, events)
+	}
+
+	return events, unsubscribe
+}
+
+func (b *secretBroker) publish(event models.SecretEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.watchers[event.UserID] {
+		select {
+		case events <- event:
+		default:
+			b.log.Warn().Int("user", event.UserID).Msg("secret watcher is too slow, dropping it")
+			b.drop(event.UserID, events)
+		}
+	}
+}
+
+func (b *secretBroker) dropAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, watchers := range b.watchers {
+		for events := range watchers {
+			b.drop(userID, events)
+		}
+	}
+}
+
+// drop unregisters the watcher and closes its channel. It must be called with the lock held.
+func (b *secretBroker) drop(userID int, events chan models.SecretEvent) {
+	if _, ok := b.watchers[userID][events]; !ok {
+		return
+	}
+
+	delete(b.watchers[userID], events)
+	if len(b.watchers[userID]) == 0 {
+		delete(b.watchers, userID)
+	}
+
+	close(events)
+}
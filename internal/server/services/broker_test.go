@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+// drain reads the events until the channel is closed or no event arrives in time.
+// It reports whether the channel was closed.
+func drain(events <-chan models.SecretEvent) ([]models.SecretEvent, bool) {
+	var received []models.SecretEvent
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return received, true
+			}
+
+			received = append(received, event)
+		case <-time.After(100 * time.Millisecond):
+			return received, false
+		}
+	}
+}
+
+func TestSecretBroker_Run(t *testing.T) {
+	log := logger.NewLogger()
+
+	event := models.SecretEvent{Kind: models.SecretEventUpdated, Revision: 2, SecretID: 10, UserID: 1}
+	listening := make(chan struct{})
+
+	listener := new(mocks.MockSecretListener)
+	listener.On("Listen", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			<-listening
+			args.Get(1).(func(models.SecretEvent))(event)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(context.Canceled).Once()
+
+	broker := NewSecretBroker(listener, &log)
+
+	events, stop := broker.Subscribe(1)
+	defer stop()
+
+	otherEvents, otherStop := broker.Subscribe(2)
+	defer otherStop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		broker.Run(ctx)
+		close(done)
+	}()
+
+	close(listening)
+
+	received, closed := drain(events)
+	assert.Equal(t, []models.SecretEvent{event}, received)
+	assert.False(t, closed)
+
+	received, closed = drain(otherEvents)
+	assert.Empty(t, received)
+	assert.False(t, closed)
+
+	cancel()
+	<-done
+
+	_, closed = drain(events)
+	assert.True(t, closed, "watchers are dropped when the broker stops")
+
+	stoppedEvents, _ := broker.Subscribe(1)
+	_, closed = drain(stoppedEvents)
+	assert.True(t, closed, "subscription to a stopped broker is closed")
+}
+
+func TestSecretBroker_ListenerFailure(t *testing.T) {
+	log := logger.NewLogger()
+
+	failed := make(chan struct{})
+
+	listener := new(mocks.MockSecretListener)
+	listener.On("Listen", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { close(failed) }).
+		Return(errors.New("connection lost")).Once()
+	listener.On("Listen", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(context.Canceled)
+
+	broker := NewSecretBroker(listener, &log)
+	events, stop := broker.Subscribe(1)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go broker.Run(ctx)
+	<-failed
+
+	_, closed := drain(events)
+	assert.True(t, closed, "watchers are dropped when events may be lost")
+}
+
+func TestSecretBroker_Subscribe(t *testing.T) {
+	log := logger.NewLogger()
+
+	broker := NewSecretBroker(new(mocks.MockSecretListener), &log).(*secretBroker)
+
+	t.Run("slow watcher is dropped", func(t *testing.T) {
+		events, stop := broker.Subscribe(1)
+		defer stop()
+
+		for i := 0; i <= watcherBufferSize; i++ {
+			broker.publish(models.SecretEvent{Kind: models.SecretEventCreated, SecretID: i, UserID: 1})
+		}
+
+		received, closed := drain(events)
+		assert.Len(t, received, watcherBufferSize)
+		assert.True(t, closed)
+	})
+
+	t.Run("stopped watcher receives nothing", func(t *testing.T) {
+		events, stop := broker.Subscribe(1)
+		stop()
+		stop()
+
+		broker.publish(models.SecretEvent{Kind: models.SecretEventCreated, SecretID: 1, UserID: 1})
+
+		received, closed := drain(events)
+		assert.Empty(t, received)
+		assert.True(t, closed)
+		assert.Empty(t, broker.watchers)
+	})
+}
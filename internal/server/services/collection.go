@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// ErrBinaryCollection is returned when a secret of a collection is binary. The file of a binary
+// secret is encrypted with the key of the user who uploaded it and cannot be read by other members.
+var ErrBinaryCollection = errors.New("binary secrets cannot be stored in collections")
+
+// createCollectionSecret creates the secret in its collection if the user is a member of the
+// organization owning the collection who can write its secrets. repository.ErrNoRows is returned
+// if there is no such collection or the user is not a member of its organization.
+func (s *secretService) createCollectionSecret(ctx context.Context, userID int, secretModel *models.Secret) error {
+	if isClientSideEncryption(ctx) {
+		return ErrOrganizationUnavailable
+	}
+
+	member, err := s.repo.GetCollectionMember(ctx, secretModel.CollectionID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get collection member")
+		}
+
+		return err
+	}
+
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	secretID, err := s.repo.NextSecretID(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to reserve secret id")
+
+		return err
+	}
+
+	secret, err := s.sealCollectionSecret(ctx, member.OrganizationID, secretID, secretModel)
+	if err != nil {
+		return err
+	}
+
+	secret.CollectionID = secretModel.CollectionID
+
+	if err := s.repo.CreateCollectionSecret(ctx, secret); err != nil {
+		s.log.Error().Err(err).Msg("failed to create collection secret")
+
+		return err
+	}
+
+	return nil
+}
+
+// updateCollectionSecret updates the secret of a collection on behalf of the member
+// if the role of the member allows it, see UpdateSecret.
+func (s *secretService) updateCollectionSecret(
+	ctx context.Context,
+	member *repository.Member,
+	secretModel *models.Secret,
+) error {
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	secret, err := s.sealCollectionSecret(ctx, member.OrganizationID, secretModel.ID, secretModel)
+	if err != nil {
+		return err
+	}
+
+	secret.Version = secretModel.Version
+
+	if err := s.repo.UpdateCollectionSecret(ctx, secret); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.collectionConflict(ctx, secretModel.ID)
+		}
+
+		s.log.Error().Err(err).Msg("failed to update collection secret")
+
+		return err
+	}
+
+	secretModel.Version = secret.Version
+
+	return nil
+}
+
+// deleteCollectionSecret moves the secret of a collection to the trash on behalf of the member
+// if the role of the member allows it, see DeleteSecret.
+func (s *secretService) deleteCollectionSecret(ctx context.Context, member *repository.Member, secretID, version int) error {
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	if err := s.repo.DeleteCollectionSecret(ctx, secretID, version); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.collectionConflict(ctx, secretID)
+		}
+
+		s.log.Error().Err(err).Msg("failed to delete collection secret")
+
+		return err
+	}
+
+	return nil
+}
+
+// restoreCollectionSecret moves the secret of a collection out of the trash on behalf of the member
+// if the role of the member allows it, see RestoreSecret.
+func (s *secretService) restoreCollectionSecret(ctx context.Context, member *repository.Member, secretID int) error {
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	if err := s.repo.RestoreCollectionSecret(ctx, secretID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to restore collection secret")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// purgeCollectionSecret permanently removes the secret of a collection from the trash on behalf
+// of the member if the role of the member allows it, see PurgeSecret.
+func (s *secretService) purgeCollectionSecret(ctx context.Context, member *repository.Member, secretID int) error {
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	if err := s.repo.PurgeCollectionSecret(ctx, secretID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to purge collection secret")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// listCollectionVersions retrieves the previous versions of the secret of a collection, see ListVersions.
+// Every member of the organization owning the collection can read them.
+func (s *secretService) listCollectionVersions(ctx context.Context, secretID int) ([]models.Secret, error) {
+	versions, err := s.repo.GetCollectionVersions(ctx, secretID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get collection secret versions")
+
+		return nil, err
+	}
+
+	orgKeys := make(map[int][]byte)
+
+	result := make([]models.Secret, len(versions))
+	for i := range versions {
+		key, err := s.organizationKey(ctx, orgKeys, versions[i].OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i], err = s.openSecret(key, &versions[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// restoreCollectionVersion replaces the secret of a collection with its previous version on behalf
+// of the member if the role of the member allows it, see RestoreVersion.
+func (s *secretService) restoreCollectionVersion(
+	ctx context.Context,
+	member *repository.Member,
+	secretID, version, currentVersion int,
+) error {
+	if !hasRole(member.Role, models.OrganizationRoleMember) {
+		return ErrInsufficientRole
+	}
+
+	previous, err := s.repo.GetCollectionVersion(ctx, secretID, version)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get collection secret version")
+		}
+
+		return err
+	}
+
+	restored := &repository.Secret{
+		ID:             secretID,
+		OrganizationID: previous.OrganizationID,
+		Type:           previous.Type,
+		Content:        previous.Content,
+		MetaData:       previous.MetaData,
+		Name:           previous.Name,
+		Tags:           previous.Tags,
+		Folder:         previous.Folder,
+		Version:        currentVersion,
+	}
+
+	if err := s.repo.UpdateCollectionSecret(ctx, restored); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.collectionConflict(ctx, secretID)
+		}
+
+		s.log.Error().Err(err).Msg("failed to restore collection secret version")
+
+		return err
+	}
+
+	return nil
+}
+
+// secretMember returns the membership of the user in the organization owning the collection
+// of the secret, or nil if the secret is not in a collection of an organization of the user.
+// Users with client-side encryption are members of no organization.
+func (s *secretService) secretMember(ctx context.Context, userID, secretID int) (*repository.Member, error) {
+	if isClientSideEncryption(ctx) {
+		return nil, nil
+	}
+
+	member, err := s.repo.GetSecretMember(ctx, secretID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, nil
+		}
+
+		s.log.Error().Err(err).Msg("failed to get secret member")
+
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// collectionConflict is the conflict method for the secrets of collections.
+func (s *secretService) collectionConflict(ctx context.Context, secretID int) error {
+	secret, err := s.repo.GetCollectionSecret(ctx, secretID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get collection secret")
+		}
+
+		return err
+	}
+
+	key, err := s.keys.GetOrganizationKey(ctx, secret.OrganizationID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.openSecret(key, secret)
+	if err != nil {
+		return err
+	}
+
+	return &ConflictError{Current: &current}
+}
+
+// sealCollectionSecret validates the secret of a collection and prepares it for storage.
+// The secret is encrypted with the key of the organization and bound to the organization,
+// its ID and type. Secrets of collections get no blind indexes and cannot be binary.
+func (s *secretService) sealCollectionSecret(
+	ctx context.Context,
+	organizationID int,
+	secretID int,
+	secretModel *models.Secret,
+) (*repository.Secret, error) {
+	if err := validatePayload(secretModel, false); err != nil {
+		s.log.Error().Err(err).Str("type", secretModel.Type).Msg("invalid secret payload")
+
+		return nil, err
+	}
+
+	if secretModel.Type == models.SecretTypeBinary {
+		return nil, ErrBinaryCollection
+	}
+
+	if err := normalizeLabels(secretModel); err != nil {
+		s.log.Error().Err(err).Msg("invalid secret labels")
+
+		return nil, err
+	}
+
+	content, err := encodePayload(secretModel.Payload)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to encode payload")
+
+		return nil, err
+	}
+
+	key, err := s.keys.GetOrganizationKey(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &repository.Secret{
+		ID:             secretID,
+		OrganizationID: organizationID,
+		Type:           secretModel.Type,
+		Name:           secretModel.Name,
+		Tags:           secretModel.Tags,
+		Folder:         secretModel.Folder,
+	}
+
+	if err := s.encryptFields(key, secret, content, secretModel.MetaData); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// organizationKey returns the key of the organization from the keys already unwrapped
+// or unwraps it and adds it to them.
+func (s *secretService) organizationKey(ctx context.Context, keys map[int][]byte, organizationID int) ([]byte, error) {
+	if key, ok := keys[organizationID]; ok {
+		return key, nil
+	}
+
+	key, err := s.keys.GetOrganizationKey(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys[organizationID] = key
+
+	return key, nil
+}
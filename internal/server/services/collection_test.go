@@ -0,0 +1,466 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+var organizationKey = []byte("organization-key")
+
+func Test_secretService_CreateCollectionSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr         error
+		prepareRepo         func(s *mocks.MockSecretRepository)
+		secret              *models.Secret
+		name                string
+		clientSideEncrypted bool
+	}{
+		{
+			name:   "success: secret created in collection",
+			secret: &models.Secret{CollectionID: 3, Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetCollectionMember", mock.Anything, 3, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("NextSecretID", mock.Anything).Return(13, nil).Times(1)
+				s.On("CreateCollectionSecret", mock.Anything, &repository.Secret{
+					ID:             13,
+					OrganizationID: 5,
+					CollectionID:   3,
+					Type:           models.SecretTypeText,
+					Content:        []byte("org-content"),
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name:                "error: client-side encryption",
+			secret:              &models.Secret{CollectionID: 3, Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+			clientSideEncrypted: true,
+			prepareRepo:         func(s *mocks.MockSecretRepository) {},
+			expectedErr:         ErrOrganizationUnavailable,
+		},
+		{
+			name:   "error: not a member",
+			secret: &models.Secret{CollectionID: 3, Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetCollectionMember", mock.Anything, 3, 1).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name:   "error: read-only member",
+			secret: &models.Secret{CollectionID: 3, Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetCollectionMember", mock.Anything, 3, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name:   "error: binary secret",
+			secret: &models.Secret{CollectionID: 3, Type: models.SecretTypeBinary, Payload: &models.Binary{}},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetCollectionMember", mock.Anything, 3, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("NextSecretID", mock.Anything).Return(13, nil).Times(1)
+			},
+			expectedErr: ErrBinaryCollection,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Encrypt", organizationKey, payloadHeader+`{"body":"body"}`,
+				[]byte("organization:5;secret:13;type:TEXT;field:content")).Return([]byte("org-content"), nil).Maybe()
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil).Maybe()
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSideEncrypted)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.CreateSecret(ctx, tt.secret)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_UpdateCollectionSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	stored := &repository.Secret{
+		ID:             13,
+		OrganizationID: 5,
+		CollectionID:   3,
+		Type:           models.SecretTypeText,
+		Content:        []byte("stored-content"),
+	}
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+	}{
+		{
+			name: "success: member updates secret",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("UpdateCollectionSecret", mock.Anything, &repository.Secret{
+					ID:             13,
+					OrganizationID: 5,
+					Type:           models.SecretTypeText,
+					Content:        []byte("org-content"),
+					Version:        2,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: read-only member",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: version conflict",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+				s.On("UpdateCollectionSecret", mock.Anything, mock.Anything).Return(repository.ErrNoRows).Times(1)
+				s.On("GetCollectionSecret", mock.Anything, 13).Return(stored, nil).Times(1)
+			},
+			expectedErr: &ConflictError{Current: &models.Secret{
+				ID:           13,
+				CollectionID: 3,
+				Type:         models.SecretTypeText,
+				Payload:      &models.Text{Body: "current"},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Encrypt", organizationKey, payloadHeader+`{"body":"new"}`,
+				[]byte("organization:5;secret:13;type:TEXT;field:content")).Return([]byte("org-content"), nil).Maybe()
+			mockEncryption.On("Decrypt", organizationKey, []byte("stored-content"),
+				[]byte("organization:5;secret:13;type:TEXT;field:content")).Return(payloadHeader+`{"body":"current"}`, nil).Maybe()
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil).Maybe()
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.UpdateSecret(ctx, &models.Secret{
+				ID:      13,
+				Type:    models.SecretTypeText,
+				Payload: &models.Text{Body: "new"},
+				Version: 2,
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_DeleteCollectionSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+	}{
+		{
+			name: "success: member deletes secret",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("DeleteCollectionSecret", mock.Anything, 13, 2).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: read-only member",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: secret already deleted",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("DeleteCollectionSecret", mock.Anything, 13, 2).Return(repository.ErrNoRows).Times(1)
+				s.On("GetCollectionSecret", mock.Anything, 13).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, nil, nil, nil, HistoryRetention{}, 0)
+			err := secretService.DeleteSecret(ctx, 13, 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_GetUserSecrets_Collections(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("GetUserSecrets", mock.Anything, 1, mock.Anything).Return([]repository.Secret{
+		{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("user-content")},
+		{
+			ID:             14,
+			OrganizationID: 5,
+			CollectionID:   3,
+			Collection:     "infra",
+			Organization:   "team",
+			Permission:     models.SharePermissionReadOnly,
+			Type:           models.SecretTypeText,
+			Content:        []byte("org-content"),
+		},
+	}, nil).Times(1)
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("Decrypt", testKey, []byte("user-content"),
+		[]byte("user:1;secret:13;type:TEXT;field:content")).Return(payloadHeader+`{"body":"mine"}`, nil).Times(1)
+	mockEncryption.On("Decrypt", organizationKey, []byte("org-content"),
+		[]byte("organization:5;secret:14;type:TEXT;field:content")).Return(payloadHeader+`{"body":"team"}`, nil).Times(1)
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil).Times(1)
+	mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil).Times(1)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+	page, err := secretService.GetUserSecrets(ctx, &models.SecretFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Secret{
+		{ID: 13, UserID: 1, Type: models.SecretTypeText, Payload: &models.Text{Body: "mine"}},
+		{
+			ID:           14,
+			CollectionID: 3,
+			Collection:   "infra",
+			Organization: "team",
+			Permission:   models.SharePermissionReadOnly,
+			Type:         models.SecretTypeText,
+			Payload:      &models.Text{Body: "team"},
+		},
+	}, page.Secrets)
+	mockKeys.AssertExpectations(t)
+}
+
+func Test_secretService_SyncCollectionSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("GetChanges", mock.Anything, 2, int64(5)).Return(&repository.Changes{
+		Secrets: []repository.Secret{{
+			ID:             14,
+			OrganizationID: 5,
+			CollectionID:   3,
+			Collection:     "infra",
+			Organization:   "team",
+			Permission:     models.SharePermissionReadWrite,
+			Type:           models.SecretTypeText,
+			Content:        []byte("org-content"),
+			Version:        2,
+		}},
+		Revision: 6,
+	}, nil).Times(1)
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("Decrypt", organizationKey, []byte("org-content"),
+		[]byte("organization:5;secret:14;type:TEXT;field:content")).Return(payloadHeader+`{"body":"team"}`, nil).Times(1)
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetUserKey", mock.Anything, 2).Return(recipientKey, nil)
+	mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil).Times(1)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 2)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+	changes, err := secretService.Sync(ctx, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Changes{
+		Secrets: []models.Secret{{
+			ID:           14,
+			CollectionID: 3,
+			Collection:   "infra",
+			Organization: "team",
+			Permission:   models.SharePermissionReadWrite,
+			Type:         models.SecretTypeText,
+			Payload:      &models.Text{Body: "team"},
+			Version:      2,
+		}},
+		Revision: 6,
+	}, changes)
+	mockRepo.AssertExpectations(t)
+	mockEncryption.AssertExpectations(t)
+}
+
+func Test_secretService_RestoreCollectionSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+		purge       bool
+	}{
+		{
+			name: "success: member restores secret",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("RestoreCollectionSecret", mock.Anything, 13).Return(nil).Times(1)
+			},
+		},
+		{
+			name:  "success: member purges secret",
+			purge: true,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+				s.On("PurgeCollectionSecret", mock.Anything, 13).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: read-only member restores secret",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name:  "error: read-only member purges secret",
+			purge: true,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: secret not in trash",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecretMember", mock.Anything, 13, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("RestoreCollectionSecret", mock.Anything, 13).Return(repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, nil, nil, nil, HistoryRetention{}, 0)
+
+			var err error
+			if tt.purge {
+				err = secretService.PurgeSecret(ctx, 13)
+			} else {
+				err = secretService.RestoreSecret(ctx, 13)
+			}
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_CollectionVersions(t *testing.T) {
+	log := logger.NewLogger()
+
+	previous := &repository.Secret{
+		ID:             13,
+		OrganizationID: 5,
+		CollectionID:   3,
+		Type:           models.SecretTypeText,
+		Content:        []byte("org-content"),
+		Name:           "deploy",
+		Version:        1,
+	}
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("GetSecretMember", mock.Anything, 13, 1).
+		Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(2)
+	mockRepo.On("GetCollectionVersions", mock.Anything, 13).Return([]repository.Secret{*previous}, nil).Times(1)
+	mockRepo.On("GetCollectionVersion", mock.Anything, 13, 1).Return(previous, nil).Times(1)
+	mockRepo.On("UpdateCollectionSecret", mock.Anything, &repository.Secret{
+		ID:             13,
+		OrganizationID: 5,
+		Type:           models.SecretTypeText,
+		Content:        []byte("org-content"),
+		Name:           "deploy",
+		Version:        3,
+	}).Return(nil).Times(1)
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("Decrypt", organizationKey, []byte("org-content"),
+		[]byte("organization:5;secret:13;type:TEXT;field:content")).Return(payloadHeader+`{"body":"old"}`, nil).Times(1)
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil).Times(1)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+
+	versions, err := secretService.ListVersions(ctx, 13)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Secret{{
+		ID:           13,
+		CollectionID: 3,
+		Type:         models.SecretTypeText,
+		Payload:      &models.Text{Body: "old"},
+		Name:         "deploy",
+		Version:      1,
+	}}, versions)
+
+	err = secretService.RestoreVersion(ctx, 13, 1, 3)
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockKeys.AssertExpectations(t)
+}
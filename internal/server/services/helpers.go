@@ -19,3 +19,18 @@ func extractUserIDFromCtx(ctx context.Context) (int, error) {
 
 	return userID, nil
 }
+
+func extractSessionIDFromCtx(ctx context.Context) (string, error) {
+	sessionID, ok := ctx.Value(interceptors.SessionIDKey).(string)
+	if !ok {
+		return "", ErrExtractFromContext
+	}
+
+	return sessionID, nil
+}
+
+func isClientSideEncryption(ctx context.Context) bool {
+	clientSide, _ := ctx.Value(interceptors.ClientSideEncryptionKey).(bool)
+
+	return clientSide
+}
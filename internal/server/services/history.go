@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// HistoryRetention defines how long the previous versions of secrets are kept. A version is
+// removed once it was replaced more than MaxAge ago or there are MaxVersions newer versions.
+type HistoryRetention struct {
+	MaxAge      time.Duration
+	MaxVersions int
+}
+
+// ListVersions retrieves the previous versions of the secret of the user or of a secret of
+// a collection of the user's organizations, the latest first.
+func (s *secretService) ListVersions(ctx context.Context, secretID int) ([]models.Secret, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	member, err := s.secretMember(ctx, userID, secretID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member != nil {
+		return s.listCollectionVersions(ctx, secretID)
+	}
+
+	versions, err := s.repo.GetVersions(ctx, secretID, userID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get secret versions")
+
+		return nil, err
+	}
+
+	var key []byte
+	if !isClientSideEncryption(ctx) && len(versions) > 0 {
+		key, err = s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]models.Secret, len(versions))
+	for i := range versions {
+		result[i], err = s.openSecret(key, &versions[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// RestoreVersion replaces the secret with its previous version if the current version of the
+// secret is currentVersion. The stored ciphertext of the version is restored as is, it is bound
+// to the secret and not to its version. A secret of a collection is restored if the role of the user
+// in the organization owning the collection allows it, ErrInsufficientRole is returned otherwise.
+// repository.ErrNoRows is returned if the version is not kept, ConflictError if the secret was
+// changed in the meantime.
+func (s *secretService) RestoreVersion(ctx context.Context, secretID, version, currentVersion int) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	member, err := s.secretMember(ctx, userID, secretID)
+	if err != nil {
+		return err
+	}
+
+	if member != nil {
+		return s.restoreCollectionVersion(ctx, member, secretID, version, currentVersion)
+	}
+
+	previous, err := s.repo.GetVersion(ctx, secretID, userID, version)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get secret version")
+		}
+
+		return err
+	}
+
+	restored := &repository.Secret{
+		ID:       secretID,
+		UserID:   userID,
+		Type:     previous.Type,
+		Content:  previous.Content,
+		MetaData: previous.MetaData,
+		BlobID:   previous.BlobID,
+		Name:     previous.Name,
+		Tags:     previous.Tags,
+		Folder:   previous.Folder,
+		Version:  currentVersion,
+	}
+
+	if !isClientSideEncryption(ctx) {
+		key, err := s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		opened, err := s.openSecret(key, previous)
+		if err != nil {
+			return err
+		}
+
+		restored.Indexes = secretIndexes(blindIndexKey(key), opened.Payload)
+
+		shares, err := s.repo.GetShares(ctx, secretID)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to get secret shares")
+
+			return err
+		}
+
+		restored.Shares, err = s.sealShares(ctx, restored, &opened, shares)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.UpdateSecret(ctx, restored); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.conflict(ctx, userID, secretID)
+		}
+
+		s.log.Error().Err(err).Msg("failed to restore secret version")
+
+		return err
+	}
+
+	return nil
+}
+
+// deleteExpiredVersions removes the versions of secrets which are expired according to the history retention.
+func (s *secretService) deleteExpiredVersions(ctx context.Context) {
+	removed, err := s.repo.DeleteExpiredVersions(ctx, time.Now().Add(-s.retention.MaxAge), s.retention.MaxVersions)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to delete expired secret versions")
+		}
+
+		return
+	}
+
+	if removed > 0 {
+		s.log.Info().Int64("count", removed).Msg("deleted expired secret versions")
+	}
+}
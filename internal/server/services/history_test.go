@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func Test_secretService_ListVersions(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+		expected    []models.Secret
+	}{
+		{
+			name: "success: decrypted versions",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersions", mock.Anything, 13, 1).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("encrypted-content"), Version: 2},
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("encrypted-content"), Version: 1},
+					}, nil).Times(1)
+			},
+			expected: []models.Secret{
+				{ID: 13, UserID: 1, Type: models.SecretTypeText, Payload: &models.Text{Body: "old"}, Version: 2},
+				{ID: 13, UserID: 1, Type: models.SecretTypeText, Payload: &models.Text{Body: "old"}, Version: 1},
+			},
+		},
+		{
+			name: "error: failed to get versions",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersions", mock.Anything, 13, 1).Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+			mockRepo.On("GetSecretMember", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrNoRows).Maybe()
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), []byte("user:1;secret:13;type:TEXT;field:content")).
+				Return(payloadHeader+`{"body":"old"}`, nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			versions, err := secretService.ListVersions(ctx, 13)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, versions)
+		})
+	}
+}
+
+func Test_secretService_RestoreVersion(t *testing.T) {
+	log := logger.NewLogger()
+	indexKey := blindIndexKey(testKey)
+
+	previous := &repository.Secret{
+		ID:       13,
+		UserID:   1,
+		Type:     models.SecretTypeCredentials,
+		Content:  []byte("encrypted-content"),
+		MetaData: []byte("encrypted-meta"),
+		BlobID:   "blob",
+		Name:     "GitHub",
+		Tags:     []string{"dev"},
+		Folder:   "Work",
+		Version:  1,
+	}
+
+	tests := []struct {
+		expectedErr         error
+		prepareRepo         func(s *mocks.MockSecretRepository)
+		name                string
+		clientSideEncrypted bool
+	}{
+		{
+			name: "success: version restored with its indexes",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersion", mock.Anything, 13, 1, 1).Return(previous, nil).Times(1)
+				s.On("UpdateSecret", mock.Anything, &repository.Secret{
+					ID:       13,
+					UserID:   1,
+					Type:     models.SecretTypeCredentials,
+					Content:  []byte("encrypted-content"),
+					MetaData: []byte("encrypted-meta"),
+					BlobID:   "blob",
+					Name:     "GitHub",
+					Tags:     []string{"dev"},
+					Folder:   "Work",
+					Indexes: []repository.BlindIndex{
+						blindIndex(indexKey, indexFieldDomain, "example.com"),
+						blindIndex(indexKey, indexFieldLogin, "john"),
+					},
+					Version: 3,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name:                "success: client-side encrypted version restored as is",
+			clientSideEncrypted: true,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersion", mock.Anything, 13, 1, 1).Return(previous, nil).Times(1)
+				s.On("UpdateSecret", mock.Anything, &repository.Secret{
+					ID:       13,
+					UserID:   1,
+					Type:     models.SecretTypeCredentials,
+					Content:  []byte("encrypted-content"),
+					MetaData: []byte("encrypted-meta"),
+					BlobID:   "blob",
+					Name:     "GitHub",
+					Tags:     []string{"dev"},
+					Folder:   "Work",
+					Version:  3,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: version not found",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersion", mock.Anything, 13, 1, 1).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name: "error: secret changed in the meantime",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetVersion", mock.Anything, 13, 1, 1).Return(previous, nil).Times(1)
+				s.On("UpdateSecret", mock.Anything, mock.Anything).Return(repository.ErrNoRows).Times(1)
+				s.On("GetSecret", mock.Anything, 13, 1).Return(&repository.Secret{
+					ID:       13,
+					UserID:   1,
+					Type:     models.SecretTypeCredentials,
+					Content:  []byte("encrypted-content"),
+					MetaData: []byte("encrypted-meta"),
+					Version:  4,
+				}, nil).Times(1)
+			},
+			expectedErr: &ConflictError{Current: &models.Secret{
+				ID:       13,
+				UserID:   1,
+				Type:     models.SecretTypeCredentials,
+				Payload:  &models.Credentials{Login: "John", Password: "password", URL: "https://example.com"},
+				MetaData: "meta",
+				Version:  4,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+			mockRepo.On("GetSecretMember", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrNoRows).Maybe()
+			mockRepo.On("GetShares", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+				Return(payloadHeader+`{"login":"John","password":"password","url":"https://example.com"}`, nil)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-meta"), mock.Anything).
+				Return("meta", nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSideEncrypted)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.RestoreVersion(ctx, 13, 1, 3)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
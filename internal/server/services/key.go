@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// userKeyTTL is the period of time an unwrapped user key is kept in memory.
+const userKeyTTL = time.Minute
+
+// KeyService is an interface that defines methods for managing the data encryption keys
+// of users and organizations.
+type KeyService interface {
+	CreateUserKey(ctx context.Context, userID int) error
+	GetUserKey(ctx context.Context, userID int) ([]byte, error)
+	GetOrganizationKey(ctx context.Context, organizationID int) ([]byte, error)
+	RotateMasterKey(ctx context.Context, batchSize int) (int, error)
+}
+
+type cachedKey struct {
+	expiresAt time.Time
+	key       []byte
+}
+
+type keyService struct {
+	repo      repository.KeyRepository
+	crypt     encryption.Encryption
+	log       *zerolog.Logger
+	cache     map[int]cachedKey
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewKeyService creates and returns a new KeyService instance.
+func NewKeyService(
+	repo repository.KeyRepository,
+	log *zerolog.Logger,
+	crypt encryption.Encryption,
+) KeyService {
+	return &keyService{
+		repo:  repo,
+		log:   log,
+		crypt: crypt,
+		cache: make(map[int]cachedKey),
+	}
+}
+
+// CreateUserKey generates a random data encryption key for the user and stores it wrapped by the master key.
+func (k *keyService) CreateUserKey(ctx context.Context, userID int) error {
+	key, err := k.crypt.GenerateKey()
+	if err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to generate user key")
+
+		return err
+	}
+
+	if err := k.saveUserKey(ctx, userID, key); err != nil {
+		return err
+	}
+
+	k.store(userID, key)
+
+	return nil
+}
+
+// GetUserKey returns the unwrapped data encryption key of the user.
+// Users registered before data encryption keys were introduced get their legacy
+// derived key stored on first use, so that their existing secrets stay readable.
+func (k *keyService) GetUserKey(ctx context.Context, userID int) ([]byte, error) {
+	if key, ok := k.load(userID); ok {
+		return key, nil
+	}
+
+	userKey, err := k.repo.GetUserKey(ctx, userID)
+	if errors.Is(err, repository.ErrNoRows) {
+		return k.migrateLegacyKey(ctx, userID)
+	}
+
+	if err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to get user key")
+
+		return nil, err
+	}
+
+	key, err := k.crypt.UnwrapKey(userKey.WrappedKey, userKey.Version)
+	if err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to unwrap user key")
+
+		return nil, err
+	}
+
+	k.store(userID, key)
+
+	return key, nil
+}
+
+// GetOrganizationKey returns the unwrapped data encryption key of the organization.
+func (k *keyService) GetOrganizationKey(ctx context.Context, organizationID int) ([]byte, error) {
+	orgKey, err := k.repo.GetOrganizationKey(ctx, organizationID)
+	if err != nil {
+		k.log.Error().Err(err).Int("organization", organizationID).Msg("failed to get organization key")
+
+		return nil, err
+	}
+
+	key, err := k.crypt.UnwrapKey(orgKey.WrappedKey, orgKey.Version)
+	if err != nil {
+		k.log.Error().Err(err).Int("organization", organizationID).Msg("failed to unwrap organization key")
+
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (k *keyService) migrateLegacyKey(ctx context.Context, userID int) ([]byte, error) {
+	key, err := k.crypt.DeriveKey(userID)
+	if err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to derive legacy user key")
+
+		return nil, err
+	}
+
+	err = k.saveUserKey(ctx, userID, key)
+	if errors.Is(err, repository.ErrKeyAlreadyExist) {
+		// a concurrent request has stored the key first
+		return k.GetUserKey(ctx, userID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	k.log.Info().Int("user", userID).Msg("legacy user key was stored")
+
+	k.store(userID, key)
+
+	return key, nil
+}
+
+// RotateMasterKey re-wraps the keys of all users and organizations with the current master key
+// in batches of the given size and returns the number of processed keys. Legacy users without
+// a stored key get their derived key stored first. Keys already wrapped by the current
+// master key are skipped, so an interrupted rotation can be resumed by calling it again.
+func (k *keyService) RotateMasterKey(ctx context.Context, batchSize int) (int, error) {
+	var processed int
+
+	for {
+		userIDs, err := k.repo.GetUsersWithoutKey(ctx, batchSize)
+		if err != nil {
+			k.log.Error().Err(err).Msg("failed to get users without key")
+
+			return processed, err
+		}
+
+		if len(userIDs) == 0 {
+			break
+		}
+
+		for _, userID := range userIDs {
+			if _, err := k.migrateLegacyKey(ctx, userID); err != nil {
+				return processed, err
+			}
+		}
+
+		processed += len(userIDs)
+	}
+
+	for {
+		userKeys, err := k.repo.GetStaleUserKeys(ctx, k.crypt.KeyVersion(), batchSize)
+		if err != nil {
+			k.log.Error().Err(err).Msg("failed to get stale user keys")
+
+			return processed, err
+		}
+
+		if len(userKeys) == 0 {
+			break
+		}
+
+		for i := range userKeys {
+			key, err := k.crypt.UnwrapKey(userKeys[i].WrappedKey, userKeys[i].Version)
+			if err != nil {
+				k.log.Error().Err(err).Int("user", userKeys[i].UserID).Msg("failed to unwrap user key")
+
+				return processed, err
+			}
+
+			userKeys[i].WrappedKey, userKeys[i].Version, err = k.crypt.WrapKey(key)
+			if err != nil {
+				k.log.Error().Err(err).Int("user", userKeys[i].UserID).Msg("failed to wrap user key")
+
+				return processed, err
+			}
+		}
+
+		if err := k.repo.UpdateUserKeys(ctx, userKeys); err != nil {
+			k.log.Error().Err(err).Msg("failed to update user keys")
+
+			return processed, err
+		}
+
+		processed += len(userKeys)
+
+		k.log.Info().Int("processed", processed).Msg("user keys were re-wrapped")
+	}
+
+	for {
+		orgKeys, err := k.repo.GetStaleOrganizationKeys(ctx, k.crypt.KeyVersion(), batchSize)
+		if err != nil {
+			k.log.Error().Err(err).Msg("failed to get stale organization keys")
+
+			return processed, err
+		}
+
+		if len(orgKeys) == 0 {
+			break
+		}
+
+		for i := range orgKeys {
+			key, err := k.crypt.UnwrapKey(orgKeys[i].WrappedKey, orgKeys[i].Version)
+			if err != nil {
+				k.log.Error().Err(err).Int("organization", orgKeys[i].OrganizationID).Msg("failed to unwrap organization key")
+
+				return processed, err
+			}
+
+			orgKeys[i].WrappedKey, orgKeys[i].Version, err = k.crypt.WrapKey(key)
+			if err != nil {
+				k.log.Error().Err(err).Int("organization", orgKeys[i].OrganizationID).Msg("failed to wrap organization key")
+
+				return processed, err
+			}
+		}
+
+		if err := k.repo.UpdateOrganizationKeys(ctx, orgKeys); err != nil {
+			k.log.Error().Err(err).Msg("failed to update organization keys")
+
+			return processed, err
+		}
+
+		processed += len(orgKeys)
+
+		k.log.Info().Int("processed", processed).Msg("organization keys were re-wrapped")
+	}
+
+	return processed, nil
+}
+
+func (k *keyService) saveUserKey(ctx context.Context, userID int, key []byte) error {
+	wrappedKey, version, err := k.crypt.WrapKey(key)
+	if err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to wrap user key")
+
+		return err
+	}
+
+	userKey := repository.UserKey{
+		UserID:     userID,
+		WrappedKey: wrappedKey,
+		Version:    version,
+	}
+
+	if err := k.repo.SaveUserKey(ctx, userKey); err != nil {
+		k.log.Error().Err(err).Int("user", userID).Msg("failed to save user key")
+
+		return err
+	}
+
+	return nil
+}
+
+func (k *keyService) load(userID int) ([]byte, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	cached, ok := k.cache[userID]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(cached.expiresAt) {
+		delete(k.cache, userID)
+
+		return nil, false
+	}
+
+	return cached.key, true
+}
+
+func (k *keyService) store(userID int, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+
+	if now.Sub(k.lastSweep) > userKeyTTL {
+		for id, cached := range k.cache {
+			if now.After(cached.expiresAt) {
+				delete(k.cache, id)
+			}
+		}
+
+		k.lastSweep = now
+	}
+
+	k.cache[userID] = cachedKey{key: key, expiresAt: now.Add(userKeyTTL)}
+}
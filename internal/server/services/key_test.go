@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+var testUserKey = repository.UserKey{
+	UserID:     1,
+	WrappedKey: []byte("wrapped-key"),
+	Version:    1,
+}
+
+func Test_keyService_CreateUserKey(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr       error
+		prepareRepo       func(r *mocks.MockKeyRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+	}{
+		{
+			name: "success: key created",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("SaveUserKey", mock.Anything, testUserKey).
+					Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("GenerateKey").Return(testKey, nil).Times(1)
+				e.On("WrapKey", testKey).Return([]byte("wrapped-key"), 1, nil).Times(1)
+			},
+		},
+		{
+			name:        "error: failed to generate key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("GenerateKey").Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name:        "error: failed to wrap key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("GenerateKey").Return(testKey, nil).Times(1)
+				e.On("WrapKey", testKey).Return(nil, 0, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to save key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("SaveUserKey", mock.Anything, testUserKey).
+					Return(errInternal).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("GenerateKey").Return(testKey, nil).Times(1)
+				e.On("WrapKey", testKey).Return([]byte("wrapped-key"), 1, nil).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockKeyRepository)
+			mockEncryption := new(mocks.MockEncryption)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareEncryption(mockEncryption)
+
+			keyService := NewKeyService(mockRepo, &log, mockEncryption)
+			err := keyService.CreateUserKey(context.Background(), 1)
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func Test_keyService_GetUserKey(t *testing.T) {
+	log := logger.NewLogger()
+
+	type expected struct {
+		err error
+		key []byte
+	}
+
+	tests := []struct {
+		expected          expected
+		prepareRepo       func(r *mocks.MockKeyRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+	}{
+		{
+			name: "success: stored key unwrapped",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUserKey", mock.Anything, 1).
+					Return(&testUserKey, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Times(1)
+			},
+			expected: expected{
+				key: testKey,
+			},
+		},
+		{
+			name: "success: legacy key stored",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUserKey", mock.Anything, 1).
+					Return(nil, repository.ErrNoRows).Times(1)
+				r.On("SaveUserKey", mock.Anything, testUserKey).
+					Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("DeriveKey", 1).Return(testKey, nil).Times(1)
+				e.On("WrapKey", testKey).Return([]byte("wrapped-key"), 1, nil).Times(1)
+			},
+			expected: expected{
+				key: testKey,
+			},
+		},
+		{
+			name: "error: failed to get key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUserKey", mock.Anything, 1).
+					Return(nil, errInternal).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected: expected{
+				err: errInternal,
+			},
+		},
+		{
+			name: "error: failed to unwrap key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUserKey", mock.Anything, 1).
+					Return(&testUserKey, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(nil, errInternal).Times(1)
+			},
+			expected: expected{
+				err: errInternal,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockKeyRepository)
+			mockEncryption := new(mocks.MockEncryption)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareEncryption(mockEncryption)
+
+			keyService := NewKeyService(mockRepo, &log, mockEncryption)
+			key, err := keyService.GetUserKey(context.Background(), 1)
+
+			assert.Equal(t, tt.expected.err, err)
+			assert.Equal(t, tt.expected.key, key)
+		})
+	}
+}
+
+func Test_keyService_GetOrganizationKey(t *testing.T) {
+	log := logger.NewLogger()
+
+	type expected struct {
+		err error
+		key []byte
+	}
+
+	tests := []struct {
+		expected          expected
+		prepareRepo       func(r *mocks.MockKeyRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+	}{
+		{
+			name: "success: stored key unwrapped",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetOrganizationKey", mock.Anything, 5).
+					Return(&repository.OrganizationKey{OrganizationID: 5, WrappedKey: []byte("wrapped-key"), Version: 1}, nil).
+					Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Times(1)
+			},
+			expected: expected{
+				key: testKey,
+			},
+		},
+		{
+			name: "error: failed to get key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetOrganizationKey", mock.Anything, 5).
+					Return(nil, repository.ErrNoRows).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected: expected{
+				err: repository.ErrNoRows,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockKeyRepository)
+			mockEncryption := new(mocks.MockEncryption)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareEncryption(mockEncryption)
+
+			keyService := NewKeyService(mockRepo, &log, mockEncryption)
+			key, err := keyService.GetOrganizationKey(context.Background(), 5)
+
+			assert.Equal(t, tt.expected.err, err)
+			assert.Equal(t, tt.expected.key, key)
+		})
+	}
+}
+
+func Test_keyService_GetUserKey_Cached(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockRepo := new(mocks.MockKeyRepository)
+	mockRepo.On("GetUserKey", mock.Anything, 1).
+		Return(&testUserKey, nil).Once()
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Once()
+
+	keyService := NewKeyService(mockRepo, &log, mockEncryption)
+
+	for i := 0; i < 3; i++ {
+		key, err := keyService.GetUserKey(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, testKey, key)
+	}
+
+	mockRepo.AssertNumberOfCalls(t, "GetUserKey", 1)
+	mockEncryption.AssertNumberOfCalls(t, "UnwrapKey", 1)
+}
+
+func Test_keyService_RotateMasterKey(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr       error
+		prepareRepo       func(r *mocks.MockKeyRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+		expectedProcessed int
+	}{
+		{
+			name: "success: legacy and stale keys of users and organizations processed",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUsersWithoutKey", mock.Anything, 2).
+					Return([]int{1}, nil).Once()
+				r.On("GetUsersWithoutKey", mock.Anything, 2).
+					Return(nil, nil).Once()
+				r.On("SaveUserKey", mock.Anything, repository.UserKey{
+					UserID: 1, WrappedKey: []byte("new-wrapped-key"), Version: 2,
+				}).Return(nil).Once()
+				r.On("GetStaleUserKeys", mock.Anything, 2, 2).
+					Return([]repository.UserKey{
+						{UserID: 2, WrappedKey: []byte("wrapped-key"), Version: 1},
+						{UserID: 3, WrappedKey: []byte("wrapped-key"), Version: 1},
+					}, nil).Once()
+				r.On("GetStaleUserKeys", mock.Anything, 2, 2).
+					Return(nil, nil).Once()
+				r.On("UpdateUserKeys", mock.Anything, []repository.UserKey{
+					{UserID: 2, WrappedKey: []byte("new-wrapped-key"), Version: 2},
+					{UserID: 3, WrappedKey: []byte("new-wrapped-key"), Version: 2},
+				}).Return(nil).Once()
+				r.On("GetStaleOrganizationKeys", mock.Anything, 2, 2).
+					Return([]repository.OrganizationKey{
+						{OrganizationID: 5, WrappedKey: []byte("wrapped-key"), Version: 1},
+					}, nil).Once()
+				r.On("GetStaleOrganizationKeys", mock.Anything, 2, 2).
+					Return(nil, nil).Once()
+				r.On("UpdateOrganizationKeys", mock.Anything, []repository.OrganizationKey{
+					{OrganizationID: 5, WrappedKey: []byte("new-wrapped-key"), Version: 2},
+				}).Return(nil).Once()
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("KeyVersion").Return(2)
+				e.On("DeriveKey", 1).Return(testKey, nil).Once()
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Times(3)
+				e.On("WrapKey", testKey).Return([]byte("new-wrapped-key"), 2, nil).Times(4)
+			},
+			expectedProcessed: 4,
+		},
+		{
+			name: "error: failed to unwrap stale key",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUsersWithoutKey", mock.Anything, 2).
+					Return(nil, nil).Once()
+				r.On("GetStaleUserKeys", mock.Anything, 2, 2).
+					Return([]repository.UserKey{
+						{UserID: 2, WrappedKey: []byte("wrapped-key"), Version: 1},
+					}, nil).Once()
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("KeyVersion").Return(2)
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(nil, errInternal).Once()
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to update organization keys",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUsersWithoutKey", mock.Anything, 2).
+					Return(nil, nil).Once()
+				r.On("GetStaleUserKeys", mock.Anything, 2, 2).
+					Return(nil, nil).Once()
+				r.On("GetStaleOrganizationKeys", mock.Anything, 2, 2).
+					Return([]repository.OrganizationKey{
+						{OrganizationID: 5, WrappedKey: []byte("wrapped-key"), Version: 1},
+					}, nil).Once()
+				r.On("UpdateOrganizationKeys", mock.Anything, mock.Anything).
+					Return(errInternal).Once()
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("KeyVersion").Return(2)
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Once()
+				e.On("WrapKey", testKey).Return([]byte("new-wrapped-key"), 2, nil).Once()
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to update keys",
+			prepareRepo: func(r *mocks.MockKeyRepository) {
+				r.On("GetUsersWithoutKey", mock.Anything, 2).
+					Return(nil, nil).Once()
+				r.On("GetStaleUserKeys", mock.Anything, 2, 2).
+					Return([]repository.UserKey{
+						{UserID: 2, WrappedKey: []byte("wrapped-key"), Version: 1},
+					}, nil).Once()
+				r.On("UpdateUserKeys", mock.Anything, mock.Anything).
+					Return(errInternal).Once()
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("KeyVersion").Return(2)
+				e.On("UnwrapKey", []byte("wrapped-key"), 1).Return(testKey, nil).Once()
+				e.On("WrapKey", testKey).Return([]byte("new-wrapped-key"), 2, nil).Once()
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockKeyRepository)
+			mockEncryption := new(mocks.MockEncryption)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareEncryption(mockEncryption)
+
+			keyService := NewKeyService(mockRepo, &log, mockEncryption)
+			processed, err := keyService.RotateMasterKey(context.Background(), 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedProcessed, processed)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+const (
+	maxLabelLength = 255
+	maxTagLength   = 64
+	maxTags        = 32
+)
+
+// ErrInvalidLabels is returned when the name, the folder or the tags of a secret are too long
+// or there are too many tags.
+var ErrInvalidLabels = errors.New("secret labels are invalid")
+
+// normalizeLabels trims the name, the folder and the tags of the secret and validates them.
+// Empty and duplicate tags are dropped and the rest are sorted, so equal sets of tags are stored alike.
+func normalizeLabels(secret *models.Secret) error {
+	secret.Name = strings.TrimSpace(secret.Name)
+	secret.Folder = strings.TrimSpace(secret.Folder)
+
+	if utf8.RuneCountInString(secret.Name) > maxLabelLength || utf8.RuneCountInString(secret.Folder) > maxLabelLength {
+		return ErrInvalidLabels
+	}
+
+	var tags []string
+
+	seen := make(map[string]bool, len(secret.Tags))
+
+	for _, tag := range secret.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return ErrInvalidLabels
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if len(tags) > maxTags {
+		return ErrInvalidLabels
+	}
+
+	sort.Strings(tags)
+	secret.Tags = tags
+
+	return nil
+}
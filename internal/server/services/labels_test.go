@@ -0,0 +1,62 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+func Test_normalizeLabels(t *testing.T) {
+	tests := []struct {
+		secret      *models.Secret
+		expected    *models.Secret
+		expectedErr error
+		name        string
+	}{
+		{
+			name:     "success: labels are trimmed and tags deduplicated",
+			secret:   &models.Secret{Name: " GitHub ", Folder: " Work ", Tags: []string{"dev", " ", "ci", "dev "}},
+			expected: &models.Secret{Name: "GitHub", Folder: "Work", Tags: []string{"ci", "dev"}},
+		},
+		{
+			name:     "success: no labels",
+			secret:   &models.Secret{},
+			expected: &models.Secret{},
+		},
+		{
+			name:        "error: name too long",
+			secret:      &models.Secret{Name: strings.Repeat("a", maxLabelLength+1)},
+			expectedErr: ErrInvalidLabels,
+		},
+		{
+			name:        "error: tag too long",
+			secret:      &models.Secret{Tags: []string{strings.Repeat("a", maxTagLength+1)}},
+			expectedErr: ErrInvalidLabels,
+		},
+		{
+			name: "error: too many tags",
+			secret: &models.Secret{Tags: func() []string {
+				tags := make([]string, maxTags+1)
+				for i := range tags {
+					tags[i] = strings.Repeat("a", i+1)
+				}
+
+				return tags
+			}()},
+			expectedErr: ErrInvalidLabels,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeLabels(tt.secret)
+
+			assert.Equal(t, tt.expectedErr, err)
+			if tt.expected != nil {
+				assert.Equal(t, tt.expected, tt.secret)
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+var (
+	// ErrOrganizationUnavailable is returned when a user with client-side encryption creates
+	// or joins an organization. The secrets of organizations are encrypted by the server.
+	ErrOrganizationUnavailable = errors.New("organizations are not available with client-side encryption")
+	// ErrInvalidRole is returned when the role of a member is unknown.
+	ErrInvalidRole = errors.New("organization role is invalid")
+	// ErrInsufficientRole is returned when the role of the user in the organization does not allow the operation.
+	ErrInsufficientRole = errors.New("organization role does not allow the operation")
+	// ErrInvalidName is returned when the name of an organization or a collection is empty or too long.
+	ErrInvalidName = errors.New("name is invalid")
+	// ErrInviteeNotFound is returned when there is no user to invite to an organization.
+	ErrInviteeNotFound = errors.New("user to invite is not found")
+	// ErrMemberNotFound is returned when the user to change is not a member of the organization.
+	ErrMemberNotFound = errors.New("member is not found")
+)
+
+// roleRanks orders the roles of members by the operations they allow,
+// every role allows the operations of the lower ones.
+var roleRanks = map[string]int{
+	models.OrganizationRoleReadOnly: 1,
+	models.OrganizationRoleMember:   2,
+	models.OrganizationRoleAdmin:    3,
+	models.OrganizationRoleOwner:    4,
+}
+
+// OrganizationService is an interface that defines methods for managing organizations,
+// their members, invites and collections.
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, name string) (int, error)
+	ListOrganizations(ctx context.Context) ([]models.Organization, error)
+	DeleteOrganization(ctx context.Context, organizationID int) error
+	InviteMember(ctx context.Context, organizationID int, login, role string) error
+	ListInvites(ctx context.Context) ([]models.Invite, error)
+	AcceptInvite(ctx context.Context, organizationID int) error
+	DeclineInvite(ctx context.Context, organizationID int) error
+	ListMembers(ctx context.Context, organizationID int) ([]models.Member, error)
+	UpdateMemberRole(ctx context.Context, organizationID int, login, role string) error
+	RemoveMember(ctx context.Context, organizationID int, login string) error
+	CreateCollection(ctx context.Context, organizationID int, name string) (int, error)
+	ListCollections(ctx context.Context, organizationID int) ([]models.Collection, error)
+	DeleteCollection(ctx context.Context, organizationID, collectionID int) error
+}
+
+type organizationService struct {
+	repo  repository.OrganizationRepository
+	log   *zerolog.Logger
+	crypt encryption.Encryption
+}
+
+// NewOrganizationService creates and returns a new OrganizationService instance.
+func NewOrganizationService(
+	repo repository.OrganizationRepository,
+	log *zerolog.Logger,
+	crypt encryption.Encryption,
+) OrganizationService {
+	return &organizationService{
+		repo:  repo,
+		log:   log,
+		crypt: crypt,
+	}
+}
+
+// CreateOrganization creates an organization with the user as its owner and returns its ID.
+// The organization gets a random data encryption key for the secrets of its collections.
+func (o *organizationService) CreateOrganization(ctx context.Context, name string) (int, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return 0, err
+	}
+
+	if isClientSideEncryption(ctx) {
+		return 0, ErrOrganizationUnavailable
+	}
+
+	name, err = normalizeName(name)
+	if err != nil {
+		return 0, err
+	}
+
+	key, err := o.crypt.GenerateKey()
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to generate organization key")
+
+		return 0, err
+	}
+
+	org := &repository.Organization{Name: name}
+
+	org.WrappedKey, org.KeyVersion, err = o.crypt.WrapKey(key)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to wrap organization key")
+
+		return 0, err
+	}
+
+	if err := o.repo.CreateOrganization(ctx, org, userID); err != nil {
+		o.log.Error().Err(err).Msg("failed to create organization")
+
+		return 0, err
+	}
+
+	return org.ID, nil
+}
+
+// ListOrganizations retrieves the organizations the user is a member of.
+func (o *organizationService) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	orgs, err := o.repo.GetOrganizations(ctx, userID)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to get organizations")
+
+		return nil, err
+	}
+
+	result := make([]models.Organization, len(orgs))
+	for i, org := range orgs {
+		result[i] = models.Organization{
+			ID:        org.ID,
+			Name:      org.Name,
+			Role:      org.Role,
+			CreatedAt: org.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteOrganization removes the organization along with its collections and their secrets.
+// Only the owners can delete an organization.
+func (o *organizationService) DeleteOrganization(ctx context.Context, organizationID int) error {
+	if _, err := o.member(ctx, organizationID, models.OrganizationRoleOwner); err != nil {
+		return err
+	}
+
+	if err := o.repo.DeleteOrganization(ctx, organizationID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			o.log.Error().Err(err).Msg("failed to delete organization")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// InviteMember invites the user with the login to the organization with the role or changes
+// the role of the invite sent before. repository.ErrAlreadyMember is returned if the user
+// is already a member of the organization.
+func (o *organizationService) InviteMember(ctx context.Context, organizationID int, login, role string) error {
+	if !validRole(role) {
+		return ErrInvalidRole
+	}
+
+	actor, err := o.member(ctx, organizationID, models.OrganizationRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if !canManage(actor.Role, role) {
+		return ErrInsufficientRole
+	}
+
+	invitee, err := o.repo.GetInvitee(ctx, login)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrInviteeNotFound
+		}
+
+		o.log.Error().Err(err).Msg("failed to get invitee")
+
+		return err
+	}
+
+	if invitee.ClientSideEncryption {
+		return ErrOrganizationUnavailable
+	}
+
+	invite := &repository.Invite{
+		OrganizationID: organizationID,
+		UserID:         invitee.ID,
+		InvitedBy:      actor.UserID,
+		Role:           role,
+	}
+
+	if err := o.repo.CreateInvite(ctx, invite); err != nil {
+		if !errors.Is(err, repository.ErrAlreadyMember) {
+			o.log.Error().Err(err).Msg("failed to create invite")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ListInvites retrieves the pending invites of the user.
+func (o *organizationService) ListInvites(ctx context.Context) ([]models.Invite, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	invites, err := o.repo.GetInvites(ctx, userID)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to get invites")
+
+		return nil, err
+	}
+
+	result := make([]models.Invite, len(invites))
+	for i, invite := range invites {
+		result[i] = models.Invite{
+			OrganizationID: invite.OrganizationID,
+			Organization:   invite.Organization,
+			Role:           invite.Role,
+			InvitedBy:      invite.Inviter,
+			CreatedAt:      invite.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// AcceptInvite makes the user a member of the organization with the role of the invite.
+// repository.ErrNoRows is returned if the user has no invite to the organization.
+func (o *organizationService) AcceptInvite(ctx context.Context, organizationID int) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	if isClientSideEncryption(ctx) {
+		return ErrOrganizationUnavailable
+	}
+
+	if err := o.repo.AcceptInvite(ctx, organizationID, userID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			o.log.Error().Err(err).Msg("failed to accept invite")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// DeclineInvite removes the invite of the user to the organization.
+// repository.ErrNoRows is returned if the user has no invite to the organization.
+func (o *organizationService) DeclineInvite(ctx context.Context, organizationID int) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	if err := o.repo.DeleteInvite(ctx, organizationID, userID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			o.log.Error().Err(err).Msg("failed to delete invite")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ListMembers retrieves the members of the organization, which every member can see.
+func (o *organizationService) ListMembers(ctx context.Context, organizationID int) ([]models.Member, error) {
+	if _, err := o.member(ctx, organizationID, models.OrganizationRoleReadOnly); err != nil {
+		return nil, err
+	}
+
+	members, err := o.repo.GetMembers(ctx, organizationID)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to get members")
+
+		return nil, err
+	}
+
+	result := make([]models.Member, len(members))
+	for i, member := range members {
+		result[i] = models.Member{
+			Login:     member.Login,
+			Role:      member.Role,
+			CreatedAt: member.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// UpdateMemberRole changes the role of the member with the login. Admins manage only the members
+// with lower roles, owners manage all members. ErrMemberNotFound is returned if there is
+// no such member, repository.ErrLastOwner if the only owner of the organization gets another role.
+func (o *organizationService) UpdateMemberRole(ctx context.Context, organizationID int, login, role string) error {
+	if !validRole(role) {
+		return ErrInvalidRole
+	}
+
+	actor, err := o.member(ctx, organizationID, models.OrganizationRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	target, err := o.memberByLogin(ctx, organizationID, login)
+	if err != nil {
+		return err
+	}
+
+	if !canManage(actor.Role, target.Role) || !canManage(actor.Role, role) {
+		return ErrInsufficientRole
+	}
+
+	if err := o.repo.UpdateMemberRole(ctx, organizationID, target.UserID, role); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+
+		if !errors.Is(err, repository.ErrLastOwner) {
+			o.log.Error().Err(err).Msg("failed to update member role")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// RemoveMember removes the member with the login from the organization. Every member can leave
+// the organization, the other members are removed according to the rules of UpdateMemberRole.
+// ErrMemberNotFound is returned if there is no such member, repository.ErrLastOwner
+// if the member is the only owner of the organization.
+func (o *organizationService) RemoveMember(ctx context.Context, organizationID int, login string) error {
+	actor, err := o.member(ctx, organizationID, models.OrganizationRoleReadOnly)
+	if err != nil {
+		return err
+	}
+
+	target, err := o.memberByLogin(ctx, organizationID, login)
+	if err != nil {
+		return err
+	}
+
+	if target.UserID != actor.UserID && !canManage(actor.Role, target.Role) {
+		return ErrInsufficientRole
+	}
+
+	if err := o.repo.RemoveMember(ctx, organizationID, target.UserID); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrMemberNotFound
+		}
+
+		if !errors.Is(err, repository.ErrLastOwner) {
+			o.log.Error().Err(err).Msg("failed to remove member")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// CreateCollection creates a collection in the organization and returns its ID.
+// repository.ErrCollectionAlreadyExist is returned if the organization has a collection with the name.
+func (o *organizationService) CreateCollection(ctx context.Context, organizationID int, name string) (int, error) {
+	name, err := normalizeName(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := o.member(ctx, organizationID, models.OrganizationRoleAdmin); err != nil {
+		return 0, err
+	}
+
+	collection := &repository.Collection{OrganizationID: organizationID, Name: name}
+
+	if err := o.repo.CreateCollection(ctx, collection); err != nil {
+		if !errors.Is(err, repository.ErrCollectionAlreadyExist) {
+			o.log.Error().Err(err).Msg("failed to create collection")
+		}
+
+		return 0, err
+	}
+
+	return collection.ID, nil
+}
+
+// ListCollections retrieves the collections of the organization, which every member can see.
+func (o *organizationService) ListCollections(ctx context.Context, organizationID int) ([]models.Collection, error) {
+	if _, err := o.member(ctx, organizationID, models.OrganizationRoleReadOnly); err != nil {
+		return nil, err
+	}
+
+	collections, err := o.repo.GetCollections(ctx, organizationID)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to get collections")
+
+		return nil, err
+	}
+
+	result := make([]models.Collection, len(collections))
+	for i, collection := range collections {
+		result[i] = models.Collection{
+			ID:        collection.ID,
+			Name:      collection.Name,
+			CreatedAt: collection.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteCollection removes the collection of the organization along with its secrets.
+// repository.ErrNoRows is returned if the organization has no such collection.
+func (o *organizationService) DeleteCollection(ctx context.Context, organizationID, collectionID int) error {
+	if _, err := o.member(ctx, organizationID, models.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	if err := o.repo.DeleteCollection(ctx, organizationID, collectionID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			o.log.Error().Err(err).Msg("failed to delete collection")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// member returns the membership of the user in the organization if the user has at least
+// the given role. repository.ErrNoRows is returned if the user is not a member of the organization,
+// ErrInsufficientRole if the role of the user is lower.
+func (o *organizationService) member(ctx context.Context, organizationID int, least string) (*repository.Member, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		o.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	member, err := o.repo.GetMember(ctx, organizationID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			o.log.Error().Err(err).Msg("failed to get member")
+		}
+
+		return nil, err
+	}
+
+	if !hasRole(member.Role, least) {
+		return nil, ErrInsufficientRole
+	}
+
+	return member, nil
+}
+
+// memberByLogin returns the membership of the user with the login in the organization.
+// ErrMemberNotFound is returned if there is no such member.
+func (o *organizationService) memberByLogin(
+	ctx context.Context,
+	organizationID int,
+	login string,
+) (*repository.Member, error) {
+	member, err := o.repo.GetMemberByLogin(ctx, organizationID, login)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+
+		o.log.Error().Err(err).Msg("failed to get member")
+
+		return nil, err
+	}
+
+	return member, nil
+}
+
+// normalizeName trims the name of an organization or a collection and validates it.
+func normalizeName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxLabelLength {
+		return "", ErrInvalidName
+	}
+
+	return name, nil
+}
+
+func validRole(role string) bool {
+	_, ok := roleRanks[role]
+
+	return ok
+}
+
+// hasRole reports whether the role allows the operations of the least role.
+func hasRole(role, least string) bool {
+	return roleRanks[role] >= roleRanks[least]
+}
+
+// canManage reports whether a member with the role can grant the managed role, change it or
+// remove a member having it. Owners manage all roles, admins only the roles lower than theirs.
+func canManage(role, managed string) bool {
+	if role == models.OrganizationRoleOwner {
+		return true
+	}
+
+	return role == models.OrganizationRoleAdmin && roleRanks[managed] < roleRanks[models.OrganizationRoleAdmin]
+}
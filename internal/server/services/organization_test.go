@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func Test_organizationService_CreateOrganization(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr         error
+		prepareRepo         func(s *mocks.MockOrganizationRepository)
+		name                string
+		orgName             string
+		expectedID          int
+		clientSideEncrypted bool
+	}{
+		{
+			name:    "success: organization created",
+			orgName: "  team  ",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("CreateOrganization", mock.Anything, &repository.Organization{
+					Name:       "team",
+					WrappedKey: []byte("wrapped-key"),
+					KeyVersion: 1,
+				}, 1).Run(func(args mock.Arguments) {
+					args.Get(1).(*repository.Organization).ID = 5
+				}).Return(nil).Times(1)
+			},
+			expectedID: 5,
+		},
+		{
+			name:                "error: client-side encryption",
+			orgName:             "team",
+			clientSideEncrypted: true,
+			prepareRepo:         func(s *mocks.MockOrganizationRepository) {},
+			expectedErr:         ErrOrganizationUnavailable,
+		},
+		{
+			name:        "error: empty name",
+			orgName:     "   ",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {},
+			expectedErr: ErrInvalidName,
+		},
+		{
+			name:    "error: failed to create organization",
+			orgName: "team",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("CreateOrganization", mock.Anything, mock.Anything, 1).Return(errors.New("db error")).Times(1)
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockOrganizationRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("GenerateKey").Return([]byte("org-key"), nil).Maybe()
+			mockEncryption.On("WrapKey", []byte("org-key")).Return([]byte("wrapped-key"), 1, nil).Maybe()
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSideEncrypted)
+
+			orgService := NewOrganizationService(mockRepo, &log, mockEncryption)
+			id, err := orgService.CreateOrganization(ctx, tt.orgName)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expectedID, id)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_organizationService_InviteMember(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockOrganizationRepository)
+		name        string
+		role        string
+	}{
+		{
+			name: "success: member invited",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+				s.On("GetInvitee", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("CreateInvite", mock.Anything, &repository.Invite{
+					OrganizationID: 5,
+					UserID:         2,
+					InvitedBy:      1,
+					Role:           models.OrganizationRoleMember,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name:        "error: invalid role",
+			role:        "GUEST",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {},
+			expectedErr: ErrInvalidRole,
+		},
+		{
+			name: "error: not a member",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name: "error: member cannot invite",
+			role: models.OrganizationRoleReadOnly,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: admin cannot invite admin",
+			role: models.OrganizationRoleAdmin,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: invitee not found",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetInvitee", mock.Anything, "bob").Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrInviteeNotFound,
+		},
+		{
+			name: "error: invitee with client-side encryption",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetInvitee", mock.Anything, "bob").
+					Return(&repository.Recipient{ID: 2, ClientSideEncryption: true}, nil).Times(1)
+			},
+			expectedErr: ErrOrganizationUnavailable,
+		},
+		{
+			name: "error: already a member",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetInvitee", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("CreateInvite", mock.Anything, mock.Anything).Return(repository.ErrAlreadyMember).Times(1)
+			},
+			expectedErr: repository.ErrAlreadyMember,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockOrganizationRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			orgService := NewOrganizationService(mockRepo, &log, nil)
+			err := orgService.InviteMember(ctx, 5, "bob", tt.role)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_organizationService_UpdateMemberRole(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockOrganizationRepository)
+		name        string
+		role        string
+	}{
+		{
+			name: "success: owner promotes member to admin",
+			role: models.OrganizationRoleAdmin,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 2, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("UpdateMemberRole", mock.Anything, 5, 2, models.OrganizationRoleAdmin).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: admin cannot change admin",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 2, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: member not found",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrMemberNotFound,
+		},
+		{
+			name: "error: last owner",
+			role: models.OrganizationRoleMember,
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 2, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("UpdateMemberRole", mock.Anything, 5, 2, models.OrganizationRoleMember).
+					Return(repository.ErrLastOwner).Times(1)
+			},
+			expectedErr: repository.ErrLastOwner,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockOrganizationRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			orgService := NewOrganizationService(mockRepo, &log, nil)
+			err := orgService.UpdateMemberRole(ctx, 5, "bob", tt.role)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_organizationService_RemoveMember(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockOrganizationRepository)
+		name        string
+	}{
+		{
+			name: "success: read-only member leaves",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+				s.On("RemoveMember", mock.Anything, 5, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "success: admin removes member",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 2, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("RemoveMember", mock.Anything, 5, 2).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: member cannot remove others",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleMember}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 2, Role: models.OrganizationRoleReadOnly}, nil).Times(1)
+			},
+			expectedErr: ErrInsufficientRole,
+		},
+		{
+			name: "error: last owner leaves",
+			prepareRepo: func(s *mocks.MockOrganizationRepository) {
+				s.On("GetMember", mock.Anything, 5, 1).
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("GetMemberByLogin", mock.Anything, 5, "bob").
+					Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+				s.On("RemoveMember", mock.Anything, 5, 1).Return(repository.ErrLastOwner).Times(1)
+			},
+			expectedErr: repository.ErrLastOwner,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockOrganizationRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			orgService := NewOrganizationService(mockRepo, &log, nil)
+			err := orgService.RemoveMember(ctx, 5, "bob")
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_organizationService_DeleteOrganization(t *testing.T) {
+	log := logger.NewLogger()
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	t.Run("error: admin cannot delete organization", func(t *testing.T) {
+		mockRepo := new(mocks.MockOrganizationRepository)
+		mockRepo.On("GetMember", mock.Anything, 5, 1).
+			Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleAdmin}, nil).Times(1)
+
+		err := NewOrganizationService(mockRepo, &log, nil).DeleteOrganization(ctx, 5)
+
+		assert.Equal(t, ErrInsufficientRole, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("success: owner deletes organization", func(t *testing.T) {
+		mockRepo := new(mocks.MockOrganizationRepository)
+		mockRepo.On("GetMember", mock.Anything, 5, 1).
+			Return(&repository.Member{OrganizationID: 5, UserID: 1, Role: models.OrganizationRoleOwner}, nil).Times(1)
+		mockRepo.On("DeleteOrganization", mock.Anything, 5).Return(nil).Times(1)
+
+		err := NewOrganizationService(mockRepo, &log, nil).DeleteOrganization(ctx, 5)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_canManage(t *testing.T) {
+	assert.True(t, canManage(models.OrganizationRoleOwner, models.OrganizationRoleOwner))
+	assert.True(t, canManage(models.OrganizationRoleAdmin, models.OrganizationRoleMember))
+	assert.True(t, canManage(models.OrganizationRoleAdmin, models.OrganizationRoleReadOnly))
+	assert.False(t, canManage(models.OrganizationRoleAdmin, models.OrganizationRoleAdmin))
+	assert.False(t, canManage(models.OrganizationRoleAdmin, models.OrganizationRoleOwner))
+	assert.False(t, canManage(models.OrganizationRoleMember, models.OrganizationRoleReadOnly))
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+const (
+	// DefaultPageSize is the number of secrets returned by page if the page size is not set.
+	DefaultPageSize = 100
+	// MaxPageSize is the maximum number of secrets returned by page.
+	MaxPageSize = 500
+)
+
+// ErrInvalidPageToken is returned when the page token was not returned by a previous page.
+var ErrInvalidPageToken = errors.New("page token is invalid")
+
+// pageToken is the position of the last secret of a page. The next page starts after it.
+type pageToken struct {
+	CreatedAt time.Time `json:"c"`
+	ID        int       `json:"i"`
+}
+
+func encodePageToken(secret *repository.Secret) string {
+	data, _ := json.Marshal(pageToken{CreatedAt: secret.CreatedAt, ID: secret.ID})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (*pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	var position pageToken
+	if err := json.Unmarshal(data, &position); err != nil || position.ID <= 0 {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &position, nil
+}
+
+// repositoryFilter converts the filter into the repository filter of its page.
+// One more secret than the page size is selected to learn whether there is a next page.
+func repositoryFilter(filter *models.SecretFilter) (repository.SecretFilter, error) {
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	repoFilter := repository.SecretFilter{
+		CreatedAfter:  filter.CreatedAfter,
+		CreatedBefore: filter.CreatedBefore,
+		UpdatedAfter:  filter.UpdatedAfter,
+		UpdatedBefore: filter.UpdatedBefore,
+		Type:          filter.Type,
+		Tag:           filter.Tag,
+		Folder:        filter.Folder,
+		Name:          filter.Name,
+		Limit:         pageSize + 1,
+	}
+
+	if filter.PageToken != "" {
+		position, err := decodePageToken(filter.PageToken)
+		if err != nil {
+			return repository.SecretFilter{}, err
+		}
+
+		repoFilter.AfterCreatedAt = position.CreatedAt
+		repoFilter.AfterID = position.ID
+	}
+
+	return repoFilter, nil
+}
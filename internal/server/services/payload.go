@@ -0,0 +1,106 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/pkg/totp"
+)
+
+// ErrInvalidPayload is returned when a secret has no payload, the payload does not
+// match the secret type or the payload does not match the encryption mode of the user.
+// Only binary secrets may have a blob attached, OTP secrets must hold a valid otpauth URI.
+var ErrInvalidPayload = errors.New("secret payload does not match secret type")
+
+func validatePayload(secret *models.Secret, clientSideEncryption bool) error {
+	if secret.Payload == nil {
+		return ErrInvalidPayload
+	}
+
+	if secret.BlobID != "" && secret.Type != models.SecretTypeBinary {
+		return ErrInvalidPayload
+	}
+
+	if _, ok := secret.Payload.(*models.Encrypted); ok != clientSideEncryption {
+		return ErrInvalidPayload
+	}
+
+	if clientSideEncryption {
+		if newPayload(secret.Type) == nil {
+			return ErrInvalidPayload
+		}
+
+		return nil
+	}
+
+	if secret.Payload.SecretType() != secret.Type {
+		return ErrInvalidPayload
+	}
+
+	if otp, ok := secret.Payload.(*models.OTP); ok {
+		if _, err := totp.ParseURI(otp.URI); err != nil {
+			return ErrInvalidPayload
+		}
+	}
+
+	return nil
+}
+
+// newPayload returns an empty payload of the given secret type or nil if the type is unknown.
+func newPayload(secretType string) models.Payload {
+	switch secretType {
+	case models.SecretTypeCredentials:
+		return &models.Credentials{}
+	case models.SecretTypeCard:
+		return &models.Card{}
+	case models.SecretTypeText:
+		return &models.Text{}
+	case models.SecretTypeBinary:
+		return &models.Binary{}
+	case models.SecretTypeOTP:
+		return &models.OTP{}
+	default:
+		return nil
+	}
+}
+
+// payloadHeader marks the content holding a structured payload. Secrets saved before payloads
+// became structured hold free-form content without the header, the NUL byte it starts with
+// does not occur in the text typed by users.
+const payloadHeader = "\x00v1:"
+
+func encodePayload(payload models.Payload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return payloadHeader + string(data), nil
+}
+
+// decodePayload restores the payload of the given secret type from its stored form.
+// Content without payloadHeader was saved before payloads became structured and is
+// returned as a Text payload as is, whatever it looks like, so that it stays readable.
+func decodePayload(secretType string, content string) (models.Payload, string) {
+	data, ok := strings.CutPrefix(content, payloadHeader)
+	if !ok {
+		return &models.Text{Body: content}, models.SecretTypeText
+	}
+
+	payload := newPayload(secretType)
+	if payload == nil {
+		return &models.Text{Body: data}, models.SecretTypeText
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(payload); err != nil {
+		return &models.Text{Body: data}, models.SecretTypeText
+	}
+
+	return payload, secretType
+}
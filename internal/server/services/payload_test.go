@@ -0,0 +1,163 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+)
+
+func Test_decodePayload(t *testing.T) {
+	tests := []struct {
+		payload    models.Payload
+		name       string
+		secretType string
+		content    string
+	}{
+		{
+			name:       "should decode credentials",
+			secretType: models.SecretTypeCredentials,
+			content:    payloadHeader + `{"login":"user","password":"pass","url":"example.com"}`,
+			payload:    &models.Credentials{Login: "user", Password: "pass", URL: "example.com"},
+		},
+		{
+			name:       "should decode card",
+			secretType: models.SecretTypeCard,
+			content:    payloadHeader + `{"number":"4111","holder":"JOHN DOE","expiry":"12/30","cvv":"123"}`,
+			payload:    &models.Card{Number: "4111", Holder: "JOHN DOE", Expiry: "12/30", CVV: "123"},
+		},
+		{
+			name:       "should decode binary",
+			secretType: models.SecretTypeBinary,
+			content:    payloadHeader + `{"filename":"a.bin","mime":"application/octet-stream","data":"AAE="}`,
+			payload:    &models.Binary{Filename: "a.bin", Mime: "application/octet-stream", Data: []byte{0, 1}},
+		},
+		{
+			name:       "should decode otp",
+			secretType: models.SecretTypeOTP,
+			content:    payloadHeader + `{"uri":"otpauth://totp/GitHub:john?secret=JBSWY3DPEHPK3PXP"}`,
+			payload:    &models.OTP{URI: "otpauth://totp/GitHub:john?secret=JBSWY3DPEHPK3PXP"},
+		},
+		{
+			name:       "should return legacy content as text",
+			secretType: models.SecretTypeCredentials,
+			content:    "user:pass",
+			payload:    &models.Text{Body: "user:pass"},
+		},
+		{
+			name:       "should return content with unknown fields as text",
+			secretType: models.SecretTypeText,
+			content:    payloadHeader + `{"note":"hello"}`,
+			payload:    &models.Text{Body: `{"note":"hello"}`},
+		},
+		{
+			name:       "should return legacy json text as is",
+			secretType: models.SecretTypeText,
+			content:    `{}`,
+			payload:    &models.Text{Body: `{}`},
+		},
+		{
+			name:       "should return legacy text matching payload as is",
+			secretType: models.SecretTypeText,
+			content:    `{"body":"hello"}`,
+			payload:    &models.Text{Body: `{"body":"hello"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, secretType := decodePayload(tt.secretType, tt.content)
+
+			assert.Equal(t, tt.payload, payload)
+			assert.Equal(t, tt.payload.SecretType(), secretType)
+		})
+	}
+}
+
+func Test_encodePayload(t *testing.T) {
+	payload := &models.Card{Number: "4111", Holder: "JOHN DOE", Expiry: "12/30", CVV: "123"}
+
+	content, err := encodePayload(payload)
+	assert.NoError(t, err)
+
+	decoded, secretType := decodePayload(models.SecretTypeCard, content)
+	assert.Equal(t, payload, decoded)
+	assert.Equal(t, models.SecretTypeCard, secretType)
+}
+
+func Test_validatePayload(t *testing.T) {
+	tests := []struct {
+		secret               *models.Secret
+		expectedErr          error
+		name                 string
+		clientSideEncryption bool
+	}{
+		{
+			name:   "success: payload matches type",
+			secret: &models.Secret{Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+		},
+		{
+			name:        "error: no payload",
+			secret:      &models.Secret{Type: models.SecretTypeText},
+			expectedErr: ErrInvalidPayload,
+		},
+		{
+			name:        "error: payload does not match type",
+			secret:      &models.Secret{Type: models.SecretTypeCard, Payload: &models.Text{Body: "body"}},
+			expectedErr: ErrInvalidPayload,
+		},
+		{
+			name:        "error: encrypted payload without client-side encryption",
+			secret:      &models.Secret{Type: models.SecretTypeText, Payload: &models.Encrypted{Data: []byte("data")}},
+			expectedErr: ErrInvalidPayload,
+		},
+		{
+			name:                 "success: encrypted payload with client-side encryption",
+			secret:               &models.Secret{Type: models.SecretTypeCard, Payload: &models.Encrypted{Data: []byte("data")}},
+			clientSideEncryption: true,
+		},
+		{
+			name:                 "error: plain payload with client-side encryption",
+			secret:               &models.Secret{Type: models.SecretTypeText, Payload: &models.Text{Body: "body"}},
+			clientSideEncryption: true,
+			expectedErr:          ErrInvalidPayload,
+		},
+		{
+			name: "success: blob attached to binary secret",
+			secret: &models.Secret{
+				Type:    models.SecretTypeBinary,
+				Payload: &models.Binary{Filename: "file", Size: 10},
+				BlobID:  "blob",
+			},
+		},
+		{
+			name:                 "error: blob attached to text secret",
+			secret:               &models.Secret{Type: models.SecretTypeText, Payload: &models.Encrypted{Data: []byte("data")}, BlobID: "blob"},
+			clientSideEncryption: true,
+			expectedErr:          ErrInvalidPayload,
+		},
+		{
+			name: "success: valid otpauth uri",
+			secret: &models.Secret{
+				Type:    models.SecretTypeOTP,
+				Payload: &models.OTP{URI: "otpauth://totp/GitHub:john?secret=JBSWY3DPEHPK3PXP"},
+			},
+		},
+		{
+			name:        "error: invalid otpauth uri",
+			secret:      &models.Secret{Type: models.SecretTypeOTP, Payload: &models.OTP{URI: "https://example.com"}},
+			expectedErr: ErrInvalidPayload,
+		},
+		{
+			name:                 "error: unknown type with client-side encryption",
+			secret:               &models.Secret{Type: "UNKNOWN", Payload: &models.Encrypted{Data: []byte("data")}},
+			clientSideEncryption: true,
+			expectedErr:          ErrInvalidPayload,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedErr, validatePayload(tt.secret, tt.clientSideEncryption))
+		})
+	}
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -10,34 +13,100 @@ import (
 	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
 )
 
+// Names of the secret fields which are encrypted separately.
+const (
+	fieldContent  = "content"
+	fieldMetaData = "meta_data"
+)
+
+// cleanupInterval is the period of time between the removals of expired versions of secrets,
+// of expired secrets in the trash and of expired reservations of secret IDs.
+const cleanupInterval = time.Hour
+
+// reservationTTL limits how long an ID reserved with ReserveSecretID can be used to create a secret.
+const reservationTTL = 24 * time.Hour
+
 // SecretService is an interface that defines methods for handling secret related operations.
 type SecretService interface {
 	CreateSecret(ctx context.Context, req *models.Secret) error
-	GetUserSecrets(ctx context.Context) ([]models.Secret, error)
+	ReserveSecretID(ctx context.Context) (int, error)
+	GetUserSecrets(ctx context.Context, filter *models.SecretFilter) (*models.SecretPage, error)
 	UpdateSecret(ctx context.Context, secret *models.Secret) error
-	DeleteSecret(ctx context.Context, secretID int) error
+	DeleteSecret(ctx context.Context, secretID int, version int) error
+	Sync(ctx context.Context, sinceRevision int64) (*models.Changes, error)
+	Watch(ctx context.Context) (<-chan models.SecretEvent, func(), error)
+	FindSecretsByDomain(ctx context.Context, domain, login string) ([]models.Secret, error)
+	ListVersions(ctx context.Context, secretID int) ([]models.Secret, error)
+	RestoreVersion(ctx context.Context, secretID, version, currentVersion int) error
+	ListTrash(ctx context.Context) ([]models.Secret, error)
+	RestoreSecret(ctx context.Context, secretID int) error
+	PurgeSecret(ctx context.Context, secretID int) error
+	ShareSecret(ctx context.Context, secretID int, login, permission string) error
+	UnshareSecret(ctx context.Context, secretID int, login string) error
+	UpgradeSecrets(ctx context.Context, batchSize int) (int, error)
+	Run(ctx context.Context)
+}
+
+// ErrVersionConflict is returned when the secret was changed since the client fetched it.
+var ErrVersionConflict = errors.New("secret was modified concurrently")
+
+// ErrInvalidSecretID is returned when a secret is created with an ID the user has not reserved.
+var ErrInvalidSecretID = errors.New("secret id is not reserved")
+
+// ConflictError is returned by UpdateSecret and DeleteSecret when the expected version of the
+// secret is not current. It holds the current copy of the secret, so the client can merge
+// the changes or overwrite them. It matches ErrVersionConflict.
+type ConflictError struct {
+	Current *models.Secret
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return ErrVersionConflict.Error()
+}
+
+// Unwrap returns ErrVersionConflict.
+func (e *ConflictError) Unwrap() error {
+	return ErrVersionConflict
 }
 
 type secretService struct {
-	repo  repository.SecretRepository
-	log   *zerolog.Logger
-	crypt encryption.Encryption
+	repo           repository.SecretRepository
+	log            *zerolog.Logger
+	crypt          encryption.Encryption
+	keys           KeyService
+	broker         SecretBroker
+	retention      HistoryRetention
+	trashRetention time.Duration
 }
 
 // NewSecretService creates and returns a new SecretService instance.
+// The previous versions of secrets are kept according to the history retention,
+// the deleted secrets are kept in the trash for trashRetention.
 func NewSecretService(
 	repo repository.SecretRepository,
 	log *zerolog.Logger,
 	crypt encryption.Encryption,
+	keys KeyService,
+	broker SecretBroker,
+	retention HistoryRetention,
+	trashRetention time.Duration,
 ) SecretService {
 	return &secretService{
-		repo:  repo,
-		log:   log,
-		crypt: crypt,
+		repo:           repo,
+		log:            log,
+		crypt:          crypt,
+		keys:           keys,
+		broker:         broker,
+		retention:      retention,
+		trashRetention: trashRetention,
 	}
 }
 
-// CreateSecret creates a new secret for the user.
+// CreateSecret creates a new secret for the user, or in the collection of the secret if it has one.
+// Only the members of the organization owning the collection who can write its secrets can create
+// secrets in it, ErrInsufficientRole is returned for the others and repository.ErrNoRows
+// for the users who are not members.
 func (s *secretService) CreateSecret(ctx context.Context, secretModel *models.Secret) error {
 	userID, err := extractUserIDFromCtx(ctx)
 	if err != nil {
@@ -46,40 +115,81 @@ func (s *secretService) CreateSecret(ctx context.Context, secretModel *models.Se
 		return err
 	}
 
-	s.crypt.GenerateKey(userID)
+	if secretModel.CollectionID != 0 {
+		return s.createCollectionSecret(ctx, userID, secretModel)
+	}
 
-	secret := &repository.Secret{
-		UserID: userID,
-		Type:   secretModel.Type,
+	secretID, err := s.newSecretID(ctx, userID, secretModel.ID)
+	if err != nil {
+		return err
 	}
 
-	secret.Content, err = s.crypt.Encrypt(secretModel.Content)
+	secret, err := s.sealSecret(ctx, userID, secretID, secretModel)
 	if err != nil {
-		s.log.Error().Err(err).Msg("failed to encrypt content")
+		return err
+	}
+
+	if err := s.repo.Create(ctx, secret); err != nil {
+		s.log.Error().Err(err).Msg("failed to create secret")
 
 		return err
 	}
 
-	if secretModel.MetaData != "" {
-		secret.MetaData, err = s.crypt.Encrypt(secretModel.MetaData)
+	return nil
+}
+
+// ReserveSecretID reserves an ID for a secret the user creates later with CreateSecret.
+// Clients with client-side encryption bind the ciphertexts of the secret to the ID.
+func (s *secretService) ReserveSecretID(ctx context.Context) (int, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return 0, err
+	}
+
+	secretID, err := s.repo.ReserveSecretID(ctx, userID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to reserve secret id")
+
+		return 0, err
+	}
+
+	return secretID, nil
+}
+
+// newSecretID returns the ID for a new secret of the user. The ID reserved by the user is used
+// once, ErrInvalidSecretID is returned if the user has not reserved it. Without a reserved ID
+// a new one is chosen.
+func (s *secretService) newSecretID(ctx context.Context, userID, reservedID int) (int, error) {
+	if reservedID == 0 {
+		secretID, err := s.repo.NextSecretID(ctx)
 		if err != nil {
-			s.log.Error().Err(err).Msg("failed to encrypt meta data")
+			s.log.Error().Err(err).Msg("failed to reserve secret id")
 
-			return err
+			return 0, err
 		}
+
+		return secretID, nil
 	}
 
-	if err := s.repo.Create(ctx, secret); err != nil {
-		s.log.Error().Err(err).Msg("failed to create secret")
+	if err := s.repo.ClaimSecretID(ctx, reservedID, userID); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return 0, ErrInvalidSecretID
+		}
 
-		return err
+		s.log.Error().Err(err).Msg("failed to claim secret id")
+
+		return 0, err
 	}
 
-	return nil
+	return reservedID, nil
 }
 
-// GetUserSecrets retrieves all secrets associated with the user.
-func (s *secretService) GetUserSecrets(ctx context.Context) ([]models.Secret, error) {
+// GetUserSecrets retrieves a page of the secrets of the user matching the filter, including
+// the secrets shared with the user and the secrets of the collections of the user's organizations.
+// ErrInvalidPageToken is returned if the page token of the filter is malformed.
+func (s *secretService) GetUserSecrets(ctx context.Context, filter *models.SecretFilter) (*models.SecretPage, error) {
 	userID, err := extractUserIDFromCtx(ctx)
 	if err != nil {
 		s.log.Error().Err(err).Msg("failed to extract user from context")
@@ -87,51 +197,144 @@ func (s *secretService) GetUserSecrets(ctx context.Context) ([]models.Secret, er
 		return nil, err
 	}
 
-	secrets, err := s.repo.GetUserSecrets(ctx, userID)
+	repoFilter, err := repositoryFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.repo.GetUserSecrets(ctx, userID, repoFilter)
 	if err != nil {
 		s.log.Error().Err(err).Msg("failed to get user secrets")
 
 		return nil, err
 	}
 
-	s.crypt.GenerateKey(userID)
+	var page models.SecretPage
+	if len(secrets) == repoFilter.Limit {
+		secrets = secrets[:len(secrets)-1]
+		page.NextPageToken = encodePageToken(&secrets[len(secrets)-1])
+	}
 
-	modelSecrets := make([]models.Secret, len(secrets))
-	for i := range secrets {
-		secret := models.Secret{
-			ID:        secrets[i].ID,
-			UserID:    secrets[i].UserID,
-			Type:      secrets[i].Type,
-			CreatedAt: secrets[i].CreatedAt,
-		}
+	page.Secrets, err = s.openSecrets(ctx, userID, secrets)
+	if err != nil {
+		return nil, err
+	}
 
-		decryptedContent, err := s.crypt.Decrypt(secrets[i].Content)
-		if err != nil {
-			s.log.Error().Err(err).Msg("failed to decrypt secret content")
+	return &page, nil
+}
 
-			return nil, err
-		}
+// Sync retrieves the changes of the user's secrets, the secrets shared with the user and the
+// secrets of the collections of the user's organizations made after the given revision.
+// Zero revision yields all secrets of the user.
+func (s *secretService) Sync(ctx context.Context, sinceRevision int64) (*models.Changes, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
 
-		secret.Content = decryptedContent
+		return nil, err
+	}
 
-		if secrets[i].MetaData != nil {
-			decryptedMeta, err := s.crypt.Decrypt(secrets[i].MetaData)
-			if err != nil {
-				s.log.Error().Err(err).Msg("failed to decrypt secret meta data")
+	changes, err := s.repo.GetChanges(ctx, userID, sinceRevision)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get secret changes")
 
-				return nil, err
-			}
+		return nil, err
+	}
+
+	result := &models.Changes{
+		DeletedIDs: changes.DeletedIDs,
+		Revision:   changes.Revision,
+	}
+
+	if len(changes.Secrets) == 0 {
+		return result, nil
+	}
+
+	result.Secrets, err = s.openSecrets(ctx, userID, changes.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// FindSecretsByDomain finds the credentials of the user bound to the domain or to one of its
+// parent domains, optionally narrowed down to the login. The credentials are looked up by their
+// blind indexes, which the server keeps only for the secrets it encrypts itself, so
+// ErrBlindIndexUnavailable is returned for users with client-side encryption.
+func (s *secretService) FindSecretsByDomain(ctx context.Context, domain, login string) ([]models.Secret, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
 
-			secret.MetaData = decryptedMeta
+		return nil, err
+	}
+
+	if isClientSideEncryption(ctx) {
+		return nil, ErrBlindIndexUnavailable
+	}
+
+	domain, err = normalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.keys.GetUserKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	indexKey := blindIndexKey(key)
+
+	var indexes []repository.BlindIndex
+	for _, d := range parentDomains(domain) {
+		indexes = append(indexes, blindIndex(indexKey, indexFieldDomain, d))
+	}
+
+	if login = normalizeLogin(login); login != "" {
+		indexes = append(indexes, blindIndex(indexKey, indexFieldLogin, login))
+	}
+
+	secrets, err := s.repo.FindSecrets(ctx, userID, indexes)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to find secrets")
+
+		return nil, err
+	}
+
+	result := make([]models.Secret, len(secrets))
+	for i := range secrets {
+		result[i], err = s.openSecret(key, &secrets[i])
+		if err != nil {
+			return nil, err
 		}
+	}
+
+	return result, nil
+}
+
+// Watch subscribes to the changes of the user's secrets. It returns the channel of events
+// and the function which stops the subscription. The channel is closed when the delivery of
+// further events cannot be guaranteed, in which case the client has to sync and watch again.
+func (s *secretService) Watch(ctx context.Context) (<-chan models.SecretEvent, func(), error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
 
-		modelSecrets[i] = secret
+		return nil, nil, err
 	}
 
-	return modelSecrets, nil
+	events, stop := s.broker.Subscribe(userID)
+
+	return events, stop, nil
 }
 
-// UpdateSecret updates the provided secret.
+// UpdateSecret updates the provided secret if its version is current and sets the new
+// version on it. A secret shared with the user is updated on behalf of its owner if the user
+// has write access to it, ErrReadOnlyShare is returned otherwise. The shared copies of the
+// secret are updated along with it. A secret of a collection is updated if the role of the user
+// in the organization owning the collection allows it, ErrInsufficientRole is returned otherwise.
+// ConflictError is returned if the secret was changed in the meantime.
 func (s *secretService) UpdateSecret(ctx context.Context, secretModel *models.Secret) error {
 	userID, err := extractUserIDFromCtx(ctx)
 	if err != nil {
@@ -140,39 +343,52 @@ func (s *secretService) UpdateSecret(ctx context.Context, secretModel *models.Se
 		return err
 	}
 
-	secret := &repository.Secret{
-		ID:     secretModel.ID,
-		UserID: userID,
-		Type:   secretModel.Type,
+	member, err := s.secretMember(ctx, userID, secretModel.ID)
+	if err != nil {
+		return err
 	}
 
-	secret.Content, err = s.crypt.Encrypt(secretModel.Content)
+	if member != nil {
+		return s.updateCollectionSecret(ctx, member, secretModel)
+	}
+
+	shares, ownerID, err := s.sharedSecret(ctx, userID, secretModel.ID)
 	if err != nil {
-		s.log.Error().Err(err).Msg("failed to encrypt content")
+		return err
+	}
 
+	secret, err := s.sealSecret(ctx, ownerID, secretModel.ID, secretModel)
+	if err != nil {
 		return err
 	}
 
-	if secretModel.MetaData != "" {
-		secret.MetaData, err = s.crypt.Encrypt(secretModel.MetaData)
-		if err != nil {
-			s.log.Error().Err(err).Msg("failed to encrypt meta data")
+	secret.Version = secretModel.Version
 
-			return err
-		}
+	secret.Shares, err = s.sealShares(ctx, secret, secretModel, shares)
+	if err != nil {
+		return err
 	}
 
 	if err := s.repo.UpdateSecret(ctx, secret); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.conflict(ctx, ownerID, secretModel.ID)
+		}
+
 		s.log.Error().Err(err).Msg("failed to update secret")
 
 		return err
 	}
 
+	secretModel.Version = secret.Version
+
 	return nil
 }
 
-// DeleteSecret removes the secret with provided ID.
-func (s *secretService) DeleteSecret(ctx context.Context, secretID int) error {
+// DeleteSecret moves the secret with provided ID to the trash if its version is current.
+// A secret of a collection is moved to the trash if the role of the user in the organization
+// owning the collection allows it, ErrInsufficientRole is returned otherwise.
+// ConflictError is returned if the secret was changed in the meantime.
+func (s *secretService) DeleteSecret(ctx context.Context, secretID int, version int) error {
 	userID, err := extractUserIDFromCtx(ctx)
 	if err != nil {
 		s.log.Error().Err(err).Msg("failed to extract user from context")
@@ -180,7 +396,20 @@ func (s *secretService) DeleteSecret(ctx context.Context, secretID int) error {
 		return err
 	}
 
-	if err := s.repo.DeleteSecret(ctx, secretID, userID); err != nil {
+	member, err := s.secretMember(ctx, userID, secretID)
+	if err != nil {
+		return err
+	}
+
+	if member != nil {
+		return s.deleteCollectionSecret(ctx, member, secretID, version)
+	}
+
+	if err := s.repo.DeleteSecret(ctx, secretID, userID, version); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return s.conflict(ctx, userID, secretID)
+		}
+
 		s.log.Error().Err(err).Msg("failed to delete secret")
 
 		return err
@@ -188,3 +417,265 @@ func (s *secretService) DeleteSecret(ctx context.Context, secretID int) error {
 
 	return nil
 }
+
+// Run periodically removes the versions of secrets which are expired according to the
+// history retention, the secrets which are in the trash for longer than the trash
+// retention and the expired reservations of secret IDs until the context is done.
+func (s *secretService) Run(ctx context.Context) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.deleteExpiredVersions(ctx)
+		s.purgeTrash(ctx)
+		s.deleteExpiredReservations(ctx)
+	}
+}
+
+// deleteExpiredReservations removes the reservations of secret IDs which were not used in time.
+func (s *secretService) deleteExpiredReservations(ctx context.Context) {
+	removed, err := s.repo.DeleteExpiredReservations(ctx, time.Now().Add(-reservationTTL))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to delete expired reservations")
+		}
+
+		return
+	}
+
+	if removed > 0 {
+		s.log.Info().Int64("count", removed).Msg("deleted expired reservations")
+	}
+}
+
+// conflict tells a write of a secret that does not exist from a write of an outdated version.
+// It returns repository.ErrNoRows in the former case and ConflictError with the current copy
+// of the secret in the latter.
+func (s *secretService) conflict(ctx context.Context, userID int, secretID int) error {
+	secret, err := s.repo.GetSecret(ctx, secretID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get secret")
+		}
+
+		return err
+	}
+
+	var key []byte
+	if !isClientSideEncryption(ctx) {
+		key, err = s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	current, err := s.openSecret(key, secret)
+	if err != nil {
+		return err
+	}
+
+	return &ConflictError{Current: &current}
+}
+
+// sealSecret validates the secret and prepares it for storage. Secrets of users with client-side
+// encryption are already encrypted and are stored as is. All other secrets are encrypted with
+// the user's key, bound to their owner, ID and type, and get the blind indexes of their encrypted
+// fields. The labels of a secret are never encrypted.
+func (s *secretService) sealSecret(
+	ctx context.Context,
+	userID int,
+	secretID int,
+	secretModel *models.Secret,
+) (*repository.Secret, error) {
+	clientSideEncryption := isClientSideEncryption(ctx)
+
+	if err := validatePayload(secretModel, clientSideEncryption); err != nil {
+		s.log.Error().Err(err).Str("type", secretModel.Type).Msg("invalid secret payload")
+
+		return nil, err
+	}
+
+	if err := normalizeLabels(secretModel); err != nil {
+		s.log.Error().Err(err).Msg("invalid secret labels")
+
+		return nil, err
+	}
+
+	secret := &repository.Secret{
+		ID:     secretID,
+		UserID: userID,
+		Type:   secretModel.Type,
+		BlobID: secretModel.BlobID,
+		Name:   secretModel.Name,
+		Tags:   secretModel.Tags,
+		Folder: secretModel.Folder,
+	}
+
+	if clientSideEncryption {
+		secret.Content = secretModel.Payload.(*models.Encrypted).Data
+
+		if secretModel.MetaData != "" {
+			secret.MetaData = []byte(secretModel.MetaData)
+		}
+
+		return secret, nil
+	}
+
+	content, err := encodePayload(secretModel.Payload)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to encode payload")
+
+		return nil, err
+	}
+
+	key, err := s.keys.GetUserKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret.Indexes = secretIndexes(blindIndexKey(key), secretModel.Payload)
+
+	if err := s.encryptFields(key, secret, content, secretModel.MetaData); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// encryptFields encrypts the content and the meta data of the secret with the key.
+func (s *secretService) encryptFields(key []byte, secret *repository.Secret, content, metaData string) error {
+	var err error
+
+	secret.Content, err = s.crypt.Encrypt(key, content, associatedData(secret, fieldContent))
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to encrypt content")
+
+		return err
+	}
+
+	if metaData != "" {
+		secret.MetaData, err = s.crypt.Encrypt(key, metaData, associatedData(secret, fieldMetaData))
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to encrypt meta data")
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openSecrets converts the stored secrets of the user into their service-layer representation.
+// The secrets of collections are opened with the keys of the organizations owning them,
+// the other secrets with the key of the user.
+func (s *secretService) openSecrets(ctx context.Context, userID int, secrets []repository.Secret) ([]models.Secret, error) {
+	var key []byte
+	if !isClientSideEncryption(ctx) {
+		var err error
+
+		key, err = s.keys.GetUserKey(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	orgKeys := make(map[int][]byte)
+
+	result := make([]models.Secret, len(secrets))
+	for i := range secrets {
+		secretKey := key
+		if secrets[i].OrganizationID != 0 {
+			var err error
+
+			secretKey, err = s.organizationKey(ctx, orgKeys, secrets[i].OrganizationID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		opened, err := s.openSecret(secretKey, &secrets[i])
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = opened
+	}
+
+	return result, nil
+}
+
+// openSecret converts the stored secret into its service-layer representation.
+// A nil key means that the secret is encrypted by the client and is returned as is.
+func (s *secretService) openSecret(key []byte, secret *repository.Secret) (models.Secret, error) {
+	secretModel := models.Secret{
+		ID:           secret.ID,
+		UserID:       secret.UserID,
+		Type:         secret.Type,
+		CreatedAt:    secret.CreatedAt,
+		UpdatedAt:    secret.UpdatedAt,
+		DeletedAt:    secret.DeletedAt,
+		Version:      secret.Version,
+		BlobID:       secret.BlobID,
+		Name:         secret.Name,
+		Tags:         secret.Tags,
+		Folder:       secret.Folder,
+		Permission:   secret.Permission,
+		Owner:        secret.Owner,
+		CollectionID: secret.CollectionID,
+		Collection:   secret.Collection,
+		Organization: secret.Organization,
+	}
+
+	if key == nil {
+		secretModel.Payload = &models.Encrypted{Data: secret.Content}
+		secretModel.MetaData = string(secret.MetaData)
+
+		return secretModel, nil
+	}
+
+	decryptedContent, err := s.crypt.Decrypt(key, secret.Content, associatedData(secret, fieldContent))
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to decrypt secret content")
+
+		return models.Secret{}, err
+	}
+
+	secretModel.Payload, secretModel.Type = decodePayload(secret.Type, decryptedContent)
+
+	if secret.MetaData != nil {
+		decryptedMeta, err := s.crypt.Decrypt(key, secret.MetaData, associatedData(secret, fieldMetaData))
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to decrypt secret meta data")
+
+			return models.Secret{}, err
+		}
+
+		secretModel.MetaData = decryptedMeta
+	}
+
+	return secretModel, nil
+}
+
+// associatedData returns the data authenticated along with the encrypted field of the secret.
+// It prevents moving encrypted values between users, secrets, secret types or fields.
+// The shared copies of a secret are bound to the users they are shared with,
+// the secrets of collections to the organizations owning them.
+func associatedData(secret *repository.Secret, field string) []byte {
+	if secret.OrganizationID != 0 {
+		return []byte(fmt.Sprintf("organization:%d;secret:%d;type:%s;field:%s",
+			secret.OrganizationID, secret.ID, secret.Type, field))
+	}
+
+	userID := secret.UserID
+	if secret.SharedWith != 0 {
+		userID = secret.SharedWith
+	}
+
+	return []byte(fmt.Sprintf("user:%d;secret:%d;type:%s;field:%s", userID, secret.ID, secret.Type, field))
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	pb "github.com/PrahaTurbo/goph-keeper/api/proto"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
 	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
@@ -18,6 +21,8 @@ import (
 
 type badContextKey struct{}
 
+var testKey = []byte("test-key")
+
 func Test_secretService_CreateSecret(t *testing.T) {
 	log := logger.NewLogger()
 
@@ -32,11 +37,12 @@ func Test_secretService_CreateSecret(t *testing.T) {
 			name: "success: created secret",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {
 				s.On("Create", mock.Anything, &repository.Secret{
+					ID:       13,
 					UserID:   1,
 					Type:     pb.SecretType_BINARY.String(),
 					Content:  []byte("encrypted-data"),
@@ -44,33 +50,111 @@ func Test_secretService_CreateSecret(t *testing.T) {
 				}).Return(nil).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return([]byte("encrypted-data"), nil).Times(2)
 			},
 		},
+		{
+			name: "success: credentials with blind indexes",
+			modelsSecret: &models.Secret{
+				Type:    pb.SecretType_CREDENTIALS.String(),
+				Payload: &models.Credentials{Login: "john", Password: "password", URL: "https://example.com"},
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("Create", mock.Anything, &repository.Secret{
+					ID:      13,
+					UserID:  1,
+					Type:    pb.SecretType_CREDENTIALS.String(),
+					Content: []byte("encrypted-data"),
+					Indexes: []repository.BlindIndex{
+						blindIndex(blindIndexKey(testKey), indexFieldDomain, "example.com"),
+						blindIndex(blindIndexKey(testKey), indexFieldLogin, "john"),
+					},
+				}).Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
+					Return([]byte("encrypted-data"), nil).Times(1)
+			},
+		},
+		{
+			name: "success: created secret with reserved id",
+			modelsSecret: &models.Secret{
+				ID:      21,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "content"},
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("ClaimSecretID", mock.Anything, 21, 1).Return(nil).Times(1)
+				s.On("Create", mock.Anything, &repository.Secret{
+					ID:      21,
+					UserID:  1,
+					Type:    pb.SecretType_TEXT.String(),
+					Content: []byte("encrypted-data"),
+				}).Return(nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, mock.Anything, []byte("user:1;secret:21;type:TEXT;field:content")).
+					Return([]byte("encrypted-data"), nil).Times(1)
+			},
+		},
+		{
+			name: "error: secret id is not reserved",
+			modelsSecret: &models.Secret{
+				ID:      21,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "content"},
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("ClaimSecretID", mock.Anything, 21, 1).Return(repository.ErrNoRows).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrInvalidSecretID,
+		},
 		{
 			name: "error: failed to extract user id from context",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo:       func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {},
 			expectedErr:       ErrExtractFromContext,
 		},
+		{
+			name: "error: failed to reserve secret id",
+			modelsSecret: &models.Secret{
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "content"},
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("NextSecretID", mock.Anything).Return(0, errInternal).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       errInternal,
+		},
+		{
+			name: "error: payload does not match type",
+			modelsSecret: &models.Secret{
+				Type:     pb.SecretType_CARD.String(),
+				Payload:  &models.Text{Body: "test"},
+				MetaData: "test",
+			},
+			prepareRepo:       func(s *mocks.MockSecretRepository) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrInvalidPayload,
+		},
 		{
 			name: "error: failed to encrypt",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return(nil, errInternal).Times(1)
 			},
 			expectedErr: errInternal,
@@ -78,16 +162,15 @@ func Test_secretService_CreateSecret(t *testing.T) {
 		{
 			name: "error: failed to encrypt meta data",
 			modelsSecret: &models.Secret{
-				Type:     pb.SecretType_BINARY.String(),
-				Content:  "content",
+				Type:     pb.SecretType_TEXT.String(),
+				Payload:  &models.Text{Body: "content"},
 				MetaData: "meta",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", "content").
+				e.On("Encrypt", testKey, payloadHeader+`{"body":"content"}`, []byte("user:1;secret:13;type:TEXT;field:content")).
 					Return([]byte("encrypted-data"), nil).Times(1)
-				e.On("Encrypt", "meta").
+				e.On("Encrypt", testKey, "meta", []byte("user:1;secret:13;type:TEXT;field:meta_data")).
 					Return(nil, errInternal).Times(1)
 			},
 			expectedErr: errInternal,
@@ -96,11 +179,12 @@ func Test_secretService_CreateSecret(t *testing.T) {
 			name: "error: failed to create secret",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {
 				s.On("Create", mock.Anything, &repository.Secret{
+					ID:       13,
 					UserID:   1,
 					Type:     pb.SecretType_BINARY.String(),
 					Content:  []byte("encrypted-data"),
@@ -108,8 +192,7 @@ func Test_secretService_CreateSecret(t *testing.T) {
 				}).Return(errInternal).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return([]byte("encrypted-data"), nil).Times(2)
 			},
 			expectedErr: errInternal,
@@ -121,6 +204,7 @@ func Test_secretService_CreateSecret(t *testing.T) {
 			mockEncryption := new(mocks.MockEncryption)
 
 			tt.prepareRepo(mockRepo)
+			mockRepo.On("NextSecretID", mock.Anything).Return(13, nil)
 			tt.prepareEncryption(mockEncryption)
 
 			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
@@ -129,7 +213,10 @@ func Test_secretService_CreateSecret(t *testing.T) {
 				ctx = context.WithValue(context.Background(), badContextKey{}, 1)
 			}
 
-			secretService := NewSecretService(mockRepo, &log, mockEncryption)
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
 			err := secretService.CreateSecret(ctx, tt.modelsSecret)
 
 			assert.Equal(t, tt.expectedErr, err)
@@ -155,22 +242,23 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 		{
 			name: "success: created secret",
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("GetUserSecrets", mock.Anything, 1).
+				s.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{Limit: DefaultPageSize + 1}).
 					Return([]repository.Secret{
 						{
 							ID:        13,
 							UserID:    1,
 							Type:      pb.SecretType_BINARY.String(),
-							Content:   []byte("encrypted-data"),
+							Content:   []byte("encrypted-content"),
 							MetaData:  []byte("encrypted-data"),
 							CreatedAt: now,
 						},
 					}, nil).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Decrypt", mock.Anything).
-					Return("decrypted-data", nil).Times(2)
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+					Return(payloadHeader+`{"filename":"file.txt","data":"dGVzdA=="}`, nil).Times(1)
+				e.On("Decrypt", testKey, []byte("encrypted-data"), mock.Anything).
+					Return("decrypted-data", nil).Times(1)
 			},
 			expected: expected{
 				secrets: []models.Secret{
@@ -178,7 +266,7 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 						ID:        13,
 						UserID:    1,
 						Type:      pb.SecretType_BINARY.String(),
-						Content:   "decrypted-data",
+						Payload:   &models.Binary{Filename: "file.txt", Data: []byte("test")},
 						MetaData:  "decrypted-data",
 						CreatedAt: now,
 					},
@@ -186,6 +274,37 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 				err: nil,
 			},
 		},
+		{
+			name: "success: legacy content returned as text",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{Limit: DefaultPageSize + 1}).
+					Return([]repository.Secret{
+						{
+							ID:        13,
+							UserID:    1,
+							Type:      pb.SecretType_CREDENTIALS.String(),
+							Content:   []byte("encrypted-content"),
+							CreatedAt: now,
+						},
+					}, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+					Return("login: password", nil).Times(1)
+			},
+			expected: expected{
+				secrets: []models.Secret{
+					{
+						ID:        13,
+						UserID:    1,
+						Type:      pb.SecretType_TEXT.String(),
+						Payload:   &models.Text{Body: "login: password"},
+						CreatedAt: now,
+					},
+				},
+				err: nil,
+			},
+		},
 		{
 			name:              "error: failed to extract user id from context",
 			prepareRepo:       func(s *mocks.MockSecretRepository) {},
@@ -197,7 +316,7 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 		{
 			name: "error: failed to get secrets",
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("GetUserSecrets", mock.Anything, 1).
+				s.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{Limit: DefaultPageSize + 1}).
 					Return(nil, errInternal).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {},
@@ -208,7 +327,7 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 		{
 			name: "success: failed to decrypt content",
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("GetUserSecrets", mock.Anything, 1).
+				s.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{Limit: DefaultPageSize + 1}).
 					Return([]repository.Secret{
 						{
 							ID:        13,
@@ -221,8 +340,7 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 					}, nil).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Decrypt", []byte("encrypted-content")).
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
 					Return("", errInternal).Times(1)
 			},
 			expected: expected{
@@ -232,7 +350,7 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 		{
 			name: "success: failed to decrypt meta data",
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("GetUserSecrets", mock.Anything, 1).
+				s.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{Limit: DefaultPageSize + 1}).
 					Return([]repository.Secret{
 						{
 							ID:        13,
@@ -245,10 +363,229 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 					}, nil).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Decrypt", []byte("encrypted-content")).
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
 					Return("decrypted-content", nil).Times(1)
-				e.On("Decrypt", []byte("encrypted-meta")).
+				e.On("Decrypt", testKey, []byte("encrypted-meta"), mock.Anything).
+					Return("", errInternal).Times(1)
+			},
+			expected: expected{
+				err: errInternal,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			mockEncryption := new(mocks.MockEncryption)
+
+			tt.prepareRepo(mockRepo)
+			tt.prepareEncryption(mockEncryption)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			if tt.expected.err == ErrExtractFromContext {
+				ctx = context.WithValue(context.Background(), badContextKey{}, 1)
+			}
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			page, err := secretService.GetUserSecrets(ctx, &models.SecretFilter{})
+
+			assert.Equal(t, tt.expected.err, err)
+			if tt.expected.err != nil {
+				assert.Nil(t, page)
+				return
+			}
+
+			assert.Equal(t, tt.expected.secrets, page.Secrets)
+			assert.Empty(t, page.NextPageToken)
+		})
+	}
+}
+
+func Test_secretService_GetUserSecrets_Pages(t *testing.T) {
+	log := logger.NewLogger()
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	stored := func(id int) repository.Secret {
+		return repository.Secret{
+			ID:        id,
+			UserID:    1,
+			Type:      models.SecretTypeText,
+			Content:   []byte("encrypted-content"),
+			CreatedAt: now.Add(time.Duration(id) * time.Second),
+			Name:      "github",
+		}
+	}
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{
+		Type:  models.SecretTypeText,
+		Name:  "git",
+		Limit: 3,
+	}).Return([]repository.Secret{stored(1), stored(2), stored(3)}, nil).Times(1)
+	mockRepo.On("GetUserSecrets", mock.Anything, 1, repository.SecretFilter{
+		Type:           models.SecretTypeText,
+		Name:           "git",
+		Limit:          3,
+		AfterCreatedAt: stored(2).CreatedAt,
+		AfterID:        2,
+	}).Return([]repository.Secret{stored(3)}, nil).Times(1)
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+		Return(payloadHeader+`{"body":"text"}`, nil)
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+
+	filter := &models.SecretFilter{Type: models.SecretTypeText, Name: "git", PageSize: 2}
+
+	page, err := secretService.GetUserSecrets(ctx, filter)
+	assert.NoError(t, err)
+	assert.Len(t, page.Secrets, 2)
+	assert.Equal(t, 2, page.Secrets[1].ID)
+	assert.NotEmpty(t, page.NextPageToken)
+
+	filter.PageToken = page.NextPageToken
+
+	page, err = secretService.GetUserSecrets(ctx, filter)
+	assert.NoError(t, err)
+	assert.Len(t, page.Secrets, 1)
+	assert.Equal(t, 3, page.Secrets[0].ID)
+	assert.Empty(t, page.NextPageToken)
+
+	filter.PageToken = "not-a-token"
+
+	page, err = secretService.GetUserSecrets(ctx, filter)
+	assert.Equal(t, ErrInvalidPageToken, err)
+	assert.Nil(t, page)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_repositoryFilter(t *testing.T) {
+	tests := []struct {
+		filter        models.SecretFilter
+		name          string
+		expectedLimit int
+	}{
+		{name: "default page size", filter: models.SecretFilter{}, expectedLimit: DefaultPageSize + 1},
+		{name: "page size", filter: models.SecretFilter{PageSize: 10}, expectedLimit: 11},
+		{name: "page size reduced to maximum", filter: models.SecretFilter{PageSize: 10000}, expectedLimit: MaxPageSize + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := repositoryFilter(&tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedLimit, filter.Limit)
+		})
+	}
+}
+
+func Test_secretService_Sync(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	type expected struct {
+		err     error
+		changes *models.Changes
+	}
+
+	tests := []struct {
+		expected          expected
+		prepareRepo       func(s *mocks.MockSecretRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+	}{
+		{
+			name: "success: changed and deleted secrets",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetChanges", mock.Anything, 1, int64(5)).
+					Return(&repository.Changes{
+						Secrets: []repository.Secret{
+							{
+								ID:        13,
+								UserID:    1,
+								Type:      pb.SecretType_TEXT.String(),
+								Content:   []byte("encrypted-content"),
+								CreatedAt: now,
+								Version:   2,
+							},
+						},
+						DeletedIDs: []int{7},
+						Revision:   8,
+					}, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+					Return(payloadHeader+`{"body":"text"}`, nil).Times(1)
+			},
+			expected: expected{
+				changes: &models.Changes{
+					Secrets: []models.Secret{
+						{
+							ID:        13,
+							UserID:    1,
+							Type:      pb.SecretType_TEXT.String(),
+							Payload:   &models.Text{Body: "text"},
+							CreatedAt: now,
+							Version:   2,
+						},
+					},
+					DeletedIDs: []int{7},
+					Revision:   8,
+				},
+			},
+		},
+		{
+			name: "success: no changes",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetChanges", mock.Anything, 1, int64(5)).
+					Return(&repository.Changes{Revision: 5}, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected: expected{
+				changes: &models.Changes{Revision: 5},
+			},
+		},
+		{
+			name:              "error: failed to extract user id from context",
+			prepareRepo:       func(s *mocks.MockSecretRepository) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected: expected{
+				err: ErrExtractFromContext,
+			},
+		},
+		{
+			name: "error: failed to get changes",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetChanges", mock.Anything, 1, int64(5)).
+					Return(nil, errInternal).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expected: expected{
+				err: errInternal,
+			},
+		},
+		{
+			name: "error: failed to decrypt content",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetChanges", mock.Anything, 1, int64(5)).
+					Return(&repository.Changes{
+						Secrets: []repository.Secret{
+							{ID: 13, UserID: 1, Type: pb.SecretType_TEXT.String(), Content: []byte("encrypted-content")},
+						},
+						Revision: 8,
+					}, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
 					Return("", errInternal).Times(1)
 			},
 			expected: expected{
@@ -270,15 +607,51 @@ func Test_secretService_GetUserSecrets(t *testing.T) {
 				ctx = context.WithValue(context.Background(), badContextKey{}, 1)
 			}
 
-			secretService := NewSecretService(mockRepo, &log, mockEncryption)
-			actualSecrets, err := secretService.GetUserSecrets(ctx)
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			changes, err := secretService.Sync(ctx, 5)
 
 			assert.Equal(t, tt.expected.err, err)
-			assert.Equal(t, tt.expected.secrets, actualSecrets)
+			assert.Equal(t, tt.expected.changes, changes)
 		})
 	}
 }
 
+func Test_secretService_Watch(t *testing.T) {
+	log := logger.NewLogger()
+
+	t.Run("success: subscribed to the user's events", func(t *testing.T) {
+		events := make(chan models.SecretEvent)
+		stopped := false
+
+		mockBroker := new(mocks.MockSecretBroker)
+		mockBroker.On("Subscribe", 1).
+			Return((<-chan models.SecretEvent)(events), func() { stopped = true }).Times(1)
+
+		secretService := NewSecretService(nil, &log, nil, nil, mockBroker, HistoryRetention{}, 0)
+
+		ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+		actualEvents, stop, err := secretService.Watch(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, (<-chan models.SecretEvent)(events), actualEvents)
+
+		stop()
+		assert.True(t, stopped)
+		mockBroker.AssertExpectations(t)
+	})
+
+	t.Run("error: failed to extract user id from context", func(t *testing.T) {
+		secretService := NewSecretService(nil, &log, nil, nil, new(mocks.MockSecretBroker), HistoryRetention{}, 0)
+
+		_, _, err := secretService.Watch(context.WithValue(context.Background(), badContextKey{}, 1))
+
+		assert.Equal(t, ErrExtractFromContext, err)
+	})
+}
+
 func Test_secretService_UpdateSecret(t *testing.T) {
 	log := logger.NewLogger()
 
@@ -288,13 +661,15 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 		prepareRepo       func(s *mocks.MockSecretRepository)
 		prepareEncryption func(e *mocks.MockEncryption)
 		name              string
+		expectedVersion   int
 	}{
 		{
 			name: "success: updated secret",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
+				Version:  2,
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {
 				s.On("UpdateSecret", mock.Anything, &repository.Secret{
@@ -302,36 +677,103 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 					Type:     pb.SecretType_BINARY.String(),
 					Content:  []byte("encrypted-data"),
 					MetaData: []byte("encrypted-data"),
+					Version:  2,
+				}).Run(func(args mock.Arguments) {
+					args.Get(1).(*repository.Secret).Version = 3
 				}).Return(nil).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return([]byte("encrypted-data"), nil).Times(2)
 			},
+			expectedVersion: 3,
+		},
+		{
+			name: "error: secret was changed concurrently",
+			modelsSecret: &models.Secret{
+				ID:      13,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "mine"},
+				Version: 2,
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("UpdateSecret", mock.Anything, mock.Anything).
+					Return(repository.ErrNoRows).Times(1)
+				s.On("GetSecret", mock.Anything, 13, 1).
+					Return(&repository.Secret{
+						ID:      13,
+						UserID:  1,
+						Type:    pb.SecretType_TEXT.String(),
+						Content: []byte("encrypted-data"),
+						Version: 3,
+					}, nil).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
+					Return([]byte("encrypted-data"), nil).Times(1)
+				e.On("Decrypt", testKey, []byte("encrypted-data"), []byte("user:1;secret:13;type:TEXT;field:content")).
+					Return(payloadHeader+`{"body":"theirs"}`, nil).Times(1)
+			},
+			expectedErr: &ConflictError{Current: &models.Secret{
+				ID:      13,
+				UserID:  1,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "theirs"},
+				Version: 3,
+			}},
+		},
+		{
+			name: "error: secret not found",
+			modelsSecret: &models.Secret{
+				ID:      13,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "mine"},
+				Version: 2,
+			},
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("UpdateSecret", mock.Anything, mock.Anything).
+					Return(repository.ErrNoRows).Times(1)
+				s.On("GetSecret", mock.Anything, 13, 1).
+					Return(nil, repository.ErrNoRows).Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
+					Return([]byte("encrypted-data"), nil).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
 		},
 		{
 			name: "error: failed to extract user id from context",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo:       func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {},
 			expectedErr:       ErrExtractFromContext,
 		},
+		{
+			name: "error: payload does not match type",
+			modelsSecret: &models.Secret{
+				Type:     pb.SecretType_CARD.String(),
+				Payload:  &models.Text{Body: "test"},
+				MetaData: "test",
+			},
+			prepareRepo:       func(s *mocks.MockSecretRepository) {},
+			prepareEncryption: func(e *mocks.MockEncryption) {},
+			expectedErr:       ErrInvalidPayload,
+		},
 		{
 			name: "error: failed to encrypt",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return(nil, errInternal).Times(1)
 			},
 			expectedErr: errInternal,
@@ -339,16 +781,16 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 		{
 			name: "error: failed to encrypt meta data",
 			modelsSecret: &models.Secret{
-				Type:     pb.SecretType_BINARY.String(),
-				Content:  "content",
+				ID:       13,
+				Type:     pb.SecretType_TEXT.String(),
+				Payload:  &models.Text{Body: "content"},
 				MetaData: "meta",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", "content").
+				e.On("Encrypt", testKey, payloadHeader+`{"body":"content"}`, []byte("user:1;secret:13;type:TEXT;field:content")).
 					Return([]byte("encrypted-data"), nil).Times(1)
-				e.On("Encrypt", "meta").
+				e.On("Encrypt", testKey, "meta", []byte("user:1;secret:13;type:TEXT;field:meta_data")).
 					Return(nil, errInternal).Times(1)
 			},
 			expectedErr: errInternal,
@@ -357,7 +799,7 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 			name: "error: failed to update secret",
 			modelsSecret: &models.Secret{
 				Type:     pb.SecretType_BINARY.String(),
-				Content:  "test",
+				Payload:  &models.Binary{Data: []byte("test")},
 				MetaData: "test",
 			},
 			prepareRepo: func(s *mocks.MockSecretRepository) {
@@ -369,8 +811,7 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 				}).Return(errInternal).Times(1)
 			},
 			prepareEncryption: func(e *mocks.MockEncryption) {
-				e.On("GenerateKey", 1).Times(1)
-				e.On("Encrypt", mock.Anything).
+				e.On("Encrypt", testKey, mock.Anything, mock.Anything).
 					Return([]byte("encrypted-data"), nil).Times(2)
 			},
 			expectedErr: errInternal,
@@ -382,6 +823,8 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 			mockEncryption := new(mocks.MockEncryption)
 
 			tt.prepareRepo(mockRepo)
+			mockRepo.On("GetShares", mock.Anything, mock.Anything).Return(nil, nil).Maybe()
+			mockRepo.On("GetSecretMember", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrNoRows).Maybe()
 			tt.prepareEncryption(mockEncryption)
 
 			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
@@ -390,10 +833,17 @@ func Test_secretService_UpdateSecret(t *testing.T) {
 				ctx = context.WithValue(context.Background(), badContextKey{}, 1)
 			}
 
-			secretService := NewSecretService(mockRepo, &log, mockEncryption)
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
 			err := secretService.UpdateSecret(ctx, tt.modelsSecret)
 
 			assert.Equal(t, tt.expectedErr, err)
+
+			if tt.expectedErr == nil {
+				assert.Equal(t, tt.expectedVersion, tt.modelsSecret.Version)
+			}
 		})
 	}
 }
@@ -402,24 +852,57 @@ func Test_secretService_DeleteSecret(t *testing.T) {
 	log := logger.NewLogger()
 
 	tests := []struct {
-		expectedErr error
-		prepareRepo func(s *mocks.MockSecretRepository)
-		name        string
-		secretID    int
+		expectedErr       error
+		prepareRepo       func(s *mocks.MockSecretRepository)
+		prepareEncryption func(e *mocks.MockEncryption)
+		name              string
+		secretID          int
 	}{
 		{
 			name:     "success: deleted secret",
 			secretID: 132,
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("DeleteSecret", mock.Anything, 132, 1).
+				s.On("DeleteSecret", mock.Anything, 132, 1, 2).
 					Return(nil).Times(1)
 			},
 		},
+		{
+			name:     "error: secret was changed concurrently",
+			secretID: 132,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("DeleteSecret", mock.Anything, 132, 1, 2).
+					Return(repository.ErrNoRows).Times(1)
+				s.On("GetSecret", mock.Anything, 132, 1).
+					Return(&repository.Secret{ID: 132, UserID: 1, Type: pb.SecretType_TEXT.String(), Version: 3}, nil).
+					Times(1)
+			},
+			prepareEncryption: func(e *mocks.MockEncryption) {
+				e.On("Decrypt", testKey, mock.Anything, mock.Anything).Return(payloadHeader+`{"body":"theirs"}`, nil).Times(1)
+			},
+			expectedErr: &ConflictError{Current: &models.Secret{
+				ID:      132,
+				UserID:  1,
+				Type:    pb.SecretType_TEXT.String(),
+				Payload: &models.Text{Body: "theirs"},
+				Version: 3,
+			}},
+		},
+		{
+			name:     "error: secret not found",
+			secretID: 132,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("DeleteSecret", mock.Anything, 132, 1, 2).
+					Return(repository.ErrNoRows).Times(1)
+				s.On("GetSecret", mock.Anything, 132, 1).
+					Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
 		{
 			name:     "error: failed to delete secret",
 			secretID: 132,
 			prepareRepo: func(s *mocks.MockSecretRepository) {
-				s.On("DeleteSecret", mock.Anything, 132, 1).
+				s.On("DeleteSecret", mock.Anything, 132, 1, 2).
 					Return(errInternal).Times(1)
 			},
 			expectedErr: errInternal,
@@ -437,6 +920,11 @@ func Test_secretService_DeleteSecret(t *testing.T) {
 			mockEncryption := new(mocks.MockEncryption)
 
 			tt.prepareRepo(mockRepo)
+			mockRepo.On("GetSecretMember", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrNoRows).Maybe()
+
+			if tt.prepareEncryption != nil {
+				tt.prepareEncryption(mockEncryption)
+			}
 
 			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
 
@@ -444,10 +932,261 @@ func Test_secretService_DeleteSecret(t *testing.T) {
 				ctx = context.WithValue(context.Background(), badContextKey{}, 1)
 			}
 
-			secretService := NewSecretService(mockRepo, &log, mockEncryption)
-			err := secretService.DeleteSecret(ctx, tt.secretID)
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.DeleteSecret(ctx, tt.secretID, 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+		})
+	}
+}
+
+func Test_secretService_ClientSideEncryption(t *testing.T) {
+	log := logger.NewLogger()
+	now := time.Now()
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+	ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, true)
+
+	stored := repository.Secret{
+		ID:        13,
+		UserID:    1,
+		Type:      models.SecretTypeCard,
+		Content:   []byte("client-ciphertext"),
+		MetaData:  []byte("client-meta"),
+		CreatedAt: now,
+	}
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("NextSecretID", mock.Anything).Return(13, nil)
+	mockRepo.On("Create", mock.Anything, &repository.Secret{
+		ID:       13,
+		UserID:   1,
+		Type:     models.SecretTypeCard,
+		Content:  []byte("client-ciphertext"),
+		MetaData: []byte("client-meta"),
+	}).Return(nil).Times(1)
+	mockRepo.On("GetUserSecrets", mock.Anything, 1, mock.Anything).
+		Return([]repository.Secret{stored}, nil).Times(1)
+
+	// Neither encryption nor key service expectations are set: any call fails the test.
+	mockEncryption := new(mocks.MockEncryption)
+	mockKeys := new(mocks.MockKeyService)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+
+	err := secretService.CreateSecret(ctx, &models.Secret{
+		Type:     models.SecretTypeCard,
+		Payload:  &models.Encrypted{Data: []byte("client-ciphertext")},
+		MetaData: "client-meta",
+	})
+	assert.NoError(t, err)
+
+	err = secretService.CreateSecret(ctx, &models.Secret{
+		Type:    models.SecretTypeCard,
+		Payload: &models.Card{Number: "4111"},
+	})
+	assert.Equal(t, ErrInvalidPayload, err)
+
+	page, err := secretService.GetUserSecrets(ctx, &models.SecretFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Secret{
+		{
+			ID:        13,
+			UserID:    1,
+			Type:      models.SecretTypeCard,
+			Payload:   &models.Encrypted{Data: []byte("client-ciphertext")},
+			MetaData:  "client-meta",
+			CreatedAt: now,
+		},
+	}, page.Secrets)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_secretService_FindSecretsByDomain(t *testing.T) {
+	log := logger.NewLogger()
+	indexKey := blindIndexKey(testKey)
+
+	tests := []struct {
+		expectedErr         error
+		prepareRepo         func(s *mocks.MockSecretRepository)
+		expected            []models.Secret
+		name                string
+		domain              string
+		login               string
+		clientSideEncrypted bool
+	}{
+		{
+			name:   "success: credentials of the domain and its parent domains",
+			domain: "https://login.example.com/signin",
+			login:  "John",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("FindSecrets", mock.Anything, 1, []repository.BlindIndex{
+					blindIndex(indexKey, indexFieldDomain, "login.example.com"),
+					blindIndex(indexKey, indexFieldDomain, "example.com"),
+					blindIndex(indexKey, indexFieldLogin, "john"),
+				}).Return([]repository.Secret{
+					{ID: 13, UserID: 1, Type: models.SecretTypeCredentials, Content: []byte("encrypted-content")},
+				}, nil).Times(1)
+			},
+			expected: []models.Secret{
+				{
+					ID:      13,
+					UserID:  1,
+					Type:    models.SecretTypeCredentials,
+					Payload: &models.Credentials{Login: "john", Password: "password", URL: "example.com"},
+				},
+			},
+		},
+		{
+			name:        "error: invalid domain",
+			domain:      "",
+			prepareRepo: func(s *mocks.MockSecretRepository) {},
+			expectedErr: ErrInvalidDomain,
+		},
+		{
+			name:                "error: client-side encryption",
+			domain:              "example.com",
+			clientSideEncrypted: true,
+			prepareRepo:         func(s *mocks.MockSecretRepository) {},
+			expectedErr:         ErrBlindIndexUnavailable,
+		},
+		{
+			name:   "error: failed to find secrets",
+			domain: "example.com",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("FindSecrets", mock.Anything, 1, mock.Anything).Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), mock.Anything).
+				Return(payloadHeader+`{"login":"john","password":"password","url":"example.com"}`, nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSideEncrypted)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			secrets, err := secretService.FindSecretsByDomain(ctx, tt.domain, tt.login)
 
 			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, secrets)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_ConcurrentUsers(t *testing.T) {
+	log := logger.NewLogger()
+
+	const (
+		usersCount   = 16
+		secretsCount = 5
+	)
+
+	var mu sync.Mutex
+	storage := make(map[int][]repository.Secret)
+	lastSecretID := 0
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("NextSecretID", mock.Anything).
+		Return(func(_ context.Context) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			lastSecretID++
+
+			return lastSecretID, nil
+		})
+	mockRepo.On("Create", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, secret *repository.Secret) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			storage[secret.UserID] = append(storage[secret.UserID], *secret)
+
+			return nil
 		})
+	mockRepo.On("GetUserSecrets", mock.Anything, mock.Anything, mock.Anything).
+		Return(func(_ context.Context, userID int, _ repository.SecretFilter) ([]repository.Secret, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return append([]repository.Secret(nil), storage[userID]...), nil
+		})
+
+	keyStorage := make(map[int]repository.UserKey)
+
+	mockKeyRepo := new(mocks.MockKeyRepository)
+	mockKeyRepo.On("SaveUserKey", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, key repository.UserKey) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			keyStorage[key.UserID] = key
+
+			return nil
+		})
+	mockKeyRepo.On("GetUserKey", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, userID int) (*repository.UserKey, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			key, ok := keyStorage[userID]
+			if !ok {
+				return nil, repository.ErrNoRows
+			}
+
+			return &key, nil
+		})
+
+	cryptoSrvc := encryption.NewCryptoService("secret", 1, "", true)
+	keyService := NewKeyService(mockKeyRepo, &log, cryptoSrvc)
+	secretService := NewSecretService(mockRepo, &log, cryptoSrvc, keyService, nil, HistoryRetention{}, 0)
+
+	var wg sync.WaitGroup
+	for userID := 1; userID <= usersCount; userID++ {
+		wg.Add(1)
+
+		go func(userID int) {
+			defer wg.Done()
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, userID)
+			if err := keyService.CreateUserKey(ctx, userID); !assert.NoError(t, err) {
+				return
+			}
+
+			for i := 0; i < secretsCount; i++ {
+				err := secretService.CreateSecret(ctx, &models.Secret{
+					Type:     models.SecretTypeText,
+					Payload:  &models.Text{Body: fmt.Sprintf("user-%d", userID)},
+					MetaData: fmt.Sprintf("meta-%d", userID),
+				})
+				assert.NoError(t, err)
+
+				page, err := secretService.GetUserSecrets(ctx, &models.SecretFilter{})
+				assert.NoError(t, err)
+				assert.Len(t, page.Secrets, i+1)
+
+				for _, secret := range page.Secrets {
+					assert.Equal(t, &models.Text{Body: fmt.Sprintf("user-%d", userID)}, secret.Payload)
+					assert.Equal(t, fmt.Sprintf("meta-%d", userID), secret.MetaData)
+				}
+			}
+		}(userID)
 	}
+
+	wg.Wait()
 }
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+var (
+	// ErrSharingUnavailable is returned when the owner of a secret or the user it is shared with
+	// uses client-side encryption, so the server cannot make a copy of the secret for the user.
+	ErrSharingUnavailable = errors.New("sharing is not available with client-side encryption")
+	// ErrInvalidPermission is returned when the permission of a share is unknown.
+	ErrInvalidPermission = errors.New("share permission is invalid")
+	// ErrRecipientNotFound is returned when there is no user to share a secret with.
+	ErrRecipientNotFound = errors.New("user to share with is not found")
+	// ErrInvalidRecipient is returned when the owner shares a secret with themselves.
+	ErrInvalidRecipient = errors.New("secret cannot be shared with its owner")
+	// ErrBinaryShare is returned when a binary secret is shared or a shared secret is made binary.
+	// The file of a binary secret is encrypted with the key of its owner and cannot be shared.
+	ErrBinaryShare = errors.New("binary secrets cannot be shared")
+	// ErrReadOnlyShare is returned when a secret shared read-only is updated.
+	ErrReadOnlyShare = errors.New("secret is shared read-only")
+)
+
+// ShareSecret grants the user with the login access to the secret with provided ID or changes
+// the access granted before. The user gets a copy of the secret encrypted with the user's key,
+// which is replaced on every write of the secret. repository.ErrNoRows is returned if the user
+// owns no such secret, ErrVersionConflict if the secret was changed in the meantime.
+func (s *secretService) ShareSecret(ctx context.Context, secretID int, login, permission string) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	if isClientSideEncryption(ctx) {
+		return ErrSharingUnavailable
+	}
+
+	if permission != models.SharePermissionReadOnly && permission != models.SharePermissionReadWrite {
+		return ErrInvalidPermission
+	}
+
+	secret, err := s.repo.GetSecret(ctx, secretID, userID)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to get secret")
+		}
+
+		return err
+	}
+
+	if secret.Type == models.SecretTypeBinary {
+		return ErrBinaryShare
+	}
+
+	recipient, err := s.recipient(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	if recipient.ID == userID {
+		return ErrInvalidRecipient
+	}
+
+	key, err := s.keys.GetUserKey(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	opened, err := s.openSecret(key, secret)
+	if err != nil {
+		return err
+	}
+
+	shares, err := s.sealShares(ctx, secret, &opened, []repository.Share{{
+		SecretID:   secretID,
+		UserID:     recipient.ID,
+		OwnerID:    userID,
+		Permission: permission,
+	}})
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.ShareSecret(ctx, &shares[0], secret.Version); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrVersionConflict
+		}
+
+		s.log.Error().Err(err).Msg("failed to share secret")
+
+		return err
+	}
+
+	return nil
+}
+
+// UnshareSecret revokes the access to the secret with provided ID granted to the user with the login.
+// repository.ErrNoRows is returned if the secret of the user is not shared with that user.
+func (s *secretService) UnshareSecret(ctx context.Context, secretID int, login string) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	recipient, err := s.recipient(ctx, login)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UnshareSecret(ctx, secretID, userID, recipient.ID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to unshare secret")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// recipient retrieves the user with the login a secret can be shared with.
+func (s *secretService) recipient(ctx context.Context, login string) (*repository.Recipient, error) {
+	recipient, err := s.repo.GetRecipient(ctx, login)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrRecipientNotFound
+		}
+
+		s.log.Error().Err(err).Msg("failed to get recipient")
+
+		return nil, err
+	}
+
+	if recipient.ClientSideEncryption {
+		return nil, ErrSharingUnavailable
+	}
+
+	return recipient, nil
+}
+
+// sharedSecret returns the shares of the secret and the owner of the secret on behalf of whom
+// the user writes it: the user itself or the owner who granted the user write access.
+// Users with client-side encryption have no shares.
+func (s *secretService) sharedSecret(ctx context.Context, userID, secretID int) ([]repository.Share, int, error) {
+	if isClientSideEncryption(ctx) {
+		return nil, userID, nil
+	}
+
+	shares, err := s.repo.GetShares(ctx, secretID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get secret shares")
+
+		return nil, 0, err
+	}
+
+	for _, share := range shares {
+		if share.UserID != userID {
+			continue
+		}
+
+		if share.Permission != models.SharePermissionReadWrite {
+			return nil, 0, ErrReadOnlyShare
+		}
+
+		return shares, share.OwnerID, nil
+	}
+
+	return shares, userID, nil
+}
+
+// sealShares encrypts the copies of the secret for the users of the shares
+// with their keys and binds every copy to its user.
+func (s *secretService) sealShares(
+	ctx context.Context,
+	secret *repository.Secret,
+	secretModel *models.Secret,
+	shares []repository.Share,
+) ([]repository.Share, error) {
+	if len(shares) == 0 {
+		return nil, nil
+	}
+
+	if secret.Type == models.SecretTypeBinary {
+		return nil, ErrBinaryShare
+	}
+
+	content, err := encodePayload(secretModel.Payload)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to encode payload")
+
+		return nil, err
+	}
+
+	sealed := make([]repository.Share, len(shares))
+	for i, share := range shares {
+		key, err := s.keys.GetUserKey(ctx, share.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		shared := &repository.Secret{ID: secret.ID, UserID: secret.UserID, Type: secret.Type, SharedWith: share.UserID}
+
+		share.Content, err = s.crypt.Encrypt(key, content, associatedData(shared, fieldContent))
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to encrypt shared content")
+
+			return nil, err
+		}
+
+		share.MetaData = nil
+		if secretModel.MetaData != "" {
+			share.MetaData, err = s.crypt.Encrypt(key, secretModel.MetaData, associatedData(shared, fieldMetaData))
+			if err != nil {
+				s.log.Error().Err(err).Msg("failed to encrypt shared meta data")
+
+				return nil, err
+			}
+		}
+
+		sealed[i] = share
+	}
+
+	return sealed, nil
+}
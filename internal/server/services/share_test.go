@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+var recipientKey = []byte("recipient-key")
+
+func Test_secretService_ShareSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	stored := &repository.Secret{
+		ID:       13,
+		UserID:   1,
+		Type:     models.SecretTypeText,
+		Content:  []byte("encrypted-content"),
+		MetaData: []byte("encrypted-meta"),
+		Version:  2,
+	}
+
+	tests := []struct {
+		expectedErr         error
+		prepareRepo         func(s *mocks.MockSecretRepository)
+		name                string
+		permission          string
+		clientSideEncrypted bool
+	}{
+		{
+			name:       "success: secret shared",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(stored, nil).Times(1)
+				s.On("GetRecipient", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("ShareSecret", mock.Anything, &repository.Share{
+					SecretID:   13,
+					UserID:     2,
+					OwnerID:    1,
+					Permission: models.SharePermissionReadOnly,
+					Content:    []byte("shared-content"),
+					MetaData:   []byte("shared-meta"),
+				}, 2).Return(nil).Times(1)
+			},
+		},
+		{
+			name:                "error: client-side encryption",
+			permission:          models.SharePermissionReadOnly,
+			clientSideEncrypted: true,
+			prepareRepo:         func(s *mocks.MockSecretRepository) {},
+			expectedErr:         ErrSharingUnavailable,
+		},
+		{
+			name:        "error: invalid permission",
+			permission:  "ADMIN",
+			prepareRepo: func(s *mocks.MockSecretRepository) {},
+			expectedErr: ErrInvalidPermission,
+		},
+		{
+			name:       "error: secret not found",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name:       "error: binary secret",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).
+					Return(&repository.Secret{ID: 13, UserID: 1, Type: models.SecretTypeBinary}, nil).Times(1)
+			},
+			expectedErr: ErrBinaryShare,
+		},
+		{
+			name:       "error: recipient not found",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(stored, nil).Times(1)
+				s.On("GetRecipient", mock.Anything, "bob").Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrRecipientNotFound,
+		},
+		{
+			name:       "error: recipient with client-side encryption",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(stored, nil).Times(1)
+				s.On("GetRecipient", mock.Anything, "bob").
+					Return(&repository.Recipient{ID: 2, ClientSideEncryption: true}, nil).Times(1)
+			},
+			expectedErr: ErrSharingUnavailable,
+		},
+		{
+			name:       "error: shared with owner",
+			permission: models.SharePermissionReadOnly,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(stored, nil).Times(1)
+				s.On("GetRecipient", mock.Anything, "bob").Return(&repository.Recipient{ID: 1}, nil).Times(1)
+			},
+			expectedErr: ErrInvalidRecipient,
+		},
+		{
+			name:       "error: secret changed in the meantime",
+			permission: models.SharePermissionReadWrite,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSecret", mock.Anything, 13, 1).Return(stored, nil).Times(1)
+				s.On("GetRecipient", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("ShareSecret", mock.Anything, mock.Anything, 2).Return(repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrVersionConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), []byte("user:1;secret:13;type:TEXT;field:content")).
+				Return(payloadHeader+`{"body":"shared"}`, nil)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-meta"), []byte("user:1;secret:13;type:TEXT;field:meta_data")).
+				Return("meta", nil)
+			mockEncryption.On("Encrypt", recipientKey, payloadHeader+`{"body":"shared"}`, []byte("user:2;secret:13;type:TEXT;field:content")).
+				Return([]byte("shared-content"), nil)
+			mockEncryption.On("Encrypt", recipientKey, "meta", []byte("user:2;secret:13;type:TEXT;field:meta_data")).
+				Return([]byte("shared-meta"), nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+			mockKeys.On("GetUserKey", mock.Anything, 2).Return(recipientKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+			ctx = context.WithValue(ctx, interceptors.ClientSideEncryptionKey, tt.clientSideEncrypted)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.ShareSecret(ctx, 13, "bob", tt.permission)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_UnshareSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+	}{
+		{
+			name: "success: share revoked",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetRecipient", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("UnshareSecret", mock.Anything, 13, 1, 2).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: recipient not found",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetRecipient", mock.Anything, "bob").Return(nil, repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrRecipientNotFound,
+		},
+		{
+			name: "error: share not found",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetRecipient", mock.Anything, "bob").Return(&repository.Recipient{ID: 2}, nil).Times(1)
+				s.On("UnshareSecret", mock.Anything, 13, 1, 2).Return(repository.ErrNoRows).Times(1)
+			},
+			expectedErr: repository.ErrNoRows,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, nil, nil, nil, HistoryRetention{}, 0)
+			err := secretService.UnshareSecret(ctx, 13, "bob")
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_UpdateSharedSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+		userID      int
+	}{
+		{
+			name:   "success: owner updates shared copies",
+			userID: 1,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetShares", mock.Anything, 13).Return([]repository.Share{
+					{SecretID: 13, UserID: 2, OwnerID: 1, Permission: models.SharePermissionReadOnly},
+				}, nil).Times(1)
+				s.On("UpdateSecret", mock.Anything, &repository.Secret{
+					ID:       13,
+					UserID:   1,
+					Type:     models.SecretTypeText,
+					Content:  []byte("owner-content"),
+					MetaData: []byte("owner-meta"),
+					Shares: []repository.Share{{
+						SecretID:   13,
+						UserID:     2,
+						OwnerID:    1,
+						Permission: models.SharePermissionReadOnly,
+						Content:    []byte("shared-content"),
+						MetaData:   []byte("shared-meta"),
+					}},
+					Version: 2,
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name:   "success: user with write access updates on behalf of owner",
+			userID: 2,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetShares", mock.Anything, 13).Return([]repository.Share{
+					{SecretID: 13, UserID: 2, OwnerID: 1, Permission: models.SharePermissionReadWrite},
+				}, nil).Times(1)
+				s.On("UpdateSecret", mock.Anything, mock.MatchedBy(func(secret *repository.Secret) bool {
+					return secret.UserID == 1 && len(secret.Shares) == 1 && secret.Shares[0].UserID == 2
+				})).Return(nil).Times(1)
+			},
+		},
+		{
+			name:   "error: read-only share",
+			userID: 2,
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetShares", mock.Anything, 13).Return([]repository.Share{
+					{SecretID: 13, UserID: 2, OwnerID: 1, Permission: models.SharePermissionReadOnly},
+				}, nil).Times(1)
+			},
+			expectedErr: ErrReadOnlyShare,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+			mockRepo.On("GetSecretMember", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrNoRows).Maybe()
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Encrypt", testKey, payloadHeader+`{"body":"new"}`, []byte("user:1;secret:13;type:TEXT;field:content")).
+				Return([]byte("owner-content"), nil)
+			mockEncryption.On("Encrypt", testKey, "meta", []byte("user:1;secret:13;type:TEXT;field:meta_data")).
+				Return([]byte("owner-meta"), nil)
+			mockEncryption.On("Encrypt", recipientKey, payloadHeader+`{"body":"new"}`, []byte("user:2;secret:13;type:TEXT;field:content")).
+				Return([]byte("shared-content"), nil)
+			mockEncryption.On("Encrypt", recipientKey, "meta", []byte("user:2;secret:13;type:TEXT;field:meta_data")).
+				Return([]byte("shared-meta"), nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+			mockKeys.On("GetUserKey", mock.Anything, 2).Return(recipientKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, tt.userID)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			err := secretService.UpdateSecret(ctx, &models.Secret{
+				ID:       13,
+				Type:     models.SecretTypeText,
+				Payload:  &models.Text{Body: "new"},
+				MetaData: "meta",
+				Version:  2,
+			})
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_SyncSharedSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("GetChanges", mock.Anything, 2, int64(5)).Return(&repository.Changes{
+		Secrets: []repository.Secret{{
+			ID:         13,
+			UserID:     1,
+			Type:       models.SecretTypeText,
+			Content:    []byte("shared-content"),
+			Version:    3,
+			SharedWith: 2,
+			Permission: models.SharePermissionReadOnly,
+			Owner:      "alice",
+		}},
+		DeletedIDs: []int{7},
+		Revision:   6,
+	}, nil).Times(1)
+
+	mockEncryption := new(mocks.MockEncryption)
+	mockEncryption.On("Decrypt", recipientKey, []byte("shared-content"), []byte("user:2;secret:13;type:TEXT;field:content")).
+		Return(payloadHeader+`{"body":"text"}`, nil).Times(1)
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetUserKey", mock.Anything, 2).Return(recipientKey, nil)
+
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 2)
+
+	secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+	changes, err := secretService.Sync(ctx, 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &models.Changes{
+		Secrets: []models.Secret{{
+			ID:         13,
+			UserID:     1,
+			Type:       models.SecretTypeText,
+			Payload:    &models.Text{Body: "text"},
+			Version:    3,
+			Permission: models.SharePermissionReadOnly,
+			Owner:      "alice",
+		}},
+		DeletedIDs: []int{7},
+		Revision:   6,
+	}, changes)
+	mockRepo.AssertExpectations(t)
+	mockEncryption.AssertExpectations(t)
+}
+
+func Test_associatedData(t *testing.T) {
+	secret := &repository.Secret{ID: 13, UserID: 1, Type: models.SecretTypeText}
+	assert.Equal(t, []byte("user:1;secret:13;type:TEXT;field:content"), associatedData(secret, fieldContent))
+
+	secret.SharedWith = 2
+	assert.Equal(t, []byte("user:2;secret:13;type:TEXT;field:content"), associatedData(secret, fieldContent))
+
+	secret = &repository.Secret{ID: 13, OrganizationID: 5, Type: models.SecretTypeText}
+	assert.Equal(t, []byte("organization:5;secret:13;type:TEXT;field:content"), associatedData(secret, fieldContent))
+}
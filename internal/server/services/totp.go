@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/totp"
+)
+
+const (
+	totpIssuer        = "GophKeeper"
+	totpChallengeTTL  = 5 * time.Minute
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 5
+)
+
+var (
+	// ErrTOTPAlreadyEnabled is returned when the user enrolls an authenticator while
+	// two-factor authentication is already enabled.
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTOTPNotEnabled is returned when the user has no authenticator to confirm or disable.
+	ErrTOTPNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidTOTPCode is returned when the one-time or recovery code is wrong or already used.
+	ErrInvalidTOTPCode = errors.New("two-factor authentication code is invalid")
+	// ErrInvalidChallenge is returned when the second factor challenge is malformed or expired.
+	ErrInvalidChallenge = errors.New("two-factor authentication challenge is invalid")
+)
+
+// EnableTOTP generates a new authenticator secret for the user and stores it encrypted
+// with the user's key. Two-factor authentication stays disabled until the user proves
+// possession of the authenticator with ConfirmTOTP.
+func (a *authService) EnableTOTP(ctx context.Context) (*models.TOTPEnrollment, error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to generate totp secret")
+
+		return nil, err
+	}
+
+	key, err := a.keys.GetUserKey(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedSecret, err := a.crypt.Encrypt(key, secret, totpAssociatedData(user.ID))
+	if err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to encrypt totp secret")
+
+		return nil, err
+	}
+
+	if err := a.repo.SetTOTPSecret(ctx, user.ID, encryptedSecret); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to save totp secret")
+
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user provides a valid code of the
+// enrolled authenticator. It returns the recovery codes which can be used instead of
+// one-time codes if the authenticator is lost. The codes are shown only once.
+// Wrong codes count toward the account lockout.
+func (a *authService) ConfirmTOTP(ctx context.Context, code string) ([]string, error) {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	if user.TOTPSecret == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	if err := a.checkSecondFactor(ctx, user, code, a.verifyTOTPCode); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+
+	for i := range codes {
+		codes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := a.repo.EnableTOTP(ctx, user.ID, hashes); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrTOTPNotEnabled
+		}
+
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to enable totp")
+
+		return nil, err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("two-factor authentication was enabled")
+
+	return codes, nil
+}
+
+// DisableTOTP disables two-factor authentication of the user. The request has to be
+// confirmed with a one-time code or a recovery code. Wrong codes count toward the account lockout.
+func (a *authService) DisableTOTP(ctx context.Context, code string) error {
+	user, err := a.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !user.TOTPEnabled {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := a.checkSecondFactor(ctx, user, code, a.verifySecondFactor); err != nil {
+		return err
+	}
+
+	if err := a.repo.DisableTOTP(ctx, user.ID); err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to disable totp")
+
+		return err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("two-factor authentication was disabled")
+
+	return nil
+}
+
+// LoginTOTP completes the login of a user with two-factor authentication enabled. It accepts
+// the challenge returned by Login and a one-time code or a recovery code, starts a new session
+// and returns a pair of tokens. Wrong codes count toward the account lockout.
+func (a *authService) LoginTOTP(ctx context.Context, challenge string, code string) (*models.Tokens, error) {
+	claims, err := a.jwtManager.ParseChallenge(challenge)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := a.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return nil, ErrInvalidChallenge
+		}
+
+		a.log.Error().Err(err).Int("user", claims.UserID).Msg("cannot find user in database")
+
+		return nil, err
+	}
+
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := a.checkSecondFactor(ctx, user, code, a.verifySecondFactor); err != nil {
+		return nil, err
+	}
+
+	tokens, err := a.startSession(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("user logged in with second factor")
+
+	return tokens, nil
+}
+
+// checkSecondFactor checks the code of the user with verify. Wrong codes count toward the lockout
+// just like failed logins, and ErrInvalidTOTPCode is returned while the account is locked.
+func (a *authService) checkSecondFactor(
+	ctx context.Context,
+	user *models.User,
+	code string,
+	verify func(ctx context.Context, user *models.User, code string) error,
+) error {
+	if time.Now().Before(user.LockedUntil) {
+		a.log.Warn().Int("user", user.ID).Msg("second factor attempt to locked account")
+
+		return ErrInvalidTOTPCode
+	}
+
+	if err := verify(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			if err := a.recordLoginFailure(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		return err
+	}
+
+	return a.resetLoginFailures(ctx, user)
+}
+
+// totpChallenge returns the tokens of a login which has to be completed with LoginTOTP.
+func (a *authService) totpChallenge(user *models.User) (*models.Tokens, error) {
+	challenge, err := a.jwtManager.GenerateChallenge(user.ID, totpChallengeTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Tokens{TOTPChallenge: challenge}, nil
+}
+
+// verifySecondFactor accepts either a one-time code of the user's authenticator
+// or one of the user's recovery codes.
+func (a *authService) verifySecondFactor(ctx context.Context, user *models.User, code string) error {
+	if isTOTPCode(code) {
+		return a.verifyTOTPCode(ctx, user, code)
+	}
+
+	if err := a.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrInvalidTOTPCode
+		}
+
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to use recovery code")
+
+		return err
+	}
+
+	a.log.Info().Int("user", user.ID).Msg("recovery code was used")
+
+	return nil
+}
+
+// verifyTOTPCode checks the one-time code against the user's authenticator. Every code
+// is accepted only once, so an intercepted code cannot be replayed.
+func (a *authService) verifyTOTPCode(ctx context.Context, user *models.User, code string) error {
+	key, err := a.keys.GetUserKey(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	secret, err := a.crypt.Decrypt(key, user.TOTPSecret, totpAssociatedData(user.ID))
+	if err != nil {
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to decrypt totp secret")
+
+		return err
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+
+	if err := a.repo.UseTOTPCounter(ctx, user.ID, counter); err != nil {
+		if errors.Is(err, repository.ErrNoRows) {
+			return ErrInvalidTOTPCode
+		}
+
+		a.log.Error().Err(err).Int("user", user.ID).Msg("failed to save totp counter")
+
+		return err
+	}
+
+	return nil
+}
+
+// totpAssociatedData binds the encrypted authenticator secret to its owner.
+func totpAssociatedData(userID int) []byte {
+	return []byte(fmt.Sprintf("user:%d;field:totp_secret", userID))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateRecoveryCode returns a random code formatted as two dash-separated groups
+// of hex digits, e.g. "1f2e3-d4c5b".
+func generateRecoveryCode() (string, error) {
+	code := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+
+	encoded := hex.EncodeToString(code)
+
+	return encoded[:recoveryCodeSize] + "-" + encoded[recoveryCodeSize:], nil
+}
+
+// hashRecoveryCode returns the hash under which the recovery code is stored. The code is
+// normalized first, so it is accepted regardless of case and separators.
+func hashRecoveryCode(code string) []byte {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+
+	return hash[:]
+}
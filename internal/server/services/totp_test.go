@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/encryption"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/jwt"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+	"github.com/PrahaTurbo/goph-keeper/pkg/totp"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var testTOTPUserKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTOTPTestService(repo *mocks.MockAuthRepository, jwtManager *jwt.JWTManager) AuthService {
+	log := logger.NewLogger()
+
+	mockKeys := new(mocks.MockKeyService)
+	mockKeys.On("GetUserKey", mock.Anything, 1).Return(testTOTPUserKey, nil)
+
+	mockTokens := new(mocks.MockTokenRepository)
+	mockTokens.On("SaveRefreshToken", context.Background(), mock.Anything).Return(nil)
+
+	mockSessions := new(mocks.MockSessionRepository)
+	mockSessions.On("CreateSession", context.Background(), mock.Anything).Return(nil)
+
+	return NewAuthService(
+		repo,
+		mockTokens,
+		mockSessions,
+		&log,
+		jwtManager,
+		mockKeys,
+		encryption.NewCryptoService("test-secret", 1, "", true),
+		refreshTokenTTL,
+		testLockout,
+	)
+}
+
+// totpUser returns a user enrolled with testTOTPSecret.
+func totpUser(t *testing.T, enabled bool) *models.User {
+	t.Helper()
+
+	crypt := encryption.NewCryptoService("test-secret", 1, "", true)
+	secret, err := crypt.Encrypt(testTOTPUserKey, testTOTPSecret, totpAssociatedData(1))
+	assert.NoError(t, err)
+
+	return &models.User{ID: 1, Login: "login", TOTPSecret: secret, TOTPEnabled: enabled}
+}
+
+func currentTOTPCode(t *testing.T) string {
+	t.Helper()
+
+	code, err := totp.Code(testTOTPSecret, time.Now())
+	assert.NoError(t, err)
+
+	return code
+}
+
+func Test_authService_EnableTOTP(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository)
+		name        string
+	}{
+		{
+			name: "success: authenticator enrolled",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Login: "login"}, nil).Times(1)
+				r.On("SetTOTPSecret", ctx, 1, mock.Anything).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: already enabled",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+			},
+			expectedErr: ErrTOTPAlreadyEnabled,
+		},
+		{
+			name: "error: enabled concurrently",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Login: "login"}, nil).Times(1)
+				r.On("SetTOTPSecret", ctx, 1, mock.Anything).Return(repository.ErrNoRows).Times(1)
+			},
+			expectedErr: ErrTOTPAlreadyEnabled,
+		},
+		{
+			name: "error: failed to save secret",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1, Login: "login"}, nil).Times(1)
+				r.On("SetTOTPSecret", ctx, 1, mock.Anything).Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			tt.prepare(mockRepo)
+
+			enrollment, err := newTOTPTestService(mockRepo, jwtManager).EnableTOTP(ctx)
+
+			assert.Equal(t, tt.expectedErr, err)
+
+			if tt.expectedErr != nil {
+				assert.Nil(t, enrollment)
+				return
+			}
+
+			assert.NotEmpty(t, enrollment.Secret)
+			assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+			mockRepo.AssertCalled(t, "SetTOTPSecret", ctx, 1, mock.MatchedBy(func(secret []byte) bool {
+				return !assert.ObjectsAreEqual([]byte(enrollment.Secret), secret)
+			}))
+		})
+	}
+}
+
+func Test_authService_ConfirmTOTP(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository)
+		name        string
+		code        string
+	}{
+		{
+			name: "success: two-factor authentication enabled",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, false), nil).Times(1)
+				r.On("UseTOTPCounter", ctx, 1, mock.Anything).Return(nil).Times(1)
+				r.On("EnableTOTP", ctx, 1, mock.Anything).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: wrong code",
+			code: "000000",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, false), nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: wrong code locks account",
+			code: "000000",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, false), nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(3, nil).Times(1)
+				r.On("LockUser", ctx, 1, mock.Anything).Return(nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: account is locked",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				user := totpUser(t, false)
+				user.LockedUntil = time.Now().Add(time.Minute)
+
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: code is already used",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, false), nil).Times(1)
+				r.On("UseTOTPCounter", ctx, 1, mock.Anything).Return(repository.ErrNoRows).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: no authenticator enrolled",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(&models.User{ID: 1}, nil).Times(1)
+			},
+			expectedErr: ErrTOTPNotEnabled,
+		},
+		{
+			name: "error: already enabled",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+			},
+			expectedErr: ErrTOTPAlreadyEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			tt.prepare(mockRepo)
+
+			codes, err := newTOTPTestService(mockRepo, jwtManager).ConfirmTOTP(ctx, tt.code)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+
+			if tt.expectedErr != nil {
+				assert.Nil(t, codes)
+				mockRepo.AssertNotCalled(t, "EnableTOTP", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.Len(t, codes, recoveryCodeCount)
+			mockRepo.AssertCalled(t, "EnableTOTP", ctx, 1, mock.MatchedBy(func(hashes [][]byte) bool {
+				return assert.ObjectsAreEqual(hashRecoveryCode(codes[0]), hashes[0]) && len(hashes) == len(codes)
+			}))
+		})
+	}
+}
+
+func Test_authService_DisableTOTP(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+	ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository)
+		name        string
+		code        string
+	}{
+		{
+			name: "success: disabled with one-time code",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("UseTOTPCounter", ctx, 1, mock.Anything).Return(nil).Times(1)
+				r.On("DisableTOTP", ctx, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "success: disabled with recovery code",
+			code: "ABCDE-12345",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("UseRecoveryCode", ctx, 1, hashRecoveryCode("abcde12345")).Return(nil).Times(1)
+				r.On("DisableTOTP", ctx, 1).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "error: unknown recovery code",
+			code: "abcde-12345",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("UseRecoveryCode", ctx, 1, mock.Anything).Return(repository.ErrNoRows).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: wrong code locks account",
+			code: "000000",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("RecordLoginFailure", ctx, 1).Return(3, nil).Times(1)
+				r.On("LockUser", ctx, 1, mock.Anything).Return(nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: account is locked",
+			code: "ABCDE-12345",
+			prepare: func(r *mocks.MockAuthRepository) {
+				user := totpUser(t, true)
+				user.LockedUntil = time.Now().Add(time.Minute)
+
+				r.On("GetUserByID", ctx, 1).Return(user, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name: "error: not enabled",
+			code: currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", ctx, 1).Return(totpUser(t, false), nil).Times(1)
+			},
+			expectedErr: ErrTOTPNotEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			tt.prepare(mockRepo)
+
+			err := newTOTPTestService(mockRepo, jwtManager).DisableTOTP(ctx, tt.code)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_authService_LoginTOTP(t *testing.T) {
+	jwtManager := jwt.NewJWTManager("test-secret", time.Minute)
+
+	challenge, err := jwtManager.GenerateChallenge(1, time.Minute)
+	assert.NoError(t, err)
+
+	accessToken, err := jwtManager.Generate(1, "session", false)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		expectedErr error
+		prepare     func(r *mocks.MockAuthRepository)
+		name        string
+		challenge   string
+		code        string
+	}{
+		{
+			name:      "success: logged in with one-time code",
+			challenge: challenge,
+			code:      currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", context.Background(), 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("UseTOTPCounter", context.Background(), 1, mock.Anything).Return(nil).Times(1)
+			},
+		},
+		{
+			name:      "success: logged in with recovery code",
+			challenge: challenge,
+			code:      "abcde-12345",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", context.Background(), 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("UseRecoveryCode", context.Background(), 1, hashRecoveryCode("abcde-12345")).
+					Return(nil).Times(1)
+			},
+		},
+		{
+			name:      "error: wrong code",
+			challenge: challenge,
+			code:      "000000",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", context.Background(), 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("RecordLoginFailure", context.Background(), 1).Return(1, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name:      "error: wrong code locks account",
+			challenge: challenge,
+			code:      "000000",
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", context.Background(), 1).Return(totpUser(t, true), nil).Times(1)
+				r.On("RecordLoginFailure", context.Background(), 1).Return(3, nil).Times(1)
+				r.On("LockUser", context.Background(), 1, mock.Anything).Return(nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name:      "error: account is locked",
+			challenge: challenge,
+			code:      currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				user := totpUser(t, true)
+				user.LockedUntil = time.Now().Add(time.Minute)
+
+				r.On("GetUserByID", context.Background(), 1).Return(user, nil).Times(1)
+			},
+			expectedErr: ErrInvalidTOTPCode,
+		},
+		{
+			name:        "error: access token used as challenge",
+			challenge:   accessToken,
+			code:        currentTOTPCode(t),
+			prepare:     func(r *mocks.MockAuthRepository) {},
+			expectedErr: ErrInvalidChallenge,
+		},
+		{
+			name:      "error: two-factor authentication was disabled",
+			challenge: challenge,
+			code:      currentTOTPCode(t),
+			prepare: func(r *mocks.MockAuthRepository) {
+				r.On("GetUserByID", context.Background(), 1).Return(totpUser(t, false), nil).Times(1)
+			},
+			expectedErr: ErrInvalidChallenge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockAuthRepository)
+			tt.prepare(mockRepo)
+
+			tokens, err := newTOTPTestService(mockRepo, jwtManager).LoginTOTP(context.Background(), tt.challenge, tt.code)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+
+			if tt.expectedErr != nil {
+				assert.Nil(t, tokens)
+				return
+			}
+
+			claims, err := jwtManager.Parse(tokens.AccessToken)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, claims.UserID)
+			assert.NotEmpty(t, tokens.RefreshToken)
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// ListTrash retrieves the secrets of the user in the trash and the secrets in the trash of the collections
+// of the user's organizations the user can write secrets of, the latest deleted first.
+func (s *secretService) ListTrash(ctx context.Context) ([]models.Secret, error) {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return nil, err
+	}
+
+	secrets, err := s.repo.GetTrash(ctx, userID)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to get trash")
+
+		return nil, err
+	}
+
+	if len(secrets) == 0 {
+		return []models.Secret{}, nil
+	}
+
+	return s.openSecrets(ctx, userID, secrets)
+}
+
+// RestoreSecret moves the secret with provided ID out of the trash. A secret of a collection
+// is restored if the role of the user in the organization owning the collection allows it,
+// ErrInsufficientRole is returned otherwise.
+// repository.ErrNoRows is returned if the secret is not in the trash.
+func (s *secretService) RestoreSecret(ctx context.Context, secretID int) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	member, err := s.secretMember(ctx, userID, secretID)
+	if err != nil {
+		return err
+	}
+
+	if member != nil {
+		return s.restoreCollectionSecret(ctx, member, secretID)
+	}
+
+	if err := s.repo.RestoreSecret(ctx, secretID, userID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to restore secret")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// PurgeSecret permanently removes the secret with provided ID from the trash. A secret of a collection
+// is removed if the role of the user in the organization owning the collection allows it,
+// ErrInsufficientRole is returned otherwise.
+// repository.ErrNoRows is returned if the secret is not in the trash.
+func (s *secretService) PurgeSecret(ctx context.Context, secretID int) error {
+	userID, err := extractUserIDFromCtx(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to extract user from context")
+
+		return err
+	}
+
+	member, err := s.secretMember(ctx, userID, secretID)
+	if err != nil {
+		return err
+	}
+
+	if member != nil {
+		return s.purgeCollectionSecret(ctx, member, secretID)
+	}
+
+	if err := s.repo.PurgeSecret(ctx, secretID, userID); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Msg("failed to purge secret")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// purgeTrash permanently removes the secrets which are in the trash for longer than the trash retention.
+func (s *secretService) purgeTrash(ctx context.Context) {
+	purged, err := s.repo.PurgeTrash(ctx, time.Now().Add(-s.trashRetention))
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error().Err(err).Msg("failed to purge trash")
+		}
+
+		return
+	}
+
+	if purged > 0 {
+		s.log.Info().Int64("count", purged).Msg("purged secrets from trash")
+	}
+}
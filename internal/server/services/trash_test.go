@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/interceptors"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func Test_secretService_ListTrash(t *testing.T) {
+	log := logger.NewLogger()
+	deletedAt := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+		expected    []models.Secret
+	}{
+		{
+			name: "success: decrypted secrets in trash",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetTrash", mock.Anything, 1).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("encrypted-content"), DeletedAt: deletedAt, Version: 2},
+					}, nil).Times(1)
+			},
+			expected: []models.Secret{
+				{ID: 13, UserID: 1, Type: models.SecretTypeText, Payload: &models.Text{Body: "deleted"}, DeletedAt: deletedAt, Version: 2},
+			},
+		},
+		{
+			name: "success: secret of collection in trash",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetTrash", mock.Anything, 1).
+					Return([]repository.Secret{
+						{
+							ID:             13,
+							OrganizationID: 5,
+							CollectionID:   3,
+							Collection:     "infra",
+							Organization:   "team",
+							Type:           models.SecretTypeText,
+							Content:        []byte("org-content"),
+							DeletedAt:      deletedAt,
+							Version:        2,
+						},
+					}, nil).Times(1)
+			},
+			expected: []models.Secret{
+				{
+					ID:           13,
+					CollectionID: 3,
+					Collection:   "infra",
+					Organization: "team",
+					Type:         models.SecretTypeText,
+					Payload:      &models.Text{Body: "deleted"},
+					DeletedAt:    deletedAt,
+					Version:      2,
+				},
+			},
+		},
+		{
+			name: "success: empty trash",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetTrash", mock.Anything, 1).Return(nil, nil).Times(1)
+			},
+			expected: []models.Secret{},
+		},
+		{
+			name: "error: failed to get trash",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetTrash", mock.Anything, 1).Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("encrypted-content"), []byte("user:1;secret:13;type:TEXT;field:content")).
+				Return(payloadHeader+`{"body":"deleted"}`, nil)
+			mockEncryption.On("Decrypt", organizationKey, []byte("org-content"), []byte("organization:5;secret:13;type:TEXT;field:content")).
+				Return(payloadHeader+`{"body":"deleted"}`, nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+			mockKeys.On("GetOrganizationKey", mock.Anything, 5).Return(organizationKey, nil)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			secrets, err := secretService.ListTrash(ctx)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, secrets)
+		})
+	}
+}
+
+func Test_secretService_RestoreSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		repoErr     error
+		expectedErr error
+		name        string
+	}{
+		{
+			name: "success: secret restored",
+		},
+		{
+			name:        "error: secret not in trash",
+			repoErr:     repository.ErrNoRows,
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name:        "error: failed to restore secret",
+			repoErr:     errInternal,
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			mockRepo.On("RestoreSecret", mock.Anything, 13, 1).Return(tt.repoErr).Times(1)
+			mockRepo.On("GetSecretMember", mock.Anything, 13, 1).Return(nil, repository.ErrNoRows).Times(1)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, nil, nil, nil, HistoryRetention{}, 0)
+			err := secretService.RestoreSecret(ctx, 13)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_PurgeSecret(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		repoErr     error
+		expectedErr error
+		name        string
+	}{
+		{
+			name: "success: secret purged",
+		},
+		{
+			name:        "error: secret not in trash",
+			repoErr:     repository.ErrNoRows,
+			expectedErr: repository.ErrNoRows,
+		},
+		{
+			name:        "error: failed to purge secret",
+			repoErr:     errInternal,
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			mockRepo.On("PurgeSecret", mock.Anything, 13, 1).Return(tt.repoErr).Times(1)
+			mockRepo.On("GetSecretMember", mock.Anything, 13, 1).Return(nil, repository.ErrNoRows).Times(1)
+
+			ctx := context.WithValue(context.Background(), interceptors.UserIDKey, 1)
+
+			secretService := NewSecretService(mockRepo, &log, nil, nil, nil, HistoryRetention{}, 0)
+			err := secretService.PurgeSecret(ctx, 13)
+
+			assert.Equal(t, tt.expectedErr, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_secretService_purgeTrash(t *testing.T) {
+	log := logger.NewLogger()
+	retention := 24 * time.Hour
+
+	mockRepo := new(mocks.MockSecretRepository)
+	mockRepo.On("PurgeTrash", mock.Anything, mock.MatchedBy(func(deletedBefore time.Time) bool {
+		return time.Since(deletedBefore) >= retention && time.Since(deletedBefore) < retention+time.Minute
+	})).Return(int64(2), nil).Times(1)
+
+	secretService := &secretService{repo: mockRepo, log: &log, trashRetention: retention}
+	secretService.purgeTrash(context.Background())
+
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/blobstore"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+)
+
+// UpgradeSecrets re-encrypts the secrets encrypted by the server, including their kept versions,
+// which are still stored in the format without the header and associated data, and stores the
+// blind indexes of the credentials stored before the indexes were kept, in batches of the given
+// size. It returns the number of upgraded secrets and versions. Secrets already in the current
+// format and indexed are skipped, so an interrupted upgrade can be resumed by calling it again.
+// Once it completes, the fallback to the legacy format can be turned off.
+func (s *secretService) UpgradeSecrets(ctx context.Context, batchSize int) (int, error) {
+	var upgraded, afterID int
+
+	for {
+		secrets, err := s.repo.GetSealedSecrets(ctx, afterID, batchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to get secrets")
+
+			return upgraded, err
+		}
+
+		if len(secrets) == 0 {
+			return upgraded, nil
+		}
+
+		for i := range secrets {
+			count, err := s.upgradeSecret(ctx, &secrets[i])
+			if err != nil {
+				return upgraded, err
+			}
+
+			upgraded += count
+		}
+
+		afterID = secrets[len(secrets)-1].ID
+	}
+}
+
+// upgradeSecret re-encrypts the secret and its kept versions stored in the legacy format.
+// It returns the number of upgraded records.
+func (s *secretService) upgradeSecret(ctx context.Context, secret *repository.Secret) (int, error) {
+	key, err := s.keys.GetUserKey(ctx, secret.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	var upgraded int
+
+	previousContent := secret.Content
+
+	resealed, err := s.resealSecret(key, secret)
+	if err != nil {
+		return upgraded, err
+	}
+
+	if resealed {
+		err := s.repo.ResealSecret(ctx, secret, previousContent)
+		switch {
+		case errors.Is(err, repository.ErrNoRows):
+			// The secret was changed in the meantime and is stored in the current format.
+		case err != nil:
+			s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to update secret")
+
+			return upgraded, err
+		default:
+			upgraded++
+		}
+	}
+
+	if secret.Type == models.SecretTypeCredentials {
+		indexed, err := s.indexSecret(ctx, key, secret)
+		if err != nil {
+			return upgraded, err
+		}
+
+		if indexed && !resealed {
+			upgraded++
+		}
+	}
+
+	versions, err := s.repo.GetSealedVersions(ctx, secret.ID)
+	if err != nil {
+		s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to get secret versions")
+
+		return upgraded, err
+	}
+
+	for i := range versions {
+		previousContent := versions[i].Content
+
+		resealed, err := s.resealSecret(key, &versions[i])
+		if err != nil {
+			return upgraded, err
+		}
+
+		if !resealed {
+			continue
+		}
+
+		err = s.repo.ResealVersion(ctx, &versions[i], previousContent)
+		switch {
+		case errors.Is(err, repository.ErrNoRows):
+			// The version expired in the meantime.
+		case err != nil:
+			s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to update secret version")
+
+			return upgraded, err
+		default:
+			upgraded++
+		}
+	}
+
+	return upgraded, nil
+}
+
+// resealSecret re-encrypts the content and meta data of the secret in place if they are stored
+// in the legacy format and reports whether they were.
+func (s *secretService) resealSecret(key []byte, secret *repository.Secret) (bool, error) {
+	content, contentResealed, err := s.crypt.Reseal(key, secret.Content, associatedData(secret, fieldContent))
+	if err != nil {
+		s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to reseal secret content")
+
+		return false, err
+	}
+
+	secret.Content = content
+
+	if secret.MetaData == nil {
+		return contentResealed, nil
+	}
+
+	metaData, metaResealed, err := s.crypt.Reseal(key, secret.MetaData, associatedData(secret, fieldMetaData))
+	if err != nil {
+		s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to reseal secret meta data")
+
+		return false, err
+	}
+
+	secret.MetaData = metaData
+
+	return contentResealed || metaResealed, nil
+}
+
+// indexSecret stores the blind indexes of the credentials stored before the indexes were kept
+// and reports whether it did.
+func (s *secretService) indexSecret(ctx context.Context, key []byte, secret *repository.Secret) (bool, error) {
+	content, err := s.crypt.Decrypt(key, secret.Content, associatedData(secret, fieldContent))
+	if err != nil {
+		s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to decrypt secret content")
+
+		return false, err
+	}
+
+	payload, _ := decodePayload(secret.Type, content)
+
+	secret.Indexes = secretIndexes(blindIndexKey(key), payload)
+	if len(secret.Indexes) == 0 {
+		return false, nil
+	}
+
+	err = s.repo.IndexSecret(ctx, secret)
+	switch {
+	case errors.Is(err, repository.ErrNoRows):
+		// The secret was changed or indexed in the meantime.
+		return false, nil
+	case err != nil:
+		s.log.Error().Err(err).Int("secret", secret.ID).Msg("failed to index secret")
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpgradeBlobs encrypts the chunks of the blobs which were encrypted by the server before the last
+// chunk was marked again and stores the hashes of the blobs uploaded before the hashes were kept,
+// in batches of the given size. It returns the number of upgraded blobs. Corrupted blobs are
+// skipped. The chunks already encrypted again are kept, so an interrupted upgrade can be
+// resumed by calling it again. A blob being upgraded fails to download until it is upgraded.
+// Once it completes, the fallback to the legacy chunks can be turned off.
+func (s *blobService) UpgradeBlobs(ctx context.Context, batchSize int) (int, error) {
+	var (
+		upgraded int
+		afterID  string
+	)
+
+	for {
+		blobs, err := s.repo.GetOutdatedBlobs(ctx, afterID, batchSize)
+		if err != nil {
+			s.log.Error().Err(err).Msg("failed to get outdated blobs")
+
+			return upgraded, err
+		}
+
+		if len(blobs) == 0 {
+			return upgraded, nil
+		}
+
+		for i := range blobs {
+			err := s.upgradeBlob(ctx, &blobs[i])
+			switch {
+			case errors.Is(err, ErrBlobCorrupted):
+				s.log.Error().Str("blob", blobs[i].ID).Msg("skipped corrupted blob")
+			case errors.Is(err, repository.ErrNoRows):
+				// The blob was removed in the meantime.
+			case err != nil:
+				return upgraded, err
+			default:
+				upgraded++
+			}
+		}
+
+		afterID = blobs[len(blobs)-1].ID
+	}
+}
+
+// upgradeBlob encrypts the legacy chunks of the blob again and stores the hash of its chunks.
+func (s *blobService) upgradeBlob(ctx context.Context, blob *repository.Blob) error {
+	var key []byte
+	if blob.LegacyChunks {
+		var err error
+
+		key, err = s.keys.GetUserKey(ctx, blob.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	hash := sha256.New()
+
+	for seq := 0; seq < blob.Chunks; seq++ {
+		data, err := s.store.GetChunk(ctx, blob.ID, seq)
+		if err != nil {
+			s.log.Error().Err(err).Str("blob", blob.ID).Int("seq", seq).Msg("failed to get blob chunk")
+
+			if errors.Is(err, blobstore.ErrNotFound) {
+				return ErrBlobCorrupted
+			}
+
+			return err
+		}
+
+		if key != nil {
+			if data, err = s.resealChunk(ctx, key, blob, seq, data); err != nil {
+				return err
+			}
+		}
+
+		hash.Write(data)
+	}
+
+	blob.Hash = hash.Sum(nil)
+
+	if err := s.repo.UpgradeBlob(ctx, blob); err != nil {
+		if !errors.Is(err, repository.ErrNoRows) {
+			s.log.Error().Err(err).Str("blob", blob.ID).Msg("failed to upgrade blob")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// resealChunk encrypts the legacy chunk of the blob with the given sequence number again with
+// the mark of the last chunk, stores it and returns the stored data. A chunk already encrypted
+// again is returned as is.
+func (s *blobService) resealChunk(
+	ctx context.Context,
+	key []byte,
+	blob *repository.Blob,
+	seq int,
+	data []byte,
+) ([]byte, error) {
+	last := seq == blob.Chunks-1
+
+	if _, err := s.crypt.Decrypt(key, data, blobAssociatedData(blob, seq, last)); err == nil {
+		return data, nil
+	}
+
+	chunk, err := s.crypt.Decrypt(key, data, legacyBlobAssociatedData(blob, seq))
+	if err != nil {
+		s.log.Error().Err(err).Str("blob", blob.ID).Int("seq", seq).Msg("failed to decrypt blob chunk")
+
+		return nil, ErrBlobCorrupted
+	}
+
+	encrypted, err := s.crypt.Encrypt(key, chunk, blobAssociatedData(blob, seq, last))
+	if err != nil {
+		s.log.Error().Err(err).Msg("failed to encrypt blob chunk")
+
+		return nil, err
+	}
+
+	if err := s.store.PutChunk(ctx, blob.ID, seq, encrypted); err != nil {
+		s.log.Error().Err(err).Msg("failed to store blob chunk")
+
+		return nil, err
+	}
+
+	return encrypted, nil
+}
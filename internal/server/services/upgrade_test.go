@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/PrahaTurbo/goph-keeper/internal/server/blobstore"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/mocks"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/models"
+	"github.com/PrahaTurbo/goph-keeper/internal/server/repository"
+	"github.com/PrahaTurbo/goph-keeper/pkg/logger"
+)
+
+func Test_secretService_UpgradeSecrets(t *testing.T) {
+	log := logger.NewLogger()
+
+	contentAD := []byte("user:1;secret:13;type:TEXT;field:content")
+	metaAD := []byte("user:1;secret:13;type:TEXT;field:meta_data")
+	credentialsAD := []byte("user:1;secret:14;type:CREDENTIALS;field:content")
+	credentials := payloadHeader + `{"login":"john","password":"password","url":"https://example.com"}`
+	indexKey := blindIndexKey(testKey)
+
+	tests := []struct {
+		expectedErr error
+		prepareRepo func(s *mocks.MockSecretRepository)
+		name        string
+		expected    int
+	}{
+		{
+			name: "success: legacy secret and version resealed",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("legacy"), MetaData: []byte("legacy-meta"), Version: 2},
+					}, nil).Times(1)
+				s.On("GetSealedSecrets", mock.Anything, 13, 2).Return(nil, nil).Times(1)
+				s.On("ResealSecret", mock.Anything, &repository.Secret{
+					ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("sealed"), MetaData: []byte("sealed-meta"), Version: 2,
+				}, []byte("legacy")).Return(nil).Times(1)
+				s.On("GetSealedVersions", mock.Anything, 13).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("legacy"), Version: 1},
+					}, nil).Times(1)
+				s.On("ResealVersion", mock.Anything, &repository.Secret{
+					ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("sealed"), Version: 1,
+				}, []byte("legacy")).Return(nil).Times(1)
+			},
+			expected: 2,
+		},
+		{
+			name: "success: current secret skipped",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("sealed"), Version: 2},
+					}, nil).Times(1)
+				s.On("GetSealedSecrets", mock.Anything, 13, 2).Return(nil, nil).Times(1)
+				s.On("GetSealedVersions", mock.Anything, 13).Return(nil, nil).Times(1)
+			},
+		},
+		{
+			name: "success: credentials indexed",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 14, UserID: 1, Type: models.SecretTypeCredentials, Content: []byte("sealed"), Version: 1},
+					}, nil).Times(1)
+				s.On("GetSealedSecrets", mock.Anything, 14, 2).Return(nil, nil).Times(1)
+				s.On("IndexSecret", mock.Anything, &repository.Secret{
+					ID: 14, UserID: 1, Type: models.SecretTypeCredentials, Content: []byte("sealed"), Version: 1,
+					Indexes: []repository.BlindIndex{
+						blindIndex(indexKey, indexFieldDomain, "example.com"),
+						blindIndex(indexKey, indexFieldLogin, "john"),
+					},
+				}).Return(nil).Times(1)
+				s.On("GetSealedVersions", mock.Anything, 14).Return(nil, nil).Times(1)
+			},
+			expected: 1,
+		},
+		{
+			name: "success: indexed credentials skipped",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 14, UserID: 1, Type: models.SecretTypeCredentials, Content: []byte("sealed"), Version: 1},
+					}, nil).Times(1)
+				s.On("GetSealedSecrets", mock.Anything, 14, 2).Return(nil, nil).Times(1)
+				s.On("IndexSecret", mock.Anything, mock.Anything).Return(repository.ErrNoRows).Times(1)
+				s.On("GetSealedVersions", mock.Anything, 14).Return(nil, nil).Times(1)
+			},
+		},
+		{
+			name: "error: failed to index credentials",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 14, UserID: 1, Type: models.SecretTypeCredentials, Content: []byte("sealed"), Version: 1},
+					}, nil).Times(1)
+				s.On("IndexSecret", mock.Anything, mock.Anything).Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "success: secret changed concurrently",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("legacy"), Version: 2},
+					}, nil).Times(1)
+				s.On("GetSealedSecrets", mock.Anything, 13, 2).Return(nil, nil).Times(1)
+				s.On("ResealSecret", mock.Anything, mock.Anything, []byte("legacy")).Return(repository.ErrNoRows).Times(1)
+				s.On("GetSealedVersions", mock.Anything, 13).Return(nil, nil).Times(1)
+			},
+		},
+		{
+			name: "error: failed to update secret",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).
+					Return([]repository.Secret{
+						{ID: 13, UserID: 1, Type: models.SecretTypeText, Content: []byte("legacy"), Version: 2},
+					}, nil).Times(1)
+				s.On("ResealSecret", mock.Anything, mock.Anything, []byte("legacy")).Return(errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+		{
+			name: "error: failed to get secrets",
+			prepareRepo: func(s *mocks.MockSecretRepository) {
+				s.On("GetSealedSecrets", mock.Anything, 0, 2).Return(nil, errInternal).Times(1)
+			},
+			expectedErr: errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockSecretRepository)
+			tt.prepareRepo(mockRepo)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Reseal", testKey, []byte("legacy"), contentAD).Return([]byte("sealed"), true, nil)
+			mockEncryption.On("Reseal", testKey, []byte("sealed"), contentAD).Return([]byte("sealed"), false, nil)
+			mockEncryption.On("Reseal", testKey, []byte("legacy-meta"), metaAD).Return([]byte("sealed-meta"), true, nil)
+			mockEncryption.On("Reseal", testKey, []byte("sealed"), credentialsAD).Return([]byte("sealed"), false, nil)
+			mockEncryption.On("Decrypt", testKey, []byte("sealed"), credentialsAD).Return(credentials, nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			secretService := NewSecretService(mockRepo, &log, mockEncryption, mockKeys, nil, HistoryRetention{}, 0)
+			upgraded, err := secretService.UpgradeSecrets(context.Background(), 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, upgraded)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_blobService_UpgradeBlobs(t *testing.T) {
+	log := logger.NewLogger()
+
+	tests := []struct {
+		expectedErr  error
+		prepareRepo  func(r *mocks.MockBlobRepository)
+		prepareStore func(s *mocks.MockBlobStore)
+		name         string
+		expected     int
+	}{
+		{
+			name: "success: legacy chunks encrypted again",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetOutdatedBlobs", mock.Anything, "", 2).
+					Return([]repository.Blob{
+						{ID: "blob", UserID: 1, Chunks: 2, Completed: true, LegacyChunks: true},
+					}, nil).Times(1)
+				r.On("GetOutdatedBlobs", mock.Anything, "blob", 2).Return(nil, nil).Times(1)
+				r.On("UpgradeBlob", mock.Anything, mock.MatchedBy(func(b *repository.Blob) bool {
+					return assert.ObjectsAreEqual(hashOf("sealed-first", "sealed-second"), b.Hash)
+				})).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("legacy-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("legacy-second"), nil).Times(1)
+				s.On("PutChunk", mock.Anything, "blob", 0, []byte("sealed-first")).Return(nil).Times(1)
+				s.On("PutChunk", mock.Anything, "blob", 1, []byte("sealed-second")).Return(nil).Times(1)
+			},
+			expected: 1,
+		},
+		{
+			name: "success: chunk encrypted again before is kept",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetOutdatedBlobs", mock.Anything, "", 2).
+					Return([]repository.Blob{
+						{ID: "blob", UserID: 1, Chunks: 2, Completed: true, LegacyChunks: true},
+					}, nil).Times(1)
+				r.On("GetOutdatedBlobs", mock.Anything, "blob", 2).Return(nil, nil).Times(1)
+				r.On("UpgradeBlob", mock.Anything, mock.MatchedBy(func(b *repository.Blob) bool {
+					return assert.ObjectsAreEqual(hashOf("sealed-first", "sealed-second"), b.Hash)
+				})).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("sealed-first"), nil).Times(1)
+				s.On("GetChunk", mock.Anything, "blob", 1).Return([]byte("legacy-second"), nil).Times(1)
+				s.On("PutChunk", mock.Anything, "blob", 1, []byte("sealed-second")).Return(nil).Times(1)
+			},
+			expected: 1,
+		},
+		{
+			name: "success: hash of blob stored",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetOutdatedBlobs", mock.Anything, "", 2).
+					Return([]repository.Blob{{ID: "blob", UserID: 1, Chunks: 1, Completed: true}}, nil).Times(1)
+				r.On("GetOutdatedBlobs", mock.Anything, "blob", 2).Return(nil, nil).Times(1)
+				r.On("UpgradeBlob", mock.Anything, mock.MatchedBy(func(b *repository.Blob) bool {
+					return assert.ObjectsAreEqual(hashOf("client-sealed"), b.Hash)
+				})).Return(nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return([]byte("client-sealed"), nil).Times(1)
+			},
+			expected: 1,
+		},
+		{
+			name: "success: corrupted blob skipped",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetOutdatedBlobs", mock.Anything, "", 2).
+					Return([]repository.Blob{
+						{ID: "blob", UserID: 1, Chunks: 2, Completed: true, LegacyChunks: true},
+					}, nil).Times(1)
+				r.On("GetOutdatedBlobs", mock.Anything, "blob", 2).Return(nil, nil).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {
+				s.On("GetChunk", mock.Anything, "blob", 0).Return(nil, blobstore.ErrNotFound).Times(1)
+			},
+		},
+		{
+			name: "error: failed to get blobs",
+			prepareRepo: func(r *mocks.MockBlobRepository) {
+				r.On("GetOutdatedBlobs", mock.Anything, "", 2).Return(nil, errInternal).Times(1)
+			},
+			prepareStore: func(s *mocks.MockBlobStore) {},
+			expectedErr:  errInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mocks.MockBlobRepository)
+			mockStore := new(mocks.MockBlobStore)
+			tt.prepareRepo(mockRepo)
+			tt.prepareStore(mockStore)
+
+			mockEncryption := new(mocks.MockEncryption)
+			mockEncryption.On("Decrypt", testKey, []byte("sealed-first"), []byte("user:1;blob:blob;chunk:0;last:false")).
+				Return("first", nil)
+			mockEncryption.On("Decrypt", testKey, []byte("legacy-first"), []byte("user:1;blob:blob;chunk:0")).
+				Return("first", nil)
+			mockEncryption.On("Decrypt", testKey, []byte("legacy-second"), []byte("user:1;blob:blob;chunk:1")).
+				Return("second", nil)
+			mockEncryption.On("Decrypt", testKey, mock.Anything, mock.Anything).Return("", errInternal)
+			mockEncryption.On("Encrypt", testKey, "first", []byte("user:1;blob:blob;chunk:0;last:false")).
+				Return([]byte("sealed-first"), nil)
+			mockEncryption.On("Encrypt", testKey, "second", []byte("user:1;blob:blob;chunk:1;last:true")).
+				Return([]byte("sealed-second"), nil)
+
+			mockKeys := new(mocks.MockKeyService)
+			mockKeys.On("GetUserKey", mock.Anything, 1).Return(testKey, nil)
+
+			blobService := NewBlobService(mockRepo, mockStore, &log, mockEncryption, mockKeys, 20, false)
+			upgraded, err := blobService.UpgradeBlobs(context.Background(), 2)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, upgraded)
+			mockRepo.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
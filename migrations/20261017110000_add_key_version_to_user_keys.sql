@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user_keys ADD COLUMN key_version INT NOT NULL DEFAULT 1;
+
+CREATE INDEX idx_user_keys_key_version ON user_keys (key_version);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_user_keys_key_version;
+
+ALTER TABLE user_keys DROP COLUMN key_version;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The revision of a user is incremented on every change of their secrets. The changed secret
+-- or the tombstone of the deleted secret gets the new revision, so clients can fetch
+-- only the changes made since the revision they have seen.
+ALTER TABLE users
+    ADD COLUMN revision BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE secrets
+    ADD COLUMN revision BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE secrets
+    ALTER COLUMN revision DROP DEFAULT;
+
+CREATE INDEX idx_secrets_user_id_revision ON secrets (user_id, revision);
+
+CREATE TABLE IF NOT EXISTS secret_tombstones (
+    secret_id INT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    revision BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_secret_tombstones_user_id_revision ON secret_tombstones (user_id, revision);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE secret_tombstones;
+
+ALTER TABLE secrets
+    DROP COLUMN revision;
+
+ALTER TABLE users
+    DROP COLUMN revision;
+-- +goose StatementEnd
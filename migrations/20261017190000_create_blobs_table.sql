@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Files of binary secrets are stored as blobs split into chunks, outside of the secrets table.
+-- A blob is uploaded first and becomes complete once all its chunks are stored, after that
+-- it can be attached to a single secret of its owner.
+CREATE TABLE IF NOT EXISTS blobs (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    size BIGINT NOT NULL DEFAULT 0,
+    chunks INT NOT NULL DEFAULT 0,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_blobs_user_id ON blobs (user_id);
+
+CREATE TABLE IF NOT EXISTS blob_chunks (
+    blob_id VARCHAR(64) NOT NULL REFERENCES blobs (id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (blob_id, seq)
+);
+
+ALTER TABLE secrets
+    ADD COLUMN blob_id VARCHAR(64) UNIQUE REFERENCES blobs (id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE secrets
+    DROP COLUMN blob_id;
+
+DROP TABLE blob_chunks;
+
+DROP TABLE blobs;
+-- +goose StatementEnd
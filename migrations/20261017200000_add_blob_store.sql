@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The chunks of blobs are kept in a pluggable blob store, the table only holds their
+-- metadata and the hash of the stored content. Deleting a blob by any means, including
+-- the removal of its owner, queues its chunks for removal from the store.
+ALTER TABLE blobs
+    ADD COLUMN hash BYTEA;
+
+CREATE TABLE IF NOT EXISTS deleted_blobs (
+    blob_id VARCHAR(64) PRIMARY KEY,
+    deleted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE FUNCTION queue_blob_deletion() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO deleted_blobs (blob_id) VALUES (OLD.id) ON CONFLICT DO NOTHING;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER blobs_queue_deletion
+    AFTER DELETE ON blobs
+    FOR EACH ROW EXECUTE FUNCTION queue_blob_deletion();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER blobs_queue_deletion ON blobs;
+
+DROP FUNCTION queue_blob_deletion();
+
+DROP TABLE deleted_blobs;
+
+ALTER TABLE blobs
+    DROP COLUMN hash;
+-- +goose StatementEnd
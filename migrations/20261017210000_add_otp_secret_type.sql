@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE secret_type ADD VALUE IF NOT EXISTS 'OTP';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- An enum value cannot be dropped, so the type is recreated without it. The OTP secrets
+-- are kept as text ones, whose content stays readable.
+UPDATE secrets SET type = 'TEXT' WHERE type = 'OTP';
+
+ALTER TYPE secret_type RENAME TO secret_type_old;
+
+CREATE TYPE secret_type AS ENUM (
+    'CREDENTIALS',
+    'TEXT',
+    'BINARY',
+    'CARD'
+);
+
+ALTER TABLE secrets
+    ALTER COLUMN type TYPE secret_type USING type::TEXT::secret_type;
+
+DROP TYPE secret_type_old;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The labels are stored unencrypted, so secrets can be listed and filtered by them.
+ALTER TABLE secrets
+    ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN folder VARCHAR(255) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE secrets
+    DROP COLUMN name,
+    DROP COLUMN tags,
+    DROP COLUMN folder;
+-- +goose StatementEnd
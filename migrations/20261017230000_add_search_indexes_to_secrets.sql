@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE secrets
+    ADD COLUMN updated_at TIMESTAMP;
+
+UPDATE secrets SET updated_at = created_at;
+
+ALTER TABLE secrets
+    ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN updated_at SET NOT NULL;
+
+-- Secrets are listed by pages ordered by creation, filtered by their labels.
+CREATE INDEX idx_secrets_user_id_created_at ON secrets (user_id, created_at, id);
+
+CREATE INDEX idx_secrets_user_id_folder ON secrets (user_id, folder);
+
+CREATE INDEX idx_secrets_tags ON secrets USING GIN (tags);
+
+-- Trigram index for the search of names by substring.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_secrets_name_trgm ON secrets USING GIN (name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_secrets_name_trgm;
+
+DROP INDEX idx_secrets_tags;
+
+DROP INDEX idx_secrets_user_id_folder;
+
+DROP INDEX idx_secrets_user_id_created_at;
+
+ALTER TABLE secrets
+    DROP COLUMN updated_at;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Blind indexes are keyed hashes of encrypted fields of secrets, such as the domain and
+-- the login of credentials. They allow exact-match lookups without decrypting the secrets.
+CREATE TABLE IF NOT EXISTS secret_indexes (
+    secret_id INT NOT NULL REFERENCES secrets (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    field VARCHAR(32) NOT NULL,
+    value BYTEA NOT NULL,
+    PRIMARY KEY (secret_id, field)
+);
+
+CREATE INDEX idx_secret_indexes_user_id_field_value ON secret_indexes (user_id, field, value);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE secret_indexes;
+-- +goose StatementEnd
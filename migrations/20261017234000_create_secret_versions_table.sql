@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every update of a secret keeps the replaced version here, still encrypted, so it can be
+-- restored. A blob of a kept version stays attached to it until the version is removed.
+CREATE TABLE IF NOT EXISTS secret_versions (
+    secret_id INT NOT NULL REFERENCES secrets (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    version INT NOT NULL,
+    type secret_type NOT NULL,
+    content BYTEA NOT NULL,
+    meta_data BYTEA,
+    blob_id VARCHAR(64) REFERENCES blobs (id),
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    folder VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (secret_id, version)
+);
+
+CREATE INDEX idx_secret_versions_archived_at ON secret_versions (archived_at);
+
+CREATE INDEX idx_secret_versions_blob_id ON secret_versions (blob_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE secret_versions;
+-- +goose StatementEnd
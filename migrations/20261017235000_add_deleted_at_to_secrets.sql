@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Deleted secrets are moved to the trash first: they get the time of the deletion and a tombstone,
+-- so other devices remove them, but stay stored until they are restored or purged.
+ALTER TABLE secrets
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_secrets_deleted_at ON secrets (deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM secrets WHERE deleted_at IS NOT NULL;
+
+DROP INDEX idx_secrets_deleted_at;
+
+ALTER TABLE secrets
+    DROP COLUMN deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Shares grant other users access to secrets. Every share holds a copy of the content
+-- and the meta data of the secret encrypted with the key of the user it is granted to,
+-- which is replaced on every write of the secret.
+CREATE TABLE IF NOT EXISTS secret_shares (
+    secret_id INT NOT NULL REFERENCES secrets (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    permission VARCHAR(16) NOT NULL CHECK (permission IN ('READ_ONLY', 'READ_WRITE')),
+    content BYTEA NOT NULL,
+    meta_data BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (secret_id, user_id)
+);
+
+CREATE INDEX idx_secret_shares_user_id ON secret_shares (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE secret_shares;
+-- +goose StatementEnd
@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Organizations own the secrets of their collections. The secrets of an organization are
+-- encrypted with its own data encryption key, which is wrapped by the master key like
+-- the keys of users, so all members can read them.
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    wrapped_key BYTEA NOT NULL,
+    key_version INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_organizations_key_version ON organizations (key_version);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id INT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('OWNER', 'ADMIN', 'MEMBER', 'READ_ONLY')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members (user_id);
+
+-- Invites become memberships with their role once the invited users accept them.
+CREATE TABLE IF NOT EXISTS organization_invites (
+    organization_id INT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    invited_by INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL CHECK (role IN ('OWNER', 'ADMIN', 'MEMBER', 'READ_ONLY')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_invites_user_id ON organization_invites (user_id);
+
+CREATE TABLE IF NOT EXISTS collections (
+    id SERIAL PRIMARY KEY,
+    organization_id INT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, name)
+);
+
+-- A secret belongs either to a user or to a collection of an organization.
+ALTER TABLE secrets
+    ALTER COLUMN user_id DROP NOT NULL,
+    ADD COLUMN collection_id INT REFERENCES collections (id) ON DELETE CASCADE,
+    ADD CONSTRAINT secrets_owner_check CHECK ((user_id IS NULL) <> (collection_id IS NULL));
+
+CREATE INDEX idx_secrets_collection_id ON secrets (collection_id) WHERE collection_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM secrets WHERE collection_id IS NOT NULL;
+
+DROP INDEX idx_secrets_collection_id;
+
+ALTER TABLE secrets
+    DROP CONSTRAINT secrets_owner_check,
+    DROP COLUMN collection_id,
+    ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE collections;
+DROP TABLE organization_invites;
+DROP TABLE organization_members;
+DROP TABLE organizations;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Reservations hold the IDs of secrets reserved by the clients with client-side encryption,
+-- which bind the ciphertexts of a new secret to its ID before they send it.
+CREATE TABLE IF NOT EXISTS secret_reservations (
+    id INT PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_secret_reservations_created_at ON secret_reservations (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE secret_reservations;
+-- +goose StatementEnd
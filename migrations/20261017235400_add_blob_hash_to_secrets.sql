@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A secret keeps the hash of the content of its blob next to the reference to the blob,
+-- so the stored chunks are checked against the content the secret was written with.
+-- The hash of a blob is copied to the secret when the blob is attached to it.
+ALTER TABLE secrets
+    ADD COLUMN blob_hash BYTEA;
+
+ALTER TABLE secret_versions
+    ADD COLUMN blob_hash BYTEA;
+
+UPDATE secrets
+SET blob_hash = blobs.hash
+FROM blobs
+WHERE secrets.blob_id = blobs.id;
+
+UPDATE secret_versions
+SET blob_hash = blobs.hash
+FROM blobs
+WHERE secret_versions.blob_id = blobs.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE secret_versions
+    DROP COLUMN blob_hash;
+
+ALTER TABLE secrets
+    DROP COLUMN blob_hash;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The users a secret is shared with have revisions of their own. Every change of the secret
+-- gets a new revision of each of them, kept here, so the secret is synced to their devices
+-- like their own secrets. A user the secret is not available to anymore gets a tombstone.
+CREATE TABLE IF NOT EXISTS secret_revisions (
+    secret_id INT NOT NULL REFERENCES secrets (id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    revision BIGINT NOT NULL,
+    PRIMARY KEY (secret_id, user_id)
+);
+
+CREATE INDEX idx_secret_revisions_user_id_revision ON secret_revisions (user_id, revision);
+
+UPDATE users
+SET revision = revision + 1
+WHERE id IN (SELECT user_id FROM secret_shares);
+
+INSERT INTO secret_revisions (secret_id, user_id, revision)
+SELECT sh.secret_id, sh.user_id, u.revision
+FROM secret_shares sh
+JOIN users u ON u.id = sh.user_id;
+
+ALTER TABLE secret_tombstones
+    DROP CONSTRAINT secret_tombstones_pkey,
+    ADD PRIMARY KEY (secret_id, user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM secret_tombstones t
+USING secrets s
+WHERE s.id = t.secret_id AND s.user_id IS DISTINCT FROM t.user_id;
+
+DELETE FROM secret_tombstones t
+USING secret_tombstones o
+WHERE o.secret_id = t.secret_id AND o.user_id < t.user_id;
+
+ALTER TABLE secret_tombstones
+    DROP CONSTRAINT secret_tombstones_pkey,
+    ADD PRIMARY KEY (secret_id);
+
+DROP TABLE secret_revisions;
+-- +goose StatementEnd
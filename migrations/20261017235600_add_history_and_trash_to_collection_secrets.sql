@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The secrets of collections belong to no user, so every member of the organization owning
+-- a collection gets revisions of its secrets in secret_revisions, like the users a secret
+-- is shared with. Their replaced versions are kept and deleted secrets are moved to the trash
+-- like the secrets of users, the versions of the secrets of collections belong to no user either.
+ALTER TABLE secret_versions
+    ALTER COLUMN user_id DROP NOT NULL;
+
+UPDATE users
+SET revision = revision + 1
+WHERE id IN (
+    SELECT m.user_id
+    FROM organization_members m
+    JOIN collections c ON c.organization_id = m.organization_id
+    JOIN secrets s ON s.collection_id = c.id
+);
+
+INSERT INTO secret_revisions (secret_id, user_id, revision)
+SELECT s.id, m.user_id, u.revision
+FROM secrets s
+JOIN collections c ON c.id = s.collection_id
+JOIN organization_members m ON m.organization_id = c.organization_id
+JOIN users u ON u.id = m.user_id
+WHERE s.deleted_at IS NULL
+ON CONFLICT (secret_id, user_id) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM secrets WHERE collection_id IS NOT NULL AND deleted_at IS NOT NULL;
+
+DELETE FROM secret_revisions r
+USING secrets s
+WHERE s.id = r.secret_id AND s.collection_id IS NOT NULL;
+
+DELETE FROM secret_versions WHERE user_id IS NULL;
+
+ALTER TABLE secret_versions
+    ALTER COLUMN user_id SET NOT NULL;
+-- +goose StatementEnd
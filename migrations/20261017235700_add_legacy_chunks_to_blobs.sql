@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The chunks encrypted by the server are bound to their position and the last chunk of a blob
+-- is marked, so a blob cannot be truncated or extended unnoticed. The blobs encrypted before
+-- are flagged until the upgrade-secrets command encrypts their chunks again and stores
+-- the hashes of the blobs uploaded before the hashes were kept.
+ALTER TABLE blobs
+    ADD COLUMN legacy_chunks BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE blobs
+SET legacy_chunks = TRUE
+FROM users
+WHERE users.id = blobs.user_id AND NOT users.client_side_encryption;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE blobs
+    DROP COLUMN legacy_chunks;
+-- +goose StatementEnd
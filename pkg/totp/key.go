@@ -0,0 +1,148 @@
+package totp
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of one-time passwords of an otpauth URI.
+const (
+	TypeTOTP = "totp"
+	TypeHOTP = "hotp"
+)
+
+// ErrInvalidURI is returned when an otpauth URI is malformed or has unsupported parameters.
+var ErrInvalidURI = errors.New("otpauth uri is invalid")
+
+var algorithms = map[string]func() hash.Hash{
+	"SHA1":   sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+// Key holds the parameters of a one-time password generator imported from an otpauth URI.
+// Period is used by time-based keys, Counter by counter-based ones.
+type Key struct {
+	Type      string
+	Issuer    string
+	Account   string
+	Secret    string
+	Algorithm string
+	Period    time.Duration
+	Counter   int64
+	Digits    int
+}
+
+// ParseURI parses an otpauth URI in the format used by authenticator applications:
+//
+//	otpauth://totp/Issuer:account?secret=BASE32&issuer=Issuer&algorithm=SHA1&digits=6&period=30
+//
+// Missing parameters take the defaults of the format: SHA1, 6 digits and 30 seconds.
+// Counter-based keys use the hotp type and the counter parameter instead of period.
+func ParseURI(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth" {
+		return nil, ErrInvalidURI
+	}
+
+	key := &Key{
+		Type:      strings.ToLower(u.Host),
+		Algorithm: "SHA1",
+		Digits:    Digits,
+		Period:    Period,
+	}
+
+	if key.Type != TypeTOTP && key.Type != TypeHOTP {
+		return nil, ErrInvalidURI
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		key.Issuer, key.Account = issuer, strings.TrimSpace(account)
+	} else {
+		key.Account = label
+	}
+
+	params := u.Query()
+
+	key.Secret = params.Get("secret")
+	if _, err := decodeSecret(key.Secret); err != nil {
+		return nil, ErrInvalidURI
+	}
+
+	if issuer := params.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+
+	if algorithm := params.Get("algorithm"); algorithm != "" {
+		key.Algorithm = strings.ToUpper(algorithm)
+		if _, ok := algorithms[key.Algorithm]; !ok {
+			return nil, ErrInvalidURI
+		}
+	}
+
+	if digits := params.Get("digits"); digits != "" {
+		key.Digits, err = strconv.Atoi(digits)
+		if err != nil || key.Digits < 6 || key.Digits > 8 {
+			return nil, ErrInvalidURI
+		}
+	}
+
+	if period := params.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return nil, ErrInvalidURI
+		}
+
+		key.Period = time.Duration(seconds) * time.Second
+	}
+
+	if counter := params.Get("counter"); counter != "" {
+		key.Counter, err = strconv.ParseInt(counter, 10, 64)
+		if err != nil || key.Counter < 0 {
+			return nil, ErrInvalidURI
+		}
+	}
+
+	return key, nil
+}
+
+// Code returns the code of the key for the given time. Counter-based keys
+// ignore the time and return the code of their counter.
+func (k *Key) Code(t time.Time) (string, error) {
+	secret, err := decodeSecret(k.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	newHash, ok := algorithms[k.Algorithm]
+	if !ok {
+		return "", ErrInvalidURI
+	}
+
+	c := k.Counter
+	if k.Type == TypeTOTP {
+		c = t.Unix() / int64(k.Period/time.Second)
+	}
+
+	return generate(newHash, secret, c, k.Digits), nil
+}
+
+// Remaining returns the period of time the code of the given time stays valid.
+// It is zero for counter-based keys, whose codes do not expire.
+func (k *Key) Remaining(t time.Time) time.Duration {
+	if k.Type != TypeTOTP {
+		return 0
+	}
+
+	period := int64(k.Period / time.Second)
+
+	return time.Duration(period-t.Unix()%period) * time.Second
+}
@@ -0,0 +1,127 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		expectedErr error
+		expected    *Key
+		name        string
+		uri         string
+	}{
+		{
+			name: "defaults",
+			uri:  "otpauth://totp/GitHub:john?secret=JBSWY3DPEHPK3PXP",
+			expected: &Key{
+				Type:      TypeTOTP,
+				Issuer:    "GitHub",
+				Account:   "john",
+				Secret:    "JBSWY3DPEHPK3PXP",
+				Algorithm: "SHA1",
+				Digits:    6,
+				Period:    30 * time.Second,
+			},
+		},
+		{
+			name: "all parameters",
+			uri:  "otpauth://totp/ACME%20Co:john@example.com?secret=JBSWY3DPEHPK3PXP&issuer=ACME&algorithm=sha256&digits=8&period=60",
+			expected: &Key{
+				Type:      TypeTOTP,
+				Issuer:    "ACME",
+				Account:   "john@example.com",
+				Secret:    "JBSWY3DPEHPK3PXP",
+				Algorithm: "SHA256",
+				Digits:    8,
+				Period:    60 * time.Second,
+			},
+		},
+		{
+			name: "counter-based",
+			uri:  "otpauth://hotp/john?secret=JBSWY3DPEHPK3PXP&counter=5",
+			expected: &Key{
+				Type:      TypeHOTP,
+				Account:   "john",
+				Secret:    "JBSWY3DPEHPK3PXP",
+				Algorithm: "SHA1",
+				Digits:    6,
+				Period:    30 * time.Second,
+				Counter:   5,
+			},
+		},
+		{name: "wrong scheme", uri: "https://totp/john?secret=JBSWY3DPEHPK3PXP", expectedErr: ErrInvalidURI},
+		{name: "unknown type", uri: "otpauth://motp/john?secret=JBSWY3DPEHPK3PXP", expectedErr: ErrInvalidURI},
+		{name: "missing secret", uri: "otpauth://totp/john", expectedErr: ErrInvalidURI},
+		{name: "invalid secret", uri: "otpauth://totp/john?secret=not-base32", expectedErr: ErrInvalidURI},
+		{name: "unknown algorithm", uri: "otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&algorithm=MD5", expectedErr: ErrInvalidURI},
+		{name: "too many digits", uri: "otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&digits=10", expectedErr: ErrInvalidURI},
+		{name: "invalid period", uri: "otpauth://totp/john?secret=JBSWY3DPEHPK3PXP&period=0", expectedErr: ErrInvalidURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseURI(tt.uri)
+
+			assert.Equal(t, tt.expectedErr, err)
+			assert.Equal(t, tt.expected, key)
+		})
+	}
+}
+
+func TestKey_Code(t *testing.T) {
+	encode := base32.StdEncoding.EncodeToString
+
+	tests := []struct {
+		key      *Key
+		name     string
+		expected string
+		unix     int64
+	}{
+		{
+			name:     "SHA1",
+			key:      &Key{Type: TypeTOTP, Secret: encode([]byte("12345678901234567890")), Algorithm: "SHA1", Digits: 8, Period: Period},
+			unix:     59,
+			expected: "94287082",
+		},
+		{
+			name: "SHA256",
+			key: &Key{Type: TypeTOTP, Secret: encode([]byte("12345678901234567890123456789012")),
+				Algorithm: "SHA256", Digits: 8, Period: Period},
+			unix:     1111111109,
+			expected: "68084774",
+		},
+		{
+			name: "SHA512",
+			key: &Key{Type: TypeTOTP, Secret: encode([]byte("1234567890123456789012345678901234567890123456789012345678901234")),
+				Algorithm: "SHA512", Digits: 8, Period: Period},
+			unix:     2000000000,
+			expected: "38618901",
+		},
+		{
+			name:     "counter-based",
+			key:      &Key{Type: TypeHOTP, Secret: rfcSecret, Algorithm: "SHA1", Digits: 6, Counter: 1},
+			unix:     2000000000,
+			expected: "287082",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := tt.key.Code(time.Unix(tt.unix, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestKey_Remaining(t *testing.T) {
+	key := &Key{Type: TypeTOTP, Period: Period}
+	assert.Equal(t, 30*time.Second, key.Remaining(time.Unix(60, 0)))
+	assert.Equal(t, time.Second, key.Remaining(time.Unix(89, 0)))
+
+	key = &Key{Type: TypeHOTP, Period: Period}
+	assert.Zero(t, key.Remaining(time.Unix(89, 0)))
+}
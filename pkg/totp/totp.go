@@ -0,0 +1,123 @@
+// Package totp implements time-based one-time passwords as defined in RFC 6238,
+// compatible with common authenticator applications. It also generates the time-based
+// and counter-based (RFC 4226) passwords of keys imported from otpauth URIs.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the number of digits of a generated code.
+	Digits = 6
+	// Period is the time step during which a code is valid.
+	Period = 30 * time.Second
+
+	secretSize = 20
+	// skew is the number of time steps before and after the current one whose codes are accepted,
+	// so clock drift between the server and the authenticator does not break the login.
+	skew = 1
+)
+
+// ErrInvalidSecret is returned when the secret is not a valid base32 string.
+var ErrInvalidSecret = errors.New("totp secret is invalid")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random secret encoded with base32.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Code returns the code of the secret for the given time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	return generate(sha1.New, key, counter(t), Digits), nil
+}
+
+// Validate checks the code against the secret for the given time, tolerating a clock drift
+// of one time step. It returns the time step counter of the matched code, which can be
+// used to reject a second use of the same code.
+func Validate(secret string, passcode string, t time.Time) (int64, bool) {
+	key, err := decodeSecret(secret)
+	if err != nil || len(passcode) != Digits {
+		return 0, false
+	}
+
+	current := counter(t)
+	for c := current - skew; c <= current+skew; c++ {
+		if subtle.ConstantTimeCompare([]byte(generate(sha1.New, key, c, Digits)), []byte(passcode)) == 1 {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth URI of the secret, which authenticator applications
+// import from a QR code or a link.
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, params.Encode())
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+
+	key, err := encoding.DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	return key, nil
+}
+
+func counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// generate implements the HOTP algorithm from RFC 4226 with the given hash function.
+// HMAC-SHA1 is the only algorithm supported by most authenticator applications.
+func generate(newHash func() hash.Hash, key []byte, counter int64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+
+	mac := hmac.New(newHash, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%modulo)
+}